@@ -0,0 +1,40 @@
+// Command server runs the router, HTTP API and wire protocol front end
+// without the desktop shell.
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"sql-sharding-v2/internal/app"
+	"sql-sharding-v2/pkg/logger"
+)
+
+const shutdownTimeout = 30 * time.Second
+
+func main() {
+
+	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	server := app.New()
+
+	if err := server.Start(context.Background(), logger.NewSlogSink()); err != nil {
+		logger.Logger.Error("server startup failed", "error", err)
+		os.Exit(1)
+	}
+
+	<-signalCtx.Done()
+	logger.Logger.Info("shutdown signal received")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Logger.Error("server shutdown failed", "error", err)
+		os.Exit(1)
+	}
+}
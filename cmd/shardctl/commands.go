@@ -0,0 +1,1101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"sql-sharding-v2/internal/app"
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/reshard"
+	"sql-sharding-v2/internal/shardkey"
+	"sql-sharding-v2/internal/shardstats"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+)
+
+// runFunc executes a parsed command with its positional arguments
+type runFunc func(a *app.App, args []string) (*result, error)
+
+type command struct {
+	resource string
+	action   string
+	summary  string
+	setup    func(fs *flag.FlagSet) runFunc
+}
+
+func (c command) name() string {
+	return c.resource + " " + c.action
+}
+
+var commands = []command{
+
+	// projects
+	{"project", "list", "list projects", func(fs *flag.FlagSet) runFunc {
+		return func(a *app.App, _ []string) (*result, error) {
+			projects, err := a.ListProjects()
+			if err != nil {
+				return nil, err
+			}
+			return projectsResult(projects), nil
+		}
+	}},
+	{"project", "get", "show a project: get <project-id>", func(fs *flag.FlagSet) runFunc {
+		return func(a *app.App, args []string) (*result, error) {
+			id, err := arg(args, "project-id")
+			if err != nil {
+				return nil, err
+			}
+			project, err := a.FetchProjectByID(id)
+			if err != nil {
+				return nil, err
+			}
+			return projectsResult([]repository.Project{project}), nil
+		}
+	}},
+	{"project", "create", "create a project: -name <name> [-description <text>]", func(fs *flag.FlagSet) runFunc {
+		name := fs.String("name", "", "project name")
+		description := fs.String("description", "", "project description")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("name", *name); err != nil {
+				return nil, err
+			}
+			project, err := a.CreateProject(*name, *description)
+			if err != nil {
+				return nil, err
+			}
+			return projectsResult([]repository.Project{*project}), nil
+		}
+	}},
+	{"project", "delete", "delete a project: delete <project-id>", func(fs *flag.FlagSet) runFunc {
+		return idAction("project-id", "project deleted", func(a *app.App, id string) error {
+			return a.DeleteProject(id)
+		})
+	}},
+	{"project", "activate", "activate a project: activate <project-id>", func(fs *flag.FlagSet) runFunc {
+		return idAction("project-id", "project activated", func(a *app.App, id string) error {
+			return a.Activateproject(id)
+		})
+	}},
+	{"project", "deactivate", "deactivate a project: deactivate <project-id>", func(fs *flag.FlagSet) runFunc {
+		return idAction("project-id", "project deactivated", func(a *app.App, id string) error {
+			return a.Deactivateproject(id)
+		})
+	}},
+
+	// shards
+	{"shard", "list", "list shards: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			shards, err := a.ListShards(*project)
+			if err != nil {
+				return nil, err
+			}
+			return shardsResult(shards), nil
+		}
+	}},
+	{"shard", "add", "add a shard: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			shard, err := a.AddShard(*project)
+			if err != nil {
+				return nil, err
+			}
+			return shardsResult([]repository.Shard{*shard}), nil
+		}
+	}},
+	{"shard", "activate", "activate a shard: activate <shard-id>", func(fs *flag.FlagSet) runFunc {
+		return idAction("shard-id", "shard activated", func(a *app.App, id string) error {
+			return a.ActivateShard(id)
+		})
+	}},
+	{"shard", "deactivate", "deactivate a shard: deactivate <shard-id>", func(fs *flag.FlagSet) runFunc {
+		return idAction("shard-id", "shard deactivated", func(a *app.App, id string) error {
+			return a.DeactivateShard(id)
+		})
+	}},
+	{"shard", "drain", "move every hash slot of a shard to the other active shards and wait: drain <shard-id>", func(fs *flag.FlagSet) runFunc {
+		return func(a *app.App, args []string) (*result, error) {
+			id, err := arg(args, "shard-id")
+			if err != nil {
+				return nil, err
+			}
+			job, err := a.DrainShard(id)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := waitReshardJob(a, job.ProjectID, job.ID); err != nil {
+				return nil, err
+			}
+			report, err := a.GetShardDrain(id)
+			if err != nil {
+				return nil, err
+			}
+			return drainResult(report), nil
+		}
+	}},
+	{"shard", "drain-status", "show the slots a shard still owns and its drain progress: drain-status <shard-id>", func(fs *flag.FlagSet) runFunc {
+		return func(a *app.App, args []string) (*result, error) {
+			id, err := arg(args, "shard-id")
+			if err != nil {
+				return nil, err
+			}
+			report, err := a.GetShardDrain(id)
+			if err != nil {
+				return nil, err
+			}
+			return drainResult(report), nil
+		}
+	}},
+	{"shard", "delete", "delete an inactive or drained shard owning no hash slots: delete <shard-id>", func(fs *flag.FlagSet) runFunc {
+		return idAction("shard-id", "shard deleted", func(a *app.App, id string) error {
+			status, err := a.DeleteShard(id)
+			if err != nil {
+				return err
+			}
+			if status != "DELETED" {
+				return fmt.Errorf("shard not deleted: %s", status)
+			}
+			return nil
+		})
+	}},
+
+	// shard connections
+	{"connection", "get", "show shard connection: get <shard-id>", func(fs *flag.FlagSet) runFunc {
+		return func(a *app.App, args []string) (*result, error) {
+			id, err := arg(args, "shard-id")
+			if err != nil {
+				return nil, err
+			}
+			conn, err := a.FetchConnectionInfo(id)
+			if err != nil {
+				return nil, err
+			}
+			return connectionResult(conn), nil
+		}
+	}},
+	{"connection", "add", "add shard connection: -shard -host -port -database -user -password", func(fs *flag.FlagSet) runFunc {
+		conn := connectionFlags(fs)
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := conn.validate(); err != nil {
+				return nil, err
+			}
+			info := conn.toConnection()
+			if err := a.AddConnection(&info); err != nil {
+				return nil, err
+			}
+			return messageResult("connection added", info.ShardID), nil
+		}
+	}},
+	{"connection", "update", "update shard connection: -shard -host -port -database -user -password", func(fs *flag.FlagSet) runFunc {
+		conn := connectionFlags(fs)
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := conn.validate(); err != nil {
+				return nil, err
+			}
+			info := conn.toConnection()
+			if err := a.UpdateConnection(info); err != nil {
+				return nil, err
+			}
+			return messageResult("connection updated", info.ShardID), nil
+		}
+	}},
+
+	// schemas
+	{"schema", "history", "list schema versions: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			history, err := a.GetSchemaHistory(*project)
+			if err != nil {
+				return nil, err
+			}
+			return schemasResult(history), nil
+		}
+	}},
+	{"schema", "draft", "create a schema draft: -project <id> (-file <ddl.sql> | -sql <ddl>)", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		ddl := ddlFlags(fs)
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			sqlText, err := ddl.read()
+			if err != nil {
+				return nil, err
+			}
+			draft, err := a.CreateSchemaDraft(*project, sqlText)
+			if err != nil {
+				return nil, err
+			}
+			return schemasResult([]repository.ProjectSchema{*draft}), nil
+		}
+	}},
+	{"schema", "update", "update a schema draft: -project <id> -schema <id> (-file | -sql)", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		schemaID := fs.String("schema", "", "schema id")
+		ddl := ddlFlags(fs)
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := required("schema", *schemaID); err != nil {
+				return nil, err
+			}
+			sqlText, err := ddl.read()
+			if err != nil {
+				return nil, err
+			}
+			if err := a.UpdateProjectSchemaDraft(*project, *schemaID, sqlText); err != nil {
+				return nil, err
+			}
+			return messageResult("schema draft updated", *schemaID), nil
+		}
+	}},
+	{"schema", "commit", "commit a schema draft: -project <id> -schema <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		schemaID := fs.String("schema", "", "schema id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := required("schema", *schemaID); err != nil {
+				return nil, err
+			}
+			if err := a.CommitSchemaDraft(*project, *schemaID); err != nil {
+				return nil, err
+			}
+			return messageResult("schema committed", *schemaID), nil
+		}
+	}},
+	{"schema", "execute", "execute the pending schema on all shards: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := a.ExecuteProjectSchema(*project); err != nil {
+				return nil, err
+			}
+			return messageResult("schema executed", *project), nil
+		}
+	}},
+	{"schema", "retry", "retry a failed schema execution: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := a.RetrySchemaExecution(*project); err != nil {
+				return nil, err
+			}
+			return messageResult("schema execution retried", *project), nil
+		}
+	}},
+	{"schema", "status", "show per-shard execution status: status <schema-id>", func(fs *flag.FlagSet) runFunc {
+		return func(a *app.App, args []string) (*result, error) {
+			id, err := arg(args, "schema-id")
+			if err != nil {
+				return nil, err
+			}
+			statuses, err := a.GetSchemaExecutionStatus(id)
+			if err != nil {
+				return nil, err
+			}
+			return executionStatusResult(statuses), nil
+		}
+	}},
+
+	// shard keys
+	{"keys", "list", "list shard keys: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			keys, err := a.FetchShardKeys(*project)
+			if err != nil {
+				return nil, err
+			}
+			return shardKeysResult(keys), nil
+		}
+	}},
+	{"keys", "recompute", "rerun shard key inference: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := a.RecomputeKeys(*project); err != nil {
+				return nil, err
+			}
+			keys, err := a.FetchShardKeys(*project)
+			if err != nil {
+				return nil, err
+			}
+			return shardKeysResult(keys), nil
+		}
+	}},
+	{"keys", "lock", "pin the shard key of a table: -project <id> -table <name> [-column <name>]", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		table := fs.String("table", "", "table name")
+		column := fs.String("column", "", "shard key column, the current key if empty")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := required("table", *table); err != nil {
+				return nil, err
+			}
+			if err := a.LockShardKey(*project, *table, *column); err != nil {
+				return nil, err
+			}
+			keys, err := a.FetchShardKeys(*project)
+			if err != nil {
+				return nil, err
+			}
+			return shardKeysResult(keys), nil
+		}
+	}},
+	{"keys", "unlock", "release a pinned shard key back to inference: -project <id> -table <name>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		table := fs.String("table", "", "table name")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := required("table", *table); err != nil {
+				return nil, err
+			}
+			if err := a.UnlockShardKey(*project, *table); err != nil {
+				return nil, err
+			}
+			keys, err := a.FetchShardKeys(*project)
+			if err != nil {
+				return nil, err
+			}
+			return shardKeysResult(keys), nil
+		}
+	}},
+	{"keys", "groups", "show co-location groups and tables needing a denormalized key column: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			groups, err := a.GetColocationGroups(*project)
+			if err != nil {
+				return nil, err
+			}
+			return colocationResult(groups), nil
+		}
+	}},
+	{"keys", "runs", "list shard key inference runs: -project <id> [-limit n]", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		limit := fs.Int("limit", 20, "most runs to show")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			runs, err := a.ListInferenceRuns(*project, *limit)
+			if err != nil {
+				return nil, err
+			}
+			return inferenceRunsResult(runs), nil
+		}
+	}},
+	{"keys", "report", "show every ranked candidate of an inference run: -project <id> [-run <id>]", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		runID := fs.String("run", "latest", "run id, the newest run if omitted")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			run, err := a.GetInferenceRun(*project, *runID)
+			if err != nil {
+				return nil, err
+			}
+			return inferenceReportResult(run), nil
+		}
+	}},
+	{"keys", "simulate", "route statements under candidate keys without executing: -project <id> [-keys t=col,...] [-shards n] [-file <queries.sql>] [-workload]", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		keys := fs.String("keys", "", "candidate keys as table=column pairs, other tables keep their key")
+		shards := fs.Int("shards", 0, "shard count to simulate, the active shards if 0")
+		file := fs.String("file", "", `file of ;-separated statements, "-" for stdin`)
+		workload := fs.Bool("workload", false, "also simulate the captured and imported workload")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			keyMap := make(map[string]string)
+			for _, pair := range splitList(*keys) {
+				table, column, ok := strings.Cut(pair, "=")
+				if !ok || table == "" || column == "" {
+					return nil, fmt.Errorf("%w: -keys expects table=column pairs", errUsage)
+				}
+				keyMap[table] = column
+			}
+			var queries []repository.WorkloadQuery
+			if *file != "" {
+				data, err := readInput(*file)
+				if err != nil {
+					return nil, err
+				}
+				statements, err := pg_query.SplitWithParser(string(data), true)
+				if err != nil {
+					return nil, fmt.Errorf("%w: invalid statements file: %v", errUsage, err)
+				}
+				for _, stmt := range statements {
+					queries = append(queries, repository.WorkloadQuery{Query: stmt, Calls: 1})
+				}
+			}
+			sim, err := a.SimulateShardKeys(*project, keyMap, *shards, queries, *workload)
+			if err != nil {
+				return nil, err
+			}
+			return simulationResult(sim), nil
+		}
+	}},
+	{"keys", "replace", "replace shard keys from a JSON file: -project <id> -file <keys.json>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		file := fs.String("file", "", `JSON array of {"TableName","ShardKeyColumn","IsManual"}, "-" for stdin`)
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := required("file", *file); err != nil {
+				return nil, err
+			}
+			data, err := readInput(*file)
+			if err != nil {
+				return nil, err
+			}
+			var records []repository.ShardKeyRecord
+			if err := json.Unmarshal(data, &records); err != nil {
+				return nil, fmt.Errorf("%w: invalid keys file: %v", errUsage, err)
+			}
+			if err := a.ReplaceShardKeys(*project, records); err != nil {
+				return nil, err
+			}
+			keys, err := a.FetchShardKeys(*project)
+			if err != nil {
+				return nil, err
+			}
+			return shardKeysResult(keys), nil
+		}
+	}},
+
+	// inference profile
+	{"profile", "show", "show the scoring weights and column patterns of inference: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			profile, err := a.GetInferenceProfile(*project)
+			if err != nil {
+				return nil, err
+			}
+			return profileResult(profile), nil
+		}
+	}},
+	{"profile", "set", "replace the inference profile: -project <id> [-file <profile.json>] [-prefer a,b] [-eliminate a,b]", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		file := fs.String("file", "", `JSON {"weights":{...},"eliminated_patterns":[],"preferred_columns":[]}, "-" for stdin; omitted weights keep their default`)
+		prefer := fs.String("prefer", "", "preferred column name globs, replaces those of the file")
+		eliminate := fs.String("eliminate", "", "eliminated column name globs, replaces those of the file")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			profile := shardkey.DefaultProfile()
+			if *file != "" {
+				data, err := readInput(*file)
+				if err != nil {
+					return nil, err
+				}
+				if err := json.Unmarshal(data, &profile); err != nil {
+					return nil, fmt.Errorf("%w: invalid profile file: %v", errUsage, err)
+				}
+			}
+			if *prefer != "" {
+				profile.PreferredColumns = splitList(*prefer)
+			}
+			if *eliminate != "" {
+				profile.EliminatedPatterns = splitList(*eliminate)
+			}
+			stored, err := a.SetInferenceProfile(*project, profile)
+			if err != nil {
+				return nil, err
+			}
+			return profileResult(stored), nil
+		}
+	}},
+	{"profile", "reset", "reset the inference profile to the defaults: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := a.ResetInferenceProfile(*project); err != nil {
+				return nil, err
+			}
+			return messageResult("inference profile reset", *project), nil
+		}
+	}},
+
+	// workload
+	{"workload", "import", "import a query log to weigh shard key inference: -project <id> -file <queries.sql>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		file := fs.String("file", "", `file of ;-separated statements, "-" for stdin`)
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := required("file", *file); err != nil {
+				return nil, err
+			}
+			data, err := readInput(*file)
+			if err != nil {
+				return nil, err
+			}
+			statements, err := pg_query.SplitWithParser(string(data), true)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid query log: %v", errUsage, err)
+			}
+			queries := make([]repository.WorkloadQuery, 0, len(statements))
+			for _, stmt := range statements {
+				queries = append(queries, repository.WorkloadQuery{Query: stmt, Calls: 1})
+			}
+			imported, err := a.ImportWorkload(*project, queries)
+			if err != nil {
+				return nil, err
+			}
+			return messageResult(fmt.Sprintf("imported %d distinct statements", imported), *project), nil
+		}
+	}},
+	{"workload", "show", "show predicate and join frequencies inference weighs: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			summary, err := a.GetWorkloadSummary(*project)
+			if err != nil {
+				return nil, err
+			}
+			return workloadResult(summary), nil
+		}
+	}},
+	{"workload", "clear", "drop imported queries of a project: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := a.ClearWorkload(*project); err != nil {
+				return nil, err
+			}
+			return messageResult("workload cleared", *project), nil
+		}
+	}},
+
+	// column statistics
+	{"stats", "collect", "read column statistics from pg_stats of every active shard: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			stats, err := a.CollectColumnStatistics(*project)
+			if err != nil {
+				return nil, err
+			}
+			return statisticsResult(stats), nil
+		}
+	}},
+	{"stats", "import", "compute column statistics from sampled rows: -project <id> -file <sample.json>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		file := fs.String("file", "", `JSON array of {"table","rows":[{column: value}]}, "-" for stdin`)
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := required("file", *file); err != nil {
+				return nil, err
+			}
+			data, err := readInput(*file)
+			if err != nil {
+				return nil, err
+			}
+			var samples []shardstats.TableSample
+			if err := json.Unmarshal(data, &samples); err != nil {
+				return nil, fmt.Errorf("%w: invalid sample file: %v", errUsage, err)
+			}
+			stats, err := a.UploadColumnSample(*project, samples)
+			if err != nil {
+				return nil, err
+			}
+			return statisticsResult(stats), nil
+		}
+	}},
+	{"stats", "show", "show column statistics and the estimated load of the fullest shard: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			stats, err := a.GetColumnStatistics(*project)
+			if err != nil {
+				return nil, err
+			}
+			return statisticsResult(stats), nil
+		}
+	}},
+	{"stats", "clear", "drop column statistics of a project: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := a.ClearColumnStatistics(*project); err != nil {
+				return nil, err
+			}
+			return messageResult("column statistics cleared", *project), nil
+		}
+	}},
+
+	// shard skew
+	{"skew", "check", "read shard sizes and report data skew, traffic is only seen by the server: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			report, err := a.RefreshSkewReport(*project)
+			if err != nil {
+				return nil, err
+			}
+			return skewResult(report), nil
+		}
+	}},
+
+	// resharding
+	{"reshard", "map", "show the hash slots every shard owns: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			shardMap, err := a.GetShardMap(*project)
+			if err != nil {
+				return nil, err
+			}
+			return shardMapResult(shardMap), nil
+		}
+	}},
+	{"reshard", "rebalance", "move an even share of hash slots to a new active shard and wait: -project <id> -shard <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		shard := fs.String("shard", "", "active shard owning no hash slots")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := required("shard", *shard); err != nil {
+				return nil, err
+			}
+			job, err := a.StartRebalance(*project, *shard)
+			if err != nil {
+				return nil, err
+			}
+			return waitReshardJob(a, *project, job.ID)
+		}
+	}},
+	{"reshard", "split", "move the upper half of a shard's hash slots to an empty active shard and wait: -shard <id> -target <id>", func(fs *flag.FlagSet) runFunc {
+		shard := fs.String("shard", "", "shard to split")
+		target := fs.String("target", "", "active shard owning no hash slots")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("shard", *shard); err != nil {
+				return nil, err
+			}
+			if err := required("target", *target); err != nil {
+				return nil, err
+			}
+			job, err := a.SplitShard(*shard, *target)
+			if err != nil {
+				return nil, err
+			}
+			return waitReshardJob(a, job.ProjectID, job.ID)
+		}
+	}},
+	{"reshard", "merge", "move every hash slot of a shard into another active shard and wait: -shard <id> -into <id>", func(fs *flag.FlagSet) runFunc {
+		shard := fs.String("shard", "", "shard to merge, left draining")
+		into := fs.String("into", "", "active shard receiving its hash slots")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("shard", *shard); err != nil {
+				return nil, err
+			}
+			if err := required("into", *into); err != nil {
+				return nil, err
+			}
+			job, err := a.MergeShards(*shard, *into)
+			if err != nil {
+				return nil, err
+			}
+			return waitReshardJob(a, job.ProjectID, job.ID)
+		}
+	}},
+	{"reshard", "list", "list resharding jobs: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			jobs, err := a.ListReshardJobs(*project)
+			if err != nil {
+				return nil, err
+			}
+			return reshardJobsResult(jobs), nil
+		}
+	}},
+	{"reshard", "show", "show the moves and per-table progress of a job: -project <id> -job <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		job := fs.String("job", "", "reshard job id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := required("job", *job); err != nil {
+				return nil, err
+			}
+			report, err := a.GetReshardJob(*project, *job)
+			if err != nil {
+				return nil, err
+			}
+			return reshardJobResult(report), nil
+		}
+	}},
+	{"reshard", "resume", "resume a failed job from its checkpoints and wait: -project <id> -job <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		job := fs.String("job", "", "reshard job id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := required("job", *job); err != nil {
+				return nil, err
+			}
+			if _, err := a.ResumeReshardJob(*project, *job); err != nil {
+				return nil, err
+			}
+			return waitReshardJob(a, *project, *job)
+		}
+	}},
+	{"reshard", "cancel", "cancel a failed job before cutover, removing copied rows: -project <id> -job <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		job := fs.String("job", "", "reshard job id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := required("job", *job); err != nil {
+				return nil, err
+			}
+			cancelled, err := a.CancelReshardJob(*project, *job)
+			if err != nil {
+				return nil, err
+			}
+			return reshardJobsResult([]repository.ReshardJob{*cancelled}), nil
+		}
+	}},
+
+	// tenant isolation
+	{"tenant", "get", "show the tenant column of a project: -project <id>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			column, err := a.GetTenantColumn(*project)
+			if err != nil {
+				return nil, err
+			}
+			return tenantResult(*project, column), nil
+		}
+	}},
+	{"tenant", "set", "set the tenant column of a project, empty turns isolation off: -project <id> -column <name>", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		column := fs.String("column", "", "column holding the tenant of a row")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			if err := a.SetTenantColumn(*project, *column); err != nil {
+				return nil, err
+			}
+			column, err := a.GetTenantColumn(*project)
+			if err != nil {
+				return nil, err
+			}
+			return tenantResult(*project, column), nil
+		}
+	}},
+
+	// audit
+	{"audit", "list", "browse the audit log of a project: -project <id> [-type query|access_denied] [-fingerprint <fp>] [-limit n]", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id")
+		eventType := fs.String("type", "", "event type, all if empty")
+		fingerprint := fs.String("fingerprint", "", "statement fingerprint, all if empty")
+		limit := fs.Int("limit", 50, "most records to show")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("project", *project); err != nil {
+				return nil, err
+			}
+			records, err := a.ListAuditLog(repository.AuditFilter{
+				ProjectID:   *project,
+				EventType:   *eventType,
+				Fingerprint: *fingerprint,
+				Limit:       *limit,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return auditResult(records), nil
+		}
+	}},
+
+	// api keys
+	{"apikey", "list", "list API keys: [-project <id>]", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id, all keys if empty")
+		return func(a *app.App, _ []string) (*result, error) {
+			keys, err := a.ListAPIKeys(*project)
+			if err != nil {
+				return nil, err
+			}
+			return apiKeysResult(keys, ""), nil
+		}
+	}},
+	{"apikey", "create", "create an API key: -name <name> -role read_only|read_write|admin [-project <id>] [-tenant <id>]", func(fs *flag.FlagSet) runFunc {
+		project := fs.String("project", "", "project id, omitted for admin keys")
+		name := fs.String("name", "", "key name")
+		role := fs.String("role", "", "read_only, read_write or admin")
+		tables := fs.String("tables", "", "comma separated tables the key may reference, all if empty")
+		noBroadcast := fs.Bool("no-broadcast-writes", false, "reject writes that target more than one shard")
+		tenant := fs.String("tenant", "", "tenant the key is bound to, passed per request if empty")
+		return func(a *app.App, _ []string) (*result, error) {
+			if err := required("name", *name); err != nil {
+				return nil, err
+			}
+			if err := required("role", *role); err != nil {
+				return nil, err
+			}
+			key, secret, err := a.CreateAPIKey(*project, *name, *role, splitList(*tables), *noBroadcast, *tenant)
+			if err != nil {
+				return nil, err
+			}
+			return apiKeysResult([]repository.APIKey{*key}, secret), nil
+		}
+	}},
+	{"apikey", "rotate", "replace the secret of an API key: rotate <key-id>", func(fs *flag.FlagSet) runFunc {
+		return func(a *app.App, args []string) (*result, error) {
+			id, err := arg(args, "key-id")
+			if err != nil {
+				return nil, err
+			}
+			key, secret, err := a.RotateAPIKey(id)
+			if err != nil {
+				return nil, err
+			}
+			return apiKeysResult([]repository.APIKey{*key}, secret), nil
+		}
+	}},
+	{"apikey", "policy", "set statement restrictions: policy [-tables a,b] [-no-broadcast-writes] [-tenant <id>] <key-id>", func(fs *flag.FlagSet) runFunc {
+		tables := fs.String("tables", "", "comma separated tables the key may reference, all if empty")
+		noBroadcast := fs.Bool("no-broadcast-writes", false, "reject writes that target more than one shard")
+		tenant := fs.String("tenant", "", "tenant the key is bound to, passed per request if empty")
+		return func(a *app.App, args []string) (*result, error) {
+			id, err := arg(args, "key-id")
+			if err != nil {
+				return nil, err
+			}
+			key, err := a.UpdateAPIKeyPolicy(id, splitList(*tables), *noBroadcast, *tenant)
+			if err != nil {
+				return nil, err
+			}
+			return apiKeysResult([]repository.APIKey{*key}, ""), nil
+		}
+	}},
+	{"apikey", "revoke", "revoke an API key: revoke <key-id>", func(fs *flag.FlagSet) runFunc {
+		return idAction("key-id", "API key revoked", func(a *app.App, id string) error {
+			return a.RevokeAPIKey(id)
+		})
+	}},
+}
+
+func findCommand(resource string, action string) (command, bool) {
+	for _, c := range commands {
+		if c.resource == resource && c.action == action {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+func printCommands(w io.Writer) {
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-22s %s\n", c.name(), c.summary)
+	}
+}
+
+// idAction builds a command taking a single positional id
+func idAction(argName string, message string, do func(a *app.App, id string) error) runFunc {
+	return func(a *app.App, args []string) (*result, error) {
+		id, err := arg(args, argName)
+		if err != nil {
+			return nil, err
+		}
+		if err := do(a, id); err != nil {
+			return nil, err
+		}
+		return messageResult(message, id), nil
+	}
+}
+
+// waitReshardJob runs a job in this process until it stops, the -timeout
+// flag interrupts it and leaves it failed to resume later
+func waitReshardJob(a *app.App, projectID string, jobID string) (*result, error) {
+	job, err := a.WaitReshardJob(context.Background(), jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == reshard.StatusFailed {
+		return nil, fmt.Errorf("reshard job %s failed: %s", job.ID, job.Error)
+	}
+	report, err := a.GetReshardJob(projectID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return reshardJobResult(report), nil
+}
+
+func arg(args []string, name string) (string, error) {
+	if len(args) != 1 || args[0] == "" {
+		return "", fmt.Errorf("%w: expected <%s>", errUsage, name)
+	}
+	return args[0], nil
+}
+
+// splitList splits a comma separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var out []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func required(name string, value string) error {
+	if value == "" {
+		return fmt.Errorf("%w: -%s is required", errUsage, name)
+	}
+	return nil
+}
+
+// readInput reads a file, or stdin for "-"
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+type ddlInput struct {
+	file *string
+	sql  *string
+}
+
+func ddlFlags(fs *flag.FlagSet) ddlInput {
+	return ddlInput{
+		file: fs.String("file", "", `DDL file, "-" for stdin`),
+		sql:  fs.String("sql", "", "DDL text"),
+	}
+}
+
+func (d ddlInput) read() (string, error) {
+	switch {
+	case *d.file != "" && *d.sql != "":
+		return "", fmt.Errorf("%w: use either -file or -sql", errUsage)
+	case *d.sql != "":
+		return *d.sql, nil
+	case *d.file != "":
+		data, err := readInput(*d.file)
+		return string(data), err
+	default:
+		return "", fmt.Errorf("%w: -file or -sql is required", errUsage)
+	}
+}
+
+type connectionInput struct {
+	shard    *string
+	host     *string
+	port     *int
+	database *string
+	user     *string
+	password *string
+}
+
+func connectionFlags(fs *flag.FlagSet) connectionInput {
+	return connectionInput{
+		shard:    fs.String("shard", "", "shard id"),
+		host:     fs.String("host", "", "shard host"),
+		port:     fs.Int("port", 5432, "shard port"),
+		database: fs.String("database", "", "shard database name"),
+		user:     fs.String("user", "", "shard username"),
+		password: fs.String("password", os.Getenv("SHARD_PASSWORD"), "shard password (defaults to $SHARD_PASSWORD)"),
+	}
+}
+
+func (c connectionInput) validate() error {
+	for name, value := range map[string]string{
+		"shard":    *c.shard,
+		"host":     *c.host,
+		"database": *c.database,
+		"user":     *c.user,
+	} {
+		if err := required(name, value); err != nil {
+			return err
+		}
+	}
+	if *c.port <= 0 || *c.port > 65535 {
+		return fmt.Errorf("%w: invalid port %s", errUsage, strconv.Itoa(*c.port))
+	}
+	return nil
+}
+
+func (c connectionInput) toConnection() repository.ShardConnection {
+	return repository.ShardConnection{
+		ShardID:      *c.shard,
+		Host:         *c.host,
+		Port:         *c.port,
+		DatabaseName: *c.database,
+		Username:     *c.user,
+		Password:     *c.password,
+	}
+}
@@ -0,0 +1,112 @@
+// Command shardctl administers projects, shards, schemas and shard keys
+// against the application database.
+//
+//	shardctl [-o table|json] [-v] <resource> <action> [flags]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"sql-sharding-v2/internal/app"
+	"sql-sharding-v2/pkg/logger"
+)
+
+// exit codes
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// errUsage marks invalid invocations, reported with exitUsage
+var errUsage = errors.New("usage error")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+
+	global := flag.NewFlagSet("shardctl", flag.ContinueOnError)
+	global.SetOutput(stderr)
+
+	output := global.String("o", "table", "output format: table or json")
+	verbose := global.Bool("v", false, "write application logs to stderr")
+	timeout := global.Duration("timeout", 2*time.Minute, "overall command timeout")
+
+	global.Usage = func() {
+		fmt.Fprintln(stderr, "usage: shardctl [-o table|json] [-v] <resource> <action> [flags]")
+		fmt.Fprintln(stderr)
+		printCommands(stderr)
+	}
+
+	if err := global.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(stderr, "unknown output format %q\n", *output)
+		return exitUsage
+	}
+
+	rest := global.Args()
+	if len(rest) < 2 {
+		global.Usage()
+		return exitUsage
+	}
+
+	cmd, ok := findCommand(rest[0], rest[1])
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", rest[0]+" "+rest[1])
+		global.Usage()
+		return exitUsage
+	}
+
+	// application logs stay off stdout so output remains scriptable
+	level := slog.LevelError + 1
+	if *verbose {
+		level = slog.LevelInfo
+	}
+	logger.Logger = slog.New(slog.NewJSONHandler(stderr, &slog.HandlerOptions{Level: level}))
+
+	flags := flag.NewFlagSet(cmd.name(), flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	exec := cmd.setup(flags)
+	if err := flags.Parse(rest[2:]); err != nil {
+		return exitUsage
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	a := app.New()
+	if err := a.Open(ctx, logger.NewSlogSink()); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitFailure
+	}
+	defer a.Shutdown(context.Background())
+
+	result, err := exec(a, flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		if errors.Is(err, errUsage) {
+			return exitUsage
+		}
+		return exitFailure
+	}
+
+	if err := render(stdout, *output, result); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitFailure
+	}
+
+	return exitOK
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/reshard"
+	"sql-sharding-v2/internal/router"
+	"sql-sharding-v2/internal/shardkey"
+	"sql-sharding-v2/internal/skew"
+)
+
+// result holds a command outcome in both output shapes
+type result struct {
+	value  any
+	header []string
+	rows   [][]string
+}
+
+func render(w io.Writer, format string, res *result) error {
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(res.value)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(res.header, "\t"))
+	for _, row := range res.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func messageResult(message string, id string) *result {
+	return &result{
+		value:  map[string]string{"status": message, "id": id},
+		header: []string{"STATUS", "ID"},
+		rows:   [][]string{{message, id}},
+	}
+}
+
+func projectsResult(projects []repository.Project) *result {
+	res := &result{
+		value:  projects,
+		header: []string{"ID", "NAME", "STATUS", "SHARDS", "CREATED"},
+	}
+	for _, p := range projects {
+		res.rows = append(res.rows, []string{p.ID, p.Name, p.Status, strconv.Itoa(p.ShardCount), p.CreatedAt})
+	}
+	return res
+}
+
+func shardsResult(shards []repository.Shard) *result {
+	res := &result{
+		value:  shards,
+		header: []string{"ID", "INDEX", "STATUS", "CREATED"},
+	}
+	for _, s := range shards {
+		res.rows = append(res.rows, []string{s.ID, strconv.Itoa(s.ShardIndex), s.Status, s.CreatedAt.Format("2006-01-02 15:04:05")})
+	}
+	return res
+}
+
+func connectionResult(conn repository.ShardConnection) *result {
+	// never print the password
+	conn.Password = ""
+	return &result{
+		value:  conn,
+		header: []string{"SHARD", "HOST", "PORT", "DATABASE", "USER"},
+		rows:   [][]string{{conn.ShardID, conn.Host, strconv.Itoa(conn.Port), conn.DatabaseName, conn.Username}},
+	}
+}
+
+func schemasResult(schemas []repository.ProjectSchema) *result {
+	res := &result{
+		value:  schemas,
+		header: []string{"ID", "VERSION", "STATE", "CREATED", "ERROR"},
+	}
+	for _, s := range schemas {
+		errMsg := ""
+		if s.ErrMsg != nil {
+			errMsg = *s.ErrMsg
+		}
+		res.rows = append(res.rows, []string{s.ID, strconv.Itoa(s.Version), s.State, s.CreatedAt, errMsg})
+	}
+	return res
+}
+
+func executionStatusResult(statuses []repository.SchemaExecutionStatus) *result {
+	res := &result{
+		value:  statuses,
+		header: []string{"SHARD", "STATE", "EXECUTED", "ERROR"},
+	}
+	for _, s := range statuses {
+		res.rows = append(res.rows, []string{s.ShardID, s.State, s.ExecutedAt, s.ErrMsg})
+	}
+	return res
+}
+
+func shardKeysResult(keys []repository.ShardKeys) *result {
+	res := &result{
+		value:  keys,
+		header: []string{"TABLE", "SHARD KEY", "MANUAL", "INFERRED", "CONFLICT", "UPDATED"},
+	}
+	for _, k := range keys {
+		inferred := ""
+		if k.InferredColumn != nil {
+			inferred = *k.InferredColumn
+		}
+		res.rows = append(res.rows, []string{k.TableName, k.ShardKeyColumn, strconv.FormatBool(k.IsManualOverride), inferred, strconv.FormatBool(k.Conflict), k.UpdatedAt.Format("2006-01-02 15:04:05")})
+	}
+	return res
+}
+
+func inferenceRunsResult(runs []repository.InferenceRun) *result {
+	res := &result{
+		value:  runs,
+		header: []string{"ID", "CREATED", "TABLES", "WORKLOAD STATEMENTS"},
+	}
+	for _, r := range runs {
+		res.rows = append(res.rows, []string{r.ID, r.CreatedAt.Format("2006-01-02 15:04:05"), strconv.Itoa(r.Tables), strconv.FormatInt(r.WorkloadStatements, 10)})
+	}
+	return res
+}
+
+// inferenceReportResult lists candidates per table, the selected one marked with *
+func inferenceReportResult(run *repository.InferenceRun) *result {
+	res := &result{
+		value:  run,
+		header: []string{"TABLE", "RANK", "COLUMN", "SCORE", "SELECTED", "REASONS"},
+	}
+	for _, c := range run.Candidates {
+		selected := ""
+		if c.Selected {
+			selected = "*"
+		}
+		res.rows = append(res.rows, []string{c.TableName, strconv.Itoa(c.Rank), c.ColumnName, strconv.Itoa(c.Score), selected, strings.Join(c.Reasons, "; ")})
+	}
+	return res
+}
+
+// profileResult lists one row per weight, then the column patterns
+func profileResult(profile *repository.InferenceProfile) *result {
+	w := profile.Weights
+	res := &result{
+		value:  profile,
+		header: []string{"SETTING", "VALUE"},
+		rows: [][]string{
+			{"incoming_fk", strconv.Itoa(w.IncomingFK)},
+			{"referencing_table", strconv.Itoa(w.ReferencingTable)},
+			{"foreign_key", strconv.Itoa(w.ForeignKey)},
+			{"root_affinity", strconv.Itoa(w.RootAffinity)},
+			{"primary_key", strconv.Itoa(w.PrimaryKey)},
+			{"textual_penalty", strconv.Itoa(w.TextualPenalty)},
+			{"local", strconv.Itoa(w.Local)},
+			{"workload_predicate", strconv.Itoa(w.WorkloadPredicate)},
+			{"workload_join", strconv.Itoa(w.WorkloadJoin)},
+			{"skew_penalty", strconv.Itoa(w.SkewPenalty)},
+			{"preferred_column", strconv.Itoa(w.PreferredColumn)},
+			{"preferred_columns", strings.Join(profile.PreferredColumns, ", ")},
+			{"eliminated_patterns", strings.Join(profile.EliminatedPatterns, ", ")},
+		},
+	}
+	return res
+}
+
+// simulationResult shows the routing mix, rejected statements follow as rows
+func simulationResult(sim *router.Simulation) *result {
+	pct := func(v float64) string { return strconv.FormatFloat(v, 'f', 1, 64) + "%" }
+	res := &result{
+		value:  sim,
+		header: []string{"RESULT", "STATEMENTS", "SHARE", "DETAIL"},
+		rows: [][]string{
+			{"single", strconv.FormatInt(sim.Single, 10), pct(sim.SinglePct), ""},
+			{"multi", strconv.FormatInt(sim.Multi, 10), pct(sim.MultiPct), ""},
+			{"broadcast", strconv.FormatInt(sim.Broadcast, 10), pct(sim.BroadcastPct), ""},
+			{"rejected", strconv.FormatInt(sim.Rejected, 10), pct(sim.RejectedPct), ""},
+			{"colocated joins", strconv.FormatInt(sim.ColocatedJoins, 10), pct(sim.JoinCoverage), "of " + strconv.FormatInt(sim.Joins, 10) + " joins"},
+		},
+	}
+	for _, r := range sim.Rejections {
+		res.rows = append(res.rows, []string{"rejected", strconv.FormatInt(r.Calls, 10), "", r.Reason + ": " + r.Query})
+	}
+	return res
+}
+
+// colocationResult lists one row per table, unresolved tables name the missing column
+func colocationResult(groups []shardkey.ColocationGroup) *result {
+
+	res := &result{
+		value:  groups,
+		header: []string{"ROOT", "KEY", "TABLE", "COLUMN", "VIA", "MISSING"},
+	}
+
+	for _, g := range groups {
+		res.rows = append(res.rows, []string{g.Root, g.Key, g.Root, strings.TrimPrefix(g.Key, g.Root+"."), "", ""})
+		for _, m := range g.Members {
+			res.rows = append(res.rows, []string{g.Root, g.Key, m.Table, m.Column, m.Via, ""})
+		}
+		for _, m := range g.Unresolved {
+			res.rows = append(res.rows, []string{g.Root, g.Key, m.Table, "", m.Via, m.Missing})
+		}
+	}
+
+	return res
+}
+
+func tenantResult(projectID string, column string) *result {
+	return &result{
+		value:  map[string]string{"project_id": projectID, "column": column},
+		header: []string{"PROJECT", "TENANT COLUMN"},
+		rows:   [][]string{{projectID, column}},
+	}
+}
+
+func workloadResult(summary *shardkey.WorkloadSummary) *result {
+
+	res := &result{
+		value:  summary,
+		header: []string{"TABLE", "COLUMN", "PREDICATES", "PREDICATE SHARE", "JOINS", "JOIN SHARE", "JOINED WITH"},
+	}
+
+	for _, c := range summary.Columns {
+		res.rows = append(res.rows, []string{
+			c.Table,
+			c.Column,
+			strconv.FormatInt(c.Predicates, 10),
+			strconv.FormatFloat(c.PredicateShare, 'f', 2, 64),
+			strconv.FormatInt(c.Joins, 10),
+			strconv.FormatFloat(c.JoinShare, 'f', 2, 64),
+			c.JoinedWith,
+		})
+	}
+
+	return res
+}
+
+func statisticsResult(stats []shardkey.ColumnDistribution) *result {
+
+	res := &result{
+		value:  stats,
+		header: []string{"TABLE", "COLUMN", "DISTINCT", "NULL FRAC", "TOP FREQ", "ROWS", "SOURCE", "FULLEST SHARD", "LOW CARDINALITY"},
+	}
+
+	for _, s := range stats {
+		res.rows = append(res.rows, []string{
+			s.TableName,
+			s.ColumnName,
+			strconv.FormatFloat(s.NDistinct, 'f', 0, 64),
+			strconv.FormatFloat(s.NullFrac, 'f', 2, 64),
+			strconv.FormatFloat(s.TopFrequency, 'f', 2, 64),
+			strconv.FormatInt(s.RowCount, 10),
+			s.Source,
+			strconv.FormatFloat(s.MaxShardShare*100, 'f', 1, 64) + "%",
+			strconv.FormatBool(s.LowCardinality),
+		})
+	}
+
+	return res
+}
+
+// skewResult lists one row per shard, then one per table and alert
+func skewResult(report *skew.Report) *result {
+	res := &result{
+		value:  report,
+		header: []string{"SUBJECT", "ROWS", "BYTES", "SKEW", "DETAIL"},
+	}
+	unreachable := make(map[string]bool, len(report.Unreachable))
+	for _, id := range report.Unreachable {
+		unreachable[id] = true
+	}
+	for _, s := range report.Shards {
+		if unreachable[s.ShardID] {
+			res.rows = append(res.rows, []string{"shard " + s.ShardID, "", "", "", "unreachable"})
+			continue
+		}
+		res.rows = append(res.rows, []string{"shard " + s.ShardID, strconv.FormatInt(s.Rows, 10), strconv.FormatInt(s.Bytes, 10), "", ""})
+	}
+	res.rows = append(res.rows, []string{"all shards", "", "", strconv.FormatFloat(report.RowSkew, 'f', 2, 64), "size " + strconv.FormatFloat(report.SizeSkew, 'f', 2, 64)})
+	for _, t := range report.Tables {
+		detail := fmt.Sprintf("shard %s holds %.0f%%", t.LargestShard, t.LargestShare*100)
+		res.rows = append(res.rows, []string{"table " + t.Table, strconv.FormatInt(t.Rows, 10), "", strconv.FormatFloat(t.Skew, 'f', 2, 64), detail})
+	}
+	for _, alert := range report.Alerts {
+		res.rows = append(res.rows, []string{"alert " + alert.Kind, "", "", strconv.FormatFloat(alert.Value, 'f', 2, 64), alert.Message})
+	}
+	return res
+}
+
+// shardMapResult lists the slots and share of every shard
+func shardMapResult(m *reshard.MapReport) *result {
+	res := &result{
+		value:  m,
+		header: []string{"SHARD", "SLOTS", "SHARE", "VERSION"},
+	}
+	for _, s := range m.Shards {
+		res.rows = append(res.rows, []string{
+			s.ShardID,
+			strconv.Itoa(s.Slots),
+			strconv.FormatFloat(s.Share*100, 'f', 1, 64) + "%",
+			strconv.FormatInt(m.Version, 10),
+		})
+	}
+	return res
+}
+
+func reshardJobsResult(jobs []repository.ReshardJob) *result {
+	res := &result{
+		value:  jobs,
+		header: []string{"ID", "KIND", "SHARD", "STATUS", "PHASE", "CREATED", "ERROR"},
+	}
+	for _, j := range jobs {
+		res.rows = append(res.rows, []string{j.ID, j.Kind, j.ShardID, j.Status, j.Phase, j.CreatedAt.Format(time.RFC3339), j.Error})
+	}
+	return res
+}
+
+// reshardJobResult lists the moves of a job, then one row per table pass
+func reshardJobResult(report *reshard.JobReport) *result {
+	res := &result{
+		value:  report,
+		header: []string{"SUBJECT", "STATUS", "ROWS", "DETAIL"},
+	}
+	detail := fmt.Sprintf("%d rows cleaned", report.RowsCleaned)
+	if report.Error != "" {
+		detail += ", " + report.Error
+	}
+	if report.RowsVerified > 0 {
+		detail += fmt.Sprintf(", %d rows verified", report.RowsVerified)
+	}
+	res.rows = append(res.rows, []string{
+		"job " + report.ID,
+		report.Status + "/" + report.Phase,
+		strconv.FormatInt(report.RowsCopied, 10),
+		detail,
+	})
+	for _, m := range report.Moves {
+		res.rows = append(res.rows, []string{"move", "", "", fmt.Sprintf("%d slots %s -> %s", m.Slots, m.SourceShardID, m.TargetShardID)})
+	}
+	for _, cp := range report.Checkpoints {
+		status := cp.Phase
+		if cp.Done {
+			status += " done"
+		}
+		res.rows = append(res.rows, []string{
+			"table " + cp.TableName,
+			status,
+			strconv.FormatInt(cp.RowsWritten, 10),
+			fmt.Sprintf("shard %s scanned %d", cp.ShardID, cp.RowsScanned),
+		})
+	}
+	return res
+}
+
+// drainResult shows the shard first, then the rows of its latest drain job
+func drainResult(report *reshard.DrainReport) *result {
+	res := &result{
+		value:  report,
+		header: []string{"SUBJECT", "STATUS", "ROWS", "DETAIL"},
+	}
+	res.rows = append(res.rows, []string{
+		"shard " + report.ShardID,
+		report.Status,
+		"",
+		fmt.Sprintf("%d slots owned, deletable %t", report.Slots, report.Deletable),
+	})
+	if report.Job != nil {
+		res.rows = append(res.rows, reshardJobResult(report.Job).rows...)
+	}
+	return res
+}
+
+func auditResult(records []repository.AuditRecord) *result {
+
+	res := &result{
+		value:  records,
+		header: []string{"ID", "TIME", "EVENT", "ACTOR", "MODE", "SHARDS", "MS", "ROWS", "STATEMENT", "ERROR"},
+	}
+
+	for _, r := range records {
+		duration, rows := "", ""
+		if r.DurationMs != nil {
+			duration = strconv.FormatFloat(*r.DurationMs, 'f', 2, 64)
+		}
+		if r.RowsAffected != nil {
+			rows = strconv.FormatInt(*r.RowsAffected, 10)
+		}
+		statement := r.Normalized
+		if statement == "" {
+			statement = r.Statement
+		}
+		res.rows = append(res.rows, []string{
+			strconv.FormatInt(r.ID, 10),
+			r.OccurredAt.Format(time.RFC3339),
+			r.EventType,
+			r.Actor,
+			r.RoutingMode,
+			strconv.Itoa(len(r.TargetShards)),
+			duration,
+			rows,
+			statement,
+			r.Error,
+		})
+	}
+
+	return res
+}
+
+// apiKeysResult renders keys, with the secret of a newly issued key if given
+func apiKeysResult(keys []repository.APIKey, secret string) *result {
+
+	res := &result{
+		value:  keys,
+		header: []string{"ID", "NAME", "ROLE", "PROJECT", "TENANT", "PREFIX", "REVOKED"},
+	}
+
+	if secret != "" {
+		res.value = map[string]any{"key": keys[0], "secret": secret}
+		res.header = append(res.header, "SECRET")
+	}
+
+	for _, k := range keys {
+		project := ""
+		if k.ProjectID != nil {
+			project = *k.ProjectID
+		}
+		tenant := ""
+		if k.TenantID != nil {
+			tenant = *k.TenantID
+		}
+		row := []string{k.ID, k.Name, k.Role, project, tenant, k.KeyPrefix, strconv.FormatBool(k.RevokedAt != nil)}
+		if secret != "" {
+			row = append(row, secret)
+		}
+		res.rows = append(res.rows, row)
+	}
+
+	return res
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"context"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// wailsEventSink forwards application events to the frontend
+type wailsEventSink struct {
+	ctx context.Context
+}
+
+func newWailsEventSink(ctx context.Context) *wailsEventSink {
+	return &wailsEventSink{ctx: ctx}
+}
+
+func (s *wailsEventSink) Emit(name string, payload any) {
+	runtime.EventsEmit(s.ctx, name, payload)
+}
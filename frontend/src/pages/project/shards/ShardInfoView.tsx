@@ -15,7 +15,7 @@ import {
   AlertDialogTrigger,
 } from "@/components/ui/alert-dialog"
 
-import { repository } from "../../../../wailsjs/go/models"
+import { repository, reshard } from "../../../../wailsjs/go/models"
 
 type ShardConnectionForm = {
   host: string
@@ -27,7 +27,7 @@ type ShardConnectionForm = {
 
 type Props = {
   shardId: string
-  adminStatus: "active" | "inactive"
+  adminStatus: "active" | "inactive" | "draining"
   loadingStatus: boolean
   isActive: boolean
   connection: repository.ShardConnection | null
@@ -38,6 +38,8 @@ type Props = {
   toggleShardStatus: () => void
   handleDeleteShard: () => Promise<string>
   handleSaveConnection: () => void
+  drain: reshard.DrainReport | null
+  handleDrainShard: () => Promise<void>
 }
 
 export function ShardInfoView({
@@ -53,9 +55,15 @@ export function ShardInfoView({
   toggleShardStatus,
   handleDeleteShard,
   handleSaveConnection,
+  drain,
+  handleDrainShard,
 }: Props) {
   const [deleteError, setDeleteError] = useState<string | null>(null)
 
+  const drainJob = drain?.job
+  const drainRunning =
+    drainJob?.status === "pending" || drainJob?.status === "running"
+
   async function onDeleteShard() {
     try {
       const result = await handleDeleteShard()
@@ -71,8 +79,16 @@ export function ShardInfoView({
       }
 
       setDeleteError("Unable to delete shard.")
-    } catch {
-      setDeleteError("Unable to delete shard.")
+    } catch (err) {
+      setDeleteError(String(err) || "Unable to delete shard.")
+    }
+  }
+
+  async function onDrainShard() {
+    try {
+      await handleDrainShard()
+    } catch (err) {
+      setDeleteError(String(err) || "Unable to drain shard.")
     }
   }
 
@@ -100,9 +116,58 @@ export function ShardInfoView({
                 <span className="text-muted-foreground">Status</span>
                 <span className="capitalize">{adminStatus}</span>
               </div>
+              {drain && (
+                <div className="flex justify-between">
+                  <span className="text-muted-foreground">Hash slots</span>
+                  <span>{drain.slots}</span>
+                </div>
+              )}
             </CardContent>
           </Card>
 
+          {drainJob && (
+            <Card>
+              <CardHeader className="pb-2">
+                <CardTitle className="text-base">Drain</CardTitle>
+              </CardHeader>
+              <CardContent className="text-sm space-y-1">
+                <div className="flex justify-between">
+                  <span className="text-muted-foreground">Job</span>
+                  <span className="capitalize">
+                    {drainJob.status} · {drainJob.phase.replace("_", " ")}
+                  </span>
+                </div>
+                <div className="flex justify-between">
+                  <span className="text-muted-foreground">Rows copied</span>
+                  <span>{drainJob.rows_copied}</span>
+                </div>
+                <div className="flex justify-between">
+                  <span className="text-muted-foreground">Rows verified</span>
+                  <span>{drainJob.rows_verified}</span>
+                </div>
+                <div className="flex justify-between">
+                  <span className="text-muted-foreground">Rows cleaned</span>
+                  <span>{drainJob.rows_cleaned}</span>
+                </div>
+                <div className="flex justify-between">
+                  <span className="text-muted-foreground">Tables done</span>
+                  <span>
+                    {drainJob.checkpoints.filter((cp) => cp.done).length} /{" "}
+                    {drainJob.checkpoints.length}
+                  </span>
+                </div>
+                {drainJob.error && (
+                  <div className="text-destructive">{drainJob.error}</div>
+                )}
+                {drain?.deletable && (
+                  <div className="text-muted-foreground">
+                    Shard owns no hash slots and can be deleted
+                  </div>
+                )}
+              </CardContent>
+            </Card>
+          )}
+
           <Card>
             <CardHeader className="pb-2">
               <CardTitle className="text-base">Connection</CardTitle>
@@ -141,7 +206,7 @@ export function ShardInfoView({
             <CardContent className="flex flex-col gap-2">
               <AlertDialog>
                 <AlertDialogTrigger asChild>
-                  <Button disabled={loadingStatus}>
+                  <Button disabled={loadingStatus || adminStatus === "draining"}>
                     {isActive ? "Deactivate Shard" : "Activate Shard"}
                   </Button>
                 </AlertDialogTrigger>
@@ -160,6 +225,32 @@ export function ShardInfoView({
                 </AlertDialogContent>
               </AlertDialog>
 
+              <AlertDialog>
+                <AlertDialogTrigger asChild>
+                  <Button
+                    variant="outline"
+                    disabled={!isActive || drainRunning}
+                  >
+                    Drain Shard
+                  </Button>
+                </AlertDialogTrigger>
+                <AlertDialogContent>
+                  <AlertDialogHeader>
+                    <AlertDialogTitle>Drain shard?</AlertDialogTitle>
+                    <AlertDialogDescription>
+                      Its hash slots and rows move to the other active shards.
+                      The shard can be deleted once it owns none.
+                    </AlertDialogDescription>
+                  </AlertDialogHeader>
+                  <div className="flex justify-end gap-2">
+                    <AlertDialogCancel>Cancel</AlertDialogCancel>
+                    <AlertDialogAction onClick={onDrainShard}>
+                      Drain
+                    </AlertDialogAction>
+                  </div>
+                </AlertDialogContent>
+              </AlertDialog>
+
               <AlertDialog>
                 <AlertDialogTrigger asChild>
                   <Button variant="destructive">Delete Shard</Button>
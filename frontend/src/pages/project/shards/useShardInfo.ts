@@ -3,14 +3,19 @@ import {
   ActivateShard,
   DeactivateShard,
   DeleteShard,
+  DrainShard,
+  GetShardDrain,
   FetchShardStatus,
   FetchConnectionInfo,
   AddConnection,
   UpdateConnection,
 } from "../../../../wailsjs/go/main/App"
-import { repository } from "../../../../wailsjs/go/models"
+import { repository, reshard } from "../../../../wailsjs/go/models"
 
-type AdminStatus = "active" | "inactive"
+type AdminStatus = "active" | "inactive" | "draining"
+
+// drain progress is polled while a drain job is moving rows
+const DRAIN_POLL_MS = 3000
 
 type ShardConnectionForm = {
   host: string
@@ -32,6 +37,8 @@ export function useShardInfo(
 
   const [connDialogOpen, setConnDialogOpen] = useState(false)
 
+  const [drain, setDrain] = useState<reshard.DrainReport | null>(null)
+
   const [form, setForm] = useState<ShardConnectionForm>({
     host: "",
     port: 5432,
@@ -55,6 +62,30 @@ export function useShardInfo(
     loadStatus()
   }, [shardId])
 
+  // -------- LOAD DRAIN PROGRESS --------
+  async function loadDrain() {
+    try {
+      const report = await GetShardDrain(shardId)
+      setDrain(report)
+      setAdminStatus(report.status as AdminStatus)
+    } catch {
+      setDrain(null)
+    }
+  }
+
+  const drainRunning =
+    drain?.job?.status === "pending" || drain?.job?.status === "running"
+
+  useEffect(() => {
+    loadDrain()
+  }, [shardId])
+
+  useEffect(() => {
+    if (!drainRunning) return
+    const timer = setInterval(loadDrain, DRAIN_POLL_MS)
+    return () => clearInterval(timer)
+  }, [shardId, drainRunning])
+
   // -------- LOAD CONNECTION --------
   useEffect(() => {
     async function loadConnection() {
@@ -114,6 +145,12 @@ export function useShardInfo(
     return await DeleteShard(shardId)
   }
 
+  async function handleDrainShard() {
+    await DrainShard(shardId)
+    setAdminStatus("draining")
+    await loadDrain()
+  }
+
 
   async function handleSaveConnection() {
     const payload: repository.ShardConnection = {
@@ -150,5 +187,7 @@ export function useShardInfo(
     toggleShardStatus,
     handleDeleteShard,
     handleSaveConnection,
+    drain,
+    handleDrainShard,
   }
 }
@@ -1,6 +1,7 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {repository} from '../models';
+import {reshard} from '../models';
 import {executor} from '../models';
 import {main} from '../models';
 import {context} from '../models';
@@ -33,6 +34,8 @@ export function DeleteSchemaDraft(arg1:string):Promise<void>;
 
 export function DeleteShard(arg1:string):Promise<string>;
 
+export function DrainShard(arg1:string):Promise<repository.ReshardJob>;
+
 export function ExecuteProjectSchema(arg1:string):Promise<void>;
 
 export function ExecuteSQL(arg1:string,arg2:string):Promise<Array<executor.ExecutionResult>>;
@@ -59,10 +62,14 @@ export function GetSchemaExecutionStatus(arg1:string):Promise<Array<repository.S
 
 export function GetSchemaHistory(arg1:string):Promise<Array<repository.ProjectSchema>>;
 
+export function GetShardDrain(arg1:string):Promise<reshard.DrainReport>;
+
 export function ListProjects():Promise<Array<repository.Project>>;
 
 export function ListShards(arg1:string):Promise<Array<repository.Shard>>;
 
+export function MergeShards(arg1:string,arg2:string):Promise<repository.ReshardJob>;
+
 export function MonitorShards(arg1:context.Context):Promise<void>;
 
 export function RecomputeKeys(arg1:string):Promise<void>;
@@ -73,6 +80,8 @@ export function RetrySchemaExecution(arg1:string):Promise<void>;
 
 export function RetryShardConnections(arg1:context.Context):Promise<void>;
 
+export function SplitShard(arg1:string,arg2:string):Promise<repository.ReshardJob>;
+
 export function UpdateConnection(arg1:repository.ShardConnection):Promise<void>;
 
 export function UpdateProjectSchemaDraft(arg1:string,arg2:string,arg3:string):Promise<void>;
@@ -58,6 +58,10 @@ export function DeleteShard(arg1) {
   return window['go']['main']['App']['DeleteShard'](arg1);
 }
 
+export function DrainShard(arg1) {
+  return window['go']['main']['App']['DrainShard'](arg1);
+}
+
 export function ExecuteProjectSchema(arg1) {
   return window['go']['main']['App']['ExecuteProjectSchema'](arg1);
 }
@@ -110,6 +114,10 @@ export function GetSchemaHistory(arg1) {
   return window['go']['main']['App']['GetSchemaHistory'](arg1);
 }
 
+export function GetShardDrain(arg1) {
+  return window['go']['main']['App']['GetShardDrain'](arg1);
+}
+
 export function ListProjects() {
   return window['go']['main']['App']['ListProjects']();
 }
@@ -118,6 +126,10 @@ export function ListShards(arg1) {
   return window['go']['main']['App']['ListShards'](arg1);
 }
 
+export function MergeShards(arg1, arg2) {
+  return window['go']['main']['App']['MergeShards'](arg1, arg2);
+}
+
 export function MonitorShards(arg1) {
   return window['go']['main']['App']['MonitorShards'](arg1);
 }
@@ -138,6 +150,10 @@ export function RetryShardConnections(arg1) {
   return window['go']['main']['App']['RetryShardConnections'](arg1);
 }
 
+export function SplitShard(arg1, arg2) {
+  return window['go']['main']['App']['SplitShard'](arg1, arg2);
+}
+
 export function UpdateConnection(arg1) {
   return window['go']['main']['App']['UpdateConnection'](arg1);
 }
@@ -102,6 +102,104 @@ export namespace repository {
 	        this.applied_at = source["applied_at"];
 	    }
 	}
+	export class ReshardCheckpoint {
+	    shard_id: string;
+	    table_name: string;
+	    phase: string;
+	    last_key: string[];
+	    rows_scanned: number;
+	    rows_written: number;
+	    done: boolean;
+	    // Go type: time
+	    updated_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReshardCheckpoint(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.shard_id = source["shard_id"];
+	        this.table_name = source["table_name"];
+	        this.phase = source["phase"];
+	        this.last_key = source["last_key"];
+	        this.rows_scanned = source["rows_scanned"];
+	        this.rows_written = source["rows_written"];
+	        this.done = source["done"];
+	        this.updated_at = this.convertValues(source["updated_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ReshardJob {
+	    id: string;
+	    project_id: string;
+	    kind: string;
+	    shard_id: string;
+	    status: string;
+	    phase: string;
+	    map_version: number;
+	    error?: string;
+	    // Go type: time
+	    created_at: any;
+	    // Go type: time
+	    updated_at: any;
+	    // Go type: time
+	    completed_at?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReshardJob(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.project_id = source["project_id"];
+	        this.kind = source["kind"];
+	        this.shard_id = source["shard_id"];
+	        this.status = source["status"];
+	        this.phase = source["phase"];
+	        this.map_version = source["map_version"];
+	        this.error = source["error"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.updated_at = this.convertValues(source["updated_at"], null);
+	        this.completed_at = this.convertValues(source["completed_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class SchemaExecutionStatus {
 	    id: string;
 	    schema_id: string;
@@ -247,3 +345,125 @@ export namespace repository {
 
 }
 
+export namespace reshard {
+	
+	export class DrainReport {
+	    shard_id: string;
+	    status: string;
+	    slots: number;
+	    deletable: boolean;
+	    job?: JobReport;
+	
+	    static createFrom(source: any = {}) {
+	        return new DrainReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.shard_id = source["shard_id"];
+	        this.status = source["status"];
+	        this.slots = source["slots"];
+	        this.deletable = source["deletable"];
+	        this.job = this.convertValues(source["job"], JobReport);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class JobReport {
+	    id: string;
+	    project_id: string;
+	    kind: string;
+	    shard_id: string;
+	    status: string;
+	    phase: string;
+	    map_version: number;
+	    error?: string;
+	    // Go type: time
+	    created_at: any;
+	    // Go type: time
+	    updated_at: any;
+	    // Go type: time
+	    completed_at?: any;
+	    moves: MoveSummary[];
+	    checkpoints: repository.ReshardCheckpoint[];
+	    rows_copied: number;
+	    rows_cleaned: number;
+	    rows_verified: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new JobReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.project_id = source["project_id"];
+	        this.kind = source["kind"];
+	        this.shard_id = source["shard_id"];
+	        this.status = source["status"];
+	        this.phase = source["phase"];
+	        this.map_version = source["map_version"];
+	        this.error = source["error"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.updated_at = this.convertValues(source["updated_at"], null);
+	        this.completed_at = this.convertValues(source["completed_at"], null);
+	        this.moves = this.convertValues(source["moves"], MoveSummary);
+	        this.checkpoints = this.convertValues(source["checkpoints"], repository.ReshardCheckpoint);
+	        this.rows_copied = source["rows_copied"];
+	        this.rows_cleaned = source["rows_cleaned"];
+	        this.rows_verified = source["rows_verified"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MoveSummary {
+	    source_shard_id: string;
+	    target_shard_id: string;
+	    slots: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new MoveSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.source_shard_id = source["source_shard_id"];
+	        this.target_shard_id = source["target_shard_id"];
+	        this.slots = source["slots"];
+	    }
+	}
+
+}
+
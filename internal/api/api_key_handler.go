@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"sql-sharding-v2/internal/repository"
+)
+
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+
+	keys, err := h.app.ListAPIKeys(r.URL.Query().Get("project_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	if keys == nil {
+		keys = []repository.APIKey{}
+	}
+
+	writeJSON(w, keys)
+}
+
+func (h *Handler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+
+	var req CreateAPIKeyRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if req.Name == "" || req.Role == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "name and role are required")
+		return
+	}
+
+	key, secret, err := h.app.CreateAPIKey(req.ProjectID, req.Name, req.Role, req.AllowedTables, req.DenyBroadcastWrites, req.TenantID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSONStatus(w, http.StatusCreated, APIKeySecretResponse{
+		APIKey: *key,
+		Secret: secret,
+	})
+}
+
+func (h *Handler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+
+	key, secret, err := h.app.RotateAPIKey(r.PathValue("key_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, APIKeySecretResponse{
+		APIKey: *key,
+		Secret: secret,
+	})
+}
+
+func (h *Handler) UpdateAPIKeyPolicy(w http.ResponseWriter, r *http.Request) {
+
+	var req APIKeyPolicyRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	key, err := h.app.UpdateAPIKeyPolicy(r.PathValue("key_id"), req.AllowedTables, req.DenyBroadcastWrites, req.TenantID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, key)
+}
+
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+
+	keyID := r.PathValue("key_id")
+
+	if err := h.app.RevokeAPIKey(keyID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, StatusResponse{ID: keyID, Status: "revoked"})
+}
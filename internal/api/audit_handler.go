@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"sql-sharding-v2/internal/repository"
+	"strconv"
+	"time"
+)
+
+func (h *Handler) ListAuditLog(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+
+	filter := repository.AuditFilter{
+		ProjectID:   r.PathValue("project_id"),
+		EventType:   query.Get("event_type"),
+		Fingerprint: query.Get("fingerprint"),
+	}
+
+	var err error
+
+	if filter.Since, err = parseTime(query.Get("since")); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "since must be an RFC 3339 timestamp")
+		return
+	}
+	if filter.Until, err = parseTime(query.Get("until")); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "until must be an RFC 3339 timestamp")
+		return
+	}
+	if filter.BeforeID, err = parseInt(query.Get("before_id")); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "before_id must be a number")
+		return
+	}
+
+	limit, err := parseInt(query.Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "limit must be a number")
+		return
+	}
+	filter.Limit = int(limit)
+
+	records, err := h.app.ListAuditLog(filter)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	if records == nil {
+		records = []repository.AuditRecord{}
+	}
+
+	writeJSON(w, records)
+}
+
+// helper to parse an optional timestamp query parameter
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// helper to parse an optional integer query parameter
+func parseInt(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sql-sharding-v2/internal/auth"
+	"strings"
+)
+
+// tenantHeader names the tenant of a request for admin keys not bound to one
+const tenantHeader = "X-Tenant-ID"
+
+type principalKey struct{}
+
+func principalFrom(ctx context.Context) *auth.Principal {
+	principal, _ := ctx.Value(principalKey{}).(*auth.Principal)
+	return principal
+}
+
+// guard authenticates the caller and, for routes whose path identifies a
+// project, shard or schema, checks the caller may access that project.
+// Routes scoped by their request body check access in the handler.
+func (h *Handler) guard(rt route) http.HandlerFunc {
+
+	if !h.requireAuth {
+		return func(w http.ResponseWriter, r *http.Request) {
+			// without keys the tenant header alone confines the caller
+			if tenantID := r.Header.Get(tenantHeader); tenantID != "" {
+				principal := &auth.Principal{Role: auth.RoleAdmin, TenantID: tenantID}
+				r = r.WithContext(context.WithValue(r.Context(), principalKey{}, principal))
+			}
+			rt.handle(w, r)
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		principal, err := h.app.AuthenticateAPIKey(presentedKey(r))
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidKey) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="sql-sharding"`)
+				writeError(w, http.StatusUnauthorized, CodeUnauthorized, "missing or invalid API key")
+				return
+			}
+			writeAppError(w, err)
+			return
+		}
+
+		// management routes act on the whole project, a tenant cannot be confined there
+		if principal.TenantID != "" && !rt.runsStatements() {
+			writeError(w, http.StatusForbidden, CodeForbidden, "API key is bound to a tenant and may only run queries and transactions")
+			return
+		}
+
+		// only admin keys may assume a tenant, other keys keep their own
+		if tenantID := r.Header.Get(tenantHeader); tenantID != "" && tenantID != principal.TenantID {
+			if principal.TenantID != "" {
+				writeError(w, http.StatusForbidden, CodeForbidden, "API key is bound to another tenant")
+				return
+			}
+			if principal.Role != auth.RoleAdmin {
+				writeError(w, http.StatusForbidden, CodeForbidden, "only admin keys may choose a tenant")
+				return
+			}
+			principal.TenantID = tenantID
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), principalKey{}, principal))
+
+		projectID, scoped, err := h.pathProject(r)
+		if err != nil {
+			writeAppError(w, err)
+			return
+		}
+
+		if (scoped || rt.access == auth.AccessAdmin) && !principal.Allows(projectID, rt.access) {
+			writeError(w, http.StatusForbidden, CodeForbidden, "API key is not allowed to perform this operation")
+			return
+		}
+
+		rt.handle(w, r)
+	}
+}
+
+// authorizeProject checks access for routes that carry the project in the request body
+func (h *Handler) authorizeProject(w http.ResponseWriter, r *http.Request, projectID string, access auth.Access) bool {
+
+	if !h.requireAuth {
+		return true
+	}
+
+	if !principalFrom(r.Context()).Allows(projectID, access) {
+		writeError(w, http.StatusForbidden, CodeForbidden, "API key is not allowed to access this project")
+		return false
+	}
+
+	return true
+}
+
+// pathProject resolves the project identified by the request path, if any
+func (h *Handler) pathProject(r *http.Request) (string, bool, error) {
+
+	if projectID := r.PathValue("project_id"); projectID != "" {
+		return projectID, true, nil
+	}
+
+	if shardID := r.PathValue("shard_id"); shardID != "" {
+		projectID, err := h.app.ShardProjectID(shardID)
+		return projectID, true, err
+	}
+
+	if schemaID := r.PathValue("schema_id"); schemaID != "" {
+		projectID, err := h.app.SchemaProjectID(schemaID)
+		return projectID, true, err
+	}
+
+	return "", false, nil
+}
+
+// presentedKey reads the key from the Authorization or X-API-Key header
+func presentedKey(r *http.Request) string {
+
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+
+	return r.Header.Get("X-API-Key")
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"sql-sharding-v2/internal/router"
+	"sql-sharding-v2/internal/transaction"
+	"sql-sharding-v2/pkg/logger"
+
+	"github.com/lib/pq"
+)
+
+// error codes returned in ErrorResponse
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeAccessDenied     = "access_denied"
+	CodeNotFound         = "not_found"
+	CodeRuleViolation    = "rule_violation"
+	CodeRoutingError     = "routing_error"
+	CodeQueryFailed      = "query_failed"
+	CodeTxNotFound       = "tx_not_found"
+	CodeTxAborted        = "tx_aborted"
+	CodeShardMoving      = "shard_moving"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeInternal         = "internal_error"
+)
+
+// RuleError reports an operation rejected by an application rule,
+// such as modifying the schema of an active project
+type RuleError struct {
+	Message string
+}
+
+func (e *RuleError) Error() string {
+	return e.Message
+}
+
+func NewRuleError(message string) error {
+	return &RuleError{Message: message}
+}
+
+// errorStatus maps an application error to an HTTP status and error code
+func errorStatus(err error) (int, string) {
+
+	var ruleErr *RuleError
+	var routingErr *router.RoutingError
+	var pqErr *pq.Error
+
+	switch {
+	case errors.As(err, &ruleErr):
+		return http.StatusConflict, CodeRuleViolation
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, CodeNotFound
+	case errors.Is(err, transaction.ErrTxNotFound):
+		return http.StatusNotFound, CodeTxNotFound
+	case errors.Is(err, transaction.ErrTxAborted):
+		return http.StatusConflict, CodeTxAborted
+	case errors.As(err, &routingErr) && routingErr.Code == router.ErrAccessDenied:
+		return http.StatusForbidden, CodeAccessDenied
+	case errors.As(err, &routingErr) && routingErr.Code == router.ErrShardMoving:
+		return http.StatusServiceUnavailable, CodeShardMoving
+	case errors.As(err, &routingErr):
+		return http.StatusBadRequest, CodeRoutingError
+	case errors.As(err, &pqErr):
+		return http.StatusBadRequest, CodeQueryFailed
+	default:
+		return http.StatusInternalServerError, CodeInternal
+	}
+}
+
+// writeAppError writes an application error as ErrorResponse
+func writeAppError(w http.ResponseWriter, err error) {
+
+	status, code := errorStatus(err)
+	if status == http.StatusInternalServerError {
+		logger.Logger.Error("api request failed", "error", err)
+	}
+
+	message := err.Error()
+	if errors.Is(err, sql.ErrNoRows) {
+		message = "resource not found"
+	}
+
+	writeError(w, status, code, message)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSONStatus(w, status, ErrorResponse{
+		Error: message,
+		Code:  code,
+	})
+}
@@ -1,45 +1,165 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"sql-sharding-v2/internal/auth"
 	"sql-sharding-v2/internal/executor"
+	"sql-sharding-v2/internal/querystats"
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/reshard"
+	"sql-sharding-v2/internal/router"
+	"sql-sharding-v2/internal/shardkey"
+	"sql-sharding-v2/internal/shardstats"
+	"sql-sharding-v2/internal/skew"
 	"sql-sharding-v2/pkg/logger"
 )
 
+// Application is the subset of the app the HTTP API depends on
+type Application interface {
+	CreateProject(name string, description string) (*repository.Project, error)
+	ListProjects() ([]repository.Project, error)
+	FetchProjectByID(id string) (repository.Project, error)
+	DeleteProject(id string) error
+	Activateproject(projectID string) error
+	Deactivateproject(projectID string) error
+
+	AddShard(projectID string) (*repository.Shard, error)
+	ListShards(projectID string) ([]repository.Shard, error)
+	ActivateShard(shardID string) error
+	DeactivateShard(shardID string) error
+	DeleteShard(shardID string) (string, error)
+
+	AddConnection(connectionInfo *repository.ShardConnection) error
+	FetchConnectionInfo(shardID string) (repository.ShardConnection, error)
+	UpdateConnection(connInfo repository.ShardConnection) error
+
+	CreateSchemaDraft(projectID string, ddlSQL string) (*repository.ProjectSchema, error)
+	UpdateProjectSchemaDraft(projectID string, schemaID string, ddlSQL string) error
+	DeleteSchemaDraft(schemaID string) error
+	CommitSchemaDraft(projectID string, schemaID string) error
+	GetCurrentSchema(projectID string) (*repository.ProjectSchema, error)
+	GetSchemaHistory(projectID string) ([]repository.ProjectSchema, error)
+	GetSchemaExecutionStatus(schemaID string) ([]repository.SchemaExecutionStatus, error)
+	ExecuteProjectSchema(projectID string) error
+	RetrySchemaExecution(projectID string) error
+
+	RecomputeKeys(projectID string) error
+	FetchShardKeys(projectID string) ([]repository.ShardKeys, error)
+	ReplaceShardKeys(projectID string, keys []repository.ShardKeyRecord) error
+	LockShardKey(projectID string, tableName string, column string) error
+	UnlockShardKey(projectID string, tableName string) error
+	GetColocationGroups(projectID string) ([]shardkey.ColocationGroup, error)
+	SimulateShardKeys(projectID string, keys map[string]string, shardCount int, queries []repository.WorkloadQuery, captured bool) (*router.Simulation, error)
+	ListInferenceRuns(projectID string, limit int) ([]repository.InferenceRun, error)
+	GetInferenceRun(projectID string, runID string) (*repository.InferenceRun, error)
+	GetInferenceProfile(projectID string) (*repository.InferenceProfile, error)
+	SetInferenceProfile(projectID string, profile repository.InferenceProfile) (*repository.InferenceProfile, error)
+	ResetInferenceProfile(projectID string) error
+
+	// workload
+	ImportWorkload(projectID string, queries []repository.WorkloadQuery) (int, error)
+	GetWorkloadSummary(projectID string) (*shardkey.WorkloadSummary, error)
+	ClearWorkload(projectID string) error
+
+	// column statistics
+	CollectColumnStatistics(projectID string) ([]shardkey.ColumnDistribution, error)
+	UploadColumnSample(projectID string, samples []shardstats.TableSample) ([]shardkey.ColumnDistribution, error)
+	GetColumnStatistics(projectID string) ([]shardkey.ColumnDistribution, error)
+	ClearColumnStatistics(projectID string) error
+
+	// tenant isolation
+	GetTenantColumn(projectID string) (string, error)
+	SetTenantColumn(projectID string, column string) error
+
+	ExecuteSQLAs(ctx context.Context, principal *auth.Principal, projectID string, sql string) ([]executor.ExecutionResult, error)
+
+	BeginTransactionAs(principal *auth.Principal, projectID string) (string, error)
+	ExecuteInTransactionAs(ctx context.Context, principal *auth.Principal, txID string, sql string) ([]executor.ExecutionResult, error)
+	CommitTransaction(txID string) error
+	RollbackTransaction(txID string) error
+
+	CreateAPIKey(projectID string, name string, role string, allowedTables []string, denyBroadcastWrites bool, tenantID string) (*repository.APIKey, string, error)
+	UpdateAPIKeyPolicy(keyID string, allowedTables []string, denyBroadcastWrites bool, tenantID string) (*repository.APIKey, error)
+	ListAPIKeys(projectID string) ([]repository.APIKey, error)
+	RotateAPIKey(keyID string) (*repository.APIKey, string, error)
+	RevokeAPIKey(keyID string) error
+	AuthenticateAPIKey(secret string) (*auth.Principal, error)
+
+	// audit
+	ListAuditLog(filter repository.AuditFilter) ([]repository.AuditRecord, error)
+
+	// query statistics
+	GetQueryStats(projectID string, sortBy string, limit int) []querystats.FingerprintStats
+	GetSlowQueries(projectID string, limit int) []querystats.SlowQuery
+	ResetQueryStats(projectID string)
+
+	// shard skew
+	GetSkewReport(projectID string) (*skew.Report, error)
+	RefreshSkewReport(projectID string) (*skew.Report, error)
+
+	// resharding
+	GetShardMap(projectID string) (*reshard.MapReport, error)
+	StartRebalance(projectID string, shardID string) (*repository.ReshardJob, error)
+	ListReshardJobs(projectID string) ([]repository.ReshardJob, error)
+	GetReshardJob(projectID string, jobID string) (*reshard.JobReport, error)
+	ResumeReshardJob(projectID string, jobID string) (*repository.ReshardJob, error)
+	CancelReshardJob(projectID string, jobID string) (*repository.ReshardJob, error)
+	DrainShard(shardID string) (*repository.ReshardJob, error)
+	GetShardDrain(shardID string) (*reshard.DrainReport, error)
+	SplitShard(shardID string, targetShardID string) (*repository.ReshardJob, error)
+	MergeShards(shardID string, intoShardID string) (*repository.ReshardJob, error)
+
+	ShardProjectID(shardID string) (string, error)
+	SchemaProjectID(schemaID string) (string, error)
+	TransactionProjectID(txID string) (string, error)
+	TransactionOwner(txID string) (string, error)
+}
+
 type Handler struct {
-	app interface {
-		ExecuteSQL(projectID string, sql string) ([]executor.ExecutionResult, error)
-	}
+	app         Application
+	requireAuth bool
 }
 
-func NewHandler(app interface {
-	ExecuteSQL(projectID string, sql string) ([]executor.ExecutionResult, error)
-}) *Handler {
-	return &Handler{app: app}
+// NewHandler builds the API handler, requireAuth enforces API keys on every route
+func NewHandler(app Application, requireAuth bool) *Handler {
+	return &Handler{
+		app:         app,
+		requireAuth: requireAuth,
+	}
 }
 
 func (h *Handler) ExecuteQuery(w http.ResponseWriter, r *http.Request) {
 
 	var req ExecuteQueryRequest
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+	if !decodeBody(w, r, &req) {
 		return
 	}
 
 	if req.ProjectID == "" || req.SQL == "" {
-		http.Error(w, "project_id and sql are required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "project_id and sql are required")
+		return
+	}
+
+	if !h.authorizeProject(w, r, req.ProjectID, auth.AccessRead) {
 		return
 	}
 
-	results, err := h.app.ExecuteSQL(req.ProjectID, req.SQL)
+	results, err := h.app.ExecuteSQLAs(r.Context(), principalFrom(r.Context()), req.ProjectID, req.SQL)
 	if err != nil {
 		logger.Logger.Error("query execution failed", "error", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeAppError(w, err)
 		return
 	}
 
+	writeJSON(w, buildExecuteQueryResponse(results))
+}
+
+// buildExecuteQueryResponse converts executor results into the API shape
+func buildExecuteQueryResponse(results []executor.ExecutionResult) ExecuteQueryResponse {
+
 	resp := ExecuteQueryResponse{
 		Results: make([]ShardResultResponse, 0, len(results)),
 	}
@@ -47,7 +167,9 @@ func (h *Handler) ExecuteQuery(w http.ResponseWriter, r *http.Request) {
 	for _, r := range results {
 		out := ShardResultResponse{
 			ShardID:      r.ShardID,
+			Kind:         r.Kind.String(),
 			Columns:      r.Columns,
+			ColumnTypes:  r.ColumnTypes,
 			Rows:         r.Rows,
 			RowsAffected: r.RowsAffected,
 		}
@@ -59,6 +181,24 @@ func (h *Handler) ExecuteQuery(w http.ResponseWriter, r *http.Request) {
 		resp.Results = append(resp.Results, out)
 	}
 
+	return resp
+}
+
+// decodeBody decodes a JSON request body, writing an error response on failure
+func decodeBody(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "invalid request body")
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	writeJSONStatus(w, http.StatusOK, body)
+}
+
+func writeJSONStatus(w http.ResponseWriter, status int, body any) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
 }
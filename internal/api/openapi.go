@@ -0,0 +1,246 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// buildOpenAPI generates an OpenAPI 3 document from the route table,
+// deriving schemas from request and response types by reflection
+func buildOpenAPI(routes []route) map[string]any {
+
+	gen := &schemaGenerator{components: map[string]any{}}
+	errorSchema := gen.schemaFor(reflect.TypeOf(ErrorResponse{}))
+
+	paths := map[string]map[string]any{}
+
+	for _, rt := range routes {
+
+		op := map[string]any{
+			"summary":     rt.summary,
+			"operationId": operationID(rt),
+			"tags":        []string{rt.tag},
+		}
+
+		if params := pathParams(rt.path); len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		if rt.request != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(gen.schemaFor(reflect.TypeOf(rt.request))),
+			}
+		}
+
+		status := rt.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		op["responses"] = map[string]any{
+			strconv.Itoa(status): map[string]any{
+				"description": http.StatusText(status),
+				"content":     jsonContent(gen.schemaFor(reflect.TypeOf(rt.response))),
+			},
+			"default": map[string]any{
+				"description": "Error",
+				"content":     jsonContent(errorSchema),
+			},
+		}
+
+		if paths[rt.path] == nil {
+			paths[rt.path] = map[string]any{}
+		}
+		paths[rt.path][strings.ToLower(rt.method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "SQL Sharding API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": gen.components,
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []any{map[string]any{"apiKey": []string{}}},
+	}
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{"schema": schema},
+	}
+}
+
+// operationID derives a stable id from the handler route, e.g. post_api_projects_project_id_activate
+func operationID(rt route) string {
+	id := strings.NewReplacer("/", "_", "{", "", "}", "", "-", "_").Replace(rt.path)
+	return strings.ToLower(rt.method) + id
+}
+
+func pathParams(path string) []map[string]any {
+
+	var params []map[string]any
+
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params = append(params, map[string]any{
+				"name":     strings.Trim(segment, "{}"),
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			})
+		}
+	}
+
+	return params
+}
+
+type schemaGenerator struct {
+	components map[string]any
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+func (g *schemaGenerator) schemaFor(t reflect.Type) map[string]any {
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+
+	case reflect.Pointer:
+		schema := g.schemaFor(t.Elem())
+		if _, isRef := schema["$ref"]; isRef {
+			return map[string]any{"allOf": []any{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+
+	case reflect.String:
+		return map[string]any{"type": "string"}
+
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]any{"type": "integer", "format": "int32"}
+
+	case reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": g.schemaFor(t.Elem())}
+
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+
+	case reflect.Struct:
+		return g.structRef(t)
+
+	default:
+		// interface values, e.g. typed row values
+		return map[string]any{}
+	}
+}
+
+// structRef registers a named struct under components and returns a reference to it
+func (g *schemaGenerator) structRef(t reflect.Type) map[string]any {
+
+	name := t.Name()
+	ref := map[string]any{"$ref": "#/components/schemas/" + name}
+
+	if _, done := g.components[name]; done {
+		return ref
+	}
+
+	// placeholder guards against recursive types
+	g.components[name] = map[string]any{}
+
+	properties := map[string]any{}
+	var required []string
+	g.collectFields(t, properties, &required)
+
+	schema := map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	g.components[name] = schema
+	return ref
+}
+
+// collectFields adds the json fields of t, flattening embedded structs as encoding/json does
+func (g *schemaGenerator) collectFields(t reflect.Type, properties map[string]any, required *[]string) {
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if field.Anonymous && field.Tag.Get("json") == "" && field.Type.Kind() == reflect.Struct {
+			g.collectFields(field.Type, properties, required)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		jsonName, omitEmpty, skip := jsonField(field)
+		if skip {
+			continue
+		}
+
+		properties[jsonName] = g.schemaFor(field.Type)
+		if !omitEmpty && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, jsonName)
+		}
+	}
+}
+
+func jsonField(field reflect.StructField) (name string, omitEmpty bool, skip bool) {
+
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+
+	return name, omitEmpty, false
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"sql-sharding-v2/internal/repository"
+)
+
+func (h *Handler) ListProjects(w http.ResponseWriter, r *http.Request) {
+
+	projects, err := h.app.ListProjects()
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	if projects == nil {
+		projects = []repository.Project{}
+	}
+
+	writeJSON(w, projects)
+}
+
+func (h *Handler) CreateProject(w http.ResponseWriter, r *http.Request) {
+
+	var req CreateProjectRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "name is required")
+		return
+	}
+
+	project, err := h.app.CreateProject(req.Name, req.Description)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSONStatus(w, http.StatusCreated, project)
+}
+
+func (h *Handler) GetProject(w http.ResponseWriter, r *http.Request) {
+
+	project, err := h.app.FetchProjectByID(r.PathValue("project_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, project)
+}
+
+func (h *Handler) DeleteProject(w http.ResponseWriter, r *http.Request) {
+	h.projectAction(w, r, h.app.DeleteProject, "deleted")
+}
+
+func (h *Handler) ActivateProject(w http.ResponseWriter, r *http.Request) {
+	h.projectAction(w, r, h.app.Activateproject, "active")
+}
+
+func (h *Handler) DeactivateProject(w http.ResponseWriter, r *http.Request) {
+	h.projectAction(w, r, h.app.Deactivateproject, "inactive")
+}
+
+// projectAction runs an action on the project in the path and reports its new status
+func (h *Handler) projectAction(
+	w http.ResponseWriter,
+	r *http.Request,
+	action func(projectID string) error,
+	status string,
+) {
+
+	projectID := r.PathValue("project_id")
+
+	// resolve first so missing projects are reported as such
+	if _, err := h.app.FetchProjectByID(projectID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	if err := action(projectID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, StatusResponse{
+		ID:     projectID,
+		Status: status,
+	})
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+	"sql-sharding-v2/internal/querystats"
+)
+
+func (h *Handler) GetQueryStats(w http.ResponseWriter, r *http.Request) {
+
+	sortBy := r.URL.Query().Get("sort")
+
+	switch sortBy {
+	case "", querystats.SortTotal, querystats.SortMean, querystats.SortCalls, querystats.SortP99:
+	default:
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "sort must be total, mean, calls or p99")
+		return
+	}
+
+	limit, err := parseInt(r.URL.Query().Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "limit must be a number")
+		return
+	}
+
+	writeJSON(w, h.app.GetQueryStats(r.PathValue("project_id"), sortBy, int(limit)))
+}
+
+func (h *Handler) GetSlowQueries(w http.ResponseWriter, r *http.Request) {
+
+	limit, err := parseInt(r.URL.Query().Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "limit must be a number")
+		return
+	}
+
+	writeJSON(w, h.app.GetSlowQueries(r.PathValue("project_id"), int(limit)))
+}
+
+func (h *Handler) ResetQueryStats(w http.ResponseWriter, r *http.Request) {
+
+	projectID := r.PathValue("project_id")
+
+	h.app.ResetQueryStats(projectID)
+
+	writeJSON(w, StatusResponse{ID: projectID, Status: "reset"})
+}
+
+func (h *Handler) GetSkewReport(w http.ResponseWriter, r *http.Request) {
+
+	report, err := h.app.GetSkewReport(r.PathValue("project_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+func (h *Handler) RefreshSkewReport(w http.ResponseWriter, r *http.Request) {
+
+	report, err := h.app.RefreshSkewReport(r.PathValue("project_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, report)
+}
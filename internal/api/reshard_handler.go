@@ -0,0 +1,148 @@
+package api
+
+import (
+	"net/http"
+)
+
+func (h *Handler) GetShardMap(w http.ResponseWriter, r *http.Request) {
+
+	shardMap, err := h.app.GetShardMap(r.PathValue("project_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, shardMap)
+}
+
+func (h *Handler) StartRebalance(w http.ResponseWriter, r *http.Request) {
+
+	var req RebalanceRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if req.ShardID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "shard_id is required")
+		return
+	}
+
+	job, err := h.app.StartRebalance(r.PathValue("project_id"), req.ShardID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSONStatus(w, http.StatusAccepted, job)
+}
+
+func (h *Handler) ListReshardJobs(w http.ResponseWriter, r *http.Request) {
+
+	jobs, err := h.app.ListReshardJobs(r.PathValue("project_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, jobs)
+}
+
+func (h *Handler) GetReshardJob(w http.ResponseWriter, r *http.Request) {
+
+	report, err := h.app.GetReshardJob(r.PathValue("project_id"), r.PathValue("job_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+func (h *Handler) ResumeReshardJob(w http.ResponseWriter, r *http.Request) {
+
+	job, err := h.app.ResumeReshardJob(r.PathValue("project_id"), r.PathValue("job_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSONStatus(w, http.StatusAccepted, job)
+}
+
+func (h *Handler) CancelReshardJob(w http.ResponseWriter, r *http.Request) {
+
+	job, err := h.app.CancelReshardJob(r.PathValue("project_id"), r.PathValue("job_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, job)
+}
+
+func (h *Handler) DrainShard(w http.ResponseWriter, r *http.Request) {
+
+	job, err := h.app.DrainShard(r.PathValue("shard_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSONStatus(w, http.StatusAccepted, job)
+}
+
+func (h *Handler) GetShardDrain(w http.ResponseWriter, r *http.Request) {
+
+	report, err := h.app.GetShardDrain(r.PathValue("shard_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+func (h *Handler) SplitShard(w http.ResponseWriter, r *http.Request) {
+
+	var req SplitRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if req.TargetShardID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "target_shard_id is required")
+		return
+	}
+
+	job, err := h.app.SplitShard(r.PathValue("shard_id"), req.TargetShardID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSONStatus(w, http.StatusAccepted, job)
+}
+
+func (h *Handler) MergeShards(w http.ResponseWriter, r *http.Request) {
+
+	var req MergeRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if req.IntoShardID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "into_shard_id is required")
+		return
+	}
+
+	job, err := h.app.MergeShards(r.PathValue("shard_id"), req.IntoShardID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSONStatus(w, http.StatusAccepted, job)
+}
@@ -1,11 +1,246 @@
 package api
 
-import "net/http"
+import (
+	"net/http"
+	"sql-sharding-v2/internal/auth"
+	"sql-sharding-v2/internal/querystats"
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/reshard"
+	"sql-sharding-v2/internal/router"
+	"sql-sharding-v2/internal/shardkey"
+	"sql-sharding-v2/internal/skew"
+)
+
+// route describes an endpoint for both the mux and the OpenAPI document
+type route struct {
+	method   string
+	path     string
+	summary  string
+	tag      string
+	access   auth.Access
+	request  any // request body type, nil if the route takes no body
+	response any // success response type
+	status   int // success status, 200 if zero
+	handle   http.HandlerFunc
+}
+
+// runsStatements reports whether the route only runs statements, which the
+// router confines to the caller's tenant
+func (rt route) runsStatements() bool {
+	return rt.tag == "query" || rt.tag == "transactions"
+}
+
+func (h *Handler) routes() []route {
+	return []route{
+
+		// queries
+		{http.MethodPost, "/api/query/execute", "Route and execute a SQL statement", "query", auth.AccessRead,
+			ExecuteQueryRequest{}, ExecuteQueryResponse{}, 0, h.ExecuteQuery},
+
+		// interactive transactions
+		{http.MethodPost, "/api/tx/begin", "Begin a transaction", "transactions", auth.AccessRead,
+			BeginTransactionRequest{}, TransactionResponse{}, 0, h.BeginTransaction},
+		{http.MethodPost, "/api/tx/execute", "Execute a statement in a transaction", "transactions", auth.AccessRead,
+			TransactionQueryRequest{}, ExecuteQueryResponse{}, 0, h.ExecuteInTransaction},
+		{http.MethodPost, "/api/tx/commit", "Commit a transaction", "transactions", auth.AccessRead,
+			TransactionRequest{}, TransactionResponse{}, 0, h.CommitTransaction},
+		{http.MethodPost, "/api/tx/rollback", "Roll back a transaction", "transactions", auth.AccessRead,
+			TransactionRequest{}, TransactionResponse{}, 0, h.RollbackTransaction},
+
+		// projects
+		{http.MethodGet, "/api/projects", "List projects", "projects", auth.AccessAdmin,
+			nil, []repository.Project{}, 0, h.ListProjects},
+		{http.MethodPost, "/api/projects", "Create a project", "projects", auth.AccessAdmin,
+			CreateProjectRequest{}, repository.Project{}, http.StatusCreated, h.CreateProject},
+		{http.MethodGet, "/api/projects/{project_id}", "Get a project", "projects", auth.AccessRead,
+			nil, repository.Project{}, 0, h.GetProject},
+		{http.MethodDelete, "/api/projects/{project_id}", "Delete a project", "projects", auth.AccessAdmin,
+			nil, StatusResponse{}, 0, h.DeleteProject},
+		{http.MethodPost, "/api/projects/{project_id}/activate", "Activate a project", "projects", auth.AccessWrite,
+			nil, StatusResponse{}, 0, h.ActivateProject},
+		{http.MethodPost, "/api/projects/{project_id}/deactivate", "Deactivate a project", "projects", auth.AccessWrite,
+			nil, StatusResponse{}, 0, h.DeactivateProject},
+
+		// shards
+		{http.MethodGet, "/api/projects/{project_id}/shards", "List shards of a project", "shards", auth.AccessRead,
+			nil, []repository.Shard{}, 0, h.ListShards},
+		{http.MethodPost, "/api/projects/{project_id}/shards", "Add a shard to a project", "shards", auth.AccessWrite,
+			nil, repository.Shard{}, http.StatusCreated, h.AddShard},
+		{http.MethodDelete, "/api/shards/{shard_id}", "Delete an inactive or drained shard owning no hash slots", "shards", auth.AccessWrite,
+			nil, StatusResponse{}, 0, h.DeleteShard},
+		{http.MethodPost, "/api/shards/{shard_id}/activate", "Activate a shard", "shards", auth.AccessWrite,
+			nil, StatusResponse{}, 0, h.ActivateShard},
+		{http.MethodPost, "/api/shards/{shard_id}/deactivate", "Deactivate a shard", "shards", auth.AccessWrite,
+			nil, StatusResponse{}, 0, h.DeactivateShard},
+		{http.MethodPost, "/api/shards/{shard_id}/drain", "Mark a shard draining and move its hash slots to the other active shards", "resharding", auth.AccessAdmin,
+			nil, repository.ReshardJob{}, http.StatusAccepted, h.DrainShard},
+		{http.MethodGet, "/api/shards/{shard_id}/drain", "Hash slots a shard still owns and the progress of its drain", "resharding", auth.AccessRead,
+			nil, reshard.DrainReport{}, 0, h.GetShardDrain},
+		{http.MethodPost, "/api/shards/{shard_id}/split", "Move the upper half of a shard's hash slots to an active shard owning none", "resharding", auth.AccessAdmin,
+			SplitRequest{}, repository.ReshardJob{}, http.StatusAccepted, h.SplitShard},
+		{http.MethodPost, "/api/shards/{shard_id}/merge", "Move every hash slot of a shard into another active shard and leave it draining", "resharding", auth.AccessAdmin,
+			MergeRequest{}, repository.ReshardJob{}, http.StatusAccepted, h.MergeShards},
+
+		// resharding
+		{http.MethodGet, "/api/projects/{project_id}/shard-map", "Hash slots owned by every shard", "resharding", auth.AccessRead,
+			nil, reshard.MapReport{}, 0, h.GetShardMap},
+		{http.MethodPost, "/api/projects/{project_id}/reshard/rebalance", "Move an even share of hash slots and their rows to a new active shard", "resharding", auth.AccessAdmin,
+			RebalanceRequest{}, repository.ReshardJob{}, http.StatusAccepted, h.StartRebalance},
+		{http.MethodGet, "/api/projects/{project_id}/reshard/jobs", "List resharding jobs, newest first", "resharding", auth.AccessRead,
+			nil, []repository.ReshardJob{}, 0, h.ListReshardJobs},
+		{http.MethodGet, "/api/projects/{project_id}/reshard/jobs/{job_id}", "Resharding job with its moves and per-table progress", "resharding", auth.AccessRead,
+			nil, reshard.JobReport{}, 0, h.GetReshardJob},
+		{http.MethodPost, "/api/projects/{project_id}/reshard/jobs/{job_id}/resume", "Resume a failed resharding job from its checkpoints", "resharding", auth.AccessAdmin,
+			nil, repository.ReshardJob{}, http.StatusAccepted, h.ResumeReshardJob},
+		{http.MethodPost, "/api/projects/{project_id}/reshard/jobs/{job_id}/cancel", "Cancel a failed resharding job before cutover, removing copied rows", "resharding", auth.AccessAdmin,
+			nil, repository.ReshardJob{}, 0, h.CancelReshardJob},
+
+		// shard connections
+		{http.MethodGet, "/api/shards/{shard_id}/connection", "Get shard connection details", "shards", auth.AccessRead,
+			nil, ShardConnectionResponse{}, 0, h.GetShardConnection},
+		{http.MethodPost, "/api/shards/{shard_id}/connection", "Add shard connection details", "shards", auth.AccessWrite,
+			ShardConnectionRequest{}, StatusResponse{}, http.StatusCreated, h.AddShardConnection},
+		{http.MethodPut, "/api/shards/{shard_id}/connection", "Update shard connection details", "shards", auth.AccessWrite,
+			ShardConnectionRequest{}, StatusResponse{}, 0, h.UpdateShardConnection},
+
+		// schemas
+		{http.MethodGet, "/api/projects/{project_id}/schemas", "List committed schema versions", "schemas", auth.AccessRead,
+			nil, []repository.ProjectSchema{}, 0, h.GetSchemaHistory},
+		{http.MethodGet, "/api/projects/{project_id}/schemas/current", "Get the latest schema version", "schemas", auth.AccessRead,
+			nil, repository.ProjectSchema{}, 0, h.GetCurrentSchema},
+		{http.MethodPost, "/api/projects/{project_id}/schemas", "Create a schema draft", "schemas", auth.AccessWrite,
+			SchemaDraftRequest{}, repository.ProjectSchema{}, http.StatusCreated, h.CreateSchemaDraft},
+		{http.MethodPut, "/api/projects/{project_id}/schemas/{schema_id}", "Update a schema draft", "schemas", auth.AccessWrite,
+			SchemaDraftRequest{}, StatusResponse{}, 0, h.UpdateSchemaDraft},
+		{http.MethodPost, "/api/projects/{project_id}/schemas/{schema_id}/commit", "Commit a schema draft", "schemas", auth.AccessWrite,
+			nil, StatusResponse{}, 0, h.CommitSchemaDraft},
+		{http.MethodPost, "/api/projects/{project_id}/schemas/execute", "Execute the pending schema on all shards", "schemas", auth.AccessWrite,
+			nil, StatusResponse{}, 0, h.ExecuteSchema},
+		{http.MethodPost, "/api/projects/{project_id}/schemas/retry", "Retry a failed schema execution", "schemas", auth.AccessWrite,
+			nil, StatusResponse{}, 0, h.RetrySchema},
+		{http.MethodDelete, "/api/schemas/{schema_id}", "Delete a schema draft", "schemas", auth.AccessWrite,
+			nil, StatusResponse{}, 0, h.DeleteSchemaDraft},
+		{http.MethodGet, "/api/schemas/{schema_id}/executions", "Get per-shard execution status", "schemas", auth.AccessRead,
+			nil, []repository.SchemaExecutionStatus{}, 0, h.GetSchemaExecutions},
+
+		// shard keys
+		{http.MethodGet, "/api/projects/{project_id}/shard-keys", "List shard keys", "shard keys", auth.AccessRead,
+			nil, []repository.ShardKeys{}, 0, h.ListShardKeys},
+		{http.MethodPut, "/api/projects/{project_id}/shard-keys", "Replace shard keys", "shard keys", auth.AccessWrite,
+			ReplaceShardKeysRequest{}, []repository.ShardKeys{}, 0, h.ReplaceShardKeys},
+		{http.MethodPost, "/api/projects/{project_id}/shard-keys/recompute", "Rerun shard key inference", "shard keys", auth.AccessWrite,
+			nil, []repository.ShardKeys{}, 0, h.RecomputeShardKeys},
+		{http.MethodPost, "/api/projects/{project_id}/shard-keys/simulate", "Route statements under candidate shard keys without executing them", "shard keys", auth.AccessRead,
+			SimulateShardKeysRequest{}, router.Simulation{}, 0, h.SimulateShardKeys},
+		{http.MethodPut, "/api/projects/{project_id}/shard-keys/{table}/lock", "Pin the shard key of a table as a manual override", "shard keys", auth.AccessWrite,
+			ShardKeyLockRequest{}, []repository.ShardKeys{}, 0, h.LockShardKey},
+		{http.MethodDelete, "/api/projects/{project_id}/shard-keys/{table}/lock", "Release a manual override, the table takes the inferred key back", "shard keys", auth.AccessWrite,
+			nil, []repository.ShardKeys{}, 0, h.UnlockShardKey},
+		{http.MethodGet, "/api/projects/{project_id}/shard-keys/colocation", "Co-location groups and tables needing a denormalized key column", "shard keys", auth.AccessRead,
+			nil, []shardkey.ColocationGroup{}, 0, h.GetColocationGroups},
+		{http.MethodGet, "/api/projects/{project_id}/inference-runs", "Shard key inference runs, newest first", "shard keys", auth.AccessRead,
+			nil, []repository.InferenceRun{}, 0, h.ListInferenceRuns},
+		{http.MethodGet, "/api/projects/{project_id}/inference-runs/{run_id}", "Every ranked candidate of a run with scores and reasons, latest for the newest run", "shard keys", auth.AccessRead,
+			nil, repository.InferenceRun{}, 0, h.GetInferenceRun},
+		{http.MethodGet, "/api/projects/{project_id}/inference-profile", "Scoring weights and column patterns of shard key inference", "shard keys", auth.AccessRead,
+			nil, repository.InferenceProfile{}, 0, h.GetInferenceProfile},
+		{http.MethodPut, "/api/projects/{project_id}/inference-profile", "Replace the inference profile, omitted weights keep their default", "shard keys", auth.AccessWrite,
+			InferenceProfileRequest{}, repository.InferenceProfile{}, 0, h.SetInferenceProfile},
+		{http.MethodDelete, "/api/projects/{project_id}/inference-profile", "Reset the inference profile to the defaults", "shard keys", auth.AccessWrite,
+			nil, repository.InferenceProfile{}, 0, h.ResetInferenceProfile},
+
+		// workload
+		{http.MethodPost, "/api/projects/{project_id}/workload", "Import a query log to weigh shard key inference", "shard keys", auth.AccessWrite,
+			ImportWorkloadRequest{}, ImportWorkloadResponse{}, 0, h.ImportWorkload},
+		{http.MethodGet, "/api/projects/{project_id}/workload", "Predicate and join frequencies of imported and captured queries", "shard keys", auth.AccessRead,
+			nil, shardkey.WorkloadSummary{}, 0, h.GetWorkload},
+		{http.MethodDelete, "/api/projects/{project_id}/workload", "Drop imported queries", "shard keys", auth.AccessWrite,
+			nil, StatusResponse{}, 0, h.ClearWorkload},
+
+		// column statistics
+		{http.MethodPost, "/api/projects/{project_id}/statistics/collect", "Read column statistics from pg_stats of every active shard", "shard keys", auth.AccessWrite,
+			nil, []shardkey.ColumnDistribution{}, 0, h.CollectColumnStatistics},
+		{http.MethodPost, "/api/projects/{project_id}/statistics", "Compute column statistics from sampled rows", "shard keys", auth.AccessWrite,
+			UploadColumnSampleRequest{}, []shardkey.ColumnDistribution{}, 0, h.UploadColumnSample},
+		{http.MethodGet, "/api/projects/{project_id}/statistics", "Column statistics with the estimated load of the fullest shard", "shard keys", auth.AccessRead,
+			nil, []shardkey.ColumnDistribution{}, 0, h.GetColumnStatistics},
+		{http.MethodDelete, "/api/projects/{project_id}/statistics", "Drop column statistics, inference falls back to name heuristics", "shard keys", auth.AccessWrite,
+			nil, StatusResponse{}, 0, h.ClearColumnStatistics},
+
+		// tenant isolation
+		{http.MethodGet, "/api/projects/{project_id}/tenant", "Get the tenant column", "tenants", auth.AccessRead,
+			nil, TenantColumnResponse{}, 0, h.GetTenantColumn},
+		{http.MethodPut, "/api/projects/{project_id}/tenant", "Set the tenant column, empty turns isolation off", "tenants", auth.AccessAdmin,
+			TenantColumnRequest{}, TenantColumnResponse{}, 0, h.SetTenantColumn},
+
+		// audit
+		{http.MethodGet, "/api/projects/{project_id}/audit", "Browse the audit log, filtered by event_type, fingerprint, since, until, before_id and limit", "audit", auth.AccessAdmin,
+			nil, []repository.AuditRecord{}, 0, h.ListAuditLog},
+
+		// query statistics
+		{http.MethodGet, "/api/projects/{project_id}/query-stats", "Statement statistics per fingerprint, sorted by total, mean, calls or p99", "query stats", auth.AccessRead,
+			nil, []querystats.FingerprintStats{}, 0, h.GetQueryStats},
+		{http.MethodDelete, "/api/projects/{project_id}/query-stats", "Reset statement statistics and slow queries", "query stats", auth.AccessWrite,
+			nil, StatusResponse{}, 0, h.ResetQueryStats},
+		{http.MethodGet, "/api/projects/{project_id}/slow-queries", "Latest slow queries, newest first", "query stats", auth.AccessRead,
+			nil, []querystats.SlowQuery{}, 0, h.GetSlowQueries},
+		{http.MethodGet, "/api/projects/{project_id}/skew", "Latest shard skew report with per-shard load, skewed tables and hot key values", "query stats", auth.AccessRead,
+			nil, skew.Report{}, 0, h.GetSkewReport},
+		{http.MethodPost, "/api/projects/{project_id}/skew/refresh", "Read shard sizes and build a skew report now", "query stats", auth.AccessWrite,
+			nil, skew.Report{}, 0, h.RefreshSkewReport},
+
+		// api keys
+		{http.MethodGet, "/api/api-keys", "List API keys, optionally filtered by project_id", "api keys", auth.AccessAdmin,
+			nil, []repository.APIKey{}, 0, h.ListAPIKeys},
+		{http.MethodPost, "/api/api-keys", "Create an API key", "api keys", auth.AccessAdmin,
+			CreateAPIKeyRequest{}, APIKeySecretResponse{}, http.StatusCreated, h.CreateAPIKey},
+		{http.MethodPost, "/api/api-keys/{key_id}/rotate", "Rotate the secret of an API key", "api keys", auth.AccessAdmin,
+			nil, APIKeySecretResponse{}, 0, h.RotateAPIKey},
+		{http.MethodPut, "/api/api-keys/{key_id}/policy", "Replace the statement access policy of an API key", "api keys", auth.AccessAdmin,
+			APIKeyPolicyRequest{}, repository.APIKey{}, 0, h.UpdateAPIKeyPolicy},
+		{http.MethodDelete, "/api/api-keys/{key_id}", "Revoke an API key", "api keys", auth.AccessAdmin,
+			nil, StatusResponse{}, 0, h.RevokeAPIKey},
+	}
+}
 
 func RegisterRoutes(mux *http.ServeMux, handler *Handler) {
 
-	mux.HandleFunc(
-		"/api/query/execute",
-		handler.ExecuteQuery,
-	)
+	routes := handler.routes()
+
+	for _, rt := range routes {
+		mux.HandleFunc(rt.method+" "+rt.path, handler.guard(rt))
+	}
+
+	spec := buildOpenAPI(routes)
+	mux.HandleFunc("GET /api/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, spec)
+	})
+
+	// unmatched api requests answer with the same error shape as handlers
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		if pathHasOtherMethod(mux, r, routes) {
+			writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeError(w, http.StatusNotFound, CodeNotFound, "no such endpoint")
+	})
+}
+
+// pathHasOtherMethod reports whether the request path is served under another method
+func pathHasOtherMethod(mux *http.ServeMux, r *http.Request, routes []route) bool {
+
+	for _, rt := range routes {
+		if rt.method == r.Method {
+			continue
+		}
+
+		probe := r.Clone(r.Context())
+		probe.Method = rt.method
+
+		if _, pattern := mux.Handler(probe); pattern != "/api/" && pattern != "" {
+			return true
+		}
+	}
+
+	return false
 }
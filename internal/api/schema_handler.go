@@ -0,0 +1,140 @@
+package api
+
+import (
+	"net/http"
+	"sql-sharding-v2/internal/repository"
+)
+
+func (h *Handler) GetSchemaHistory(w http.ResponseWriter, r *http.Request) {
+
+	history, err := h.app.GetSchemaHistory(r.PathValue("project_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	if history == nil {
+		history = []repository.ProjectSchema{}
+	}
+
+	writeJSON(w, history)
+}
+
+func (h *Handler) GetCurrentSchema(w http.ResponseWriter, r *http.Request) {
+
+	schema, err := h.app.GetCurrentSchema(r.PathValue("project_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, schema)
+}
+
+func (h *Handler) CreateSchemaDraft(w http.ResponseWriter, r *http.Request) {
+
+	var req SchemaDraftRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if req.DDL == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "ddl_sql is required")
+		return
+	}
+
+	draft, err := h.app.CreateSchemaDraft(r.PathValue("project_id"), req.DDL)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSONStatus(w, http.StatusCreated, draft)
+}
+
+func (h *Handler) UpdateSchemaDraft(w http.ResponseWriter, r *http.Request) {
+
+	var req SchemaDraftRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if req.DDL == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "ddl_sql is required")
+		return
+	}
+
+	schemaID := r.PathValue("schema_id")
+
+	if err := h.app.UpdateProjectSchemaDraft(r.PathValue("project_id"), schemaID, req.DDL); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, StatusResponse{ID: schemaID, Status: "draft"})
+}
+
+func (h *Handler) CommitSchemaDraft(w http.ResponseWriter, r *http.Request) {
+
+	schemaID := r.PathValue("schema_id")
+
+	if err := h.app.CommitSchemaDraft(r.PathValue("project_id"), schemaID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, StatusResponse{ID: schemaID, Status: "pending"})
+}
+
+func (h *Handler) DeleteSchemaDraft(w http.ResponseWriter, r *http.Request) {
+
+	schemaID := r.PathValue("schema_id")
+
+	if err := h.app.DeleteSchemaDraft(schemaID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, StatusResponse{ID: schemaID, Status: "deleted"})
+}
+
+func (h *Handler) GetSchemaExecutions(w http.ResponseWriter, r *http.Request) {
+
+	statuses, err := h.app.GetSchemaExecutionStatus(r.PathValue("schema_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	if statuses == nil {
+		statuses = []repository.SchemaExecutionStatus{}
+	}
+
+	writeJSON(w, statuses)
+}
+
+func (h *Handler) ExecuteSchema(w http.ResponseWriter, r *http.Request) {
+
+	projectID := r.PathValue("project_id")
+
+	if err := h.app.ExecuteProjectSchema(projectID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, StatusResponse{ID: projectID, Status: "applied"})
+}
+
+func (h *Handler) RetrySchema(w http.ResponseWriter, r *http.Request) {
+
+	projectID := r.PathValue("project_id")
+
+	if err := h.app.RetrySchemaExecution(projectID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, StatusResponse{ID: projectID, Status: "pending"})
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"net/http"
+	"sql-sharding-v2/internal/repository"
+)
+
+func (h *Handler) ListShards(w http.ResponseWriter, r *http.Request) {
+
+	shards, err := h.app.ListShards(r.PathValue("project_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	if shards == nil {
+		shards = []repository.Shard{}
+	}
+
+	writeJSON(w, shards)
+}
+
+func (h *Handler) AddShard(w http.ResponseWriter, r *http.Request) {
+
+	projectID := r.PathValue("project_id")
+
+	if _, err := h.app.FetchProjectByID(projectID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	shard, err := h.app.AddShard(projectID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSONStatus(w, http.StatusCreated, shard)
+}
+
+func (h *Handler) ActivateShard(w http.ResponseWriter, r *http.Request) {
+
+	shardID := r.PathValue("shard_id")
+
+	if err := h.app.ActivateShard(shardID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, StatusResponse{ID: shardID, Status: "active"})
+}
+
+func (h *Handler) DeactivateShard(w http.ResponseWriter, r *http.Request) {
+
+	shardID := r.PathValue("shard_id")
+
+	if err := h.app.DeactivateShard(shardID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, StatusResponse{ID: shardID, Status: "inactive"})
+}
+
+func (h *Handler) DeleteShard(w http.ResponseWriter, r *http.Request) {
+
+	shardID := r.PathValue("shard_id")
+
+	status, err := h.app.DeleteShard(shardID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	if status != "DELETED" {
+		writeError(w, http.StatusConflict, CodeRuleViolation, "shard must be inactive before it is deleted")
+		return
+	}
+
+	writeJSON(w, StatusResponse{ID: shardID, Status: "deleted"})
+}
+
+func (h *Handler) GetShardConnection(w http.ResponseWriter, r *http.Request) {
+
+	conn, err := h.app.FetchConnectionInfo(r.PathValue("shard_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, ShardConnectionResponse{
+		ShardID:      conn.ShardID,
+		Host:         conn.Host,
+		Port:         conn.Port,
+		DatabaseName: conn.DatabaseName,
+		Username:     conn.Username,
+		CreatedAt:    conn.CreatedAt,
+		UpdatedAt:    conn.UpdatedAt,
+	})
+}
+
+func (h *Handler) AddShardConnection(w http.ResponseWriter, r *http.Request) {
+
+	conn, ok := decodeConnection(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.app.AddConnection(&conn); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSONStatus(w, http.StatusCreated, StatusResponse{ID: conn.ShardID, Status: "created"})
+}
+
+func (h *Handler) UpdateShardConnection(w http.ResponseWriter, r *http.Request) {
+
+	conn, ok := decodeConnection(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.app.UpdateConnection(conn); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, StatusResponse{ID: conn.ShardID, Status: "updated"})
+}
+
+// decodeConnection reads and validates connection details for the shard in the path
+func decodeConnection(w http.ResponseWriter, r *http.Request) (repository.ShardConnection, bool) {
+
+	var req ShardConnectionRequest
+
+	if !decodeBody(w, r, &req) {
+		return repository.ShardConnection{}, false
+	}
+
+	if req.Host == "" || req.DatabaseName == "" || req.Username == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "host, database_name and username are required")
+		return repository.ShardConnection{}, false
+	}
+
+	if req.Port == 0 {
+		req.Port = 5432
+	}
+
+	if req.Port < 0 || req.Port > 65535 {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "port is out of range")
+		return repository.ShardConnection{}, false
+	}
+
+	return repository.ShardConnection{
+		ShardID:      r.PathValue("shard_id"),
+		Host:         req.Host,
+		Port:         req.Port,
+		DatabaseName: req.DatabaseName,
+		Username:     req.Username,
+		Password:     req.Password,
+	}, true
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"net/http"
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/shardkey"
+)
+
+func (h *Handler) ListShardKeys(w http.ResponseWriter, r *http.Request) {
+	h.writeShardKeys(w, r.PathValue("project_id"))
+}
+
+func (h *Handler) ReplaceShardKeys(w http.ResponseWriter, r *http.Request) {
+
+	var req ReplaceShardKeysRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	records := make([]repository.ShardKeyRecord, 0, len(req.Keys))
+
+	for _, key := range req.Keys {
+		if key.TableName == "" || key.ShardKeyColumn == "" {
+			writeError(w, http.StatusBadRequest, CodeInvalidRequest, "table_name and shard_key_column are required for every key")
+			return
+		}
+
+		records = append(records, repository.ShardKeyRecord{
+			TableName:      key.TableName,
+			ShardKeyColumn: key.ShardKeyColumn,
+			IsManual:       key.IsManual,
+		})
+	}
+
+	projectID := r.PathValue("project_id")
+
+	if err := h.app.ReplaceShardKeys(projectID, records); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	h.writeShardKeys(w, projectID)
+}
+
+func (h *Handler) RecomputeShardKeys(w http.ResponseWriter, r *http.Request) {
+
+	projectID := r.PathValue("project_id")
+
+	if err := h.app.RecomputeKeys(projectID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	h.writeShardKeys(w, projectID)
+}
+
+func (h *Handler) LockShardKey(w http.ResponseWriter, r *http.Request) {
+
+	var req ShardKeyLockRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	projectID := r.PathValue("project_id")
+
+	if err := h.app.LockShardKey(projectID, r.PathValue("table"), req.ShardKeyColumn); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	h.writeShardKeys(w, projectID)
+}
+
+func (h *Handler) UnlockShardKey(w http.ResponseWriter, r *http.Request) {
+
+	projectID := r.PathValue("project_id")
+
+	if err := h.app.UnlockShardKey(projectID, r.PathValue("table")); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	h.writeShardKeys(w, projectID)
+}
+
+func (h *Handler) GetColocationGroups(w http.ResponseWriter, r *http.Request) {
+
+	groups, err := h.app.GetColocationGroups(r.PathValue("project_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, groups)
+}
+
+func (h *Handler) ListInferenceRuns(w http.ResponseWriter, r *http.Request) {
+
+	limit, err := parseInt(r.URL.Query().Get("limit"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "limit must be a number")
+		return
+	}
+
+	runs, err := h.app.ListInferenceRuns(r.PathValue("project_id"), int(limit))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, runs)
+}
+
+func (h *Handler) GetInferenceRun(w http.ResponseWriter, r *http.Request) {
+
+	run, err := h.app.GetInferenceRun(r.PathValue("project_id"), r.PathValue("run_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, run)
+}
+
+func (h *Handler) SimulateShardKeys(w http.ResponseWriter, r *http.Request) {
+
+	var req SimulateShardKeysRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if req.ShardCount < 0 {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "shard_count must not be negative")
+		return
+	}
+
+	queries := make([]repository.WorkloadQuery, 0, len(req.Queries))
+	for _, q := range req.Queries {
+		queries = append(queries, repository.WorkloadQuery{Query: q.Query, Calls: q.Calls})
+	}
+
+	sim, err := h.app.SimulateShardKeys(r.PathValue("project_id"), req.ShardKeys, req.ShardCount, queries, req.UseWorkload)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, sim)
+}
+
+func (h *Handler) GetInferenceProfile(w http.ResponseWriter, r *http.Request) {
+
+	profile, err := h.app.GetInferenceProfile(r.PathValue("project_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, profile)
+}
+
+func (h *Handler) SetInferenceProfile(w http.ResponseWriter, r *http.Request) {
+
+	// weights left out of the body keep their default
+	req := InferenceProfileRequest{Weights: shardkey.DefaultWeights()}
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	profile, err := h.app.SetInferenceProfile(r.PathValue("project_id"), repository.InferenceProfile{
+		Weights:            req.Weights,
+		EliminatedPatterns: req.EliminatedPatterns,
+		PreferredColumns:   req.PreferredColumns,
+	})
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, profile)
+}
+
+func (h *Handler) ResetInferenceProfile(w http.ResponseWriter, r *http.Request) {
+
+	projectID := r.PathValue("project_id")
+
+	if err := h.app.ResetInferenceProfile(projectID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	h.GetInferenceProfile(w, r)
+}
+
+func (h *Handler) writeShardKeys(w http.ResponseWriter, projectID string) {
+
+	keys, err := h.app.FetchShardKeys(projectID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	if keys == nil {
+		keys = []repository.ShardKeys{}
+	}
+
+	writeJSON(w, keys)
+}
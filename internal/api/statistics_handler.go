@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+)
+
+func (h *Handler) CollectColumnStatistics(w http.ResponseWriter, r *http.Request) {
+
+	stats, err := h.app.CollectColumnStatistics(r.PathValue("project_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, stats)
+}
+
+func (h *Handler) UploadColumnSample(w http.ResponseWriter, r *http.Request) {
+
+	var req UploadColumnSampleRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	stats, err := h.app.UploadColumnSample(r.PathValue("project_id"), req.Tables)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, stats)
+}
+
+func (h *Handler) GetColumnStatistics(w http.ResponseWriter, r *http.Request) {
+
+	stats, err := h.app.GetColumnStatistics(r.PathValue("project_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, stats)
+}
+
+func (h *Handler) ClearColumnStatistics(w http.ResponseWriter, r *http.Request) {
+
+	projectID := r.PathValue("project_id")
+
+	if err := h.app.ClearColumnStatistics(projectID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, StatusResponse{ID: projectID, Status: "cleared"})
+}
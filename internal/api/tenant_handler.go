@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+)
+
+func (h *Handler) GetTenantColumn(w http.ResponseWriter, r *http.Request) {
+
+	projectID := r.PathValue("project_id")
+
+	column, err := h.app.GetTenantColumn(projectID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, TenantColumnResponse{ProjectID: projectID, Column: column})
+}
+
+func (h *Handler) SetTenantColumn(w http.ResponseWriter, r *http.Request) {
+
+	var req TenantColumnRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	projectID := r.PathValue("project_id")
+
+	if err := h.app.SetTenantColumn(projectID, req.Column); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	column, err := h.app.GetTenantColumn(projectID)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, TenantColumnResponse{ProjectID: projectID, Column: column})
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+	"sql-sharding-v2/internal/auth"
+	"sql-sharding-v2/internal/transaction"
+	"sql-sharding-v2/pkg/logger"
+)
+
+func (h *Handler) BeginTransaction(w http.ResponseWriter, r *http.Request) {
+
+	var req BeginTransactionRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if req.ProjectID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "project_id is required")
+		return
+	}
+
+	if !h.authorizeProject(w, r, req.ProjectID, auth.AccessRead) {
+		return
+	}
+
+	txID, err := h.app.BeginTransactionAs(principalFrom(r.Context()), req.ProjectID)
+	if err != nil {
+		logger.Logger.Error("transaction begin failed", "error", err)
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, TransactionResponse{
+		TxID:   txID,
+		Status: string(transaction.TxStateActive),
+	})
+}
+
+func (h *Handler) ExecuteInTransaction(w http.ResponseWriter, r *http.Request) {
+
+	var req TransactionQueryRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if req.TxID == "" || req.SQL == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "tx_id and sql are required")
+		return
+	}
+
+	if !h.authorizeTransaction(w, r, req.TxID) {
+		return
+	}
+
+	results, err := h.app.ExecuteInTransactionAs(r.Context(), principalFrom(r.Context()), req.TxID, req.SQL)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, buildExecuteQueryResponse(results))
+}
+
+func (h *Handler) CommitTransaction(w http.ResponseWriter, r *http.Request) {
+	h.completeTransaction(w, r, h.app.CommitTransaction, transaction.TxStateCommitted)
+}
+
+func (h *Handler) RollbackTransaction(w http.ResponseWriter, r *http.Request) {
+	h.completeTransaction(w, r, h.app.RollbackTransaction, transaction.TxStateRolledBack)
+}
+
+// completeTransaction handles commit and rollback which share the same shape
+func (h *Handler) completeTransaction(
+	w http.ResponseWriter,
+	r *http.Request,
+	complete func(txID string) error,
+	state transaction.TxState,
+) {
+
+	var req TransactionRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	if req.TxID == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "tx_id is required")
+		return
+	}
+
+	if !h.authorizeTransaction(w, r, req.TxID) {
+		return
+	}
+
+	if err := complete(req.TxID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, TransactionResponse{
+		TxID:   req.TxID,
+		Status: string(state),
+	})
+}
+
+// authorizeTransaction checks the caller may access the project the transaction
+// runs against and is the API key that began it
+func (h *Handler) authorizeTransaction(w http.ResponseWriter, r *http.Request, txID string) bool {
+
+	if !h.requireAuth {
+		return true
+	}
+
+	projectID, err := h.app.TransactionProjectID(txID)
+	if err != nil {
+		writeAppError(w, err)
+		return false
+	}
+
+	if !h.authorizeProject(w, r, projectID, auth.AccessRead) {
+		return false
+	}
+
+	owner, err := h.app.TransactionOwner(txID)
+	if err != nil {
+		writeAppError(w, err)
+		return false
+	}
+
+	if principalFrom(r.Context()).KeyID != owner {
+		writeError(w, http.StatusForbidden, CodeForbidden, "transaction was begun by another API key")
+		return false
+	}
+
+	return true
+}
@@ -1,5 +1,10 @@
 package api
 
+import (
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/shardstats"
+)
+
 type ExecuteQueryRequest struct {
 	ProjectID string `json:"project_id"`
 	SQL       string `json:"sql"`
@@ -7,7 +12,9 @@ type ExecuteQueryRequest struct {
 
 type ShardResultResponse struct {
 	ShardID      string   `json:"shard_id"`
+	Kind         string   `json:"statement_kind"`
 	Columns      []string `json:"columns,omitempty"`
+	ColumnTypes  []string `json:"column_types,omitempty"`
 	Rows         [][]any  `json:"rows,omitempty"`
 	RowsAffected int64    `json:"rows_affected,omitempty"`
 	Error        string   `json:"error,omitempty"`
@@ -16,3 +23,165 @@ type ShardResultResponse struct {
 type ExecuteQueryResponse struct {
 	Results []ShardResultResponse `json:"results"`
 }
+
+type BeginTransactionRequest struct {
+	ProjectID string `json:"project_id"`
+}
+
+type TransactionRequest struct {
+	TxID string `json:"tx_id"`
+}
+
+type TransactionQueryRequest struct {
+	TxID string `json:"tx_id"`
+	SQL  string `json:"sql"`
+}
+
+type TransactionResponse struct {
+	TxID   string `json:"tx_id"`
+	Status string `json:"status"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// StatusResponse acknowledges an action on a resource
+type StatusResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+type CreateProjectRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type ShardConnectionRequest struct {
+	Host         string `json:"host"`
+	Port         int    `json:"port"`
+	DatabaseName string `json:"database_name"`
+	Username     string `json:"username"`
+	Password     string `json:"password"`
+}
+
+// ShardConnectionResponse is a shard connection without its password
+type ShardConnectionResponse struct {
+	ShardID      string `json:"shard_id"`
+	Host         string `json:"host"`
+	Port         int    `json:"port"`
+	DatabaseName string `json:"database_name"`
+	Username     string `json:"username"`
+	CreatedAt    string `json:"created_at"`
+	UpdatedAt    string `json:"updated_at"`
+}
+
+type SchemaDraftRequest struct {
+	DDL string `json:"ddl_sql"`
+}
+
+type ShardKeyRequest struct {
+	TableName      string `json:"table_name"`
+	ShardKeyColumn string `json:"shard_key_column"`
+	IsManual       bool   `json:"is_manual_override"`
+}
+
+type ReplaceShardKeysRequest struct {
+	Keys []ShardKeyRequest `json:"keys"`
+}
+
+// SimulateShardKeysRequest routes statements under candidate shard keys,
+// tables not named keep their current key
+type SimulateShardKeysRequest struct {
+	ShardKeys   map[string]string      `json:"shard_keys"`
+	ShardCount  int                    `json:"shard_count,omitempty"`
+	Queries     []WorkloadQueryRequest `json:"queries"`
+	UseWorkload bool                   `json:"use_workload"`
+}
+
+// ShardKeyLockRequest pins the key of a table, an empty column locks the current key
+type ShardKeyLockRequest struct {
+	ShardKeyColumn string `json:"shard_key_column,omitempty"`
+}
+
+type CreateAPIKeyRequest struct {
+	ProjectID           string   `json:"project_id,omitempty"`
+	Name                string   `json:"name"`
+	Role                string   `json:"role"`
+	AllowedTables       []string `json:"allowed_tables,omitempty"`
+	DenyBroadcastWrites bool     `json:"deny_broadcast_writes,omitempty"`
+	TenantID            string   `json:"tenant_id,omitempty"`
+}
+
+// APIKeyPolicyRequest replaces the statement restrictions of a key
+type APIKeyPolicyRequest struct {
+	AllowedTables       []string `json:"allowed_tables"`
+	DenyBroadcastWrites bool     `json:"deny_broadcast_writes"`
+	TenantID            string   `json:"tenant_id,omitempty"`
+}
+
+// TenantColumnRequest sets the tenant column of a project, empty turns isolation off
+type TenantColumnRequest struct {
+	Column string `json:"column"`
+}
+
+// TenantColumnResponse reports the tenant column of a project
+type TenantColumnResponse struct {
+	ProjectID string `json:"project_id"`
+	Column    string `json:"column"`
+}
+
+// WorkloadQueryRequest is one statement of an imported query log
+type WorkloadQueryRequest struct {
+	Query string `json:"query"`
+	Calls int64  `json:"calls,omitempty"`
+}
+
+// ImportWorkloadRequest imports a query log to weigh shard key inference
+type ImportWorkloadRequest struct {
+	Queries []WorkloadQueryRequest `json:"queries"`
+}
+
+// ImportWorkloadResponse reports how many distinct statements were imported
+type ImportWorkloadResponse struct {
+	ProjectID string `json:"project_id"`
+	Imported  int    `json:"imported"`
+}
+
+// InferenceProfileRequest replaces the inference profile of a project.
+// Patterns are column name globs, with a dot they match table.column.
+type InferenceProfileRequest struct {
+	Weights            repository.InferenceWeights `json:"weights"`
+	EliminatedPatterns []string                    `json:"eliminated_patterns"`
+	PreferredColumns   []string                    `json:"preferred_columns"`
+}
+
+// UploadColumnSampleRequest carries sampled rows per table, statistics are
+// computed from them when shards cannot be read directly
+type UploadColumnSampleRequest struct {
+	Tables []shardstats.TableSample `json:"tables"`
+}
+
+// RebalanceRequest names an active shard owning no hash slots, it receives
+// an even share of slots from the other shards
+type RebalanceRequest struct {
+	ShardID string `json:"shard_id"`
+}
+
+// SplitRequest names an active shard owning no hash slots, it receives the
+// upper half of the split shard's slots
+type SplitRequest struct {
+	TargetShardID string `json:"target_shard_id"`
+}
+
+// MergeRequest names the active shard receiving every slot of the merged one
+type MergeRequest struct {
+	IntoShardID string `json:"into_shard_id"`
+}
+
+// APIKeySecretResponse carries the key secret, which is only shown once
+type APIKeySecretResponse struct {
+	repository.APIKey
+	Secret string `json:"secret"`
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"sql-sharding-v2/internal/repository"
+)
+
+func (h *Handler) ImportWorkload(w http.ResponseWriter, r *http.Request) {
+
+	var req ImportWorkloadRequest
+
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	queries := make([]repository.WorkloadQuery, 0, len(req.Queries))
+	for _, q := range req.Queries {
+		queries = append(queries, repository.WorkloadQuery{Query: q.Query, Calls: q.Calls})
+	}
+
+	projectID := r.PathValue("project_id")
+
+	imported, err := h.app.ImportWorkload(projectID, queries)
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, ImportWorkloadResponse{ProjectID: projectID, Imported: imported})
+}
+
+func (h *Handler) GetWorkload(w http.ResponseWriter, r *http.Request) {
+
+	summary, err := h.app.GetWorkloadSummary(r.PathValue("project_id"))
+	if err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, summary)
+}
+
+func (h *Handler) ClearWorkload(w http.ResponseWriter, r *http.Request) {
+
+	projectID := r.PathValue("project_id")
+
+	if err := h.app.ClearWorkload(projectID); err != nil {
+		writeAppError(w, err)
+		return
+	}
+
+	writeJSON(w, StatusResponse{ID: projectID, Status: "cleared"})
+}
@@ -0,0 +1,1769 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sql-sharding-v2/internal/api"
+	"sql-sharding-v2/internal/audit"
+	"sql-sharding-v2/internal/auth"
+	"sql-sharding-v2/internal/config"
+	"sql-sharding-v2/internal/connections"
+	"sql-sharding-v2/internal/executor"
+	"sql-sharding-v2/internal/loader"
+	"sql-sharding-v2/internal/metrics"
+	"sql-sharding-v2/internal/pgwire"
+	"sql-sharding-v2/internal/querystats"
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/reshard"
+	"sql-sharding-v2/internal/router"
+	"sql-sharding-v2/internal/schema"
+	"sql-sharding-v2/internal/shardkey"
+	"sql-sharding-v2/internal/skew"
+	"sql-sharding-v2/internal/tracing"
+	"sql-sharding-v2/internal/transaction"
+	"sql-sharding-v2/pkg/logger"
+	"sync"
+	"time"
+)
+
+// App struct
+type App struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	emitter *logger.LogEmitter
+
+	// application database
+	db *sql.DB
+
+	// http
+	httpServer *http.Server
+
+	// flushes and stops trace export
+	stopTracing func(context.Context) error
+
+	// postgres wire protocol
+	pgServer *pgwire.Server
+
+	//config
+	RouterConfig router.RouterConfig
+
+	// repository
+	ProjectRepo               *repository.ProjectRepository
+	ShardRepo                 *repository.ShardRepository
+	ShardConnectionRepo       *repository.ShardConnectionRepository
+	ProjectSchemaRepo         *repository.ProjectSchemaRepository
+	SchemaExecutionStatusRepo *repository.SchemaExecutionStatusRepository
+	ColumnsRepo               *repository.ColumnRepository
+	FKEdgesRepo               *repository.FKEdgesRepository
+	ShardKeysRepo             *repository.ShardKeysRepository
+	APIKeyRepo                *repository.APIKeyRepository
+	AuditLogRepo              *repository.AuditLogRepository
+	WorkloadRepo              *repository.WorkloadRepository
+	InferenceRunRepo          *repository.InferenceRunRepository
+	ColumnStatisticsRepo      *repository.ColumnStatisticsRepository
+	InferenceProfileRepo      *repository.InferenceProfileRepository
+	ShardMapRepo              *repository.ShardMapRepository
+	ReshardRepo               *repository.ReshardRepository
+
+	// conn layer
+	ShardConnectionStore   *connections.ConnectionStore
+	ShardConnectionManager *connections.ConnectionManager
+
+	//services
+	SchemaService    *schema.SchemaService
+	InferenceService *shardkey.InferenceService
+	RouterService    *router.RouterService
+	ExecutorService  *executor.Executor
+
+	// transactions
+	TransactionManager *transaction.Manager
+
+	// audit
+	AuditRecorder *audit.Recorder
+
+	// per-fingerprint statistics
+	QueryStats *querystats.Collector
+	slowLog    *os.File
+
+	// shard skew and hot keys
+	SkewDetector *skew.Detector
+
+	// resharding, runs of this process by job id
+	Mover       *reshard.Mover
+	reshardMu   sync.Mutex
+	reshardRuns map[string]chan struct{}
+}
+
+// New creates a new App application struct
+func New() *App {
+	return &App{}
+}
+
+// Open loads configuration, migrates and connects the application database,
+// builds repositories and services and connects to shards. It starts no
+// listeners or background jobs, which is what command-line tooling needs.
+func (a *App) Open(ctx context.Context, sink logger.EventSink) error {
+	a.ctx, a.cancel = context.WithCancel(ctx)
+	a.emitter = logger.NewLogEmitter(sink)
+
+	err := loader.LoadServices(a.ctx)
+	if err != nil {
+		return err
+	}
+
+	db, err := loader.LoadAppilcationDatabase()
+	if err != nil {
+		return err
+	}
+	a.db = db
+
+	a.wire(db)
+
+	err = a.ShardConnectionManager.InititateConnectionsAll(a.ctx)
+	if err != nil {
+		logger.Logger.Error("Failed to initiate connection for active project", "error", err)
+	}
+
+	return nil
+}
+
+// Start opens the app and starts the HTTP API, wire protocol listener,
+// shard monitor and transaction reaper. Events are published to the given sink.
+func (a *App) Start(ctx context.Context, sink logger.EventSink) error {
+
+	if err := a.Open(ctx, sink); err != nil {
+		return err
+	}
+
+	// tracing
+	stopTracing, err := tracing.Setup(a.ctx, config.TracingSettings.OTLP_ENDPOINT, config.TracingSettings.SERVICE_NAME)
+	if err != nil {
+		logger.Logger.Error("Failed to set up tracing", "error", err)
+	} else {
+		a.stopTracing = stopTracing
+	}
+
+	//api
+	mux := http.NewServeMux()
+	apiHandler := api.NewHandler(a, config.ApplicationServerSettings.API_AUTH)
+	api.RegisterRoutes(mux, apiHandler)
+	metrics.RegisterPools(a.ShardConnectionStore)
+	mux.Handle("GET /metrics", metrics.Handler())
+	a.httpServer = &http.Server{
+		Addr:    config.ApplicationServerSettings.HTTP_ADDR,
+		Handler: tracing.HTTPHandler(mux),
+	}
+
+	go func() {
+		logger.Logger.Info("HTTP server started", "addr", a.httpServer.Addr)
+		if err := a.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Logger.Error("HTTP server failed", "error", err)
+		}
+	}()
+
+	// postgres wire protocol
+	a.pgServer = pgwire.NewServer(config.ApplicationServerSettings.PGWIRE_ADDR, a, config.ApplicationServerSettings.API_AUTH)
+
+	go func() {
+		if err := a.pgServer.ListenAndServe(); err != nil && err != pgwire.ErrServerClosed {
+			logger.Logger.Error("PostgreSQL wire server failed", "error", err)
+		}
+	}()
+
+	// funcs
+	go a.TransactionManager.Run(a.ctx)
+	go a.AuditRecorder.Run(a.ctx)
+	go a.QueryStats.Run(a.ctx)
+	go a.MonitorShards(a.ctx)
+	go a.SkewDetector.Run(a.ctx)
+	go a.MonitorSkew(a.ctx)
+	go a.resumeReshardJobs()
+
+	logger.Logger.Info("Application startup successful!")
+
+	return nil
+}
+
+// wire builds repositories, connection layer and services on the application database
+func (a *App) wire(db *sql.DB) {
+
+	//config
+	a.RouterConfig = router.DefaultRouterConfig()
+
+	// repos
+	a.ProjectRepo = repository.NewProjectRepository(db)
+	a.ShardRepo = repository.NewShardRepository(db)
+	a.ShardConnectionRepo = repository.NewShardConnectionRepository(db)
+	a.ProjectSchemaRepo = repository.NewProjectSchemaRepository(db)
+	a.SchemaExecutionStatusRepo = repository.NewSchemaExecutionStatusRepository(db)
+	a.ColumnsRepo = repository.NewColumnsRepository(db)
+	a.FKEdgesRepo = repository.NewFKEdgesRepository(db)
+	a.ShardKeysRepo = repository.NewShardKeysRepository(db)
+	a.APIKeyRepo = repository.NewAPIKeyRepository(db)
+	a.AuditLogRepo = repository.NewAuditLogRepository(db)
+	a.WorkloadRepo = repository.NewWorkloadRepository(db)
+	a.InferenceRunRepo = repository.NewInferenceRunRepository(db)
+	a.ColumnStatisticsRepo = repository.NewColumnStatisticsRepository(db)
+	a.InferenceProfileRepo = repository.NewInferenceProfileRepository(db)
+	a.ShardMapRepo = repository.NewShardMapRepository(db)
+	a.ReshardRepo = repository.NewReshardRepository(db)
+
+	// stores
+	a.ShardConnectionStore = connections.NewConnectionStore()
+
+	// managers
+	a.ShardConnectionManager = connections.NewConnectionManager(
+		a.ShardConnectionStore,
+		a.ProjectRepo,
+		a.ShardRepo,
+		a.ShardConnectionRepo,
+	)
+
+	// services
+	a.SchemaService = schema.NewSchemaService(
+		a.ColumnsRepo,
+		a.FKEdgesRepo,
+	)
+	a.InferenceService = shardkey.NewInferenceService(
+		a.ColumnsRepo,
+		a.FKEdgesRepo,
+		a.ShardKeysRepo,
+		a.WorkloadRepo,
+		a.InferenceRunRepo,
+		a.ColumnStatisticsRepo,
+		a.InferenceProfileRepo,
+	)
+	a.RouterService = router.NewRouterService(
+		a.ShardKeysRepo,
+		a.ShardRepo,
+		a.ShardMapRepo,
+		a.ReshardRepo,
+		a.RouterConfig,
+	)
+	a.ExecutorService = executor.NewExecutor(
+		a.ShardConnectionStore,
+	)
+	a.AuditRecorder = audit.NewRecorder(a.AuditLogRepo, audit.Settings{
+		BufferSize:    config.AuditSettings.BUFFER_SIZE,
+		BatchSize:     config.AuditSettings.BATCH_SIZE,
+		FlushInterval: config.AuditSettings.FLUSH_INTERVAL,
+		Retention:     config.AuditSettings.RETENTION,
+	})
+	a.RouterService.SetDenialRecorder(a.AuditRecorder)
+	a.QueryStats = querystats.NewCollector(querystats.Settings{
+		SlowThreshold:   config.QueryStatsSettings.SLOW_THRESHOLD,
+		MaxFingerprints: config.QueryStatsSettings.MAX_FINGERPRINTS,
+	}, a.openSlowLog())
+	a.SkewDetector = skew.NewDetector(skew.Settings{
+		Threshold:   config.SkewSettings.THRESHOLD,
+		HotKeyShare: config.SkewSettings.HOT_KEY_SHARE,
+	})
+	a.TransactionManager = transaction.NewManager(
+		a.ShardConnectionStore,
+		a.RouterService,
+		transaction.DefaultIdleTimeout,
+	)
+	a.Mover = reshard.NewMover(
+		a.ReshardRepo,
+		a.ShardMapRepo,
+		a.ShardConnectionStore,
+		a.TransactionManager,
+		reshard.Settings{
+			BatchSize:      config.ReshardSettings.BATCH_SIZE,
+			CutoverTimeout: config.ReshardSettings.CUTOVER_TIMEOUT,
+		},
+	)
+	a.reshardRuns = make(map[string]chan struct{})
+}
+
+// Shutdown stops listeners, rolls back open transactions and closes
+// shard and application database connections.
+func (a *App) Shutdown(ctx context.Context) error {
+
+	var errs []error
+
+	if a.httpServer != nil {
+		if err := a.httpServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("http server: %w", err))
+		}
+	}
+
+	if a.pgServer != nil {
+		if err := a.pgServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("wire server: %w", err))
+		}
+	}
+
+	if a.TransactionManager != nil {
+		a.TransactionManager.Close(ctx)
+	}
+
+	if a.AuditRecorder != nil {
+		a.AuditRecorder.Close(ctx)
+	}
+
+	if a.stopTracing != nil {
+		if err := a.stopTracing(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("tracing: %w", err))
+		}
+	}
+
+	if a.cancel != nil {
+		a.cancel()
+	}
+
+	a.waitReshardRuns(ctx)
+
+	if a.slowLog != nil {
+		_ = a.slowLog.Close()
+	}
+
+	if a.ShardConnectionStore != nil {
+		a.ShardConnectionStore.CloseAll()
+	}
+
+	if a.db != nil {
+		if err := a.db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("application database: %w", err))
+		}
+	}
+
+	logger.Logger.Info("Application shutdown complete")
+
+	return errors.Join(errs...)
+}
+
+// project repository - Call to add a new project
+func (a *App) CreateProject(name string, description string) (*repository.Project, error) {
+
+	result, err := a.ProjectRepo.ProjectAdd(a.ctx, name, description)
+	if err != nil {
+		logger.Logger.Error("Error while creating project", "project_name", name, "error", err)
+		a.emitter.Error("Project creation failed", "application - CreateProject", map[string]string{
+			"project_name": name,
+			"error":        err.Error(),
+		})
+		return nil, err
+	}
+
+	logger.Logger.Info("Successfully created project", "project_name", name)
+	a.emitter.Info("project creation successful", "application - CreateProject", map[string]string{
+		"project_name": name,
+	})
+
+	return result, nil
+}
+
+// project repository - Call to list existing project
+func (a *App) ListProjects() ([]repository.Project, error) {
+
+	result, err := a.ProjectRepo.ProjectList(a.ctx)
+
+	if err != nil {
+		logger.Logger.Error("Error while fetching projects", "error", err)
+		a.emitter.Error("Projects listing failed", "application - ListProjects", map[string]string{
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// project repository - Call to delete a project
+func (a *App) DeleteProject(id string) error {
+
+	err := a.ProjectRepo.ProjectRemove(a.ctx, id)
+	if err != nil {
+		logger.Logger.Error("Error while deleting project: ", "project _ id", id, "error", err)
+		a.emitter.Error("Project deletion failed", "application - DeleteProject", map[string]string{
+			"project_id": id,
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	logger.Logger.Info("Successfully deleted project", "project_id", id)
+	a.emitter.Info("Project deletion failed", "application - DeleteProject", map[string]string{
+		"project_id": id,
+	})
+
+	return nil
+}
+
+// project repository - Call to resolve a project by ID or name
+func (a *App) ResolveProject(name string) (string, error) {
+
+	projects, err := a.ListProjects()
+	if err != nil {
+		return "", err
+	}
+
+	for _, project := range projects {
+		if project.ID == name || project.Name == name {
+			return project.ID, nil
+		}
+	}
+
+	return "", fmt.Errorf("project %q not found", name)
+}
+
+// project repository - Call to fetch project by ID
+func (a *App) FetchProjectByID(id string) (repository.Project, error) {
+
+	result, err := a.ProjectRepo.GetProjectByID(a.ctx, id)
+	if err != nil {
+		logger.Logger.Error("Error while fetching project", "project_id", id, "error", err)
+		a.emitter.Error("Project fetching failed", "application - FetchProjectByID", map[string]string{
+			"project_id": id,
+			"error":      err.Error(),
+		})
+		return repository.Project{}, err
+	}
+
+	return result, err
+}
+
+// shard repository - Call to add a shard
+func (a *App) AddShard(projectID string) (*repository.Shard, error) {
+
+	result, err := a.ShardRepo.ShardAdd(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to add shard", "project_id", projectID, "error", err)
+		a.emitter.Error("Shard addition failed", "application - AddShard", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	logger.Logger.Info("Successfully created shard", "shard_id", result.ID)
+	a.emitter.Info("Shard addition successful", "application - AddShard", map[string]string{
+		"project_id": projectID,
+		"shard_id":   result.ID,
+	})
+
+	return result, nil
+}
+
+// shard repository - Call to get list of all shards
+func (a *App) ListShards(projectID string) ([]repository.Shard, error) {
+
+	result, err := a.ShardRepo.ShardList(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to list shards", "project_id", projectID, "error", err)
+		a.emitter.Error("Shard listing failed", "application - ListShards", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// shard repository - Call to deactivate a shard
+func (a *App) DeactivateShard(shardID string) error {
+
+	if err := a.ShardRepo.ShardDeactivate(a.ctx, shardID); err != nil {
+		logger.Logger.Error("Failed to deactivate shard", "shard_id", shardID, "error", err)
+		a.emitter.Error("Shard deactivation failed", "application - ShardDeactivate", map[string]string{
+			"shard_id": shardID,
+			"error":    err.Error(),
+		})
+		return err
+	}
+
+	projectID, err := a.ShardRepo.FetchProjectID(a.ctx, shardID)
+
+	shards, err := a.ListShards(projectID)
+	if err != nil {
+		a.emitter.Error("Project fetching failed for automated project deactivation", "application - ShardDeactivate", map[string]string{
+			"project_id": projectID,
+			"shard_id":   shardID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	for _, shard := range shards {
+		if shard.Status == "inactive" {
+
+			if err := a.ProjectRepo.ProjectDeactivate(a.ctx, projectID); err != nil {
+				logger.Logger.Error("Failed to deactivate project for automated project deactivation", "project_id", projectID, "shard_id", shardID, "error", err)
+				a.emitter.Error("Failed to deactivate project for automated project deactivation", "application - ShardDeactivate", map[string]string{
+					"project_id": projectID,
+					"shard_id":   shardID,
+					"error":      err.Error(),
+				})
+				return err
+			}
+
+			a.emitter.Event("project:status_changed", map[string]string{
+				"project_id": projectID,
+				"status":     "inactive",
+			})
+
+			break
+		}
+	}
+
+	logger.Logger.Info("Automatic project deactivation successful", "project_Id", projectID)
+	a.emitter.Info("Automated project deactivation successful", "application - DeactivateProject", map[string]string{
+		"project_id": projectID,
+	})
+
+	logger.Logger.Info("Successfully deactivated shard", "shard_id", shardID, "project_id", projectID)
+	a.emitter.Info("Shard deactivation successful", "application - DeactivateProject", map[string]string{
+		"project_id": projectID,
+		"shardd_id":  shardID,
+	})
+
+	return nil
+}
+
+// shard repository - Call to delete all shards of a project
+func (a *App) DeleteAllShards(projectID string) error {
+
+	err := a.ShardRepo.ShardDeleteAll(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to delete shards for project", "project_id", projectID, "error", err)
+		a.emitter.Error("Shards deletion failed", "application - DeleteAllShards", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	logger.Logger.Info("Successfully deleted all shards", "project_id", projectID)
+	a.emitter.Info("Shards deletion successful", "application - DeleteAllShards", map[string]string{
+		"project_id": projectID,
+	})
+
+	return nil
+}
+
+// shard repository - Call to delete a single shard
+func (a *App) DeleteShard(shardID string) (string, error) {
+
+	isInactive, err := a.checkIfShardInactive(shardID)
+	if err != nil {
+		a.emitter.Error("Shard deletion failed", "application - DeleteShard", map[string]string{
+			"shard_id": shardID,
+			"error":    err.Error(),
+		})
+		return "", err
+	}
+
+	if !isInactive {
+		a.emitter.Error("Shard deletion failed", "application - DeleteShard", map[string]string{
+			"shard_id": shardID,
+			"error":    "cannot delete active shard",
+		})
+		return "CANNOT_DELETE_ACTIVE_SHARD", nil
+	}
+
+	if err := a.checkShardDeletable(shardID); err != nil {
+		a.emitter.Error("Shard deletion failed", "application - DeleteShard", map[string]string{
+			"shard_id": shardID,
+			"error":    err.Error(),
+		})
+		return "", err
+	}
+
+	err = a.DeleteConnection(shardID)
+	if err != nil {
+		logger.Logger.Error("Failed to delete shard connection", "shard_id", shardID, "error", err)
+		a.emitter.Error("Shard deletion failed", "application - DeleteShard", map[string]string{
+			"shard_id": shardID,
+			"error":    "failed to delete shard connection",
+		})
+		return "", err
+	}
+
+	err = a.ShardRepo.ShardDelete(a.ctx, shardID)
+	if err != nil {
+		logger.Logger.Error("Failed to delete shard", "shard_id", shardID, "error", err)
+		a.emitter.Error("Shard deletion failed", "application - DeleteShard", map[string]string{
+			"shard_id": shardID,
+			"error":    err.Error(),
+		})
+		return "", err
+	}
+
+	logger.Logger.Info("Successfully deleted shard", "shard_id", shardID)
+	a.emitter.Info("Shard deletion successful", "application - DeleteShard", map[string]string{
+		"shard_id": shardID,
+		// "error":    err.Error(), (never forget the pain this error caused)
+	})
+
+	return "DELETED", nil
+}
+
+// shard repository - Call to activate a shard
+func (a *App) ActivateShard(shardID string) error {
+
+	err := a.RetryShardConnections(a.ctx)
+	if err != nil {
+		logger.Logger.Error("Failed to activate shard", "shard_id", shardID, "error", err)
+		a.emitter.Error("Shard Activation failed", "application - ActivateShard", map[string]string{
+			"shard_id": shardID,
+			"error":    "retry mechanism failed",
+		})
+	}
+
+	projectID, err := a.ShardRepo.FetchProjectID(a.ctx, shardID)
+	if err != nil {
+		logger.Logger.Error("Failed to activate shard", "shard_id", shardID, "error", err)
+		logger.Logger.Error("Failed to activate shard", "shard_id", shardID, "error", err)
+		a.emitter.Error("Shard Activation failed", "application - ActivateShard", map[string]string{
+			"shard_id": shardID,
+			"error":    "project fetching failed for health checking",
+		})
+		return err
+	}
+
+	isConnected, err := a.checkShardHealth(a.ctx, projectID, shardID)
+	if err != nil {
+		logger.Logger.Error("Failed to activate shard", "projectid", projectID, "shard_id", shardID, "error", err)
+		a.emitter.Error("Shard Activation failed", "application - ActivateShard", map[string]string{
+			"project_id": projectID,
+			"shard_id":   shardID,
+			"error":      "failed for checking shard health",
+		})
+		return err
+	}
+
+	if !isConnected {
+		logger.Logger.Error("Failed to activate shard", "shard_id", shardID, "error", "shard connection not available")
+		a.emitter.Error("Shard Activation failed", "application - ActivateShard", map[string]string{
+			"project_id": projectID,
+			"shard_id":   shardID,
+			"error":      "shard connection failed",
+		})
+		return err
+	}
+
+	if err := a.checkNotDraining(shardID); err != nil {
+		a.emitter.Error("Shard Activation failed", "application - ActivateShard", map[string]string{
+			"project_id": projectID,
+			"shard_id":   shardID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	// keys reach a shard joining an active project through a rebalance
+	if err := a.freezeBeforeJoin(projectID); err != nil {
+		logger.Logger.Error("Failed to activate shard", "project_id", projectID, "shard_id", shardID, "error", err)
+		a.emitter.Error("Shard Activation failed", "application - ActivateShard", map[string]string{
+			"project_id": projectID,
+			"shard_id":   shardID,
+			"error":      "shard map could not be frozen",
+		})
+		return err
+	}
+
+	err = a.ShardRepo.ShardActivate(a.ctx, shardID)
+	if err != nil {
+		logger.Logger.Error("Failed to activate shard", "shard_id", shardID, "error", err)
+		a.emitter.Error("Shard Activation failed", "application - ActivateShard", map[string]string{
+			"project_id": projectID,
+			"shard_id":   shardID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	logger.Logger.Info("Successfully activated shard", "shard_id", shardID)
+	a.emitter.Info("Shard activation successfull", "application - ActivateShard", map[string]string{
+		"shard_id": shardID,
+	})
+
+	return nil
+}
+
+// shard repository - Call to fetch status of a shard
+func (a *App) FetchShardStatus(shardID string) (string, error) {
+
+	status, err := a.ShardRepo.FetchShardStatus(a.ctx, shardID)
+	if err != nil {
+		logger.Logger.Error("Failed to fetch shard shard", "shard_Id", shardID, "error", err)
+		a.emitter.Error("Shard status fetching failed", "application - FetchShardStatus", map[string]string{
+			"shard_id": shardID,
+			"error":    err.Error(),
+		})
+		return "", err
+	}
+
+	return status, nil
+}
+
+// shard connection repository - add connection detail for one shard
+func (a *App) AddConnection(connectionInfo *repository.ShardConnection) error {
+
+	err := a.ShardConnectionRepo.ConnectionCreate(a.ctx, connectionInfo)
+	if err != nil {
+		logger.Logger.Error("Failed to add shard connection details", "shard_id", connectionInfo.ShardID, "error", err)
+		a.emitter.Error("shard connection addition failed", "application - AddConnection", map[string]string{
+			"shard_id": connectionInfo.ShardID,
+			"error":    err.Error(),
+		})
+		return err
+	}
+
+	logger.Logger.Info("Successfully added shard connection details", "shard_id", connectionInfo.ShardID)
+	a.emitter.Info("shard connection addition successfull", "application - AddConnection", map[string]string{
+		"shard_id": connectionInfo.ShardID,
+	})
+
+	return nil
+}
+
+// shard connection repo - remove connection detail of a shard
+func (a *App) DeleteConnection(shardID string) error {
+
+	err := a.ShardConnectionRepo.ConnectionRemove(a.ctx, shardID)
+	if err != nil {
+		logger.Logger.Error("Failed to remove shard connection details", "shard_id", shardID, "error", err)
+		a.emitter.Error("Shard connection deletion failed", "application - DeleteConnection", map[string]string{
+			"shard_id": shardID,
+			"error":    err.Error(),
+		})
+	}
+
+	logger.Logger.Info("Successfully deleted shard connection details", "shard_id", shardID)
+	a.emitter.Info("Shard connection deletion successfull", "application - DeleteConnection", map[string]string{
+		"shard_id": shardID,
+	})
+
+	return nil
+}
+
+// shard connection repo - fetch connection details of a shard using shard id
+func (a *App) FetchConnectionInfo(shardID string) (repository.ShardConnection, error) {
+
+	conn, err := a.ShardConnectionRepo.FetchConnectionByShardID(a.ctx, shardID)
+	if err != nil {
+		logger.Logger.Error("Failed to fecth sahrd connection infomation", "shard_id", shardID, "error", err)
+		a.emitter.Error("Shard connection info fetching failed", "application - FetchConnectionInfo", map[string]string{
+			"shard_id": shardID,
+			"error":    err.Error(),
+		})
+		return repository.ShardConnection{}, err
+	}
+
+	return conn, nil
+}
+
+// shard connection repo - update existing connection details
+func (a *App) UpdateConnection(connInfo repository.ShardConnection) error {
+
+	err := a.ShardConnectionRepo.ConnectionUpdate(a.ctx, connInfo)
+	if err != nil {
+		logger.Logger.Error("Failed to update shard connection details", "shard_id", connInfo.ShardID, "error", err)
+		a.emitter.Error("Shard connection updation failed", "application - UpdateConnection", map[string]string{
+			"shard_id": connInfo.ShardID,
+			"error":    err.Error(),
+		})
+		return err
+	}
+
+	logger.Logger.Info("Successfully updated shard connection details", "shard_id", connInfo.ShardID)
+	a.emitter.Info("Shard connection updation successfull", "application - UpdateConnection", map[string]string{
+		"shard_id": connInfo.ShardID,
+	})
+
+	return nil
+}
+
+// project repository - set status of project to active
+func (a *App) Activateproject(projectID string) error {
+
+	otherProjectsInactive, err := a.checkAllProjectsInactive()
+	if err != nil {
+		logger.Logger.Error("Failed to check status of projects for projct activation", "project_id", projectID, "error", err)
+		a.emitter.Error("Project activation failed", "application - Activateproject", map[string]string{
+			"project_id": projectID,
+			"error":      "unable to fetch projects for activation constratints",
+		})
+		return err
+	}
+
+	if otherProjectsInactive == false {
+		logger.Logger.Error("Failed to activate project", "error", "project_id", projectID, "another project is already active")
+		a.emitter.Error("Project activation failed", "application - Activateproject", map[string]string{
+			"project_id": projectID,
+			"error":      "another project is already active",
+		})
+		return api.NewRuleError("another project is already active")
+	}
+
+	allShardsNotActive, err := a.checkAllShardsActive(projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to check status of projects for projct activation", "project_id", projectID, "error", err)
+		a.emitter.Error("Project activation failed", "application - Activateproject", map[string]string{
+			"project_id": projectID,
+			"error":      "all project shards are not active",
+		})
+		return err
+	}
+
+	if allShardsNotActive == false {
+		logger.Logger.Error("Failed to activate project", "error", "project_id", projectID, "All shards are not active")
+		a.emitter.Error("Project activation failed", "application - Activateproject", map[string]string{
+			"project_id": projectID,
+			"error":      "another project is already active",
+		})
+		return api.NewRuleError("All shards are not active")
+	}
+
+	shards, err := a.ShardRepo.ShardList(a.ctx, projectID)
+	if err == nil {
+		_, err = a.freezeShardMap(projectID, shards)
+	}
+	if err != nil {
+		logger.Logger.Error("Failed to freeze shard map for project activation", "project_id", projectID, "error", err)
+		a.emitter.Error("Project activation failed", "application - Activateproject", map[string]string{
+			"project_id": projectID,
+			"error":      "shard map could not be frozen",
+		})
+		return err
+	}
+
+	err = a.ProjectRepo.ProjectActivate(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to activate project", "project_id", projectID, "error", err)
+		a.emitter.Error("Project activation failed", "application - Activateproject", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	err = a.ShardConnectionManager.InititateConnectionsAll(a.ctx)
+
+	logger.Logger.Info("Successfully activated the project", "project_id", projectID)
+	a.emitter.Info("Project activation successfull", "application - Activateproject", map[string]string{
+		"project_id": projectID,
+	})
+
+	return nil
+}
+
+// project repository - set status of project to inactive
+func (a *App) Deactivateproject(projectID string) error {
+
+	err := a.ProjectRepo.ProjectDeactivate(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to deactivate project", "project_id", projectID, "error", err)
+		a.emitter.Error("Project deletion failed", "application - Deactivateproject", map[string]string{
+			"project_Id": projectID,
+			"error":      err.Error(),
+		})
+	}
+
+	logger.Logger.Info("Successfully deactivated the project", "project_id", projectID)
+	a.emitter.Info("Project deletion successfull", "application - Deactivateproject", map[string]string{
+		"project_Id": projectID,
+	})
+
+	return nil
+}
+
+// project repository - fetch status of a project
+func (a *App) FetchProjectStatus(projectID string) (string, error) {
+
+	status, err := a.ProjectRepo.FetchProjectStatus(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to fetch project status", "project_id", projectID, "error", err)
+		a.emitter.Error("Project status fetching failed", "application - FetchProjectStatus", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return "", err
+	}
+
+	return status, nil
+
+}
+
+// project schema repository - create new schema draft
+func (a *App) CreateSchemaDraft(projectID string, ddlSQL string) (*repository.ProjectSchema, error) {
+
+	ok, err := a.checkIfProjectInactive(projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to fetch project status", "project_id", projectID, "error", err)
+		a.emitter.Error("Schema draft creation failed", "application - CreateSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error":      "failed to check project status",
+		})
+		return nil, err
+	}
+
+	if !ok {
+		a.emitter.Error("Schema draft creation failed", "application - CreateSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error":      "project is active",
+		})
+		return nil, api.NewRuleError("project must be inactive to modify schema")
+	}
+
+	inFlight, err := a.checkIfSchemaInFlight(projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to check schema in-flight status", "project_id", projectID, "error", err)
+		a.emitter.Error("Schema draft creation failed", "application - CreateSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error":      "failed to check schema in-flight status",
+		})
+		return nil, err
+	}
+
+	if inFlight {
+		a.emitter.Error("Schema draft creation failed", "application - CreateSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error":      "another schema change is already in progress",
+		})
+		return nil, api.NewRuleError("another schema change is already in progress")
+	}
+
+	schema, err := a.ProjectSchemaRepo.ProjectSchemaCreateDraft(a.ctx, projectID, ddlSQL)
+	if err != nil {
+		logger.Logger.Error("Failed to create schema draft", "project_id", projectID, "error", err)
+		a.emitter.Error("Schema draft creation failed", "application - CreateSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	logger.Logger.Info("Succesfully created schema draft of project", "project_id", projectID)
+	a.emitter.Info("Schema draft creation successfull", "application - CreateSchemaDraft", map[string]string{
+		"project_id": projectID,
+	})
+	return schema, nil
+
+}
+
+// project schema repository - commit existing schema draft
+func (a *App) CommitSchemaDraft(projectID string, schemaID string) error {
+
+	ok, err := a.checkIfProjectInactive(projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to fetch project status", "project_id", projectID, "error", err)
+		a.emitter.Error("Draft schema commit failed", "application - CommitSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error:":     "failed to check project status",
+		})
+		return err
+	}
+
+	if !ok {
+		a.emitter.Error("Draft schema commit failed", "application - CommitSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error:":     "project is active",
+		})
+		return api.NewRuleError("project must be inactive to modify schema")
+	}
+
+	ok, err = a.checkIfSchemaInProject(projectID, schemaID)
+	if err != nil {
+		logger.Logger.Error("Failed to fetch schema", "project_id", projectID, "schema_id", schemaID, "error", err)
+		a.emitter.Error("Draft schema commit failed", "application - CommitSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error:":     "failed to fetch schema",
+		})
+		return err
+	}
+	if !ok {
+		a.emitter.Error("Draft schema commit failed", "application - CommitSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error:":     "schema does not belong to project",
+		})
+		return sql.ErrNoRows
+	}
+
+	ok, err = a.checkIfSchemaDraft(schemaID)
+	if err != nil {
+		logger.Logger.Error("Failed to fetch schema state", "project_id", projectID, "error", err)
+		a.emitter.Error("Draft schema commit failed", "application - CommitSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error:":     "failed to fetch schema state",
+		})
+		return err
+	}
+	if !ok {
+		a.emitter.Error("Draft schema commit failed", "application - CommitSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error:":     "schema should be in draft before commit",
+		})
+		return api.NewRuleError("schema must be in draft state to commit")
+	}
+
+	inFlight, err := a.checkIfSchemaInFlight(projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to check schema in-flight status", "project_id", projectID, "error", err)
+		a.emitter.Error("Draft schema commit failed", "application - CommitSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error:":     "failed to check schema in-flight status",
+		})
+		return err
+	}
+	if inFlight {
+		a.emitter.Error("Draft schema commit failed", "application - CommitSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error:":     "another schema change is already in progress",
+		})
+		return api.NewRuleError("another schema change is already in progress")
+	}
+
+	projectSchema, err := a.ProjectSchemaRepo.ProjectSchemaFetchBySchemaID(a.ctx, schemaID)
+	if err != nil {
+		logger.Logger.Error("Failed to fetch schema by id", "project_id", projectID, "error", err)
+		a.emitter.Error("Draft schema commit failed", "application - CommitSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error:":     "failed to fetch schema infomation",
+		})
+		return err
+	}
+
+	destructive, err := a.checkIfDDLDestructive(projectID, projectSchema.DDL_SQL)
+	if err != nil {
+		logger.Logger.Error("Failed to validate destructive DDL", "project_id", projectID, "error", err)
+		a.emitter.Error("Draft schema commit failed", "application - CommitSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error:":     "failed to validate destructive DDL",
+		})
+		return err
+	}
+	if destructive {
+		a.emitter.Error("Draft schema commit failed", "application - CommitSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error:":     "destructive ddl in query",
+		})
+		return api.NewRuleError("destructive DDL is not allowed after initial schema")
+	}
+
+	logger.Logger.Info("Applying committed schema to metadata", "project_id", projectID, "schema_id", schemaID)
+	a.emitter.Info("applying committed schema to metadata", "application - CommitSchemaDraft", map[string]string{
+		"project_id": projectID,
+	})
+
+	err = a.SchemaService.ApplyDDLAndRecomputeShardKeys(a.ctx, projectID, projectSchema.DDL_SQL)
+	if err != nil {
+		logger.Logger.Error("Failed to apply schema to metadata", "project_id", projectID, "schema_id", schemaID, "error", err)
+		a.emitter.Error("Draft schema commit failed", "application - CommitSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error:":     "Failed to apply schema to metadata" + err.Error(),
+		})
+		return err
+	}
+
+	err = a.ProjectSchemaRepo.ProjectSchemaCommitDraft(a.ctx, schemaID)
+	if err != nil {
+		logger.Logger.Error("Failed to commit project schema", "project_id", projectID, "schema_id", schemaID, "error", err)
+		a.emitter.Error("Draft schema commit failed", "application - CommitSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error:":     "Failed to commit project schema" + err.Error(),
+		})
+		return err
+	}
+
+	logger.Logger.Info("Successfully committed project schema", "project_id", projectID, "schema_id", schemaID)
+	a.emitter.Info("Schema draft commit successfull", "application - CommitSchemaDraft", map[string]string{
+		"project_id": projectID,
+	})
+
+	return nil
+}
+
+// project schema repository - get latest schema of project
+func (a *App) GetCurrentSchema(projectID string) (*repository.ProjectSchema, error) {
+
+	schema, err := a.ProjectSchemaRepo.ProjectSchemaGetLatest(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to fetch latest schema of project", "project_id", projectID, "error", err)
+		a.emitter.Error("Current schema fetching failed", "application - GetCurrentSchema", map[string]string{
+			"projecy_id": projectID,
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	return schema, nil
+
+}
+
+// project schema repository - get history of a schema
+func (a *App) GetSchemaHistory(projectID string) ([]repository.ProjectSchema, error) {
+
+	history, err := a.ProjectSchemaRepo.ProjectSchemaFetchHistory(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to fetch project schema history", "project_id", projectID, "error", err)
+		a.emitter.Error("Schema history fetching failed", "application - GetSchemaHistory", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	return history, nil
+
+}
+
+// project schema repository - delete a draft of schema
+func (a *App) DeleteSchemaDraft(schemaID string) error {
+
+	err := a.ProjectSchemaRepo.ProjectSchemaDeleteDraft(a.ctx, schemaID)
+	if err != nil {
+		logger.Logger.Error("Failed to delete project schema draft", "schema_id", schemaID, "error", err)
+		a.emitter.Error("Schema deletion failed", "application - DeleteSchemaDraft", map[string]string{
+			"schema_id": schemaID,
+			"error":     err.Error(),
+		})
+		return err
+	}
+
+	logger.Logger.Info("Successfully deleted project schema draft", "schema_id", schemaID)
+	a.emitter.Info("Schema deletion successfull", "application - DeleteSchemaDraft", map[string]string{
+		"schema_id": schemaID,
+	})
+	return nil
+
+}
+
+// schema execution status repo -get execution status of all shards
+func (a *App) GetSchemaExecutionStatus(schemaID string) ([]repository.SchemaExecutionStatus, error) {
+
+	statuAll, err := a.SchemaExecutionStatusRepo.ExecutionRecordsFetchStatusAll(a.ctx, schemaID)
+	if err != nil {
+		logger.Logger.Error("Failed to fetch execution status of all records of schema", "schema_id", schemaID, "error", err)
+		a.emitter.Error("Schema execution status fetching failed", "application - GetSchemaExecutionStatus", map[string]string{
+			"schema_id": schemaID,
+			"error":     err.Error(),
+		})
+		return nil, err
+	}
+
+	return statuAll, err
+}
+
+// schema execution status repo - get record of failed shard executions
+func (a *App) GetFailedShardExecutions(schemaID string) ([]repository.SchemaExecutionStatus, error) {
+
+	statuAll, err := a.SchemaExecutionStatusRepo.ExecutionRecordsFetchStatusFailed(a.ctx, schemaID)
+	if err != nil {
+		logger.Logger.Error("Failed to fetch execution status of all failed records of schema", "schema_id", schemaID, "error", err)
+		a.emitter.Error("Failed shard execution status fetching failed", "application - GetFailedShardExecutions", map[string]string{
+			"schema_id": schemaID,
+			"error":     err.Error(),
+		})
+		return nil, err
+	}
+
+	return statuAll, err
+}
+
+// project schema repository - get status of a projectschema
+func (a *App) GetProjectSchemaStatus(schemaID string) (string, error) {
+	status, err := a.ProjectSchemaRepo.ProjectSchemaGetState(a.ctx, schemaID)
+	if err != nil {
+		logger.Logger.Error("Fialed to fetch status of a schema", "schema_id", schemaID, "error", err)
+		a.emitter.Error("Project schema status fetching failed", "application - GetProjectSchemaStatus", map[string]string{
+			"schema_id": schemaID,
+			"error":     err.Error(),
+		})
+		return "", err
+	}
+
+	return status, nil
+}
+
+// DDL executor - execute pending schema
+func (a *App) ExecuteProjectSchema(projectID string) error {
+
+	caps, err := a.GetSchemaCapabilities(projectID)
+	if err != nil {
+		a.emitter.Error("Project schema execution failed", "application - ExecuteProjectSchema", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	if !caps.CanExecute {
+		a.emitter.Error("Project schema execution failed", "application - ExecuteProjectSchema", map[string]string{
+			"project_id": projectID,
+			"error":      "schema execution not allowed",
+		})
+		if caps.Reason != "" {
+			return api.NewRuleError("schema execution not allowed: " + caps.Reason)
+		}
+		return api.NewRuleError("schema execution not allowed")
+	}
+
+	err = schema.ExecuteProjectSchema(
+		a.ctx,
+		projectID,
+		a.ProjectSchemaRepo,
+		a.ShardRepo,
+		a.SchemaExecutionStatusRepo,
+		func(shardID string, ddl string) error {
+			return a.execDDLonShard(projectID, shardID, ddl)
+		},
+	)
+
+	if err != nil {
+		logger.Logger.Error("failed to execute project schema", "project_id", projectID, "error", err)
+		a.emitter.Error("Project schema execution failed", "application - ExecuteProjectSchema", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	logger.Logger.Info("Successfully executed projects schema", "project_id", projectID)
+	a.emitter.Info("Project schema execution successfull", "application - ExecuteProjectSchema", map[string]string{
+		"project_id": projectID,
+	})
+
+	return nil
+}
+
+// DDL executor - retry mechanism
+func (a *App) RetrySchemaExecution(projectID string) error {
+
+	return schema.RetryFailedSchema(
+		a.ctx,
+		projectID,
+		a.ProjectSchemaRepo,
+		a.SchemaExecutionStatusRepo,
+	)
+}
+
+// shardkey service - run inference
+func (a *App) RecomputeKeys(projectID string) error {
+
+	isProjectActive, err := a.checkIfProjectInactive(projectID)
+	if err != nil {
+		logger.Logger.Error("failed to recompute shard keys", "project_id", projectID, "error", err)
+		a.emitter.Error("Shard key recomputation failed", "application -RecomputeKeys", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	if !isProjectActive {
+		a.emitter.Error("Shard key recomputation failed", "application -RecomputeKeys", map[string]string{
+			"project_id": projectID,
+			"error":      "project must be inactive before shard key recomputation",
+		})
+		return api.NewRuleError("project must be inactive before shard key recomputation")
+	}
+
+	err = a.InferenceService.ApplyShardKeyInference(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("shard key inference failed", "project_id", projectID, "error", err)
+		a.emitter.Error("Shard key recomputation failed", "application -RecomputeKeys", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	a.reportShardKeyConflicts(projectID)
+
+	logger.Logger.Info("shard key inference completed successfully", "project_id", projectID)
+	a.emitter.Info("Shard key recomputation successfull	", "application -RecomputeKeys", map[string]string{
+		"project_id": projectID,
+	})
+
+	return nil
+
+}
+
+// shard keys repository - fetch keys
+func (a *App) FetchShardKeys(projectID string) ([]repository.ShardKeys, error) {
+
+	keys, err := a.ShardKeysRepo.FetchShardKeysByProjectID(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("failed to fetch shard keys", "project_id", projectID, "error", err)
+		a.emitter.Error("Shard key fetching failed", "application - FetchShardKeys", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	return keys, nil
+
+}
+
+// inference service - co-location groups the current schema and workload lead to
+func (a *App) GetColocationGroups(projectID string) ([]shardkey.ColocationGroup, error) {
+
+	groups, err := a.InferenceService.AnalyzeColocation(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("failed to analyse co-location groups", "project_id", projectID, "error", err)
+		a.emitter.Error("Co-location analysis failed", "application - GetColocationGroups", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// shard keys repository - replace keys
+func (a *App) ReplaceShardKeys(projectID string, keys []repository.ShardKeyRecord) error {
+
+	err := a.ShardKeysRepo.ReplaceShardKeysForProject(a.ctx, projectID, keys)
+	if err != nil {
+		logger.Logger.Error("filed to replace shard keys", "projectID", projectID, "error", err)
+		a.emitter.Error("Shard key replacing failed", "application - ReplaceShardKeys", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	logger.Logger.Info("successfully replaced shard keys", "projectID", projectID)
+	a.emitter.Info("Shard key replacing successfull", "application - ReplaceShardKeys", map[string]string{
+		"project_id": projectID,
+	})
+	return nil
+}
+
+// func to execute DML quereis on repective schema
+func (a *App) ExecuteSQL(projectID string, sqlText string) ([]executor.ExecutionResult, error) {
+	return a.executeSQL(a.ctx, "", projectID, sqlText)
+}
+
+// func to execute DML queries under the access policy of an API caller,
+// traced as part of the caller's request
+func (a *App) ExecuteSQLAs(
+	reqCtx context.Context,
+	principal *auth.Principal,
+	projectID string,
+	sqlText string,
+) ([]executor.ExecutionResult, error) {
+
+	ctx, err := a.withAccessPolicy(tracing.Link(a.ctx, reqCtx), principal, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	actor := ""
+	if principal != nil {
+		actor = principal.KeyID
+	}
+
+	return a.executeSQL(ctx, actor, projectID, sqlText)
+}
+
+func (a *App) executeSQL(ctx context.Context, actor string, projectID string, sqlText string) (result []executor.ExecutionResult, err error) {
+
+	var plan *router.RoutingPlan
+	started := time.Now()
+
+	defer func() {
+		a.observeQuery(projectID, sqlText, plan, result, err, started)
+		a.AuditRecorder.RecordQuery(ctx, audit.QueryEvent{
+			ProjectID: projectID,
+			Actor:     actor,
+			SQL:       sqlText,
+			Plan:      plan,
+			Results:   result,
+			Err:       err,
+			Started:   started,
+			Duration:  time.Since(started),
+		})
+	}()
+
+	plan, err = a.RouterService.RouteSQL(
+		ctx,
+		projectID,
+		sqlText,
+	)
+	if err != nil {
+		logger.Logger.Error("Filed to route and execute query", "project_id", projectID, "error", err)
+		a.emitter.Error("Query execution failed", "application - ExecuteSQL", map[string]string{
+			"project_id": projectID,
+			"error":      "query router:" + err.Error(),
+		})
+		return nil, err
+	}
+
+	result, err = a.ExecutorService.Execute(
+		ctx,
+		projectID,
+		sqlText,
+		plan,
+	)
+	if err != nil {
+		logger.Logger.Error("failed to execute query", "project_id", projectID, "error", err)
+		logger.Logger.Error("Filed to route and execute query", "project_id", projectID, "error", err)
+		a.emitter.Error("Query execution failed", "application - ExecuteSQL", map[string]string{
+			"project_id": projectID,
+			"error":      "query executor:" + err.Error(),
+		})
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// transaction manager - begin an interactive transaction
+func (a *App) BeginTransaction(projectID string) (string, error) {
+	return a.BeginTransactionAs(nil, projectID)
+}
+
+// transaction manager - begin a transaction owned by an API caller, only the
+// same key may run statements in it or complete it
+func (a *App) BeginTransactionAs(principal *auth.Principal, projectID string) (string, error) {
+
+	status, err := a.FetchProjectStatus(projectID)
+	if err != nil {
+		return "", err
+	}
+
+	if status != "active" {
+		a.emitter.Error("Transaction begin failed", "application - BeginTransaction", map[string]string{
+			"project_id": projectID,
+			"error":      "project is not active",
+		})
+		return "", api.NewRuleError("project must be active to begin a transaction")
+	}
+
+	owner := ""
+	if principal != nil {
+		owner = principal.KeyID
+	}
+
+	session := a.TransactionManager.Begin(projectID, owner)
+
+	return session.ID, nil
+}
+
+// transaction manager - execute a statement inside a transaction
+func (a *App) ExecuteInTransaction(txID string, sqlText string) ([]executor.ExecutionResult, error) {
+
+	projectID, err := a.TransactionProjectID(txID)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.executeInTransaction(a.ctx, "", projectID, txID, sqlText)
+}
+
+// transaction manager - execute a statement under the access policy of an API caller,
+// traced as part of the caller's request
+func (a *App) ExecuteInTransactionAs(
+	reqCtx context.Context,
+	principal *auth.Principal,
+	txID string,
+	sqlText string,
+) ([]executor.ExecutionResult, error) {
+
+	projectID, err := a.TransactionProjectID(txID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := a.withAccessPolicy(tracing.Link(a.ctx, reqCtx), principal, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	actor := ""
+	if principal != nil {
+		actor = principal.KeyID
+	}
+
+	return a.executeInTransaction(ctx, actor, projectID, txID, sqlText)
+}
+
+func (a *App) executeInTransaction(
+	ctx context.Context,
+	actor string,
+	projectID string,
+	txID string,
+	sqlText string,
+) (result []executor.ExecutionResult, err error) {
+
+	var plan *router.RoutingPlan
+	started := time.Now()
+
+	defer func() {
+		a.observeQuery(projectID, sqlText, plan, result, err, started)
+		a.AuditRecorder.RecordQuery(ctx, audit.QueryEvent{
+			ProjectID: projectID,
+			Actor:     actor,
+			SQL:       sqlText,
+			Plan:      plan,
+			Results:   result,
+			Err:       err,
+			Started:   started,
+			Duration:  time.Since(started),
+		})
+	}()
+
+	result, plan, err = a.TransactionManager.Execute(ctx, txID, sqlText)
+	if err != nil {
+		logger.Logger.Error("failed to execute query in transaction", "tx_id", txID, "error", err)
+		a.emitter.Error("Transaction query execution failed", "application - ExecuteInTransaction", map[string]string{
+			"tx_id": txID,
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// transaction manager - commit a transaction
+func (a *App) CommitTransaction(txID string) error {
+
+	err := a.TransactionManager.Commit(a.ctx, txID)
+	if err != nil {
+		logger.Logger.Error("failed to commit transaction", "tx_id", txID, "error", err)
+		a.emitter.Error("Transaction commit failed", "application - CommitTransaction", map[string]string{
+			"tx_id": txID,
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	a.emitter.Info("Transaction commit successfull", "application - CommitTransaction", map[string]string{
+		"tx_id": txID,
+	})
+
+	return nil
+}
+
+// transaction manager - rollback a transaction
+func (a *App) RollbackTransaction(txID string) error {
+
+	err := a.TransactionManager.Rollback(a.ctx, txID)
+	if err != nil {
+		logger.Logger.Error("failed to rollback transaction", "tx_id", txID, "error", err)
+		a.emitter.Error("Transaction rollback failed", "application - RollbackTransaction", map[string]string{
+			"tx_id": txID,
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	return nil
+}
+
+// executor - resolve result columns of a statement for wire protocol clients,
+// described as the caller would run it after policy checks and tenant rewriting
+func (a *App) DescribeSQLAs(
+	reqCtx context.Context,
+	principal *auth.Principal,
+	projectID string,
+	sqlText string,
+	paramCount int,
+) ([]string, []string, error) {
+
+	ctx, err := a.withAccessPolicy(tracing.Link(a.ctx, reqCtx), principal, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan, err := a.RouterService.PrepareSQL(ctx, sqlText)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	shards, err := a.ShardRepo.ShardList(ctx, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, shard := range shards {
+		if shard.Status != "active" {
+			continue
+		}
+		return a.ExecutorService.Describe(ctx, projectID, shard.ID, plan, paramCount)
+	}
+
+	return nil, nil, errors.New("no active shards for project")
+}
+
+// func to continuously check  health of shards of projects
+func (a *App) MonitorShards(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Logger.Info("Shard monitor stopped")
+			a.emitter.Info("Shard monitor stopper", "application - MonitorShards", map[string]string{})
+			return
+
+		case <-ticker.C:
+			a.checkAllShards(ctx)
+		}
+	}
+}
+
+// func to retry all projects shard connections
+func (a *App) RetryShardConnections(ctx context.Context) error {
+
+	err := a.ShardConnectionManager.InititateConnectionsAll(a.ctx)
+	if err != nil {
+		logger.Logger.Error("shard connection retry mechanism failed", "error", err)
+		a.emitter.Error("Shard connection retry mechanism failed", "application - RetryShardConnections", map[string]string{
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	a.emitter.Info("Shard connection retry mechanism successfull", "application - RetryShardConnections", map[string]string{})
+	return nil
+
+}
+
+// allows flow of schema draft -> execute in fe
+func (a *App) GetSchemaCapabilities(projectID string) (*SchemaCapabilities, error) {
+	caps := &SchemaCapabilities{}
+
+	projectStatus, err := a.FetchProjectStatus(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, _ := a.GetCurrentSchema(projectID)
+
+	if projectStatus == "active" {
+		caps.Reason = "Project is active"
+		return caps, nil
+	}
+
+	if schema == nil {
+		caps.CanCreateDraft = true
+		return caps, nil
+	}
+
+	switch schema.State {
+
+	case "draft":
+		caps.CanEditDraft = true
+		caps.CanCommit = true
+
+	case "pending":
+		allActive, err := a.checkAllShardsActive(projectID)
+		if err != nil {
+			return nil, err
+		}
+		if allActive {
+			caps.CanExecute = true
+		} else {
+			caps.Reason = "All shards must be active to execute"
+		}
+
+	case "failed":
+		caps.CanRetry = true
+
+	case "applied":
+		caps.CanCreateDraft = true
+	}
+
+	return caps, nil
+}
+
+// project schema repository - update existing schema draft
+func (a *App) UpdateProjectSchemaDraft(projectID string, schemaID string, ddlSQL string) error {
+
+	ok, err := a.checkIfProjectInactive(projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to fetch project status", "project_id", projectID, "error", err)
+		a.emitter.Error("Schema draft update failed", "application - UpdateProjectSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error":      "failed to check project status",
+		})
+		return err
+	}
+
+	if !ok {
+		a.emitter.Error("Schema draft update failed", "application - UpdateProjectSchemaDraft", map[string]string{
+			"project_id": projectID,
+			"error":      "project is active",
+		})
+		return api.NewRuleError("project must be inactive to modify schema")
+	}
+
+	ok, err = a.checkIfSchemaInProject(projectID, schemaID)
+	if err != nil {
+		logger.Logger.Error("Failed to fetch schema", "schema_id", schemaID, "error", err)
+		a.emitter.Error("Schema draft update failed", "application - UpdateProjectSchemaDraft", map[string]string{
+			"schema_id": schemaID,
+			"error":     "failed to fetch schema",
+		})
+		return err
+	}
+
+	if !ok {
+		a.emitter.Error("Schema draft update failed", "application - UpdateProjectSchemaDraft", map[string]string{
+			"schema_id": schemaID,
+			"error":     "schema does not belong to project",
+		})
+		return sql.ErrNoRows
+	}
+
+	ok, err = a.checkIfSchemaDraft(schemaID)
+	if err != nil {
+		logger.Logger.Error("Failed to fetch schema state", "schema_id", schemaID, "error", err)
+		a.emitter.Error("Schema draft update failed", "application - UpdateProjectSchemaDraft", map[string]string{
+			"schema_id": schemaID,
+			"error":     "failed to fetch schema state",
+		})
+		return err
+	}
+
+	if !ok {
+		a.emitter.Error("Schema draft update failed", "application - UpdateProjectSchemaDraft", map[string]string{
+			"schema_id": schemaID,
+			"error":     "schema is not in draft state",
+		})
+		return api.NewRuleError("only draft schemas can be updated")
+	}
+
+	err = a.ProjectSchemaRepo.ProjectSchemaUpdateDraft(a.ctx, schemaID, ddlSQL)
+	if err != nil {
+		logger.Logger.Error("Failed to update schema draft", "schema_id", schemaID, "error", err)
+		a.emitter.Error("Schema draft update failed", "application - UpdateProjectSchemaDraft", map[string]string{
+			"schema_id": schemaID,
+			"error":     err.Error(),
+		})
+		return err
+	}
+
+	logger.Logger.Info("Successfully updated schema draft", "schema_id", schemaID)
+	a.emitter.Info("Schema draft updated successfully", "application - UpdateProjectSchemaDraft", map[string]string{
+		"schema_id": schemaID,
+	})
+
+	return nil
+}
+
+// helper to pass repos to DDL executor
+func (a *App) execDDLonShard(projectID string, shardID string, ddl string) error {
+
+	db, err := a.ShardConnectionStore.Get(projectID, shardID)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(a.ctx, ddl)
+	return err
+}
@@ -0,0 +1,305 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sql-sharding-v2/internal/api"
+	"sql-sharding-v2/internal/auth"
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/router"
+	"sql-sharding-v2/pkg/logger"
+	"time"
+)
+
+// how often last_used_at is refreshed for a busy key
+const keyTouchInterval = time.Minute
+
+// most audit records returned per page
+const maxAuditPage = 1000
+
+// api key repository - create a key, the secret is only returned here
+func (a *App) CreateAPIKey(
+	projectID string,
+	name string,
+	role string,
+	allowedTables []string,
+	denyBroadcastWrites bool,
+	tenantID string,
+) (*repository.APIKey, string, error) {
+
+	parsedRole, err := auth.ParseRole(role)
+	if err != nil {
+		return nil, "", api.NewRuleError(err.Error())
+	}
+
+	key := repository.APIKey{
+		Name:                name,
+		Role:                string(parsedRole),
+		AllowedTables:       allowedTables,
+		DenyBroadcastWrites: denyBroadcastWrites,
+		TenantID:            nullableTenant(tenantID),
+	}
+
+	if parsedRole == auth.RoleAdmin {
+		if projectID != "" {
+			return nil, "", api.NewRuleError("admin keys cannot be bound to a project")
+		}
+	} else {
+		if projectID == "" {
+			return nil, "", api.NewRuleError("project keys require a project")
+		}
+		if _, err := a.ProjectRepo.GetProjectByID(a.ctx, projectID); err != nil {
+			return nil, "", err
+		}
+		key.ProjectID = &projectID
+	}
+
+	secret, err := auth.GenerateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	key.KeyPrefix = auth.DisplayPrefix(secret)
+	key.KeyHash = auth.HashKey(secret)
+
+	created, err := a.APIKeyRepo.APIKeyCreate(a.ctx, key)
+	if err != nil {
+		logger.Logger.Error("Failed to create API key", "project_id", projectID, "error", err)
+		a.emitter.Error("API key creation failed", "application - CreateAPIKey", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return nil, "", err
+	}
+
+	logger.Logger.Info("Successfully created API key", "key_id", created.ID, "project_id", projectID, "role", role)
+	a.emitter.Info("API key creation successful", "application - CreateAPIKey", map[string]string{
+		"key_id":     created.ID,
+		"project_id": projectID,
+	})
+
+	return created, secret, nil
+}
+
+// api key repository - list keys of a project, or all keys
+func (a *App) ListAPIKeys(projectID string) ([]repository.APIKey, error) {
+
+	keys, err := a.APIKeyRepo.APIKeyList(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to list API keys", "project_id", projectID, "error", err)
+		a.emitter.Error("API key listing failed", "application - ListAPIKeys", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// api key repository - replace the secret of a key, the old secret stops working
+func (a *App) RotateAPIKey(keyID string) (*repository.APIKey, string, error) {
+
+	secret, err := auth.GenerateKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = a.APIKeyRepo.APIKeyRotate(a.ctx, keyID, auth.DisplayPrefix(secret), auth.HashKey(secret))
+	if err != nil {
+		logger.Logger.Error("Failed to rotate API key", "key_id", keyID, "error", err)
+		a.emitter.Error("API key rotation failed", "application - RotateAPIKey", map[string]string{
+			"key_id": keyID,
+			"error":  err.Error(),
+		})
+		return nil, "", err
+	}
+
+	key, err := a.APIKeyRepo.APIKeyFetchByID(a.ctx, keyID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	logger.Logger.Info("Successfully rotated API key", "key_id", keyID)
+	a.emitter.Info("API key rotation successful", "application - RotateAPIKey", map[string]string{
+		"key_id": keyID,
+	})
+
+	return key, secret, nil
+}
+
+// api key repository - replace the statement access policy of a key
+func (a *App) UpdateAPIKeyPolicy(
+	keyID string,
+	allowedTables []string,
+	denyBroadcastWrites bool,
+	tenantID string,
+) (*repository.APIKey, error) {
+
+	err := a.APIKeyRepo.APIKeyUpdatePolicy(a.ctx, keyID, allowedTables, denyBroadcastWrites, nullableTenant(tenantID))
+	if err != nil {
+		logger.Logger.Error("Failed to update API key policy", "key_id", keyID, "error", err)
+		a.emitter.Error("API key policy update failed", "application - UpdateAPIKeyPolicy", map[string]string{
+			"key_id": keyID,
+			"error":  err.Error(),
+		})
+		return nil, err
+	}
+
+	logger.Logger.Info("Successfully updated API key policy", "key_id", keyID)
+	a.emitter.Info("API key policy update successful", "application - UpdateAPIKeyPolicy", map[string]string{
+		"key_id": keyID,
+	})
+
+	return a.APIKeyRepo.APIKeyFetchByID(a.ctx, keyID)
+}
+
+// api key repository - revoke a key
+func (a *App) RevokeAPIKey(keyID string) error {
+
+	err := a.APIKeyRepo.APIKeyRevoke(a.ctx, keyID)
+	if err != nil {
+		logger.Logger.Error("Failed to revoke API key", "key_id", keyID, "error", err)
+		a.emitter.Error("API key revocation failed", "application - RevokeAPIKey", map[string]string{
+			"key_id": keyID,
+			"error":  err.Error(),
+		})
+		return err
+	}
+
+	logger.Logger.Info("Successfully revoked API key", "key_id", keyID)
+	a.emitter.Info("API key revocation successful", "application - RevokeAPIKey", map[string]string{
+		"key_id": keyID,
+	})
+
+	return nil
+}
+
+// api key repository - resolve the caller of a request from its key secret
+func (a *App) AuthenticateAPIKey(secret string) (*auth.Principal, error) {
+
+	if !auth.WellFormed(secret) {
+		return nil, auth.ErrInvalidKey
+	}
+
+	key, err := a.APIKeyRepo.APIKeyFetchActiveByHash(a.ctx, auth.HashKey(secret))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, auth.ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > keyTouchInterval {
+		if err := a.APIKeyRepo.APIKeyTouch(a.ctx, key.ID); err != nil {
+			logger.Logger.Warn("Failed to record API key usage", "key_id", key.ID, "error", err)
+		}
+	}
+
+	principal := &auth.Principal{
+		KeyID:               key.ID,
+		Role:                auth.Role(key.Role),
+		AllowedTables:       key.AllowedTables,
+		DenyBroadcastWrites: key.DenyBroadcastWrites,
+	}
+	if key.ProjectID != nil {
+		principal.ProjectID = *key.ProjectID
+	}
+	if key.TenantID != nil {
+		principal.TenantID = *key.TenantID
+	}
+
+	return principal, nil
+}
+
+// helper to carry the statement restrictions of a caller to the router.
+// Admin callers without a tenant are not confined to a tenant.
+func (a *App) withAccessPolicy(ctx context.Context, principal *auth.Principal, projectID string) (context.Context, error) {
+
+	if principal == nil {
+		return ctx, nil
+	}
+
+	policy := &router.AccessPolicy{
+		Subject:             principal.KeyID,
+		ReadOnly:            principal.ReadOnly(),
+		AllowedTables:       principal.AllowedTables,
+		DenyBroadcastWrites: principal.DenyBroadcastWrites,
+	}
+
+	if principal.Role != auth.RoleAdmin || principal.TenantID != "" {
+		tenant, err := a.tenantScope(ctx, projectID, principal.TenantID)
+		if err != nil {
+			return nil, err
+		}
+		policy.Tenant = tenant
+	}
+
+	return router.WithAccessPolicy(ctx, policy), nil
+}
+
+// shard repository - project a shard belongs to, used to scope requests
+func (a *App) ShardProjectID(shardID string) (string, error) {
+	return a.ShardRepo.FetchProjectID(a.ctx, shardID)
+}
+
+// project schema repository - project a schema belongs to, used to scope requests
+func (a *App) SchemaProjectID(schemaID string) (string, error) {
+
+	schema, err := a.ProjectSchemaRepo.ProjectSchemaFetchBySchemaID(a.ctx, schemaID)
+	if err != nil {
+		return "", err
+	}
+
+	return schema.ProjectID, nil
+}
+
+// transaction manager - project a transaction runs against, used to scope requests
+func (a *App) TransactionProjectID(txID string) (string, error) {
+
+	session, err := a.TransactionManager.Get(txID)
+	if err != nil {
+		return "", err
+	}
+
+	return session.ProjectID, nil
+}
+
+// transaction manager - API key that began a transaction, used to scope requests
+func (a *App) TransactionOwner(txID string) (string, error) {
+
+	session, err := a.TransactionManager.Get(txID)
+	if err != nil {
+		return "", err
+	}
+
+	return session.Owner, nil
+}
+
+// helper to store an empty tenant as NULL
+func nullableTenant(tenantID string) *string {
+	if tenantID == "" {
+		return nil
+	}
+	return &tenantID
+}
+
+// audit log repository - browse the audit log of a project, newest first
+func (a *App) ListAuditLog(filter repository.AuditFilter) ([]repository.AuditRecord, error) {
+
+	if filter.Limit <= 0 || filter.Limit > maxAuditPage {
+		filter.Limit = maxAuditPage
+	}
+
+	records, err := a.AuditLogRepo.AuditLogList(a.ctx, filter)
+	if err != nil {
+		logger.Logger.Error("Failed to list audit log", "project_id", filter.ProjectID, "error", err)
+		a.emitter.Error("Audit log listing failed", "application - ListAuditLog", map[string]string{
+			"project_id": filter.ProjectID,
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	return records, nil
+}
@@ -0,0 +1,240 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"sql-sharding-v2/internal/api"
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/reshard"
+	"sql-sharding-v2/pkg/logger"
+)
+
+// job kinds that leave their shard draining, a merge drains into one shard
+var emptyingKinds = []string{reshard.KindDrain, reshard.KindMerge}
+
+// reshard - mark a shard draining and move all its hash slots to the other
+// active shards in the background, the shard can be deleted once it owns none
+func (a *App) DrainShard(shardID string) (*repository.ReshardJob, error) {
+
+	job, err := a.planDrain(shardID)
+	if err == nil {
+		err = a.ShardRepo.ShardDrain(a.ctx, shardID)
+		if err == nil {
+			err = a.launchReshardJob(job)
+		}
+		if err != nil {
+			a.abandonReshardJob(job)
+		}
+	}
+	if err != nil {
+		logger.Logger.Error("Failed to start shard drain", "shard_id", shardID, "error", err)
+		a.emitter.Error("Shard drain start failed", "application - DrainShard", map[string]string{
+			"shard_id": shardID,
+			"error":    err.Error(),
+		})
+		return nil, err
+	}
+
+	logger.Logger.Info("Shard drain started", "project_id", job.ProjectID, "shard_id", shardID, "job_id", job.ID)
+	a.emitter.Info("Shard drain started", "application - DrainShard", map[string]string{
+		"project_id": job.ProjectID,
+		"shard_id":   shardID,
+		"job_id":     job.ID,
+	})
+	a.emitter.Event("shard:status_changed", map[string]string{
+		"shard_id": shardID,
+		"status":   "draining",
+	})
+
+	return job, nil
+}
+
+// reshard - slots a shard still owns and the progress of its latest drain
+// or merge into another shard
+func (a *App) GetShardDrain(shardID string) (*reshard.DrainReport, error) {
+
+	status, err := a.ShardRepo.FetchShardStatus(a.ctx, shardID)
+	if err != nil {
+		return nil, err
+	}
+
+	projectID, err := a.ShardRepo.FetchProjectID(a.ctx, shardID)
+	if err != nil {
+		return nil, err
+	}
+
+	slots, err := a.slotCounts(a.ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &reshard.DrainReport{
+		ShardID:   shardID,
+		Status:    status,
+		Slots:     slots[shardID],
+		Deletable: status != "active" && slots[shardID] == 0,
+	}
+
+	job, err := a.ReshardRepo.ReshardJobLatest(a.ctx, shardID, emptyingKinds)
+	if errors.Is(err, sql.ErrNoRows) {
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report.Job, err = a.describeReshardJob(job)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Status != reshard.StatusCompleted && job.Status != reshard.StatusCancelled {
+		report.Deletable = false
+	}
+
+	return report, nil
+}
+
+// helper to validate draining a shard and store its job
+func (a *App) planDrain(shardID string) (*repository.ReshardJob, error) {
+
+	projectID, err := a.ShardRepo.FetchProjectID(a.ctx, shardID)
+	if err != nil {
+		return nil, err
+	}
+
+	shards, err := a.ShardRepo.ShardList(a.ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var shard *repository.Shard
+	targets := make([]string, 0, len(shards))
+
+	for i := range shards {
+		if shards[i].ID == shardID {
+			shard = &shards[i]
+			continue
+		}
+		if shards[i].Status == "active" {
+			targets = append(targets, shards[i].ID)
+		}
+	}
+
+	if shard == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	if shard.Status == "inactive" {
+		return nil, api.NewRuleError("shard must be active to drain it")
+	}
+
+	if len(targets) == 0 {
+		return nil, api.NewRuleError("no other active shard to move hash slots to")
+	}
+
+	if err := a.checkNoReshardJob(projectID); err != nil {
+		return nil, err
+	}
+
+	shardMap, err := a.freezeShardMap(projectID, shards)
+	if err != nil {
+		return nil, err
+	}
+
+	moves := reshard.PlanDrain(shardMap.Owners, shardID, targets)
+	if len(moves) == 0 {
+		return nil, api.NewRuleError("shard owns no hash slots")
+	}
+
+	return a.createReshardJob(projectID, reshard.KindDrain, shardID, shardMap, moves)
+}
+
+// helper to count the hash slots of every shard of a project, empty while
+// the project has no shard map
+func (a *App) slotCounts(ctx context.Context, projectID string) (map[string]int, error) {
+
+	counts := make(map[string]int)
+
+	shardMap, err := a.ShardMapRepo.ShardMapGet(ctx, projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return counts, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, owner := range shardMap.Owners {
+		counts[owner]++
+	}
+
+	return counts, nil
+}
+
+// helper to reject deleting a shard that still owns hash slots or takes
+// part in an unfinished job, its rows would be lost
+func (a *App) checkShardDeletable(shardID string) error {
+
+	projectID, err := a.ShardRepo.FetchProjectID(a.ctx, shardID)
+	if err != nil {
+		return err
+	}
+
+	slots, err := a.slotCounts(a.ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	if n := slots[shardID]; n > 0 {
+		return api.NewRuleError("shard owns " + strconv.Itoa(n) + " hash slots, drain it before deleting it")
+	}
+
+	job, err := a.ReshardRepo.ReshardJobUnfinished(a.ctx, projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	moves, err := a.ReshardRepo.ReshardMoves(a.ctx, job.ID)
+	if err != nil {
+		return err
+	}
+
+	involved := job.ShardID == shardID
+	for _, move := range moves {
+		if move.SourceShardID == shardID || move.TargetShardID == shardID {
+			involved = true
+			break
+		}
+	}
+
+	if involved {
+		return api.NewRuleError("reshard job " + job.ID + " moving rows of the shard is " + job.Status + ", resume or cancel it first")
+	}
+
+	return nil
+}
+
+// helper to reject activating a shard while its drain is unfinished
+func (a *App) checkNotDraining(shardID string) error {
+
+	job, err := a.ReshardRepo.ReshardJobLatest(a.ctx, shardID, emptyingKinds)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch job.Status {
+	case reshard.StatusPending, reshard.StatusRunning, reshard.StatusFailed:
+		return api.NewRuleError("shard drain " + job.ID + " is " + job.Status + ", cancel it to keep the shard")
+	}
+
+	return nil
+}
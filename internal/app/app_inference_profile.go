@@ -0,0 +1,86 @@
+package app
+
+import (
+	"sql-sharding-v2/internal/api"
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/shardkey"
+	"sql-sharding-v2/pkg/logger"
+	"strings"
+)
+
+// inference profile repository - weights and column patterns inference scores with
+func (a *App) GetInferenceProfile(projectID string) (*repository.InferenceProfile, error) {
+
+	profile, err := a.InferenceService.LoadProfile(a.ctx, projectID)
+	if err != nil {
+		return nil, a.inferenceProfileFailed("GetInferenceProfile", projectID, err)
+	}
+
+	return &profile, nil
+}
+
+// inference profile repository - replace the profile, applies on the next recompute
+func (a *App) SetInferenceProfile(projectID string, profile repository.InferenceProfile) (*repository.InferenceProfile, error) {
+
+	profile.EliminatedPatterns = cleanPatterns(profile.EliminatedPatterns)
+	profile.PreferredColumns = cleanPatterns(profile.PreferredColumns)
+
+	if err := shardkey.ValidateProfile(profile); err != nil {
+		return nil, api.NewRuleError(err.Error())
+	}
+
+	if err := a.InferenceProfileRepo.InferenceProfileUpsert(a.ctx, projectID, &profile); err != nil {
+		return nil, a.inferenceProfileFailed("SetInferenceProfile", projectID, err)
+	}
+
+	logger.Logger.Info("Successfully set inference profile", "project_id", projectID)
+	a.emitter.Info("Inference profile update successful", "application - SetInferenceProfile", map[string]string{
+		"project_id": projectID,
+	})
+
+	return &profile, nil
+}
+
+// inference profile repository - drop the profile, inference goes back to the defaults
+func (a *App) ResetInferenceProfile(projectID string) error {
+
+	if err := a.InferenceProfileRepo.InferenceProfileDelete(a.ctx, projectID); err != nil {
+		return a.inferenceProfileFailed("ResetInferenceProfile", projectID, err)
+	}
+
+	logger.Logger.Info("Successfully reset inference profile", "project_id", projectID)
+	a.emitter.Info("Inference profile reset successful", "application - ResetInferenceProfile", map[string]string{
+		"project_id": projectID,
+	})
+
+	return nil
+}
+
+// helper to trim patterns and drop duplicates, never nil so the profile stores empty arrays
+func cleanPatterns(patterns []string) []string {
+
+	result := make([]string, 0, len(patterns))
+	seen := make(map[string]bool, len(patterns))
+
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		result = append(result, p)
+	}
+
+	return result
+}
+
+func (a *App) inferenceProfileFailed(method string, projectID string, err error) error {
+
+	logger.Logger.Error("inference profile failed", "project_id", projectID, "error", err)
+	a.emitter.Error("Inference profile failed", "application - "+method, map[string]string{
+		"project_id": projectID,
+		"error":      err.Error(),
+	})
+
+	return err
+}
@@ -0,0 +1,512 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sql-sharding-v2/internal/api"
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/reshard"
+	"sql-sharding-v2/internal/router"
+	"sql-sharding-v2/pkg/logger"
+)
+
+// shard map repository - slot ownership of a project
+func (a *App) GetShardMap(projectID string) (*reshard.MapReport, error) {
+
+	shardMap, err := a.ShardMapRepo.ShardMapGet(a.ctx, projectID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logger.Logger.Error("Failed to fetch shard map", "project_id", projectID, "error", err)
+			a.emitter.Error("Shard map fetching failed", "application - GetShardMap", map[string]string{
+				"project_id": projectID,
+				"error":      err.Error(),
+			})
+		}
+		return nil, err
+	}
+
+	return reshard.DescribeMap(shardMap), nil
+}
+
+// reshard - plan moving an even share of hash slots to an active shard that
+// owns none and start copying them in the background
+func (a *App) StartRebalance(projectID string, shardID string) (*repository.ReshardJob, error) {
+
+	job, err := a.planRebalance(projectID, shardID)
+	if err != nil {
+		logger.Logger.Error("Failed to start rebalance", "project_id", projectID, "shard_id", shardID, "error", err)
+		a.emitter.Error("Rebalance start failed", "application - StartRebalance", map[string]string{
+			"project_id": projectID,
+			"shard_id":   shardID,
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	if err := a.launchReshardJob(job); err != nil {
+		return nil, err
+	}
+
+	logger.Logger.Info("Rebalance started", "project_id", projectID, "shard_id", shardID, "job_id", job.ID)
+	a.emitter.Info("Rebalance started", "application - StartRebalance", map[string]string{
+		"project_id": projectID,
+		"shard_id":   shardID,
+		"job_id":     job.ID,
+	})
+
+	return job, nil
+}
+
+// reshard - resume a failed job from its phase and checkpoints
+func (a *App) ResumeReshardJob(projectID string, jobID string) (*repository.ReshardJob, error) {
+
+	job, err := a.fetchReshardJob(projectID, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Status != reshard.StatusFailed && job.Status != reshard.StatusPending {
+		return nil, api.NewRuleError("only failed or pending reshard jobs can be resumed, job is " + job.Status)
+	}
+
+	// the shard may have been deactivated and activated since
+	if reshard.EmptiesShard(job.Kind) {
+		if err := a.ShardRepo.ShardDrain(a.ctx, job.ShardID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := a.launchReshardJob(job); err != nil {
+		logger.Logger.Error("Failed to resume reshard job", "job_id", jobID, "error", err)
+		a.emitter.Error("Reshard job resume failed", "application - ResumeReshardJob", map[string]string{
+			"job_id": jobID,
+			"error":  err.Error(),
+		})
+		return nil, err
+	}
+
+	a.emitter.Info("Reshard job resumed", "application - ResumeReshardJob", map[string]string{
+		"project_id": job.ProjectID,
+		"job_id":     jobID,
+		"phase":      job.Phase,
+	})
+
+	return job, nil
+}
+
+// reshard - cancel a job that has not cut over, removing the rows it copied
+func (a *App) CancelReshardJob(projectID string, jobID string) (*repository.ReshardJob, error) {
+
+	job, err := a.fetchReshardJob(projectID, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Status != reshard.StatusFailed && job.Status != reshard.StatusPending {
+		return nil, api.NewRuleError("only failed or pending reshard jobs can be cancelled, job is " + job.Status)
+	}
+
+	switch job.Phase {
+	case reshard.PhaseCutover, reshard.PhaseCleanup:
+		return nil, api.NewRuleError("reshard job has reached " + job.Phase + " and can only be resumed")
+	}
+
+	keys, err := a.reshardKeys(job.ProjectID)
+	if err == nil {
+		err = a.Mover.Cancel(a.ctx, job, keys)
+	}
+	if err != nil {
+		logger.Logger.Error("Failed to cancel reshard job", "job_id", jobID, "error", err)
+		a.emitter.Error("Reshard job cancellation failed", "application - CancelReshardJob", map[string]string{
+			"job_id": jobID,
+			"error":  err.Error(),
+		})
+		return nil, err
+	}
+
+	// a shard whose drain or merge was cancelled keeps its slots and serves them
+	if reshard.EmptiesShard(job.Kind) {
+		if err := a.ShardRepo.ShardActivate(a.ctx, job.ShardID); err != nil {
+			return nil, err
+		}
+	}
+
+	a.emitter.Info("Reshard job cancelled", "application - CancelReshardJob", map[string]string{
+		"project_id": job.ProjectID,
+		"job_id":     jobID,
+	})
+
+	return a.ReshardRepo.ReshardJobGet(a.ctx, jobID)
+}
+
+// reshard repository - a job with its moves and checkpoints
+func (a *App) GetReshardJob(projectID string, jobID string) (*reshard.JobReport, error) {
+
+	job, err := a.fetchReshardJob(projectID, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.describeReshardJob(job)
+}
+
+// reshard repository - jobs of a project, newest first
+func (a *App) ListReshardJobs(projectID string) ([]repository.ReshardJob, error) {
+
+	jobs, err := a.ReshardRepo.ReshardJobList(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to list reshard jobs", "project_id", projectID, "error", err)
+		a.emitter.Error("Reshard job listing failed", "application - ListReshardJobs", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// reshard - block until a job started by this process stops running
+func (a *App) WaitReshardJob(ctx context.Context, jobID string) (*repository.ReshardJob, error) {
+
+	a.reshardMu.Lock()
+	done, ok := a.reshardRuns[jobID]
+	a.reshardMu.Unlock()
+
+	if ok {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return a.ReshardRepo.ReshardJobGet(a.ctx, jobID)
+}
+
+// helper to wait for background jobs to record where they stopped
+func (a *App) waitReshardRuns(ctx context.Context) {
+
+	a.reshardMu.Lock()
+	runs := make([]chan struct{}, 0, len(a.reshardRuns))
+	for _, done := range a.reshardRuns {
+		runs = append(runs, done)
+	}
+	a.reshardMu.Unlock()
+
+	for _, done := range runs {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// helper to build the report of a job from its moves and checkpoints
+func (a *App) describeReshardJob(job *repository.ReshardJob) (*reshard.JobReport, error) {
+
+	moves, err := a.ReshardRepo.ReshardMoves(a.ctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints, err := a.ReshardRepo.ReshardCheckpointList(a.ctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return reshard.DescribeJob(job, moves, checkpoints), nil
+}
+
+// helper to fetch a job of a project, sql.ErrNoRows for jobs of other projects
+func (a *App) fetchReshardJob(projectID string, jobID string) (*repository.ReshardJob, error) {
+
+	job, err := a.ReshardRepo.ReshardJobGet(a.ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.ProjectID != projectID {
+		return nil, sql.ErrNoRows
+	}
+
+	return job, nil
+}
+
+// helper to resume jobs left running by a process that crashed
+func (a *App) resumeReshardJobs() {
+
+	jobs, err := a.ReshardRepo.ReshardJobListRunning(a.ctx)
+	if err != nil {
+		logger.Logger.Error("Failed to list interrupted reshard jobs", "error", err)
+		return
+	}
+
+	for i := range jobs {
+		job := &jobs[i]
+
+		logger.Logger.Info("Resuming interrupted reshard job", "job_id", job.ID, "phase", job.Phase)
+
+		if err := a.launchReshardJob(job); err != nil {
+			logger.Logger.Error("Failed to resume reshard job", "job_id", job.ID, "error", err)
+		}
+	}
+}
+
+// helper to validate a rebalance onto a shard and store its job
+func (a *App) planRebalance(projectID string, shardID string) (*repository.ReshardJob, error) {
+
+	shards, err := a.ShardRepo.ShardList(a.ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *repository.Shard
+	others := make([]repository.Shard, 0, len(shards))
+
+	for i := range shards {
+		if shards[i].ID == shardID {
+			target = &shards[i]
+			continue
+		}
+		others = append(others, shards[i])
+	}
+
+	if target == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	if target.Status != "active" {
+		return nil, api.NewRuleError("shard must be active before keys move to it")
+	}
+
+	if err := a.checkNoReshardJob(projectID); err != nil {
+		return nil, err
+	}
+
+	// a project without a map routed by modulo over the shards active
+	// before the new one
+	shardMap, err := a.freezeShardMap(projectID, others)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, owner := range shardMap.Owners {
+		if owner == shardID {
+			return nil, api.NewRuleError("shard already owns hash slots")
+		}
+	}
+
+	moves := reshard.PlanRebalance(shardMap.Owners, shardID)
+	if len(moves) == 0 {
+		return nil, api.NewRuleError("no hash slots to move")
+	}
+
+	return a.createReshardJob(projectID, reshard.KindRebalance, shardID, shardMap, moves)
+}
+
+// helper to store a job with its moves against the current shard map
+func (a *App) createReshardJob(
+	projectID string,
+	kind string,
+	shardID string,
+	shardMap *repository.ShardMap,
+	moves []repository.SlotMove,
+) (*repository.ReshardJob, error) {
+
+	job := &repository.ReshardJob{
+		ProjectID:  projectID,
+		Kind:       kind,
+		ShardID:    shardID,
+		MapVersion: shardMap.Version,
+	}
+
+	if err := a.ReshardRepo.ReshardJobCreate(a.ctx, job, moves); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// helper to undo a stored job that failed to start, it has moved no rows so
+// it ends cancelled and the shard it drains serves its slots again
+func (a *App) abandonReshardJob(job *repository.ReshardJob) {
+
+	if err := a.ReshardRepo.ReshardJobFinish(a.ctx, job.ID, reshard.StatusCancelled); err != nil {
+		logger.Logger.Error("Failed to cancel reshard job that did not start", "job_id", job.ID, "error", err)
+	}
+
+	if reshard.EmptiesShard(job.Kind) {
+		if err := a.ShardRepo.ShardActivate(a.ctx, job.ShardID); err != nil {
+			logger.Logger.Error("Failed to reactivate shard of reshard job that did not start", "job_id", job.ID, "shard_id", job.ShardID, "error", err)
+		}
+	}
+}
+
+// helper to reject a new job while another one of the project is unfinished
+func (a *App) checkNoReshardJob(projectID string) error {
+
+	running, err := a.ReshardRepo.ReshardJobUnfinished(a.ctx, projectID)
+	if err == nil {
+		return api.NewRuleError("reshard job " + running.ID + " is " + running.Status + ", resume or cancel it first")
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	return nil
+}
+
+// helper to fetch the shard map of a project, storing one that reproduces
+// modulo routing over the given shards when it has none yet
+func (a *App) freezeShardMap(projectID string, shards []repository.Shard) (*repository.ShardMap, error) {
+
+	shardMap, err := a.ShardMapRepo.ShardMapGet(a.ctx, projectID)
+	if err == nil {
+		return shardMap, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	owners := router.FreezeOwners(shards)
+	if len(owners) == 0 {
+		return nil, api.NewRuleError("project has no active shards to own hash slots")
+	}
+
+	shardMap, err = a.ShardMapRepo.ShardMapCreate(a.ctx, projectID, owners)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Logger.Info("Shard map frozen", "project_id", projectID, "slots", len(shardMap.Owners))
+
+	return shardMap, nil
+}
+
+// helper to freeze the shard map of an active project before a shard joins
+// it, the modulo ring would route keys to the new shard at once
+func (a *App) freezeBeforeJoin(projectID string) error {
+
+	status, err := a.ProjectRepo.FetchProjectStatus(a.ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	if status != "active" {
+		return nil
+	}
+
+	shards, err := a.ShardRepo.ShardList(a.ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.freezeShardMap(projectID, shards)
+	return err
+}
+
+// helper to claim a job and run it in the background, one run per job
+func (a *App) launchReshardJob(job *repository.ReshardJob) error {
+
+	a.reshardMu.Lock()
+	defer a.reshardMu.Unlock()
+
+	if _, ok := a.reshardRuns[job.ID]; ok {
+		return api.NewRuleError("reshard job is already running")
+	}
+
+	// jobs left running by a stopped process are resumed as they are
+	if job.Status != reshard.StatusRunning {
+		claimed, err := a.ReshardRepo.ReshardJobClaim(a.ctx, job.ID)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return api.NewRuleError("reshard job is no longer pending or failed")
+		}
+		job.Status = reshard.StatusRunning
+	}
+
+	done := make(chan struct{})
+	a.reshardRuns[job.ID] = done
+
+	go a.runReshardJob(job, done)
+
+	return nil
+}
+
+// helper to run a claimed job, a failure keeps its phase to resume from
+func (a *App) runReshardJob(job *repository.ReshardJob, done chan struct{}) {
+
+	defer func() {
+		a.reshardMu.Lock()
+		delete(a.reshardRuns, job.ID)
+		a.reshardMu.Unlock()
+		close(done)
+	}()
+
+	keys, err := a.reshardKeys(job.ProjectID)
+	if err == nil {
+		err = a.Mover.Run(a.ctx, job, keys)
+	}
+
+	// stopped with the process, marked failed so any process may resume it
+	// from its checkpoints
+	if err != nil && a.ctx.Err() != nil {
+		logger.Logger.Info("Reshard job interrupted", "job_id", job.ID, "phase", job.Phase)
+
+		if err := a.ReshardRepo.ReshardJobFail(context.Background(), job.ID, "interrupted during "+job.Phase); err != nil {
+			logger.Logger.Error("Failed to record reshard job interruption", "job_id", job.ID, "error", err)
+		}
+		return
+	}
+
+	if err != nil {
+		logger.Logger.Error("Reshard job failed", "job_id", job.ID, "phase", job.Phase, "error", err)
+		a.emitter.Error("Reshard job failed", "application - runReshardJob", map[string]string{
+			"project_id": job.ProjectID,
+			"job_id":     job.ID,
+			"phase":      job.Phase,
+			"error":      err.Error(),
+		})
+
+		if err := a.ReshardRepo.ReshardJobFail(a.ctx, job.ID, err.Error()); err != nil {
+			logger.Logger.Error("Failed to record reshard job failure", "job_id", job.ID, "error", err)
+		}
+		return
+	}
+
+	logger.Logger.Info("Reshard job completed", "job_id", job.ID, "kind", job.Kind)
+	a.emitter.Info("Reshard job completed", "application - runReshardJob", map[string]string{
+		"project_id": job.ProjectID,
+		"job_id":     job.ID,
+		"kind":       job.Kind,
+	})
+}
+
+// helper to list the sharded tables of a project, parents first
+func (a *App) reshardKeys(projectID string) ([]reshard.ShardKey, error) {
+
+	stored, err := a.ShardKeysRepo.FetchShardKeysByProjectID(a.ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	edges, err := a.FKEdgesRepo.GetEdgesByProjectID(a.ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]reshard.ShardKey, 0, len(stored))
+	for _, k := range stored {
+		keys = append(keys, reshard.ShardKey{
+			Table:  k.TableName,
+			Column: k.ShardKeyColumn,
+		})
+	}
+
+	return reshard.OrderTables(keys, edges), nil
+}
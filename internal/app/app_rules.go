@@ -1,8 +1,9 @@
-package main
+package app
 
 import (
 	"context"
 	"database/sql"
+	"sql-sharding-v2/internal/metrics"
 	"sql-sharding-v2/pkg/logger"
 	"strings"
 )
@@ -47,8 +48,9 @@ func (a *App) checkAllShardsActive(projectID string) (bool, error) {
 		return false, nil
 	}
 
+	// draining shards keep serving the slots they still own
 	for _, shard := range shards {
-		if shard.Status != "active" {
+		if shard.Status != "active" && shard.Status != "draining" {
 			return false, nil
 		}
 	}
@@ -150,6 +152,17 @@ func (a *App) checkIfSchemaDraft(schemaID string) (bool, error) {
 	return false, nil
 }
 
+// to check if the schema belongs to the project it is modified through
+func (a *App) checkIfSchemaInProject(projectID string, schemaID string) (bool, error) {
+
+	schema, err := a.ProjectSchemaRepo.ProjectSchemaFetchBySchemaID(a.ctx, schemaID)
+	if err != nil {
+		return false, err
+	}
+
+	return schema.ProjectID == projectID, nil
+}
+
 // to check if any schema is already pending or applying for a project
 func (a *App) checkIfSchemaInFlight(projectID string) (bool, error) {
 
@@ -231,8 +244,21 @@ func (a *App) checkAllShards(ctx context.Context) {
 		return
 	}
 
+	slots, err := a.slotCounts(ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to count shard slots", "error", err)
+		return
+	}
+
 	for _, shard := range shards {
+		// a drained shard serves nothing and is waiting to be deleted
+		if shard.Status == "draining" && slots[shard.ID] == 0 {
+			continue
+		}
+
 		healthy, err := a.checkShardHealth(ctx, projectID, shard.ID)
+		metrics.ObserveShardHealth(projectID, shard.ID, err == nil && healthy)
+
 		if err != nil || !healthy {
 
 			_ = a.DeactivateShard(shard.ID)
@@ -0,0 +1,305 @@
+package app
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sql-sharding-v2/internal/api"
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/router"
+	"sql-sharding-v2/internal/shardkey"
+	"sql-sharding-v2/pkg/logger"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// most inference runs listed at once
+const maxInferenceRunPage = 100
+
+// most statements a single simulation routes
+const maxSimulationStatements = 10000
+
+// shard keys repository - pin the key of a table so inference leaves it alone,
+// an empty column locks the current key
+func (a *App) LockShardKey(projectID string, tableName string, column string) error {
+
+	column = strings.TrimSpace(column)
+
+	current, err := a.currentShardKey(projectID, tableName)
+	if err != nil {
+		return a.shardKeyLockFailed("LockShardKey", projectID, tableName, err)
+	}
+
+	if column == "" {
+		if current == nil {
+			return api.NewRuleError(fmt.Sprintf("table %s has no shard key to lock, name a column", tableName))
+		}
+		column = current.ShardKeyColumn
+	}
+
+	if err := a.checkShardKeyColumn(projectID, tableName, column); err != nil {
+		return err
+	}
+
+	if current == nil || current.ShardKeyColumn != column {
+		if err := a.checkShardKeyChangeAllowed(projectID); err != nil {
+			return err
+		}
+	}
+
+	if err := a.ShardKeysRepo.LockShardKey(a.ctx, projectID, tableName, column); err != nil {
+		return a.shardKeyLockFailed("LockShardKey", projectID, tableName, err)
+	}
+
+	logger.Logger.Info("shard key locked", "project_id", projectID, "table", tableName, "column", column)
+	a.emitter.Info("Shard key locked", "application - LockShardKey", map[string]string{
+		"project_id": projectID,
+		"table":      tableName,
+		"column":     column,
+	})
+
+	return nil
+}
+
+// shard keys repository - release a pinned key, the table takes the inferred key back
+func (a *App) UnlockShardKey(projectID string, tableName string) error {
+
+	current, err := a.currentShardKey(projectID, tableName)
+	if err != nil {
+		return a.shardKeyLockFailed("UnlockShardKey", projectID, tableName, err)
+	}
+
+	if current == nil {
+		return a.shardKeyLockFailed("UnlockShardKey", projectID, tableName, sql.ErrNoRows)
+	}
+
+	if !current.IsManualOverride {
+		return nil
+	}
+
+	if current.Conflict {
+		if err := a.checkShardKeyChangeAllowed(projectID); err != nil {
+			return err
+		}
+	}
+
+	if err := a.ShardKeysRepo.UnlockShardKey(a.ctx, projectID, tableName); err != nil {
+		return a.shardKeyLockFailed("UnlockShardKey", projectID, tableName, err)
+	}
+
+	logger.Logger.Info("shard key unlocked", "project_id", projectID, "table", tableName)
+	a.emitter.Info("Shard key unlocked", "application - UnlockShardKey", map[string]string{
+		"project_id": projectID,
+		"table":      tableName,
+	})
+
+	return nil
+}
+
+// helper to report manual overrides the latest inference run disagrees with
+func (a *App) reportShardKeyConflicts(projectID string) {
+
+	keys, err := a.ShardKeysRepo.FetchShardKeysByProjectID(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Warn("failed to check shard key conflicts", "project_id", projectID, "error", err)
+		return
+	}
+
+	for _, k := range keys {
+		if !k.Conflict {
+			continue
+		}
+
+		logger.Logger.Warn(
+			"manual shard key conflicts with inference",
+			"project_id", projectID,
+			"table", k.TableName,
+			"manual", k.ShardKeyColumn,
+			"inferred", *k.InferredColumn,
+		)
+		a.emitter.Warn("Manual shard key conflicts with inference", "application - RecomputeKeys", map[string]string{
+			"project_id": projectID,
+			"table":      k.TableName,
+			"manual":     k.ShardKeyColumn,
+			"inferred":   *k.InferredColumn,
+		})
+	}
+}
+
+// helper to fetch the stored key of a table, nil when it has none
+func (a *App) currentShardKey(projectID string, tableName string) (*repository.ShardKeys, error) {
+
+	keys, err := a.ShardKeysRepo.FetchShardKeysByProjectID(a.ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range keys {
+		if keys[i].TableName == tableName {
+			return &keys[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// helper to check a column exists and may serve as shard key
+func (a *App) checkShardKeyColumn(projectID string, tableName string, column string) error {
+
+	columns, err := a.ColumnsRepo.GetColumnsByProjectID(a.ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	tableFound := false
+
+	for _, c := range columns {
+		if c.TableName != tableName {
+			continue
+		}
+		tableFound = true
+
+		if c.ColumnName == column {
+			if c.Nullable {
+				return api.NewRuleError(fmt.Sprintf("column %s.%s is nullable and cannot be a shard key", tableName, column))
+			}
+			return nil
+		}
+	}
+
+	if !tableFound {
+		return api.NewRuleError(fmt.Sprintf("project has no table %s", tableName))
+	}
+
+	return api.NewRuleError(fmt.Sprintf("table %s has no column %s", tableName, column))
+}
+
+// helper to reject key changes while rows are being routed by the old key
+func (a *App) checkShardKeyChangeAllowed(projectID string) error {
+
+	inactive, err := a.checkIfProjectInactive(projectID)
+	if err != nil {
+		return err
+	}
+
+	if !inactive {
+		return api.NewRuleError("project must be inactive before changing a shard key")
+	}
+
+	return nil
+}
+
+func (a *App) shardKeyLockFailed(method string, projectID string, tableName string, err error) error {
+
+	logger.Logger.Error("shard key lock update failed", "project_id", projectID, "table", tableName, "error", err)
+	a.emitter.Error("Shard key lock update failed", "application - "+method, map[string]string{
+		"project_id": projectID,
+		"table":      tableName,
+		"error":      err.Error(),
+	})
+
+	return err
+}
+
+// inference run repository - latest inference runs of a project, newest first
+func (a *App) ListInferenceRuns(projectID string, limit int) ([]repository.InferenceRun, error) {
+
+	if limit <= 0 || limit > maxInferenceRunPage {
+		limit = maxInferenceRunPage
+	}
+
+	runs, err := a.InferenceRunRepo.InferenceRunList(a.ctx, projectID, limit)
+	if err != nil {
+		logger.Logger.Error("failed to list inference runs", "project_id", projectID, "error", err)
+		a.emitter.Error("Inference run listing failed", "application - ListInferenceRuns", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// inference run repository - ranked candidates of a run, "latest" for the newest run
+func (a *App) GetInferenceRun(projectID string, runID string) (*repository.InferenceRun, error) {
+
+	var run *repository.InferenceRun
+	var err error
+
+	switch {
+	case runID == "latest":
+		run, err = a.InferenceRunRepo.InferenceRunLatest(a.ctx, projectID)
+	case uuid.Validate(runID) != nil:
+		err = sql.ErrNoRows
+	default:
+		run, err = a.InferenceRunRepo.InferenceRunGet(a.ctx, projectID, runID)
+	}
+
+	if err != nil {
+		logger.Logger.Error("failed to fetch inference run", "project_id", projectID, "run_id", runID, "error", err)
+		a.emitter.Error("Inference run fetching failed", "application - GetInferenceRun", map[string]string{
+			"project_id": projectID,
+			"run_id":     runID,
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// router service - route a workload under candidate shard keys without executing it,
+// captured adds the statements recorded for inference
+func (a *App) SimulateShardKeys(
+	projectID string,
+	keys map[string]string,
+	shardCount int,
+	queries []repository.WorkloadQuery,
+	captured bool,
+) (*router.Simulation, error) {
+
+	for table, column := range keys {
+		if err := a.checkShardKeyColumn(projectID, table, column); err != nil {
+			return nil, err
+		}
+	}
+
+	if captured {
+		workload, err := a.WorkloadRepo.WorkloadList(a.ctx, projectID, time.Now().Add(-shardkey.WorkloadWindow))
+		if err != nil {
+			return nil, a.simulationFailed(projectID, err)
+		}
+		queries = append(queries, workload...)
+	}
+
+	if len(queries) == 0 {
+		return nil, api.NewRuleError("no statements to simulate, pass queries or use the captured workload")
+	}
+	if len(queries) > maxSimulationStatements {
+		return nil, api.NewRuleError(fmt.Sprintf("at most %d statements can be simulated at once", maxSimulationStatements))
+	}
+
+	sim, err := a.RouterService.Simulate(a.ctx, projectID, keys, shardCount, queries)
+	if errors.Is(err, router.ErrNoShardsToSimulate) {
+		return nil, api.NewRuleError(err.Error())
+	}
+	if err != nil {
+		return nil, a.simulationFailed(projectID, err)
+	}
+
+	return sim, nil
+}
+
+func (a *App) simulationFailed(projectID string, err error) error {
+
+	logger.Logger.Error("shard key simulation failed", "project_id", projectID, "error", err)
+	a.emitter.Error("Shard key simulation failed", "application - SimulateShardKeys", map[string]string{
+		"project_id": projectID,
+		"error":      err.Error(),
+	})
+
+	return err
+}
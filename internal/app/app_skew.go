@@ -0,0 +1,160 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sql-sharding-v2/internal/config"
+	"sql-sharding-v2/internal/metrics"
+	"sql-sharding-v2/internal/router"
+	"sql-sharding-v2/internal/shardstats"
+	"sql-sharding-v2/internal/skew"
+	"sql-sharding-v2/pkg/logger"
+	"time"
+)
+
+// func to periodically measure shard skew of the active project
+func (a *App) MonitorSkew(ctx context.Context) {
+	ticker := time.NewTicker(config.SkewSettings.INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Logger.Info("Skew monitor stopped")
+			return
+
+		case <-ticker.C:
+			projectID, err := a.ProjectRepo.FetchActiveProject(ctx)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				logger.Logger.Error("Failed to fetch active project", "error", err)
+				continue
+			}
+
+			if _, err := a.checkSkew(ctx, projectID); err != nil {
+				logger.Logger.Error("Skew check failed", "project_id", projectID, "error", err)
+			}
+		}
+	}
+}
+
+// skew detector - latest skew report of a project
+func (a *App) GetSkewReport(projectID string) (*skew.Report, error) {
+
+	report, ok := a.SkewDetector.Latest(projectID)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	return report, nil
+}
+
+// skew detector - measure shard skew of a project now, closes its traffic window
+func (a *App) RefreshSkewReport(projectID string) (*skew.Report, error) {
+
+	report, err := a.checkSkew(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("Skew check failed", "project_id", projectID, "error", err)
+		a.emitter.Error("Skew check failed", "application - RefreshSkewReport", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// helper to read table sizes of every active or draining shard, build the report and
+// raise alerts that were not raised by the previous report
+func (a *App) checkSkew(ctx context.Context, projectID string) (*skew.Report, error) {
+
+	shards, err := a.ShardRepo.ShardList(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	shardIDs := make([]string, 0, len(shards))
+	sizes := make(map[string][]shardstats.TableSize, len(shards))
+
+	for _, shard := range shards {
+		if shard.Status == "inactive" {
+			continue
+		}
+		shardIDs = append(shardIDs, shard.ID)
+
+		db, err := a.ShardConnectionStore.Get(projectID, shard.ID)
+		if err != nil {
+			logger.Logger.Warn("Shard unreachable for skew check", "project_id", projectID, "shard_id", shard.ID, "error", err)
+			continue
+		}
+
+		tables, err := shardstats.CollectTableSizes(ctx, db)
+		if err != nil {
+			logger.Logger.Warn("Failed to read shard table sizes", "project_id", projectID, "shard_id", shard.ID, "error", err)
+			continue
+		}
+		sizes[shard.ID] = tables
+	}
+
+	traffic := a.SkewDetector.Rotate(projectID)
+	report := a.SkewDetector.Analyze(projectID, shardIDs, sizes, traffic)
+
+	raised := make(map[string]bool)
+	if previous, ok := a.SkewDetector.Latest(projectID); ok {
+		for _, alert := range previous.Alerts {
+			raised[alert.Kind+"|"+alert.Subject] = true
+		}
+	}
+
+	a.SkewDetector.Store(report)
+
+	metrics.ObserveSkew(projectID, report.RowSkew, report.SizeSkew, report.StatementSkew)
+	for _, load := range report.Shards {
+		if _, ok := sizes[load.ShardID]; ok {
+			metrics.ObserveShardSize(projectID, load.ShardID, load.Rows, load.Bytes)
+		}
+	}
+
+	for _, alert := range report.Alerts {
+		if raised[alert.Kind+"|"+alert.Subject] {
+			continue
+		}
+
+		logger.Logger.Warn(alert.Message, "project_id", projectID, "kind", alert.Kind, "subject", alert.Subject, "value", alert.Value)
+		a.emitter.Warn("Shard skew detected", "application - MonitorSkew", map[string]string{
+			"project_id": projectID,
+			"kind":       alert.Kind,
+			"subject":    alert.Subject,
+			"value":      fmt.Sprintf("%.2f", alert.Value),
+			"message":    alert.Message,
+		})
+	}
+
+	return report, nil
+}
+
+// helper to hand a routed statement to the skew detector
+func (a *App) observeTraffic(projectID string, plan *router.RoutingPlan) {
+
+	event := skew.Event{
+		ProjectID: projectID,
+		Shards:    make([]string, 0, len(plan.Targets)),
+		Table:     plan.Table,
+		Column:    plan.ShardKey,
+	}
+
+	for _, target := range plan.Targets {
+		event.Shards = append(event.Shards, string(target.ShardID))
+	}
+
+	for _, value := range plan.KeyValues {
+		event.Values = append(event.Values, router.KeyText(value))
+	}
+
+	a.SkewDetector.Observe(event)
+}
@@ -0,0 +1,184 @@
+package app
+
+import (
+	"database/sql"
+
+	"sql-sharding-v2/internal/api"
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/reshard"
+	"sql-sharding-v2/pkg/logger"
+)
+
+// reshard - move the upper half of a hot shard's hash slots to an active
+// shard owning none, without touching the other shards
+func (a *App) SplitShard(shardID string, targetShardID string) (*repository.ReshardJob, error) {
+
+	job, err := a.planSplit(shardID, targetShardID)
+	if err == nil {
+		if err = a.launchReshardJob(job); err != nil {
+			a.abandonReshardJob(job)
+		}
+	}
+	if err != nil {
+		logger.Logger.Error("Failed to start shard split", "shard_id", shardID, "target_shard_id", targetShardID, "error", err)
+		a.emitter.Error("Shard split start failed", "application - SplitShard", map[string]string{
+			"shard_id":        shardID,
+			"target_shard_id": targetShardID,
+			"error":           err.Error(),
+		})
+		return nil, err
+	}
+
+	logger.Logger.Info("Shard split started", "project_id", job.ProjectID, "shard_id", shardID, "target_shard_id", targetShardID, "job_id", job.ID)
+	a.emitter.Info("Shard split started", "application - SplitShard", map[string]string{
+		"project_id":      job.ProjectID,
+		"shard_id":        shardID,
+		"target_shard_id": targetShardID,
+		"job_id":          job.ID,
+	})
+
+	return job, nil
+}
+
+// reshard - move every hash slot of a shard into another active shard and
+// leave it draining, ready to be deleted
+func (a *App) MergeShards(shardID string, intoShardID string) (*repository.ReshardJob, error) {
+
+	job, err := a.planMerge(shardID, intoShardID)
+	if err == nil {
+		err = a.ShardRepo.ShardDrain(a.ctx, shardID)
+		if err == nil {
+			err = a.launchReshardJob(job)
+		}
+		if err != nil {
+			a.abandonReshardJob(job)
+		}
+	}
+	if err != nil {
+		logger.Logger.Error("Failed to start shard merge", "shard_id", shardID, "into_shard_id", intoShardID, "error", err)
+		a.emitter.Error("Shard merge start failed", "application - MergeShards", map[string]string{
+			"shard_id":      shardID,
+			"into_shard_id": intoShardID,
+			"error":         err.Error(),
+		})
+		return nil, err
+	}
+
+	logger.Logger.Info("Shard merge started", "project_id", job.ProjectID, "shard_id", shardID, "into_shard_id", intoShardID, "job_id", job.ID)
+	a.emitter.Info("Shard merge started", "application - MergeShards", map[string]string{
+		"project_id":    job.ProjectID,
+		"shard_id":      shardID,
+		"into_shard_id": intoShardID,
+		"job_id":        job.ID,
+	})
+	a.emitter.Event("shard:status_changed", map[string]string{
+		"shard_id": shardID,
+		"status":   "draining",
+	})
+
+	return job, nil
+}
+
+// helper to validate splitting a shard onto an empty one and store its job
+func (a *App) planSplit(shardID string, targetShardID string) (*repository.ReshardJob, error) {
+
+	projectID, shards, err := a.shardPair(shardID, targetShardID)
+	if err != nil {
+		return nil, err
+	}
+
+	others := make([]repository.Shard, 0, len(shards))
+	for _, shard := range shards {
+		if shard.ID != targetShardID {
+			others = append(others, shard)
+		}
+	}
+
+	if err := a.checkNoReshardJob(projectID); err != nil {
+		return nil, err
+	}
+
+	// the target joined after the map was frozen or is left out of it
+	shardMap, err := a.freezeShardMap(projectID, others)
+	if err != nil {
+		return nil, err
+	}
+
+	slots := 0
+	for _, owner := range shardMap.Owners {
+		switch owner {
+		case targetShardID:
+			return nil, api.NewRuleError("target shard already owns hash slots")
+		case shardID:
+			slots++
+		}
+	}
+
+	if slots < 2 {
+		return nil, api.NewRuleError("shard owns too few hash slots to split")
+	}
+
+	return a.createReshardJob(projectID, reshard.KindSplit, targetShardID, shardMap,
+		reshard.PlanSplit(shardMap.Owners, shardID, targetShardID))
+}
+
+// helper to validate merging a shard into another one and store its job
+func (a *App) planMerge(shardID string, intoShardID string) (*repository.ReshardJob, error) {
+
+	projectID, shards, err := a.shardPair(shardID, intoShardID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.checkNoReshardJob(projectID); err != nil {
+		return nil, err
+	}
+
+	shardMap, err := a.freezeShardMap(projectID, shards)
+	if err != nil {
+		return nil, err
+	}
+
+	moves := reshard.PlanMerge(shardMap.Owners, shardID, intoShardID)
+	if len(moves) == 0 {
+		return nil, api.NewRuleError("shard owns no hash slots")
+	}
+
+	return a.createReshardJob(projectID, reshard.KindMerge, shardID, shardMap, moves)
+}
+
+// helper to fetch the shards of a project holding two distinct active shards
+func (a *App) shardPair(shardID string, otherID string) (string, []repository.Shard, error) {
+
+	if shardID == otherID {
+		return "", nil, api.NewRuleError("source and target shard must differ")
+	}
+
+	projectID, err := a.ShardRepo.FetchProjectID(a.ctx, shardID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	shards, err := a.ShardRepo.ShardList(a.ctx, projectID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	found := 0
+	for _, shard := range shards {
+		if shard.ID != shardID && shard.ID != otherID {
+			continue
+		}
+		if shard.Status != "active" {
+			return "", nil, api.NewRuleError("shard " + shard.ID + " must be active")
+		}
+		found++
+	}
+
+	// the other shard belongs to another project
+	if found != 2 {
+		return "", nil, sql.ErrNoRows
+	}
+
+	return projectID, shards, nil
+}
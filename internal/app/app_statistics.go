@@ -0,0 +1,135 @@
+package app
+
+import (
+	"fmt"
+	"sql-sharding-v2/internal/api"
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/shardkey"
+	"sql-sharding-v2/internal/shardstats"
+	"sql-sharding-v2/pkg/logger"
+)
+
+// most sampled rows accepted in one upload
+const maxSampleRows = 100000
+
+// column statistics repository - read pg_stats of every active shard for inference
+func (a *App) CollectColumnStatistics(projectID string) ([]shardkey.ColumnDistribution, error) {
+
+	shards, err := a.ShardRepo.ShardList(a.ctx, projectID)
+	if err != nil {
+		return nil, a.statisticsFailed("CollectColumnStatistics", projectID, err)
+	}
+
+	perShard := make([][]repository.ColumnStatistic, 0, len(shards))
+
+	for _, shard := range shards {
+		if shard.Status != "active" {
+			continue
+		}
+
+		db, err := a.ShardConnectionStore.Get(projectID, shard.ID)
+		if err != nil {
+			return nil, a.statisticsFailed("CollectColumnStatistics", projectID, fmt.Errorf("shard %s: %w", shard.ID, err))
+		}
+
+		stats, err := shardstats.CollectColumnStats(a.ctx, db)
+		if err != nil {
+			return nil, a.statisticsFailed("CollectColumnStatistics", projectID, fmt.Errorf("shard %s: %w", shard.ID, err))
+		}
+
+		perShard = append(perShard, stats)
+	}
+
+	if len(perShard) == 0 {
+		return nil, api.NewRuleError("project has no active shards to collect statistics from")
+	}
+
+	merged := shardstats.MergeColumnStats(perShard, len(perShard))
+
+	return a.storeColumnStatistics("CollectColumnStatistics", projectID, merged)
+}
+
+// column statistics repository - compute statistics from sampled rows for inference
+func (a *App) UploadColumnSample(projectID string, samples []shardstats.TableSample) ([]shardkey.ColumnDistribution, error) {
+
+	rows := 0
+	for _, sample := range samples {
+		rows += len(sample.Rows)
+	}
+
+	if rows == 0 {
+		return nil, api.NewRuleError("sample has no rows")
+	}
+	if rows > maxSampleRows {
+		return nil, api.NewRuleError(fmt.Sprintf("sample has %d rows, at most %d are accepted", rows, maxSampleRows))
+	}
+
+	shards, err := a.ShardRepo.ShardList(a.ctx, projectID)
+	if err != nil {
+		return nil, a.statisticsFailed("UploadColumnSample", projectID, err)
+	}
+
+	active := 0
+	for _, shard := range shards {
+		if shard.Status == "active" {
+			active++
+		}
+	}
+
+	stats := shardstats.SampleColumnStats(samples, active)
+
+	return a.storeColumnStatistics("UploadColumnSample", projectID, stats)
+}
+
+// column statistics repository - stored statistics with the load they imply
+func (a *App) GetColumnStatistics(projectID string) ([]shardkey.ColumnDistribution, error) {
+
+	stats, err := a.ColumnStatisticsRepo.StatisticsList(a.ctx, projectID)
+	if err != nil {
+		return nil, a.statisticsFailed("GetColumnStatistics", projectID, err)
+	}
+
+	return shardkey.DescribeStatistics(stats), nil
+}
+
+// column statistics repository - drop statistics, inference goes back to name heuristics
+func (a *App) ClearColumnStatistics(projectID string) error {
+
+	if err := a.ColumnStatisticsRepo.StatisticsClear(a.ctx, projectID); err != nil {
+		return a.statisticsFailed("ClearColumnStatistics", projectID, err)
+	}
+
+	logger.Logger.Info("Successfully cleared column statistics", "project_id", projectID)
+	a.emitter.Info("Column statistics clear successful", "application - ClearColumnStatistics", map[string]string{
+		"project_id": projectID,
+	})
+
+	return nil
+}
+
+// helper to replace the statistics of a project and describe them
+func (a *App) storeColumnStatistics(method string, projectID string, stats []repository.ColumnStatistic) ([]shardkey.ColumnDistribution, error) {
+
+	if err := a.ColumnStatisticsRepo.StatisticsReplace(a.ctx, projectID, stats); err != nil {
+		return nil, a.statisticsFailed(method, projectID, err)
+	}
+
+	logger.Logger.Info("Successfully stored column statistics", "project_id", projectID, "columns", len(stats))
+	a.emitter.Info("Column statistics update successful", "application - "+method, map[string]string{
+		"project_id": projectID,
+		"columns":    fmt.Sprint(len(stats)),
+	})
+
+	return a.GetColumnStatistics(projectID)
+}
+
+func (a *App) statisticsFailed(method string, projectID string, err error) error {
+
+	logger.Logger.Error("column statistics failed", "project_id", projectID, "error", err)
+	a.emitter.Error("Column statistics failed", "application - "+method, map[string]string{
+		"project_id": projectID,
+		"error":      err.Error(),
+	})
+
+	return err
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"log/slog"
+	"os"
+	"sql-sharding-v2/internal/config"
+	"sql-sharding-v2/internal/executor"
+	"sql-sharding-v2/internal/querystats"
+	"sql-sharding-v2/internal/router"
+	"sql-sharding-v2/pkg/logger"
+	"time"
+)
+
+// most fingerprints or slow queries returned at once
+const maxStatsPage = 500
+
+// query stats - statements of a project aggregated per fingerprint
+func (a *App) GetQueryStats(projectID string, sortBy string, limit int) []querystats.FingerprintStats {
+
+	if limit <= 0 || limit > maxStatsPage {
+		limit = maxStatsPage
+	}
+
+	return a.QueryStats.Stats(projectID, sortBy, limit)
+}
+
+// query stats - latest slow queries of a project
+func (a *App) GetSlowQueries(projectID string, limit int) []querystats.SlowQuery {
+
+	if limit <= 0 || limit > maxStatsPage {
+		limit = maxStatsPage
+	}
+
+	return a.QueryStats.SlowQueries(projectID, limit)
+}
+
+// query stats - clear the statistics and slow queries of a project
+func (a *App) ResetQueryStats(projectID string) {
+
+	a.QueryStats.Reset(projectID)
+
+	logger.Logger.Info("Query statistics reset", "project_id", projectID)
+	a.emitter.Info("Query statistics reset", "application - ResetQueryStats", map[string]string{
+		"project_id": projectID,
+	})
+}
+
+// helper to hand an executed statement to the statistics collector
+func (a *App) observeQuery(
+	projectID string,
+	sqlText string,
+	plan *router.RoutingPlan,
+	results []executor.ExecutionResult,
+	err error,
+	started time.Time,
+) {
+
+	event := querystats.Event{
+		ProjectID:   projectID,
+		SQL:         sqlText,
+		RoutingMode: router.RoutingModeRejected.String(),
+		Failed:      err != nil,
+		Started:     started,
+		Duration:    time.Since(started),
+	}
+
+	if plan != nil {
+		event.RoutingMode = plan.Mode.String()
+		event.Shards = len(plan.Targets)
+	}
+
+	for _, result := range results {
+		// writes count affected rows, returned rows included
+		if result.Kind == router.StatementKindWrite {
+			event.Rows += result.RowsAffected
+		} else {
+			event.Rows += int64(len(result.Rows))
+		}
+		if result.Err != nil {
+			event.Failed = true
+		}
+	}
+
+	a.QueryStats.Observe(event)
+
+	if plan != nil && len(plan.Targets) > 0 {
+		a.observeTraffic(projectID, plan)
+	}
+}
+
+// helper to open the slow query log, the application log is used when no file is configured
+func (a *App) openSlowLog() *slog.Logger {
+
+	path := config.QueryStatsSettings.SLOW_LOG_PATH
+	if path == "" {
+		return logger.Logger
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		logger.Logger.Error("Failed to open slow query log, using the application log", "path", path, "error", err)
+		return logger.Logger
+	}
+
+	a.slowLog = file
+	return slog.New(slog.NewJSONHandler(file, nil))
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sql-sharding-v2/internal/api"
+	"sql-sharding-v2/internal/router"
+	"sql-sharding-v2/pkg/logger"
+	"strings"
+)
+
+// project repository - column carrying the tenant of a row, empty when isolation is off
+func (a *App) GetTenantColumn(projectID string) (string, error) {
+
+	column, err := a.ProjectRepo.FetchTenantColumn(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to fetch tenant column", "project_id", projectID, "error", err)
+		a.emitter.Error("Tenant column fetch failed", "application - GetTenantColumn", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return "", err
+	}
+
+	return column, nil
+}
+
+// project repository - turn tenant isolation on for a column, empty turns it off
+func (a *App) SetTenantColumn(projectID string, column string) error {
+
+	column = strings.ToLower(strings.TrimSpace(column))
+
+	if column != "" {
+		tables, _, err := a.tenantTables(a.ctx, projectID, column)
+		if err != nil {
+			return err
+		}
+		if len(tables) == 0 {
+			return api.NewRuleError(fmt.Sprintf("no table of the project has a %s column", column))
+		}
+	}
+
+	err := a.ProjectRepo.SetTenantColumn(a.ctx, projectID, column)
+	if err != nil {
+		logger.Logger.Error("Failed to set tenant column", "project_id", projectID, "error", err)
+		a.emitter.Error("Tenant column update failed", "application - SetTenantColumn", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	logger.Logger.Info("Successfully set tenant column", "project_id", projectID, "column", column)
+	a.emitter.Info("Tenant column update successful", "application - SetTenantColumn", map[string]string{
+		"project_id": projectID,
+		"column":     column,
+	})
+
+	return nil
+}
+
+// helper to resolve the tenant scope of a caller, nil when the project is not isolated
+func (a *App) tenantScope(ctx context.Context, projectID string, tenantID string) (*router.TenantScope, error) {
+
+	column, err := a.ProjectRepo.FetchTenantColumn(ctx, projectID)
+	if err != nil || column == "" {
+		return nil, err
+	}
+
+	tables, numeric, err := a.tenantTables(ctx, projectID, column)
+	if err != nil {
+		return nil, err
+	}
+
+	return &router.TenantScope{
+		Column:  column,
+		Value:   tenantID,
+		Numeric: numeric,
+		Tables:  tables,
+	}, nil
+}
+
+// helper to find the tables carrying the tenant column and whether it is numeric
+func (a *App) tenantTables(ctx context.Context, projectID string, column string) ([]string, bool, error) {
+
+	columns, err := a.ColumnsRepo.GetColumnsByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	tables := make([]string, 0)
+	numeric := false
+
+	for _, c := range columns {
+		if !strings.EqualFold(c.ColumnName, column) {
+			continue
+		}
+
+		tables = append(tables, c.TableName)
+
+		dataType := strings.ToLower(c.DataType)
+		if strings.Contains(dataType, "int") || strings.Contains(dataType, "serial") {
+			numeric = true
+		}
+	}
+
+	return tables, numeric, nil
+}
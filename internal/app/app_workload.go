@@ -0,0 +1,120 @@
+package app
+
+import (
+	"fmt"
+	"sql-sharding-v2/internal/api"
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/shardkey"
+	"sql-sharding-v2/pkg/logger"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+)
+
+// workload repository - import a query log to weigh shard key inference, returns the statements stored
+func (a *App) ImportWorkload(projectID string, queries []repository.WorkloadQuery) (int, error) {
+
+	merged := make(map[string]*repository.WorkloadQuery)
+	order := make([]string, 0)
+
+	for i, q := range queries {
+
+		sqlText := strings.TrimSpace(q.Query)
+		if sqlText == "" {
+			continue
+		}
+
+		fingerprint, err := pg_query.Fingerprint(sqlText)
+		if err != nil {
+			return 0, api.NewRuleError(fmt.Sprintf("query %d does not parse: %v", i+1, err))
+		}
+
+		// literals are stripped so the stored log carries no row data
+		normalized, err := pg_query.Normalize(sqlText)
+		if err != nil {
+			return 0, api.NewRuleError(fmt.Sprintf("query %d does not parse: %v", i+1, err))
+		}
+
+		calls := q.Calls
+		if calls <= 0 {
+			calls = 1
+		}
+
+		if existing, ok := merged[fingerprint]; ok {
+			existing.Calls += calls
+			continue
+		}
+
+		merged[fingerprint] = &repository.WorkloadQuery{
+			Fingerprint: fingerprint,
+			Query:       normalized,
+			Calls:       calls,
+		}
+		order = append(order, fingerprint)
+	}
+
+	if len(order) == 0 {
+		return 0, api.NewRuleError("no queries to import")
+	}
+
+	records := make([]repository.WorkloadQuery, 0, len(order))
+	for _, fingerprint := range order {
+		records = append(records, *merged[fingerprint])
+	}
+
+	err := a.WorkloadRepo.WorkloadImport(a.ctx, projectID, records)
+	if err != nil {
+		logger.Logger.Error("Failed to import workload", "project_id", projectID, "error", err)
+		a.emitter.Error("Workload import failed", "application - ImportWorkload", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return 0, err
+	}
+
+	logger.Logger.Info("Successfully imported workload", "project_id", projectID, "fingerprints", len(records))
+	a.emitter.Info("Workload import successful", "application - ImportWorkload", map[string]string{
+		"project_id":   projectID,
+		"fingerprints": fmt.Sprint(len(records)),
+	})
+
+	return len(records), nil
+}
+
+// inference service - predicate and join frequencies inference would weigh
+func (a *App) GetWorkloadSummary(projectID string) (*shardkey.WorkloadSummary, error) {
+
+	workload, err := a.InferenceService.AnalyzeProjectWorkload(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to analyse workload", "project_id", projectID, "error", err)
+		a.emitter.Error("Workload analysis failed", "application - GetWorkloadSummary", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return nil, err
+	}
+
+	summary := workload.Summary()
+	return &summary, nil
+}
+
+// workload repository - drop imported queries, captured statements stay in the audit log
+func (a *App) ClearWorkload(projectID string) error {
+
+	err := a.WorkloadRepo.WorkloadClear(a.ctx, projectID)
+	if err != nil {
+		logger.Logger.Error("Failed to clear workload", "project_id", projectID, "error", err)
+		a.emitter.Error("Workload clear failed", "application - ClearWorkload", map[string]string{
+			"project_id": projectID,
+			"error":      err.Error(),
+		})
+		return err
+	}
+
+	logger.Logger.Info("Successfully cleared workload", "project_id", projectID)
+	a.emitter.Info("Workload clear successful", "application - ClearWorkload", map[string]string{
+		"project_id": projectID,
+	})
+
+	return nil
+}
@@ -0,0 +1,316 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"sql-sharding-v2/internal/executor"
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/router"
+	"sql-sharding-v2/pkg/logger"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+)
+
+// event types
+const (
+	EventAccessDenied = "access_denied"
+	EventQuery        = "query"
+)
+
+// how often records past retention are deleted
+const pruneInterval = time.Hour
+
+// keeps a batch insert well below the bind parameter limit of postgres
+const maxBatchSize = 2000
+
+// Settings controls batching and retention of the recorder
+type Settings struct {
+	BufferSize    int
+	BatchSize     int
+	FlushInterval time.Duration
+
+	// records older than this are deleted, 0 keeps them forever
+	Retention time.Duration
+}
+
+// Recorder writes audit events to the application database. Records are
+// queued and written in batches by Run, so auditing never slows down or
+// fails a request. Records are dropped when the queue is full.
+type Recorder struct {
+	repo     *repository.AuditLogRepository
+	settings Settings
+
+	queue   chan repository.AuditRecord
+	dropped atomic.Int64
+
+	running   atomic.Bool
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewRecorder(repo *repository.AuditLogRepository, settings Settings) *Recorder {
+
+	if settings.BufferSize <= 0 {
+		settings.BufferSize = 10000
+	}
+	if settings.BatchSize <= 0 {
+		settings.BatchSize = 200
+	}
+	if settings.BatchSize > maxBatchSize {
+		settings.BatchSize = maxBatchSize
+	}
+	if settings.FlushInterval <= 0 {
+		settings.FlushInterval = 2 * time.Second
+	}
+
+	return &Recorder{
+		repo:     repo,
+		settings: settings,
+		queue:    make(chan repository.AuditRecord, settings.BufferSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Record queues an event without blocking
+func (r *Recorder) Record(_ context.Context, record repository.AuditRecord) {
+
+	if record.OccurredAt.IsZero() {
+		record.OccurredAt = time.Now()
+	}
+
+	select {
+	case r.queue <- record:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// RecordDenial implements router.DenialRecorder. Like queries, only the
+// normalized text of the statement is kept.
+func (r *Recorder) RecordDenial(ctx context.Context, denial router.AccessDenial) {
+
+	logger.Logger.Warn(
+		"statement denied by access policy",
+		"project_id", denial.ProjectID,
+		"subject", denial.Subject,
+		"reason", denial.Reason,
+	)
+
+	record := repository.AuditRecord{
+		EventType: EventAccessDenied,
+		Actor:     denial.Subject,
+		Statement: denial.SQL,
+		Detail:    denial.Kind.String() + ": " + denial.Reason,
+	}
+	if denial.ProjectID != "" {
+		record.ProjectID = &denial.ProjectID
+	}
+
+	r.Record(ctx, record)
+}
+
+// QueryEvent describes one executed statement
+type QueryEvent struct {
+	ProjectID string
+	Actor     string
+	SQL       string
+	Plan      *router.RoutingPlan
+	Results   []executor.ExecutionResult
+	Err       error
+	Started   time.Time
+	Duration  time.Duration
+}
+
+// RecordQuery queues the outcome of a statement. Only the normalized text is
+// kept, constants never reach the audit log. Fingerprinting happens when
+// the batch is written, off the request path.
+func (r *Recorder) RecordQuery(ctx context.Context, event QueryEvent) {
+
+	durationMs := float64(event.Duration.Microseconds()) / 1000
+
+	record := repository.AuditRecord{
+		OccurredAt: event.Started,
+		EventType:  EventQuery,
+		Actor:      event.Actor,
+		Statement:  event.SQL,
+		DurationMs: &durationMs,
+	}
+	if event.ProjectID != "" {
+		record.ProjectID = &event.ProjectID
+	}
+
+	if event.Plan != nil {
+		record.RoutingMode = event.Plan.Mode.String()
+		for _, target := range event.Plan.Targets {
+			record.TargetShards = append(record.TargetShards, string(target.ShardID))
+		}
+	}
+
+	errs := []error{event.Err}
+
+	if event.Results != nil {
+		var affected int64
+		for _, result := range event.Results {
+			affected += result.RowsAffected
+			errs = append(errs, result.Err)
+		}
+		record.RowsAffected = &affected
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		record.Error = err.Error()
+	}
+
+	r.Record(ctx, record)
+}
+
+// Run writes queued records in batches and applies retention until ctx is
+// cancelled or Close is called. Queued records are written before it returns.
+func (r *Recorder) Run(ctx context.Context) {
+
+	r.running.Store(true)
+	defer close(r.done)
+
+	flush := time.NewTicker(r.settings.FlushInterval)
+	defer flush.Stop()
+
+	prune := time.NewTicker(pruneInterval)
+	defer prune.Stop()
+
+	r.prune()
+
+	batch := make([]repository.AuditRecord, 0, r.settings.BatchSize)
+
+	for {
+		select {
+		case record := <-r.queue:
+			batch = append(batch, record)
+			if len(batch) >= r.settings.BatchSize {
+				batch = r.write(batch)
+			}
+
+		case <-flush.C:
+			batch = r.write(batch)
+
+		case <-prune.C:
+			r.prune()
+
+		case <-ctx.Done():
+			r.drain(batch)
+			return
+
+		case <-r.stop:
+			r.drain(batch)
+			return
+		}
+	}
+}
+
+// Close stops Run and waits for queued records to be written. Without a
+// running Run the queue is written directly.
+func (r *Recorder) Close(ctx context.Context) {
+
+	r.closeOnce.Do(func() { close(r.stop) })
+
+	if !r.running.Load() {
+		r.drain(nil)
+		return
+	}
+
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		logger.Logger.Warn("audit log not flushed before shutdown", "queued", len(r.queue))
+	}
+}
+
+// drain writes the current batch and everything still queued
+func (r *Recorder) drain(batch []repository.AuditRecord) {
+
+	for {
+		select {
+		case record := <-r.queue:
+			batch = append(batch, record)
+			if len(batch) >= r.settings.BatchSize {
+				batch = r.write(batch)
+			}
+		default:
+			r.write(batch)
+			return
+		}
+	}
+}
+
+// write inserts a batch and returns it emptied for reuse. Failed batches are
+// logged and discarded so a database outage cannot exhaust memory.
+func (r *Recorder) write(batch []repository.AuditRecord) []repository.AuditRecord {
+
+	if dropped := r.dropped.Swap(0); dropped > 0 {
+		logger.Logger.Warn("audit queue full, records dropped", "dropped", dropped)
+	}
+
+	if len(batch) == 0 {
+		return batch
+	}
+
+	// statements keep no constants, denied ones included
+	for i := range batch {
+		normalize(&batch[i])
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.repo.AuditLogInsertBatch(ctx, batch); err != nil {
+		logger.Logger.Error("failed to write audit records", "records", len(batch), "error", err)
+	}
+
+	return batch[:0]
+}
+
+// prune deletes records past the retention period
+func (r *Recorder) prune() {
+
+	if r.settings.Retention <= 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	deleted, err := r.repo.AuditLogPrune(ctx, time.Now().Add(-r.settings.Retention))
+	if err != nil {
+		logger.Logger.Error("failed to prune audit log", "error", err)
+		return
+	}
+
+	if deleted > 0 {
+		logger.Logger.Info("pruned audit log", "deleted", deleted)
+	}
+}
+
+// normalize replaces the statement of a record by its fingerprint and normalized text
+func normalize(record *repository.AuditRecord) {
+
+	sql := record.Statement
+	if sql == "" {
+		return
+	}
+	record.Statement = ""
+
+	if fingerprint, err := pg_query.Fingerprint(sql); err == nil {
+		record.Fingerprint = fingerprint
+	}
+
+	normalized, err := pg_query.Normalize(sql)
+	if err != nil {
+		// unparsable statements keep no text, they may carry anything
+		return
+	}
+	record.Normalized = normalized
+}
@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"testing"
+
+	"sql-sharding-v2/internal/repository"
+)
+
+func TestNormalize(t *testing.T) {
+
+	tests := []struct {
+		name           string
+		statement      string
+		wantNormalized string
+		wantPrint      bool
+	}{
+		{"query", "SELECT * FROM orders WHERE id = 42", "SELECT * FROM orders WHERE id = $1", true},
+		{"denied write", "UPDATE users SET email = 'a@b.c' WHERE id = 7", "UPDATE users SET email = $1 WHERE id = $2", true},
+		{"unparsable", "SELECT 'secret' FROM", "", false},
+		{"empty", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := repository.AuditRecord{EventType: EventAccessDenied, Statement: tt.statement}
+
+			normalize(&record)
+
+			if record.Statement != "" {
+				t.Errorf("Statement = %q, want it cleared", record.Statement)
+			}
+			if record.Normalized != tt.wantNormalized {
+				t.Errorf("Normalized = %q, want %q", record.Normalized, tt.wantNormalized)
+			}
+			if (record.Fingerprint != "") != tt.wantPrint {
+				t.Errorf("Fingerprint = %q, want set %t", record.Fingerprint, tt.wantPrint)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// key secrets look like shk_<32 url-safe characters>
+const (
+	keyPrefix    = "shk_"
+	keyBytes     = 24
+	displayChars = 12
+)
+
+var ErrInvalidKey = errors.New("invalid API key")
+
+// GenerateKey returns a new random key secret
+func GenerateKey() (string, error) {
+
+	buf := make([]byte, keyBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashKey returns the stored form of a key secret. Secrets are random and
+// long, so a plain SHA-256 is sufficient and keeps lookups indexable.
+func HashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// DisplayPrefix returns the non-secret leading part of a key used to identify it
+func DisplayPrefix(secret string) string {
+	if len(secret) < displayChars {
+		return secret
+	}
+	return secret[:displayChars]
+}
+
+// WellFormed reports whether a presented secret has the shape of a key
+func WellFormed(secret string) bool {
+	return strings.HasPrefix(secret, keyPrefix) &&
+		len(secret) == len(keyPrefix)+base64.RawURLEncoding.EncodedLen(keyBytes)
+}
@@ -0,0 +1,76 @@
+package auth
+
+import "fmt"
+
+type Role string
+
+const (
+	// RoleReadOnly may read project resources and run read-only statements
+	RoleReadOnly Role = "read_only"
+
+	// RoleReadWrite may additionally modify project resources and run writes
+	RoleReadWrite Role = "read_write"
+
+	// RoleAdmin is not bound to a project and may manage projects and keys
+	RoleAdmin Role = "admin"
+)
+
+func ParseRole(s string) (Role, error) {
+	switch Role(s) {
+	case RoleReadOnly, RoleReadWrite, RoleAdmin:
+		return Role(s), nil
+	default:
+		return "", fmt.Errorf("unknown role %q", s)
+	}
+}
+
+// Access is the level an operation requires
+type Access int
+
+const (
+	AccessRead Access = iota
+	AccessWrite
+	AccessAdmin
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	KeyID     string
+	ProjectID string
+	Role      Role
+
+	// statement restrictions enforced by the router
+	AllowedTables       []string
+	DenyBroadcastWrites bool
+
+	// tenant whose rows the caller may touch, from the key or the request
+	TenantID string
+}
+
+// Allows reports whether the principal may perform an operation of the given
+// access level on a project. An empty projectID means a global resource.
+func (p *Principal) Allows(projectID string, access Access) bool {
+
+	if p == nil {
+		return false
+	}
+
+	if p.Role == RoleAdmin {
+		return true
+	}
+
+	if access == AccessAdmin || projectID == "" || projectID != p.ProjectID {
+		return false
+	}
+
+	if access == AccessWrite {
+		return p.Role == RoleReadWrite
+	}
+
+	return true
+}
+
+// ReadOnly reports whether the principal is limited to read-only statements
+func (p *Principal) ReadOnly() bool {
+	return p != nil && p.Role == RoleReadOnly
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"database/sql"
+	"time"
 )
 
 // store the connection credentials of the app db
@@ -21,3 +22,79 @@ type AppicationDatabaseConn struct {
 }
 
 var ApplicationDatabaseConnection AppicationDatabaseConn
+
+// listen addresses of the query front ends and server paths
+type ApplicationServerConfig struct {
+	HTTP_ADDR       string
+	PGWIRE_ADDR     string
+	MIGRATIONS_PATH string
+
+	// require API keys on the HTTP API
+	API_AUTH bool
+}
+
+var ApplicationServerSettings ApplicationServerConfig
+
+// audit log batching and retention
+type AuditConfig struct {
+	// records queued for writing, records beyond this are dropped
+	BUFFER_SIZE int
+
+	// records written per insert
+	BATCH_SIZE int
+
+	// longest time a record waits in the queue
+	FLUSH_INTERVAL time.Duration
+
+	// age after which records are deleted, 0 keeps them forever
+	RETENTION time.Duration
+}
+
+var AuditSettings AuditConfig
+
+// trace export
+type TracingConfig struct {
+	// OTLP/HTTP collector URL, e.g. http://localhost:4318, empty disables export
+	OTLP_ENDPOINT string
+	SERVICE_NAME  string
+}
+
+var TracingSettings TracingConfig
+
+// per-fingerprint statistics and slow query log
+type QueryStatsConfig struct {
+	// statements at least this slow are logged, 0 disables the slow log
+	SLOW_THRESHOLD time.Duration
+
+	// file the slow log is appended to, empty writes to the application log
+	SLOW_LOG_PATH string
+
+	// fingerprints tracked per project
+	MAX_FINGERPRINTS int
+}
+
+var QueryStatsSettings QueryStatsConfig
+
+// shard skew and hot key detection
+type SkewConfig struct {
+	// how often shard sizes are read and traffic windows closed
+	INTERVAL time.Duration
+
+	// coefficient of variation across shards above which an alert is raised
+	THRESHOLD float64
+
+	// share of key-routed statements above which a key value is reported hot
+	HOT_KEY_SHARE float64
+}
+
+var SkewSettings SkewConfig
+
+type ReshardConfig struct {
+	// rows read per batch when copying between shards
+	BATCH_SIZE int
+
+	// longest wait at cutover for transactions opened before it
+	CUTOVER_TIMEOUT time.Duration
+}
+
+var ReshardSettings ReshardConfig
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+
+	"sql-sharding-v2/internal/metrics"
 )
 
 type ConnectionStore struct {
@@ -51,3 +53,35 @@ func (s *ConnectionStore) Get(projectID, shardID string) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// closes every shard connection pool, used on shutdown
+func (s *ConnectionStore) CloseAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for projectID, projectConns := range s.conns {
+		for _, db := range projectConns {
+			_ = db.Close()
+		}
+		delete(s.conns, projectID)
+	}
+}
+
+// reports the pool stats of every shard connection, used by metrics
+func (s *ConnectionStore) PoolStats() []metrics.PoolStat {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := make([]metrics.PoolStat, 0)
+	for projectID, projectConns := range s.conns {
+		for shardID, db := range projectConns {
+			stats = append(stats, metrics.PoolStat{
+				ProjectID: projectID,
+				ShardID:   shardID,
+				Stats:     db.Stats(),
+			})
+		}
+	}
+
+	return stats
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"os"
 	"path/filepath"
+	"sql-sharding-v2/internal/config"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -10,17 +11,11 @@ import (
 )
 
 func RunMigrations(dsn string) error {
-	exePath, err := os.Executable()
+	migrationsPath, err := resolveMigrationsPath()
 	if err != nil {
 		return err
 	}
 
-	exeDir := filepath.Dir(exePath)
-
-	projectRoot := filepath.Clean(filepath.Join(exeDir, "..", ".."))
-
-	migrationsPath := filepath.Join(projectRoot, "migrations")
-
 	sourceURL := "file://" + migrationsPath
 
 	m, err := migrate.New(
@@ -37,3 +32,21 @@ func RunMigrations(dsn string) error {
 
 	return nil
 }
+
+// MIGRATIONS_PATH overrides the default location next to the desktop build output
+func resolveMigrationsPath() (string, error) {
+	if config.ApplicationServerSettings.MIGRATIONS_PATH != "" {
+		return filepath.Abs(config.ApplicationServerSettings.MIGRATIONS_PATH)
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+
+	exeDir := filepath.Dir(exePath)
+
+	projectRoot := filepath.Clean(filepath.Join(exeDir, "..", ".."))
+
+	return filepath.Join(projectRoot, "migrations"), nil
+}
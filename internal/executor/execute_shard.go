@@ -3,68 +3,173 @@ package executor
 import (
 	"context"
 	"database/sql"
+	"sql-sharding-v2/internal/metrics"
+	"sql-sharding-v2/internal/router"
+	"sql-sharding-v2/internal/tracing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// Queryer is satisfied by *sql.DB, *sql.Conn and *sql.Tx
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// ExecuteOn runs a routed statement on an already acquired shard connection,
+// used by transactions that pin connections per shard.
+func ExecuteOn(
+	ctx context.Context,
+	conn Queryer,
+	shardID string,
+	sqlText string,
+	plan *router.RoutingPlan,
+) ExecutionResult {
+	return executeOnShard(ctx, conn, shardID, sqlText, plan)
+}
+
 func executeOnShard(
 	ctx context.Context,
-	db *sql.DB,
+	db Queryer,
 	shardID string,
 	sqlText string,
+	plan *router.RoutingPlan,
 ) ExecutionResult {
 
-	// Attempt SELECT first
-	rows, err := db.QueryContext(ctx, sqlText)
-	if err == nil {
-		defer rows.Close()
+	started := time.Now()
 
-		cols, err := rows.Columns()
-		if err != nil {
-			return ExecutionResult{
-				ShardID: shardID,
-				Err:     err,
-			}
+	ctx, span := tracing.Start(ctx, "executor.shard",
+		attribute.String("shard_id", shardID),
+		attribute.String("statement.kind", plan.Kind.String()),
+	)
+
+	result := runOnShard(ctx, db, shardID, sqlText, plan)
+
+	span.SetAttributes(attribute.Int64("db.rows_affected", result.RowsAffected))
+	tracing.End(span, result.Err)
+
+	metrics.ObserveShardQuery(shardID, plan.Kind.String(), time.Since(started), result.Err)
+
+	return result
+}
+
+func runOnShard(
+	ctx context.Context,
+	db Queryer,
+	shardID string,
+	sqlText string,
+	plan *router.RoutingPlan,
+) ExecutionResult {
+
+	// the router may have rewritten the statement, e.g. for tenant isolation
+	if plan.SQL != "" {
+		sqlText = plan.SQL
+	}
+
+	// reads and writes with RETURNING produce rows
+	if plan.Kind == router.StatementKindRead || plan.HasReturning {
+		return queryOnShard(ctx, db, shardID, sqlText, plan.Kind)
+	}
+
+	res, err := db.ExecContext(ctx, sqlText)
+	if err != nil {
+		return ExecutionResult{
+			ShardID: shardID,
+			Kind:    plan.Kind,
+			Err:     err,
 		}
+	}
 
-		data := make([][]any, 0)
+	affected, _ := res.RowsAffected()
 
-		for rows.Next() {
-			values := make([]any, len(cols))
-			ptrs := make([]any, len(cols))
+	return ExecutionResult{
+		ShardID:      shardID,
+		Kind:         plan.Kind,
+		RowsAffected: affected,
+	}
+}
 
-			for i := range values {
-				ptrs[i] = &values[i]
-			}
+// queryOnShard runs a row-returning statement and collects its result set
+func queryOnShard(
+	ctx context.Context,
+	db Queryer,
+	shardID string,
+	sqlText string,
+	kind router.StatementKind,
+) ExecutionResult {
 
-			if err := rows.Scan(ptrs...); err != nil {
-				return ExecutionResult{
-					ShardID: shardID,
-					Err:     err,
-				}
-			}
+	rows, err := db.QueryContext(ctx, sqlText)
+	if err != nil {
+		return ExecutionResult{
+			ShardID: shardID,
+			Kind:    kind,
+			Err:     err,
+		}
+	}
+	defer rows.Close()
 
-			data = append(data, values)
+	cols, err := rows.Columns()
+	if err != nil {
+		return ExecutionResult{
+			ShardID: shardID,
+			Kind:    kind,
+			Err:     err,
 		}
+	}
 
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
 		return ExecutionResult{
 			ShardID: shardID,
-			Columns: cols,
-			Rows:    data,
+			Kind:    kind,
+			Err:     err,
 		}
 	}
 
-	// Fallback to DML / TX / others
-	res, execErr := db.ExecContext(ctx, sqlText)
-	if execErr != nil {
+	typeNames := columnTypeNames(colTypes)
+
+	data := make([][]any, 0)
+
+	for rows.Next() {
+		values := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+
+		if err := rows.Scan(ptrs...); err != nil {
+			return ExecutionResult{
+				ShardID: shardID,
+				Kind:    kind,
+				Err:     err,
+			}
+		}
+
+		data = append(data, convertRow(values, typeNames))
+	}
+
+	if err := rows.Err(); err != nil {
 		return ExecutionResult{
 			ShardID: shardID,
-			Err:     execErr,
+			Kind:    kind,
+			Err:     err,
 		}
 	}
 
-	affected, _ := res.RowsAffected()
+	result := ExecutionResult{
+		ShardID:     shardID,
+		Kind:        kind,
+		Columns:     cols,
+		ColumnTypes: typeNames,
+		Rows:        data,
+	}
 
-	return ExecutionResult{
-		ShardID:      shardID,
-		RowsAffected: affected,
+	// for writes with RETURNING every returned row is an affected row
+	if kind == router.StatementKindWrite {
+		result.RowsAffected = int64(len(data))
 	}
+
+	return result
 }
@@ -2,8 +2,10 @@ package executor
 
 import (
 	"context"
+	"database/sql"
 	"sql-sharding-v2/internal/connections"
 	"sql-sharding-v2/internal/router"
+	"sql-sharding-v2/pkg/logger"
 )
 
 // Executor is responsible for executing routed SQL on shards.
@@ -31,6 +33,16 @@ func (e *Executor) Execute(
 		return nil, plan.RejectError
 	}
 
+	// shadows run first so a write that cannot reach the shard its keys are
+	// copied to fails before touching the shard owning them. Rows a shadow
+	// wrote for a statement that then fails are undone by the job's next sync,
+	// which makes targets match their sources.
+	for _, shadow := range plan.Shadows {
+		if err := e.executeShadow(ctx, projectID, string(shadow.ShardID), sqlText, plan); err != nil {
+			return nil, err
+		}
+	}
+
 	results := make([]ExecutionResult, 0, len(plan.Targets))
 
 	for _, target := range plan.Targets {
@@ -39,14 +51,100 @@ func (e *Executor) Execute(
 		if err != nil {
 			results = append(results, ExecutionResult{
 				ShardID: string(target.ShardID),
+				Kind:    plan.Kind,
 				Err:     err,
 			})
 			continue
 		}
 
-		result := executeOnShard(ctx, db, string(target.ShardID), sqlText)
+		result := executeOnShard(ctx, db, string(target.ShardID), sqlText, plan)
 		results = append(results, result)
 	}
 
 	return results, nil
 }
+
+// executeShadow repeats a write on a shard its keys are being copied to.
+// A failure rejects the statement as retryable, like a write at cutover.
+func (e *Executor) executeShadow(
+	ctx context.Context,
+	projectID string,
+	shardID string,
+	sqlText string,
+	plan *router.RoutingPlan,
+) error {
+
+	db, err := e.connStore.Get(projectID, shardID)
+	if err == nil {
+		err = executeOnShard(ctx, db, shardID, sqlText, plan).Err
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	logger.Logger.Warn("shadow write failed", "project_id", projectID, "shard_id", shardID, "error", err)
+
+	return &router.RoutingError{
+		Code:    router.ErrShardMoving,
+		Message: "shard keys of this statement are moving between shards and could not be copied, retry shortly",
+	}
+}
+
+// Describe resolves the result columns of a prepared read on a single shard
+// without returning rows. The plan comes from the router, so the statement is
+// the one that would run after policy checks and tenant rewriting. Statements
+// other than reads report no columns. Parameters are bound as NULL and the
+// statement runs in a read-only transaction that is rolled back.
+func (e *Executor) Describe(
+	ctx context.Context,
+	projectID string,
+	shardID string,
+	plan *router.RoutingPlan,
+	paramCount int,
+) ([]string, []string, error) {
+
+	if plan.Mode == router.RoutingModeRejected {
+		return nil, nil, plan.RejectError
+	}
+
+	if plan.Kind != router.StatementKindRead {
+		return nil, nil, nil
+	}
+
+	db, err := e.connStore.Get(projectID, shardID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	// the router deparses the statement, a single SELECT without a trailing semicolon
+	stmt, err := tx.PrepareContext(ctx, "SELECT * FROM ("+plan.SQL+") AS describe_target LIMIT 0")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, make([]any, paramCount)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cols, columnTypeNames(colTypes), nil
+}
@@ -1,8 +1,12 @@
 package executor
 
+import "sql-sharding-v2/internal/router"
+
 type ExecutionResult struct {
 	ShardID      string
+	Kind         router.StatementKind
 	Columns      []string
+	ColumnTypes  []string
 	Rows         [][]any
 	RowsAffected int64
 	Err          error
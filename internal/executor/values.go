@@ -0,0 +1,105 @@
+package executor
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// columnTypeNames returns the lowercased PostgreSQL type name of every column
+func columnTypeNames(types []*sql.ColumnType) []string {
+
+	names := make([]string, 0, len(types))
+
+	for _, t := range types {
+		names = append(names, strings.ToLower(t.DatabaseTypeName()))
+	}
+
+	return names
+}
+
+// convertRow converts raw driver values into JSON-safe values
+func convertRow(values []any, types []string) []any {
+
+	for i, v := range values {
+		typeName := ""
+		if i < len(types) {
+			typeName = types[i]
+		}
+		values[i] = convertValue(typeName, v)
+	}
+
+	return values
+}
+
+// convertValue maps a raw lib/pq value to a well-defined JSON representation
+func convertValue(typeName string, v any) any {
+
+	switch val := v.(type) {
+
+	case nil:
+		return nil
+
+	case bool, int64, float64, string:
+		return val
+
+	case time.Time:
+		return formatTime(typeName, val)
+
+	case []byte:
+		return convertBytes(typeName, val)
+
+	default:
+		return val
+	}
+}
+
+// convertBytes decodes the text representation lib/pq hands back as []byte
+func convertBytes(typeName string, b []byte) any {
+
+	switch typeName {
+
+	case "numeric", "decimal", "money":
+		s := string(b)
+		if _, err := strconv.ParseFloat(s, 64); err != nil || !json.Valid(b) {
+			// NaN, Infinity and currency strings stay textual
+			return s
+		}
+		return json.Number(s)
+
+	case "json", "jsonb":
+		if json.Valid(b) {
+			return json.RawMessage(append([]byte(nil), b...))
+		}
+		return string(b)
+
+	case "bytea":
+		// encoded as base64 by encoding/json
+		return append([]byte(nil), b...)
+
+	default:
+		// uuid, text-like, arrays, inet, intervals and others keep their text form
+		return string(b)
+	}
+}
+
+// formatTime renders temporal values per column type
+func formatTime(typeName string, t time.Time) string {
+
+	switch typeName {
+
+	case "date":
+		return t.Format("2006-01-02")
+
+	case "time":
+		return t.Format("15:04:05.999999")
+
+	case "timetz":
+		return t.Format("15:04:05.999999Z07:00")
+
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+}
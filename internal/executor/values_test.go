@@ -0,0 +1,56 @@
+package executor
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConvertValue(t *testing.T) {
+
+	at := time.Date(2024, 3, 9, 14, 5, 7, 120000000, time.FixedZone("", 2*60*60))
+
+	tests := []struct {
+		name     string
+		typeName string
+		value    any
+		want     any
+	}{
+		{name: "null", typeName: "text", value: nil, want: nil},
+		{name: "integer", typeName: "int8", value: int64(42), want: int64(42)},
+		{name: "boolean", typeName: "bool", value: true, want: true},
+		{name: "numeric keeps its digits", typeName: "numeric", value: []byte("12345678901234567890.10"), want: json.Number("12345678901234567890.10")},
+		{name: "numeric NaN stays text", typeName: "numeric", value: []byte("NaN"), want: "NaN"},
+		{name: "money stays text", typeName: "money", value: []byte("$1,000.00"), want: "$1,000.00"},
+		{name: "jsonb is embedded", typeName: "jsonb", value: []byte(`{"a":1}`), want: json.RawMessage(`{"a":1}`)},
+		{name: "invalid json stays text", typeName: "json", value: []byte(`{`), want: "{"},
+		{name: "bytea stays binary", typeName: "bytea", value: []byte{0, 1}, want: []byte{0, 1}},
+		{name: "uuid as text", typeName: "uuid", value: []byte("6f1c0e9a-1b2c-4d5e-8f90-a1b2c3d4e5f6"), want: "6f1c0e9a-1b2c-4d5e-8f90-a1b2c3d4e5f6"},
+		{name: "date", typeName: "date", value: at, want: "2024-03-09"},
+		{name: "time", typeName: "time", value: at, want: "14:05:07.12"},
+		{name: "timetz", typeName: "timetz", value: at, want: "14:05:07.12+02:00"},
+		{name: "timestamptz", typeName: "timestamptz", value: at, want: "2024-03-09T14:05:07.12+02:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := convertValue(tt.typeName, tt.value)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertBytesCopies(t *testing.T) {
+
+	raw := []byte(`[1]`)
+
+	got := convertValue("json", raw).(json.RawMessage)
+	raw[1] = '2'
+
+	if string(got) != "[1]" {
+		t.Fatalf("converted value shares the driver buffer: %s", got)
+	}
+}
@@ -0,0 +1,203 @@
+// Package metrics exposes routing, execution, pool and shard health numbers
+// in the Prometheus format.
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "sqlshard"
+
+// Registry holds every collector served by Handler
+var Registry = prometheus.NewRegistry()
+
+var (
+	routedQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "router",
+		Name:      "queries_total",
+		Help:      "Statements routed, by routing mode and statement kind.",
+	}, []string{"mode", "kind"})
+
+	rejectedQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "router",
+		Name:      "rejections_total",
+		Help:      "Statements the router rejected, by rejection code.",
+	}, []string{"code"})
+
+	routeDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "router",
+		Name:      "route_duration_seconds",
+		Help:      "Time to parse, check and plan a statement.",
+		Buckets:   []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1},
+	})
+
+	fanOut = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "router",
+		Name:      "fanout_shards",
+		Help:      "Shards targeted per routed statement.",
+		Buckets:   []float64{1, 2, 4, 8, 16, 32, 64},
+	})
+
+	shardQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "shard",
+		Name:      "queries_total",
+		Help:      "Statements executed on a shard, by outcome (ok or error).",
+	}, []string{"shard_id", "outcome"})
+
+	shardQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "shard",
+		Name:      "query_duration_seconds",
+		Help:      "Statement latency per shard.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"shard_id", "kind"})
+
+	shardHealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "shard",
+		Name:      "healthy",
+		Help:      "1 when the last health check of a shard succeeded, 0 otherwise.",
+	}, []string{"project_id", "shard_id"})
+
+	shardHealthTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "shard",
+		Name:      "health_transitions_total",
+		Help:      "Changes of shard health seen by the shard monitor, by new state.",
+	}, []string{"project_id", "shard_id", "state"})
+
+	skewCoefficient = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "skew",
+		Name:      "coefficient",
+		Help:      "Coefficient of variation across shards of the last skew check, by dimension (rows, size or statements).",
+	}, []string{"project_id", "dimension"})
+
+	shardRows = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "shard",
+		Name:      "rows",
+		Help:      "Estimated rows on a shard at the last skew check.",
+	}, []string{"project_id", "shard_id"})
+
+	shardBytes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "shard",
+		Name:      "bytes",
+		Help:      "Total table size on a shard at the last skew check.",
+	}, []string{"project_id", "shard_id"})
+
+	reshardRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "reshard",
+		Name:      "rows_total",
+		Help:      "Rows written or deleted by resharding jobs, by job kind and phase.",
+	}, []string{"project_id", "kind", "phase"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		routedQueries,
+		rejectedQueries,
+		routeDuration,
+		fanOut,
+		shardQueries,
+		shardQueryDuration,
+		shardHealthy,
+		shardHealthTransitions,
+		skewCoefficient,
+		shardRows,
+		shardBytes,
+		reshardRows,
+	)
+}
+
+// Handler serves the registry for scraping
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveRoute records the outcome of routing one statement.
+// code is empty unless the statement was rejected.
+func ObserveRoute(mode string, kind string, code string, shards int, duration time.Duration) {
+
+	routedQueries.WithLabelValues(mode, kind).Inc()
+	routeDuration.Observe(duration.Seconds())
+
+	if code != "" {
+		rejectedQueries.WithLabelValues(code).Inc()
+		return
+	}
+
+	fanOut.Observe(float64(shards))
+}
+
+// ObserveShardQuery records one statement executed on a shard
+func ObserveShardQuery(shardID string, kind string, duration time.Duration, err error) {
+
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+
+	shardQueries.WithLabelValues(shardID, outcome).Inc()
+	shardQueryDuration.WithLabelValues(shardID, kind).Observe(duration.Seconds())
+}
+
+var (
+	healthMu   sync.Mutex
+	lastHealth = make(map[string]bool)
+)
+
+// ObserveShardHealth records a health check and counts changes of state.
+// The first check of a shard sets its state without counting a transition.
+func ObserveShardHealth(projectID string, shardID string, healthy bool) {
+
+	value := 0.0
+	state := "unhealthy"
+	if healthy {
+		value = 1
+		state = "healthy"
+	}
+	shardHealthy.WithLabelValues(projectID, shardID).Set(value)
+
+	healthMu.Lock()
+	previous, seen := lastHealth[shardID]
+	lastHealth[shardID] = healthy
+	healthMu.Unlock()
+
+	if seen && previous != healthy {
+		shardHealthTransitions.WithLabelValues(projectID, shardID, state).Inc()
+	}
+}
+
+// ObserveSkew records the coefficients of a skew check
+func ObserveSkew(projectID string, rows float64, size float64, statements float64) {
+	skewCoefficient.WithLabelValues(projectID, "rows").Set(rows)
+	skewCoefficient.WithLabelValues(projectID, "size").Set(size)
+	skewCoefficient.WithLabelValues(projectID, "statements").Set(statements)
+}
+
+// ObserveShardSize records the rows and bytes read from a shard
+func ObserveShardSize(projectID string, shardID string, rows int64, bytes int64) {
+	shardRows.WithLabelValues(projectID, shardID).Set(float64(rows))
+	shardBytes.WithLabelValues(projectID, shardID).Set(float64(bytes))
+}
+
+// ObserveReshardRows records rows a resharding job wrote or deleted
+func ObserveReshardRows(projectID string, kind string, phase string, rows int64) {
+	reshardRows.WithLabelValues(projectID, kind, phase).Add(float64(rows))
+}
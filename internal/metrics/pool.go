@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolStat is the connection pool state of one shard
+type PoolStat struct {
+	ProjectID string
+	ShardID   string
+	Stats     sql.DBStats
+}
+
+// PoolSource lists the pools to report, implemented by the connection store
+type PoolSource interface {
+	PoolStats() []PoolStat
+}
+
+var poolLabels = []string{"project_id", "shard_id"}
+
+var (
+	poolMaxOpen      = poolDesc("max_open_connections", "Maximum open connections of the pool.")
+	poolOpen         = poolDesc("open_connections", "Open connections, in use and idle.")
+	poolInUse        = poolDesc("in_use_connections", "Connections currently in use.")
+	poolIdle         = poolDesc("idle_connections", "Idle connections.")
+	poolWaitCount    = poolDesc("wait_count_total", "Connections waited for.")
+	poolWaitDuration = poolDesc("wait_duration_seconds_total", "Time spent waiting for connections.")
+	poolMaxIdleClose = poolDesc("max_idle_closed_total", "Connections closed because of the idle limit.")
+	poolLifeClose    = poolDesc("max_lifetime_closed_total", "Connections closed because of their lifetime.")
+)
+
+func poolDesc(name string, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pool", name), help, poolLabels, nil)
+}
+
+// poolCollector reads pool stats at scrape time so pools added or closed
+// later are reported without registration
+type poolCollector struct {
+	source PoolSource
+}
+
+// RegisterPools reports the pools of source on every scrape
+func RegisterPools(source PoolSource) {
+	Registry.MustRegister(&poolCollector{source: source})
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		poolMaxOpen, poolOpen, poolInUse, poolIdle,
+		poolWaitCount, poolWaitDuration, poolMaxIdleClose, poolLifeClose,
+	} {
+		ch <- desc
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+
+	for _, pool := range c.source.PoolStats() {
+		s := pool.Stats
+		labels := []string{pool.ProjectID, pool.ShardID}
+
+		ch <- prometheus.MustNewConstMetric(poolMaxOpen, prometheus.GaugeValue, float64(s.MaxOpenConnections), labels...)
+		ch <- prometheus.MustNewConstMetric(poolOpen, prometheus.GaugeValue, float64(s.OpenConnections), labels...)
+		ch <- prometheus.MustNewConstMetric(poolInUse, prometheus.GaugeValue, float64(s.InUse), labels...)
+		ch <- prometheus.MustNewConstMetric(poolIdle, prometheus.GaugeValue, float64(s.Idle), labels...)
+		ch <- prometheus.MustNewConstMetric(poolWaitCount, prometheus.CounterValue, float64(s.WaitCount), labels...)
+		ch <- prometheus.MustNewConstMetric(poolWaitDuration, prometheus.CounterValue, s.WaitDuration.Seconds(), labels...)
+		ch <- prometheus.MustNewConstMetric(poolMaxIdleClose, prometheus.CounterValue, float64(s.MaxIdleClosed), labels...)
+		ch <- prometheus.MustNewConstMetric(poolLifeClose, prometheus.CounterValue, float64(s.MaxLifetimeClosed), labels...)
+	}
+}
@@ -0,0 +1,223 @@
+package pgwire
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// type OIDs reported in RowDescription, keyed by lowercased type name
+var typeOIDs = map[string]int32{
+	"bool":        16,
+	"bytea":       17,
+	"char":        18,
+	"name":        19,
+	"int8":        20,
+	"int2":        21,
+	"int4":        23,
+	"text":        25,
+	"oid":         26,
+	"json":        114,
+	"xml":         142,
+	"cidr":        650,
+	"float4":      700,
+	"float8":      701,
+	"money":       790,
+	"inet":        869,
+	"bpchar":      1042,
+	"varchar":     1043,
+	"date":        1082,
+	"time":        1083,
+	"timestamp":   1114,
+	"timestamptz": 1184,
+	"interval":    1186,
+	"timetz":      1266,
+	"numeric":     1700,
+	"uuid":        2950,
+	"jsonb":       3802,
+	"_bool":       1000,
+	"_int2":       1005,
+	"_int4":       1007,
+	"_text":       1009,
+	"_varchar":    1015,
+	"_int8":       1016,
+	"_float8":     1022,
+	"_uuid":       2951,
+}
+
+const oidText = 25
+
+// numeric parameter OIDs that bind as unquoted literals
+var numericOIDs = map[int32]struct{}{
+	20: {}, 21: {}, 23: {}, 26: {}, 700: {}, 701: {}, 1700: {},
+}
+
+func typeOID(typeName string) int32 {
+	if oid, ok := typeOIDs[typeName]; ok {
+		return oid
+	}
+	return oidText
+}
+
+// typeSize returns the fixed width of a type or -1 for variable width
+func typeSize(oid int32) int16 {
+	switch oid {
+	case 16, 18:
+		return 1
+	case 21:
+		return 2
+	case 23, 26, 700, 1082:
+		return 4
+	case 20, 701, 1083, 1114, 1184:
+		return 8
+	case 2950:
+		return 16
+	default:
+		return -1
+	}
+}
+
+// encodeText renders a non-NULL executor value in PostgreSQL text format
+func encodeText(typeName string, v any) []byte {
+
+	switch val := v.(type) {
+
+	case bool:
+		if val {
+			return []byte("t")
+		}
+		return []byte("f")
+
+	case int64:
+		return strconv.AppendInt(nil, val, 10)
+
+	case float64:
+		return strconv.AppendFloat(nil, val, 'g', -1, 64)
+
+	case json.Number:
+		return []byte(val)
+
+	case json.RawMessage:
+		return []byte(val)
+
+	case []byte:
+		// bytea hex output
+		out := make([]byte, 2+hex.EncodedLen(len(val)))
+		copy(out, `\x`)
+		hex.Encode(out[2:], val)
+		return out
+
+	case string:
+		return []byte(formatTemporal(typeName, val))
+
+	default:
+		return []byte(fmt.Sprintf("%v", val))
+	}
+}
+
+// formatTemporal converts RFC3339 timestamps back to PostgreSQL output style
+func formatTemporal(typeName string, s string) string {
+
+	switch typeName {
+
+	case "timestamptz":
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return s
+		}
+		return t.Format("2006-01-02 15:04:05.999999-07:00")
+
+	case "timestamp":
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return s
+		}
+		return t.Format("2006-01-02 15:04:05.999999")
+
+	default:
+		return s
+	}
+}
+
+// encodeBinary renders a non-NULL executor value in PostgreSQL binary format
+func encodeBinary(typeName string, v any) ([]byte, error) {
+
+	switch typeName {
+
+	case "int2", "int4", "int8":
+		n, err := toInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		switch typeName {
+		case "int2":
+			return binary.BigEndian.AppendUint16(nil, uint16(n)), nil
+		case "int4":
+			return binary.BigEndian.AppendUint32(nil, uint32(n)), nil
+		default:
+			return binary.BigEndian.AppendUint64(nil, uint64(n)), nil
+		}
+
+	case "float4", "float8":
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("unexpected %T for %s", v, typeName)
+		}
+		if typeName == "float4" {
+			return binary.BigEndian.AppendUint32(nil, math.Float32bits(float32(f))), nil
+		}
+		return binary.BigEndian.AppendUint64(nil, math.Float64bits(f)), nil
+
+	case "bool":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("unexpected %T for bool", v)
+		}
+		if b {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+
+	case "bytea":
+		if b, ok := v.([]byte); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("unexpected %T for bytea", v)
+
+	case "uuid":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected %T for uuid", v)
+		}
+		b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+		if err != nil || len(b) != 16 {
+			return nil, fmt.Errorf("invalid uuid %q", s)
+		}
+		return b, nil
+
+	case "text", "varchar", "bpchar", "name", "json", "xml":
+		return encodeText(typeName, v), nil
+
+	case "jsonb":
+		return append([]byte{1}, encodeText(typeName, v)...), nil
+
+	default:
+		return nil, fmt.Errorf("binary format is not supported for type %s", typeName)
+	}
+}
+
+func toInt64(v any) (int64, error) {
+	switch n := v.(type) {
+	case int64:
+		return n, nil
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	default:
+		return 0, fmt.Errorf("unexpected %T for integer", v)
+	}
+}
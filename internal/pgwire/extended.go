@@ -0,0 +1,266 @@
+package pgwire
+
+import (
+	"context"
+	"fmt"
+)
+
+// preparedStatement is created by a Parse message
+type preparedStatement struct {
+	query string
+	oids  []int32
+}
+
+// portal is a bound statement ready for execution. Results are materialized
+// on first Describe or Execute and streamed out across Execute calls.
+type portal struct {
+	query   string
+	formats []int16
+	result  *queryResult
+	sent    int
+}
+
+func (s *session) handleParse(body []byte) error {
+
+	buf := &buffer{data: body}
+	name := buf.string()
+	query := buf.string()
+	n := int(buf.int16())
+
+	oids := make([]int32, 0, n)
+	for i := 0; i < n; i++ {
+		oids = append(oids, buf.int32())
+	}
+
+	if buf.err != nil {
+		return buf.err
+	}
+
+	s.statements[name] = &preparedStatement{
+		query: query,
+		oids:  oids,
+	}
+
+	s.send(newMessage('1'))
+	return nil
+}
+
+func (s *session) handleBind(body []byte) error {
+
+	buf := &buffer{data: body}
+	portalName := buf.string()
+	stmtName := buf.string()
+
+	formats := make([]int16, int(buf.int16()))
+	for i := range formats {
+		formats[i] = buf.int16()
+	}
+
+	params := make([][]byte, int(buf.int16()))
+	for i := range params {
+		length := buf.int32()
+		if length >= 0 {
+			params[i] = buf.bytes(int(length))
+		}
+	}
+
+	resultFormats := make([]int16, int(buf.int16()))
+	for i := range resultFormats {
+		resultFormats[i] = buf.int16()
+	}
+
+	if buf.err != nil {
+		return buf.err
+	}
+
+	stmt, ok := s.statements[stmtName]
+	if !ok {
+		s.extendedError(newPGError("26000", fmt.Sprintf("prepared statement %q does not exist", stmtName)))
+		return nil
+	}
+
+	for _, f := range formats {
+		if f != 0 {
+			s.extendedError(newPGError("0A000", "binary parameter format is not supported"))
+			return nil
+		}
+	}
+
+	query, err := bindParams(stmt.query, params, stmt.oids)
+	if err != nil {
+		s.extendedError(newPGError("08P01", err.Error()))
+		return nil
+	}
+
+	s.portals[portalName] = &portal{
+		query:   query,
+		formats: resultFormats,
+	}
+
+	s.send(newMessage('2'))
+	return nil
+}
+
+func (s *session) handleDescribe(body []byte) error {
+
+	buf := &buffer{data: body}
+	kind := buf.byte()
+	name := buf.string()
+	if buf.err != nil {
+		return buf.err
+	}
+
+	switch kind {
+
+	case 'S':
+		stmt, ok := s.statements[name]
+		if !ok {
+			s.extendedError(newPGError("26000", fmt.Sprintf("prepared statement %q does not exist", name)))
+			return nil
+		}
+
+		n := max(len(stmt.oids), countParams(stmt.query))
+		m := newMessage('t').int16(int16(n))
+		for i := 0; i < n; i++ {
+			oid := int32(oidText)
+			if i < len(stmt.oids) && stmt.oids[i] != 0 {
+				oid = stmt.oids[i]
+			}
+			m.int32(oid)
+		}
+		s.send(m)
+
+		s.describeStatement(stmt, n)
+
+	case 'P':
+		p, ok := s.portals[name]
+		if !ok {
+			s.extendedError(newPGError("34000", fmt.Sprintf("portal %q does not exist", name)))
+			return nil
+		}
+
+		if err := s.materialize(p); err != nil {
+			s.extendedError(toPGError(err))
+			return nil
+		}
+
+		if p.result.hasRows {
+			s.sendRowDescription(p.result, p.formats)
+		} else {
+			s.send(newMessage('n'))
+		}
+
+	default:
+		return fmt.Errorf("invalid describe target %q", kind)
+	}
+
+	return nil
+}
+
+func (s *session) handleExecute(body []byte) error {
+
+	buf := &buffer{data: body}
+	name := buf.string()
+	maxRows := int(buf.int32())
+	if buf.err != nil {
+		return buf.err
+	}
+
+	p, ok := s.portals[name]
+	if !ok {
+		s.extendedError(newPGError("34000", fmt.Sprintf("portal %q does not exist", name)))
+		return nil
+	}
+
+	if err := s.materialize(p); err != nil {
+		s.extendedError(toPGError(err))
+		return nil
+	}
+
+	end := len(p.result.rows)
+	if maxRows > 0 && end-p.sent > maxRows {
+		end = p.sent + maxRows
+	}
+
+	if err := s.sendRows(p.result, p.sent, end, p.formats); err != nil {
+		s.extendedError(toPGError(err))
+		return nil
+	}
+
+	p.sent = end
+	if p.sent < len(p.result.rows) {
+		s.send(newMessage('s'))
+		return nil
+	}
+
+	s.sendComplete(p.result)
+
+	return nil
+}
+
+func (s *session) handleClose(body []byte) error {
+
+	buf := &buffer{data: body}
+	kind := buf.byte()
+	name := buf.string()
+	if buf.err != nil {
+		return buf.err
+	}
+
+	switch kind {
+	case 'S':
+		delete(s.statements, name)
+	case 'P':
+		delete(s.portals, name)
+	}
+
+	s.send(newMessage('3'))
+	return nil
+}
+
+// describeStatement reports result columns of a prepared SELECT. Other
+// statements report NoData since their output is only known after execution.
+func (s *session) describeStatement(stmt *preparedStatement, paramCount int) {
+
+	info, err := inspectStatement(stmt.query)
+	if err != nil || info.action != actionRoute || info.command != "SELECT" {
+		s.send(newMessage('n'))
+		return
+	}
+
+	cols, types, err := s.backend.DescribeSQLAs(context.Background(), s.principal, s.projectID, stmt.query, paramCount)
+	if err != nil {
+		s.extendedError(toPGError(err))
+		return
+	}
+
+	// SELECTs that modify data or lock rows are only known after execution
+	if cols == nil {
+		s.send(newMessage('n'))
+		return
+	}
+
+	s.sendRowDescription(&queryResult{columns: cols, types: types}, nil)
+}
+
+// materialize runs the portal statement once
+func (s *session) materialize(p *portal) error {
+
+	if p.result != nil {
+		return nil
+	}
+
+	res, err := s.runStatement(p.query)
+	if err != nil {
+		return err
+	}
+
+	p.result = res
+	return nil
+}
+
+// extendedError reports an error and discards messages until the next Sync
+func (s *session) extendedError(err *pgError) {
+	s.sendError(err)
+	s.ignoreTillSync = true
+}
@@ -0,0 +1,213 @@
+package pgwire
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// protocol codes sent in place of a protocol version during startup
+const (
+	protocolVersion3 = 196608
+	sslRequestCode   = 80877103
+	gssEncRequest    = 80877104
+	cancelRequest    = 80877102
+
+	maxMessageSize = 64 << 20
+)
+
+// frontend message types
+const (
+	msgQuery     = 'Q'
+	msgParse     = 'P'
+	msgBind      = 'B'
+	msgDescribe  = 'D'
+	msgExecute   = 'E'
+	msgSync      = 'S'
+	msgClose     = 'C'
+	msgFlush     = 'H'
+	msgTerminate = 'X'
+	msgPassword  = 'p'
+)
+
+// authentication request codes
+const (
+	authOK                = 0
+	authCleartextPassword = 3
+)
+
+var errMessageTooLarge = errors.New("message exceeds maximum size")
+
+// readStartup reads an untyped startup-phase message
+func readStartup(r *bufio.Reader) (uint32, []byte, error) {
+
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[:])
+	if length < 8 || length > maxMessageSize {
+		return 0, nil, errMessageTooLarge
+	}
+
+	body := make([]byte, length-4)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+
+	return binary.BigEndian.Uint32(body[:4]), body[4:], nil
+}
+
+// readMessage reads a typed message once the session is established
+func readMessage(r *bufio.Reader) (byte, []byte, error) {
+
+	typ, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[:])
+	if length < 4 || length > maxMessageSize {
+		return 0, nil, errMessageTooLarge
+	}
+
+	body := make([]byte, length-4)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+
+	return typ, body, nil
+}
+
+// buffer decodes message bodies
+type buffer struct {
+	data []byte
+	err  error
+}
+
+func (b *buffer) string() string {
+	if b.err != nil {
+		return ""
+	}
+	for i, c := range b.data {
+		if c == 0 {
+			s := string(b.data[:i])
+			b.data = b.data[i+1:]
+			return s
+		}
+	}
+	b.err = fmt.Errorf("malformed message: unterminated string")
+	return ""
+}
+
+func (b *buffer) int16() int16 {
+	if b.err != nil {
+		return 0
+	}
+	if len(b.data) < 2 {
+		b.err = fmt.Errorf("malformed message: short int16")
+		return 0
+	}
+	v := int16(binary.BigEndian.Uint16(b.data))
+	b.data = b.data[2:]
+	return v
+}
+
+func (b *buffer) int32() int32 {
+	if b.err != nil {
+		return 0
+	}
+	if len(b.data) < 4 {
+		b.err = fmt.Errorf("malformed message: short int32")
+		return 0
+	}
+	v := int32(binary.BigEndian.Uint32(b.data))
+	b.data = b.data[4:]
+	return v
+}
+
+func (b *buffer) byte() byte {
+	if b.err != nil {
+		return 0
+	}
+	if len(b.data) < 1 {
+		b.err = fmt.Errorf("malformed message: short byte")
+		return 0
+	}
+	v := b.data[0]
+	b.data = b.data[1:]
+	return v
+}
+
+func (b *buffer) bytes(n int) []byte {
+	if b.err != nil {
+		return nil
+	}
+	if n < 0 || len(b.data) < n {
+		b.err = fmt.Errorf("malformed message: short bytes")
+		return nil
+	}
+	v := b.data[:n]
+	b.data = b.data[n:]
+	return v
+}
+
+// message builds a backend message
+type message struct {
+	typ  byte
+	data []byte
+}
+
+func newMessage(typ byte) *message {
+	return &message{typ: typ}
+}
+
+func (m *message) string(s string) *message {
+	m.data = append(m.data, s...)
+	m.data = append(m.data, 0)
+	return m
+}
+
+func (m *message) byte(c byte) *message {
+	m.data = append(m.data, c)
+	return m
+}
+
+func (m *message) int16(v int16) *message {
+	m.data = binary.BigEndian.AppendUint16(m.data, uint16(v))
+	return m
+}
+
+func (m *message) int32(v int32) *message {
+	m.data = binary.BigEndian.AppendUint32(m.data, uint32(v))
+	return m
+}
+
+func (m *message) bytes(b []byte) *message {
+	m.data = append(m.data, b...)
+	return m
+}
+
+// writeTo frames the message with its type and length
+func (m *message) writeTo(w *bufio.Writer) error {
+	if err := w.WriteByte(m.typ); err != nil {
+		return err
+	}
+
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(m.data)+4))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+
+	_, err := w.Write(m.data)
+	return err
+}
@@ -0,0 +1,124 @@
+package pgwire
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+)
+
+var numericLiteral = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// bindParams substitutes $n placeholders with literals so the router can
+// extract shard-key constants. Placeholders are found with the parser's
+// scanner, so strings, escape strings, dollar quotes and comments are left
+// untouched.
+func bindParams(query string, params [][]byte, oids []int32) (string, error) {
+
+	placeholders, err := scanParams(query)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	last := 0
+
+	for _, p := range placeholders {
+
+		if p.n < 1 || p.n > len(params) {
+			return "", fmt.Errorf("bind message supplies %d parameters, query references $%d", len(params), p.n)
+		}
+
+		var oid int32
+		if p.n-1 < len(oids) {
+			oid = oids[p.n-1]
+		}
+
+		out.WriteString(query[last:p.start])
+		out.WriteString(paramLiteral(params[p.n-1], oid))
+		last = p.end
+	}
+
+	out.WriteString(query[last:])
+
+	return out.String(), nil
+}
+
+// paramLiteral renders a text-format parameter as a SQL literal. Values of
+// numeric types stay unquoted so shard keys hash the same way as inline
+// constants, negative ones in parentheses so a preceding minus does not
+// start a comment. Values of unknown type are quoted, PostgreSQL infers
+// their type from the context like it does for string literals.
+func paramLiteral(value []byte, oid int32) string {
+
+	if value == nil {
+		return "NULL"
+	}
+
+	s := string(value)
+
+	if _, numericType := numericOIDs[oid]; numericType && numericLiteral.MatchString(s) {
+		if strings.HasPrefix(s, "-") {
+			return "(" + s + ")"
+		}
+		return s
+	}
+
+	return pq.QuoteLiteral(s)
+}
+
+// countParams returns the highest $n placeholder referenced by a query
+func countParams(query string) int {
+
+	placeholders, err := scanParams(query)
+	if err != nil {
+		// the statement fails to parse when it is bound
+		return 0
+	}
+
+	max := 0
+	for _, p := range placeholders {
+		if p.n > max {
+			max = p.n
+		}
+	}
+
+	return max
+}
+
+// placeholder is a $n parameter reference at query[start:end]
+type placeholder struct {
+	n          int
+	start, end int
+}
+
+// scanParams returns the parameter references of a query in order
+func scanParams(query string) ([]placeholder, error) {
+
+	result, err := pg_query.Scan(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var placeholders []placeholder
+
+	for _, token := range result.Tokens {
+		if token.Token != pg_query.Token_PARAM {
+			continue
+		}
+
+		start, end := int(token.Start), int(token.End)
+
+		n, err := strconv.Atoi(query[start+1 : end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter %s", query[start:end])
+		}
+
+		placeholders = append(placeholders, placeholder{n: n, start: start, end: end})
+	}
+
+	return placeholders, nil
+}
@@ -0,0 +1,129 @@
+package pgwire
+
+import "testing"
+
+func TestBindParams(t *testing.T) {
+
+	const (
+		oidUnknown = 0
+		oidInt8    = 20
+		oidText    = 25
+	)
+
+	tests := []struct {
+		name   string
+		query  string
+		params []string
+		oids   []int32
+		want   string
+	}{
+		{
+			name:   "typed numeric stays unquoted",
+			query:  "SELECT * FROM orders WHERE id = $1",
+			params: []string{"42"},
+			oids:   []int32{oidInt8},
+			want:   "SELECT * FROM orders WHERE id = 42",
+		},
+		{
+			name:   "negative numeric cannot start a comment",
+			query:  "SELECT 0-$1, 1",
+			params: []string{"-5"},
+			oids:   []int32{oidInt8},
+			want:   "SELECT 0-(-5), 1",
+		},
+		{
+			name:   "unknown type is quoted",
+			query:  "SELECT * FROM users WHERE zip = $1",
+			params: []string{"02134"},
+			oids:   []int32{oidUnknown},
+			want:   "SELECT * FROM users WHERE zip = '02134'",
+		},
+		{
+			name:   "missing type is quoted",
+			query:  "SELECT $1",
+			params: []string{"7"},
+			want:   "SELECT '7'",
+		},
+		{
+			name:   "text is quoted and escaped",
+			query:  "SELECT $1",
+			params: []string{"it's"},
+			oids:   []int32{oidText},
+			want:   "SELECT 'it''s'",
+		},
+		{
+			name:   "escape string keeps its placeholder text",
+			query:  `SELECT E'it\'s $1', $1`,
+			params: []string{"x"},
+			want:   `SELECT E'it\'s $1', 'x'`,
+		},
+		{
+			name:   "dollar quotes keep their placeholder text",
+			query:  "SELECT $$ $1 $$, $tag$ '$2 $tag$, $1",
+			params: []string{"x"},
+			want:   "SELECT $$ $1 $$, $tag$ '$2 $tag$, 'x'",
+		},
+		{
+			name:   "comments and quoted identifiers keep their placeholder text",
+			query:  "SELECT \"$1\" FROM t -- $2\nWHERE a = $1 /* $3 */",
+			params: []string{"x"},
+			want:   "SELECT \"$1\" FROM t -- $2\nWHERE a = 'x' /* $3 */",
+		},
+		{
+			name:   "null",
+			query:  "SELECT $1",
+			params: nil,
+			want:   "SELECT NULL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			params := [][]byte{nil}
+			if tt.params != nil {
+				params = make([][]byte, len(tt.params))
+				for i, p := range tt.params {
+					params[i] = []byte(p)
+				}
+			}
+
+			got, err := bindParams(tt.query, params, tt.oids)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindParamsMissing(t *testing.T) {
+
+	if _, err := bindParams("SELECT $1, $2", [][]byte{[]byte("1")}, nil); err == nil {
+		t.Fatal("expected an error for an unbound parameter")
+	}
+}
+
+func TestCountParams(t *testing.T) {
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"SELECT 1", 0},
+		{"SELECT $2, $1", 2},
+		{`SELECT E'it\'s $3', $1`, 1},
+		{"SELECT $$ $4 $$, $1", 1},
+		{"SELECT '", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := countParams(tt.query); got != tt.want {
+				t.Fatalf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
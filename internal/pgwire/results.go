@@ -0,0 +1,280 @@
+package pgwire
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"sql-sharding-v2/internal/executor"
+	"sql-sharding-v2/internal/router"
+)
+
+// queryResult is the merged outcome of a statement across shards
+type queryResult struct {
+	hasRows bool
+	columns []string
+	types   []string
+	rows    [][]any
+	tag     string
+}
+
+// pgError carries the fields of an ErrorResponse
+type pgError struct {
+	severity string
+	code     string
+	message  string
+}
+
+func newPGError(code string, message string) *pgError {
+	return &pgError{
+		severity: "ERROR",
+		code:     code,
+		message:  message,
+	}
+}
+
+// toPGError maps shard, routing and proxy errors to SQLSTATE codes
+func toPGError(err error) *pgError {
+
+	var pe *pgError
+	if errors.As(err, &pe) {
+		return pe
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return newPGError(string(pqErr.Code), pqErr.Message)
+	}
+
+	var routingErr *router.RoutingError
+	if errors.As(err, &routingErr) {
+		// clients retry serialization failures, keys are moving shards briefly
+		if routingErr.Code == router.ErrShardMoving {
+			return newPGError("40001", routingErr.Message)
+		}
+		return newPGError("0A000", routingErr.Message)
+	}
+
+	return newPGError("XX000", err.Error())
+}
+
+func (e *pgError) Error() string {
+	return e.message
+}
+
+// runStatement executes one statement, handling transaction control,
+// SET and SHOW locally and routing everything else.
+func (s *session) runStatement(sqlText string) (*queryResult, error) {
+
+	info, err := inspectStatement(sqlText)
+	if err != nil {
+		return nil, newPGError("42601", err.Error())
+	}
+
+	if s.txFailed && info.action != actionRollback && info.action != actionCommit {
+		return nil, newPGError("25P02", "current transaction is aborted, commands ignored until end of transaction block")
+	}
+
+	switch info.action {
+
+	case actionBegin:
+		if s.txID == "" {
+			txID, err := s.backend.BeginTransaction(s.projectID)
+			if err != nil {
+				return nil, err
+			}
+			s.txID = txID
+		}
+		return &queryResult{tag: "BEGIN"}, nil
+
+	case actionCommit:
+		return s.endTransaction()
+
+	case actionRollback:
+		if s.txID != "" {
+			err := s.backend.RollbackTransaction(s.txID)
+			s.txID, s.txFailed = "", false
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &queryResult{tag: "ROLLBACK"}, nil
+
+	case actionSet:
+		// session settings are not forwarded to shards
+		return &queryResult{tag: "SET"}, nil
+
+	case actionShow:
+		return &queryResult{
+			hasRows: true,
+			columns: []string{info.showName},
+			types:   []string{"text"},
+			rows:    [][]any{{showValue(info.showName)}},
+			tag:     "SHOW",
+		}, nil
+
+	case actionUnsupported:
+		return nil, newPGError("0A000", info.command+" is not supported through the sharding proxy")
+	}
+
+	var results []executor.ExecutionResult
+	if s.txID != "" {
+		results, err = s.backend.ExecuteInTransactionAs(context.Background(), s.principal, s.txID, sqlText)
+	} else {
+		results, err = s.backend.ExecuteSQLAs(context.Background(), s.principal, s.projectID, sqlText)
+	}
+
+	if err == nil {
+		for _, r := range results {
+			if r.Err != nil {
+				err = r.Err
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		if s.txID != "" {
+			s.txFailed = true
+		}
+		return nil, err
+	}
+
+	return mergeResults(info.command, results), nil
+}
+
+// endTransaction commits, or rolls back when the transaction already failed
+func (s *session) endTransaction() (*queryResult, error) {
+
+	if s.txID == "" {
+		return &queryResult{tag: "COMMIT"}, nil
+	}
+
+	txID, failed := s.txID, s.txFailed
+	s.txID, s.txFailed = "", false
+
+	if failed {
+		_ = s.backend.RollbackTransaction(txID)
+		return &queryResult{tag: "ROLLBACK"}, nil
+	}
+
+	if err := s.backend.CommitTransaction(txID); err != nil {
+		return nil, err
+	}
+
+	return &queryResult{tag: "COMMIT"}, nil
+}
+
+// mergeResults concatenates per-shard results into a single result set
+func mergeResults(command string, results []executor.ExecutionResult) *queryResult {
+
+	res := &queryResult{}
+	var affected int64
+
+	for _, r := range results {
+		if res.columns == nil && r.Columns != nil {
+			res.hasRows = true
+			res.columns = r.Columns
+			res.types = r.ColumnTypes
+		}
+		res.rows = append(res.rows, r.Rows...)
+		affected += r.RowsAffected
+	}
+
+	count := affected
+	if command == "SELECT" {
+		count = int64(len(res.rows))
+	}
+	res.tag = commandTag(command, count)
+
+	return res
+}
+
+func showValue(name string) string {
+	for k, v := range serverParams {
+		if strings.ToLower(k) == name {
+			return v
+		}
+	}
+	return ""
+}
+
+func (s *session) sendRowDescription(res *queryResult, formats []int16) {
+
+	m := newMessage('T').int16(int16(len(res.columns)))
+
+	for i, col := range res.columns {
+		oid := typeOID(columnType(res, i))
+
+		m.string(col).
+			int32(0).
+			int16(0).
+			int32(oid).
+			int16(typeSize(oid)).
+			int32(-1).
+			int16(formatFor(formats, i))
+	}
+
+	s.send(m)
+}
+
+// sendRows writes DataRow messages for rows[from:to] in the requested formats
+func (s *session) sendRows(res *queryResult, from int, to int, formats []int16) error {
+
+	for _, row := range res.rows[from:to] {
+		m := newMessage('D').int16(int16(len(row)))
+
+		for i, v := range row {
+			if v == nil {
+				m.int32(-1)
+				continue
+			}
+
+			typeName := columnType(res, i)
+
+			if formatFor(formats, i) == 1 {
+				data, err := encodeBinary(typeName, v)
+				if err != nil {
+					return newPGError("0A000", err.Error())
+				}
+				m.int32(int32(len(data))).bytes(data)
+				continue
+			}
+
+			text := encodeText(typeName, v)
+			m.int32(int32(len(text))).bytes(text)
+		}
+
+		s.send(m)
+	}
+
+	return nil
+}
+
+func columnType(res *queryResult, i int) string {
+	if i < len(res.types) {
+		return res.types[i]
+	}
+	return ""
+}
+
+// formatFor applies Bind result format codes: none means text,
+// a single code applies to every column.
+func formatFor(formats []int16, i int) int16 {
+	switch {
+	case len(formats) == 0:
+		return 0
+	case len(formats) == 1:
+		return formats[0]
+	case i < len(formats):
+		return formats[i]
+	default:
+		return 0
+	}
+}
+
+func (s *session) sendComplete(res *queryResult) {
+	s.send(newMessage('C').string(res.tag))
+}
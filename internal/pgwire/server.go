@@ -0,0 +1,134 @@
+package pgwire
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"sql-sharding-v2/internal/auth"
+	"sql-sharding-v2/internal/executor"
+	"sql-sharding-v2/pkg/logger"
+)
+
+// ErrServerClosed is returned by ListenAndServe after Shutdown
+var ErrServerClosed = errors.New("pgwire: server closed")
+
+// Backend is the subset of the app the wire protocol front end depends on
+type Backend interface {
+	ResolveProject(name string) (string, error)
+	AuthenticateAPIKey(secret string) (*auth.Principal, error)
+	ExecuteSQLAs(ctx context.Context, principal *auth.Principal, projectID string, sql string) ([]executor.ExecutionResult, error)
+	DescribeSQLAs(ctx context.Context, principal *auth.Principal, projectID string, sql string, paramCount int) ([]string, []string, error)
+
+	BeginTransaction(projectID string) (string, error)
+	ExecuteInTransactionAs(ctx context.Context, principal *auth.Principal, txID string, sql string) ([]executor.ExecutionResult, error)
+	CommitTransaction(txID string) error
+	RollbackTransaction(txID string) error
+}
+
+// Server accepts PostgreSQL protocol v3 clients and routes their statements
+// through the sharding layer.
+type Server struct {
+	addr        string
+	backend     Backend
+	requireAuth bool
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer builds the wire protocol server, requireAuth asks every client
+// for an API key as its password
+func NewServer(addr string, backend Backend, requireAuth bool) *Server {
+	return &Server{
+		addr:        addr,
+		backend:     backend,
+		requireAuth: requireAuth,
+		conns:       make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenAndServe accepts connections until Shutdown is called
+func (s *Server) ListenAndServe() error {
+
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = ln.Close()
+		return ErrServerClosed
+	}
+	s.listener = ln
+	s.mu.Unlock()
+
+	logger.Logger.Info("PostgreSQL wire server started", "addr", s.addr)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			defer s.forget(conn)
+
+			newSession(conn, s.backend, s.requireAuth).serve()
+		}()
+	}
+}
+
+// Shutdown stops accepting connections, closes open client connections
+// and waits for their sessions to finish.
+func (s *Server) Shutdown(ctx context.Context) error {
+
+	s.mu.Lock()
+	s.closed = true
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Server) forget(conn net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, conn)
+	s.mu.Unlock()
+	_ = conn.Close()
+}
@@ -0,0 +1,419 @@
+package pgwire
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+
+	"sql-sharding-v2/internal/auth"
+	"sql-sharding-v2/pkg/logger"
+)
+
+// parameters reported to clients after startup
+var serverParams = map[string]string{
+	"server_version":              "16.0 (sql-sharding)",
+	"server_encoding":             "UTF8",
+	"client_encoding":             "UTF8",
+	"DateStyle":                   "ISO, MDY",
+	"IntervalStyle":               "postgres",
+	"TimeZone":                    "UTC",
+	"integer_datetimes":           "on",
+	"standard_conforming_strings": "on",
+}
+
+// session serves a single client connection
+type session struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+	backend Backend
+
+	requireAuth bool
+	principal   *auth.Principal
+
+	projectID string
+	params    map[string]string
+
+	txID     string
+	txFailed bool
+
+	statements     map[string]*preparedStatement
+	portals        map[string]*portal
+	ignoreTillSync bool
+}
+
+func newSession(conn net.Conn, backend Backend, requireAuth bool) *session {
+	return &session{
+		conn:        conn,
+		r:           bufio.NewReader(conn),
+		w:           bufio.NewWriter(conn),
+		backend:     backend,
+		requireAuth: requireAuth,
+		params:      make(map[string]string),
+		statements:  make(map[string]*preparedStatement),
+		portals:     make(map[string]*portal),
+	}
+}
+
+func (s *session) serve() {
+
+	defer s.abandonTransaction()
+
+	if err := s.startup(); err != nil {
+		if !errors.Is(err, io.EOF) {
+			logger.Logger.Warn("pgwire startup failed", "remote", s.conn.RemoteAddr().String(), "error", err)
+		}
+		return
+	}
+
+	for {
+		typ, body, err := readMessage(s.r)
+		if err != nil {
+			return
+		}
+
+		if typ == msgTerminate {
+			return
+		}
+
+		if err := s.dispatch(typ, body); err != nil {
+			logger.Logger.Warn("pgwire session closed", "project_id", s.projectID, "error", err)
+			return
+		}
+
+		// flush is deferred while the client pipelines extended messages
+		if typ != msgParse && typ != msgBind && typ != msgDescribe && typ != msgExecute && typ != msgClose {
+			if err := s.w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// startup negotiates SSL/GSS refusals, reads startup parameters,
+// resolves the target project and authenticates the client.
+func (s *session) startup() error {
+
+negotiate:
+	for {
+		code, body, err := readStartup(s.r)
+		if err != nil {
+			return err
+		}
+
+		switch code {
+
+		case sslRequestCode, gssEncRequest:
+			// encryption is not supported, client may continue in plaintext
+			if _, err := s.conn.Write([]byte{'N'}); err != nil {
+				return err
+			}
+			continue
+
+		case cancelRequest:
+			return io.EOF
+
+		case protocolVersion3:
+			s.readStartupParams(body)
+			break negotiate
+
+		default:
+			s.sendFatal("0A000", fmt.Sprintf("unsupported frontend protocol %d", code))
+			return fmt.Errorf("unsupported protocol %d", code)
+		}
+	}
+
+	name := s.projectName()
+	if name == "" {
+		s.sendFatal("3D000", "no project selected, set the database name or the project startup option")
+		return errors.New("no project in startup parameters")
+	}
+
+	projectID, err := s.backend.ResolveProject(name)
+	if err != nil {
+		s.sendFatal("3D000", fmt.Sprintf("project %q does not exist", name))
+		return err
+	}
+	s.projectID = projectID
+
+	if s.requireAuth {
+		if err := s.authenticate(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.assumeTenant(); err != nil {
+		return err
+	}
+
+	s.send(newMessage('R').int32(authOK))
+
+	for k, v := range serverParams {
+		s.send(newMessage('S').string(k).string(v))
+	}
+	if app, ok := s.params["application_name"]; ok {
+		s.send(newMessage('S').string("application_name").string(app))
+	}
+
+	s.send(newMessage('K').int32(rand.Int31()).int32(rand.Int31()))
+	s.sendReady()
+
+	logger.Logger.Info("pgwire client connected", "project_id", projectID, "user", s.params["user"], "key_id", s.keyID())
+
+	return s.w.Flush()
+}
+
+// authenticate asks for the password in cleartext and resolves it as an
+// API key, which must grant access to the session's project
+func (s *session) authenticate() error {
+
+	s.send(newMessage('R').int32(authCleartextPassword))
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+
+	typ, body, err := readMessage(s.r)
+	if err != nil {
+		return err
+	}
+	if typ != msgPassword {
+		s.sendFatal("08P01", fmt.Sprintf("expected password message, got %q", typ))
+		return fmt.Errorf("unexpected message %q during authentication", typ)
+	}
+
+	buf := &buffer{data: body}
+	password := buf.string()
+	if buf.err != nil {
+		return buf.err
+	}
+
+	principal, err := s.backend.AuthenticateAPIKey(password)
+	if errors.Is(err, auth.ErrInvalidKey) {
+		s.sendFatal("28P01", fmt.Sprintf("password authentication failed for user %q", s.params["user"]))
+		return err
+	}
+	if err != nil {
+		s.sendFatal("XX000", "authentication failed")
+		return err
+	}
+
+	if !principal.Allows(s.projectID, auth.AccessRead) {
+		s.sendFatal("28000", "API key is not allowed to access this project")
+		return fmt.Errorf("key %s not allowed on project %s", principal.KeyID, s.projectID)
+	}
+
+	s.principal = principal
+
+	return nil
+}
+
+// assumeTenant confines the session to the tenant named in the startup
+// parameters. Only admin keys may choose a tenant, other keys keep the
+// tenant they are bound to. Without authentication the parameter alone
+// confines the session.
+func (s *session) assumeTenant() error {
+
+	tenantID := s.startupOption("tenant")
+	if tenantID == "" {
+		return nil
+	}
+
+	if !s.requireAuth {
+		s.principal = &auth.Principal{Role: auth.RoleAdmin, TenantID: tenantID}
+		return nil
+	}
+
+	switch {
+	case s.principal.TenantID == tenantID:
+		return nil
+	case s.principal.TenantID != "":
+		s.sendFatal("28000", "API key is bound to another tenant")
+		return fmt.Errorf("key %s bound to another tenant", s.principal.KeyID)
+	case s.principal.Role != auth.RoleAdmin:
+		s.sendFatal("28000", "only admin keys may choose a tenant")
+		return fmt.Errorf("key %s may not choose a tenant", s.principal.KeyID)
+	}
+
+	s.principal.TenantID = tenantID
+
+	return nil
+}
+
+func (s *session) readStartupParams(body []byte) {
+	buf := &buffer{data: body}
+	for {
+		key := buf.string()
+		if key == "" || buf.err != nil {
+			return
+		}
+		s.params[key] = buf.string()
+	}
+}
+
+// projectName picks the project from the "project" startup parameter,
+// "-c project=..." in options, or the database name, in that order.
+func (s *session) projectName() string {
+
+	if p := s.startupOption("project"); p != "" {
+		return p
+	}
+
+	return s.params["database"]
+}
+
+// startupOption reads a setting from its startup parameter or from
+// "-c name=..." in options
+func (s *session) startupOption(name string) string {
+
+	if v := s.params[name]; v != "" {
+		return v
+	}
+
+	fields := strings.Fields(s.params["options"])
+	for i, f := range fields {
+		opt := f
+		if f == "-c" && i+1 < len(fields) {
+			opt = fields[i+1]
+		}
+		opt = strings.TrimPrefix(opt, "--")
+		if v, ok := strings.CutPrefix(opt, name+"="); ok {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func (s *session) dispatch(typ byte, body []byte) error {
+
+	if s.ignoreTillSync && typ != msgSync {
+		return nil
+	}
+
+	switch typ {
+
+	case msgQuery:
+		buf := &buffer{data: body}
+		query := buf.string()
+		if buf.err != nil {
+			return buf.err
+		}
+		s.simpleQuery(query)
+		return nil
+
+	case msgParse:
+		return s.handleParse(body)
+
+	case msgBind:
+		return s.handleBind(body)
+
+	case msgDescribe:
+		return s.handleDescribe(body)
+
+	case msgExecute:
+		return s.handleExecute(body)
+
+	case msgClose:
+		return s.handleClose(body)
+
+	case msgSync:
+		s.ignoreTillSync = false
+		s.sendReady()
+		return nil
+
+	case msgFlush:
+		return s.w.Flush()
+
+	default:
+		s.sendError(newPGError("08P01", fmt.Sprintf("unsupported message type %q", typ)))
+		return nil
+	}
+}
+
+// simpleQuery runs every statement of a simple query message
+func (s *session) simpleQuery(query string) {
+
+	defer s.sendReady()
+
+	stmts, err := pg_query.SplitWithParser(query, true)
+	if err != nil {
+		s.sendError(newPGError("42601", err.Error()))
+		return
+	}
+
+	if len(stmts) == 0 {
+		s.send(newMessage('I'))
+		return
+	}
+
+	for _, stmt := range stmts {
+		res, err := s.runStatement(stmt)
+		if err != nil {
+			s.sendError(toPGError(err))
+			return
+		}
+
+		if res.hasRows {
+			s.sendRowDescription(res, nil)
+		}
+		if err := s.sendRows(res, 0, len(res.rows), nil); err != nil {
+			s.sendError(toPGError(err))
+			return
+		}
+		s.sendComplete(res)
+	}
+}
+
+// keyID identifies the authenticated API key, empty without authentication
+func (s *session) keyID() string {
+	if s.principal == nil {
+		return ""
+	}
+	return s.principal.KeyID
+}
+
+// abandonTransaction rolls back an open transaction when the client disconnects
+func (s *session) abandonTransaction() {
+	if s.txID == "" {
+		return
+	}
+	_ = s.backend.RollbackTransaction(s.txID)
+	s.txID = ""
+}
+
+func (s *session) send(m *message) {
+	_ = m.writeTo(s.w)
+}
+
+func (s *session) sendReady() {
+	status := byte('I')
+	if s.txID != "" {
+		status = 'T'
+		if s.txFailed {
+			status = 'E'
+		}
+	}
+	s.send(newMessage('Z').byte(status))
+}
+
+func (s *session) sendFatal(code string, msg string) {
+	err := newPGError(code, msg)
+	err.severity = "FATAL"
+	s.sendError(err)
+	_ = s.w.Flush()
+}
+
+func (s *session) sendError(err *pgError) {
+	s.send(newMessage('E').
+		byte('S').string(err.severity).
+		byte('V').string(err.severity).
+		byte('C').string(err.code).
+		byte('M').string(err.message).
+		byte(0))
+}
@@ -0,0 +1,195 @@
+package pgwire
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+
+	"sql-sharding-v2/internal/auth"
+	"sql-sharding-v2/internal/executor"
+)
+
+// fakeBackend resolves one project and one API key
+type fakeBackend struct {
+	key       string
+	principal *auth.Principal
+}
+
+func (b *fakeBackend) ResolveProject(name string) (string, error) {
+	if name != "shop" {
+		return "", errors.New("not found")
+	}
+	return "p1", nil
+}
+
+func (b *fakeBackend) AuthenticateAPIKey(secret string) (*auth.Principal, error) {
+	if secret != b.key {
+		return nil, auth.ErrInvalidKey
+	}
+	return b.principal, nil
+}
+
+func (b *fakeBackend) ExecuteSQLAs(context.Context, *auth.Principal, string, string) ([]executor.ExecutionResult, error) {
+	return nil, nil
+}
+
+func (b *fakeBackend) DescribeSQLAs(context.Context, *auth.Principal, string, string, int) ([]string, []string, error) {
+	return nil, nil, nil
+}
+
+func (b *fakeBackend) BeginTransaction(string) (string, error) { return "", nil }
+func (b *fakeBackend) ExecuteInTransactionAs(context.Context, *auth.Principal, string, string) ([]executor.ExecutionResult, error) {
+	return nil, nil
+}
+func (b *fakeBackend) CommitTransaction(string) error   { return nil }
+func (b *fakeBackend) RollbackTransaction(string) error { return nil }
+
+// startupMessage frames a protocol v3 startup message
+func startupMessage(params ...string) []byte {
+
+	body := binary.BigEndian.AppendUint32(nil, protocolVersion3)
+	for _, p := range params {
+		body = append(body, p...)
+		body = append(body, 0)
+	}
+	body = append(body, 0)
+
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(body)+4)), body...)
+}
+
+func TestSessionStartupAuthentication(t *testing.T) {
+
+	member := auth.Principal{KeyID: "k1", ProjectID: "p1", Role: auth.RoleReadWrite}
+	bound := auth.Principal{KeyID: "k1", ProjectID: "p1", Role: auth.RoleReadWrite, TenantID: "t1"}
+	admin := auth.Principal{KeyID: "k0", Role: auth.RoleAdmin}
+	outsider := auth.Principal{KeyID: "k2", ProjectID: "p2", Role: auth.RoleReadWrite}
+
+	tests := []struct {
+		name        string
+		requireAuth bool
+		principal   auth.Principal
+		password    string
+		tenant      string
+		wantAuthReq bool
+		wantCode    string
+		wantTenant  string
+	}{
+		{"auth disabled", false, member, "", "", false, "", ""},
+		{"auth disabled with tenant", false, member, "", "t1", false, "", "t1"},
+		{"valid key", true, member, "secret", "", true, "", ""},
+		{"invalid key", true, member, "wrong", "", true, "28P01", ""},
+		{"key of another project", true, outsider, "secret", "", true, "28000", ""},
+		{"bound key keeps its tenant", true, bound, "secret", "", true, "", "t1"},
+		{"bound key names its tenant", true, bound, "secret", "t1", true, "", "t1"},
+		{"bound key names another tenant", true, bound, "secret", "t2", true, "28000", ""},
+		{"unbound key names a tenant", true, member, "secret", "t2", true, "28000", ""},
+		{"admin key assumes a tenant", true, admin, "secret", "t2", true, "", "t2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			server, client := net.Pipe()
+			defer client.Close()
+
+			principal := tt.principal
+			backend := &fakeBackend{key: "secret", principal: &principal}
+			s := newSession(server, backend, tt.requireAuth)
+
+			done := make(chan error, 1)
+			go func() {
+				done <- s.startup()
+				server.Close()
+			}()
+
+			r := bufio.NewReader(client)
+			params := []string{"user", "app", "database", "shop"}
+			if tt.tenant != "" {
+				params = append(params, "options", "-c tenant="+tt.tenant)
+			}
+			if _, err := client.Write(startupMessage(params...)); err != nil {
+				t.Fatal(err)
+			}
+
+			typ, body, err := readMessage(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantAuthReq {
+				if typ != 'R' || binary.BigEndian.Uint32(body) != authCleartextPassword {
+					t.Fatalf("got message %q %v, want cleartext password request", typ, body)
+				}
+
+				w := bufio.NewWriter(client)
+				if err := newMessage(msgPassword).string(tt.password).writeTo(w); err != nil {
+					t.Fatal(err)
+				}
+				if err := w.Flush(); err != nil {
+					t.Fatal(err)
+				}
+
+				if typ, body, err = readMessage(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tt.wantCode != "" {
+				if typ != 'E' || !containsField(body, 'C', tt.wantCode) {
+					t.Fatalf("got message %q %q, want error %s", typ, body, tt.wantCode)
+				}
+				if err := <-done; err == nil {
+					t.Fatal("startup succeeded, want error")
+				}
+				return
+			}
+
+			if typ != 'R' || binary.BigEndian.Uint32(body) != authOK {
+				t.Fatalf("got message %q %v, want AuthenticationOk", typ, body)
+			}
+
+			// drain parameters until ReadyForQuery
+			for typ != 'Z' {
+				if typ, _, err = readMessage(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := <-done; err != nil {
+				t.Fatalf("startup: %v", err)
+			}
+			if tt.requireAuth && s.principal != &principal {
+				t.Errorf("session principal = %v, want %v", s.principal, &principal)
+			}
+			if got := s.tenantID(); got != tt.wantTenant {
+				t.Errorf("session tenant = %q, want %q", got, tt.wantTenant)
+			}
+		})
+	}
+}
+
+// tenantID is the tenant a session is confined to, empty if none
+func (s *session) tenantID() string {
+	if s.principal == nil {
+		return ""
+	}
+	return s.principal.TenantID
+}
+
+// containsField reports whether an error response carries a field value
+func containsField(body []byte, field byte, value string) bool {
+
+	buf := &buffer{data: body}
+	for {
+		f := buf.byte()
+		if f == 0 || buf.err != nil {
+			return false
+		}
+		if v := buf.string(); f == field && v == value {
+			return true
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package pgwire
+
+import (
+	"fmt"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+)
+
+// statementAction tells the session how to handle a statement
+type statementAction int
+
+const (
+	actionRoute statementAction = iota
+	actionBegin
+	actionCommit
+	actionRollback
+	actionSet
+	actionShow
+	actionUnsupported
+)
+
+// statementInfo is the parsed view of one client statement
+type statementInfo struct {
+	action   statementAction
+	command  string
+	showName string
+}
+
+// inspectStatement decides whether a statement is handled by the proxy itself
+// or routed to shards.
+func inspectStatement(sqlText string) (statementInfo, error) {
+
+	tree, err := pg_query.Parse(sqlText)
+	if err != nil {
+		return statementInfo{}, err
+	}
+
+	if len(tree.Stmts) != 1 {
+		return statementInfo{}, fmt.Errorf("expected a single statement")
+	}
+
+	node := tree.Stmts[0].Stmt
+
+	switch n := node.Node.(type) {
+
+	case *pg_query.Node_TransactionStmt:
+		switch n.TransactionStmt.Kind {
+		case pg_query.TransactionStmtKind_TRANS_STMT_BEGIN,
+			pg_query.TransactionStmtKind_TRANS_STMT_START:
+			return statementInfo{action: actionBegin, command: "BEGIN"}, nil
+
+		case pg_query.TransactionStmtKind_TRANS_STMT_COMMIT:
+			return statementInfo{action: actionCommit, command: "COMMIT"}, nil
+
+		case pg_query.TransactionStmtKind_TRANS_STMT_ROLLBACK:
+			return statementInfo{action: actionRollback, command: "ROLLBACK"}, nil
+
+		default:
+			return statementInfo{action: actionUnsupported, command: "TRANSACTION"}, nil
+		}
+
+	case *pg_query.Node_VariableSetStmt:
+		return statementInfo{action: actionSet, command: "SET"}, nil
+
+	case *pg_query.Node_VariableShowStmt:
+		return statementInfo{
+			action:   actionShow,
+			command:  "SHOW",
+			showName: strings.ToLower(n.VariableShowStmt.Name),
+		}, nil
+
+	case *pg_query.Node_SelectStmt:
+		return statementInfo{action: actionRoute, command: "SELECT"}, nil
+
+	case *pg_query.Node_InsertStmt:
+		return statementInfo{action: actionRoute, command: "INSERT"}, nil
+
+	case *pg_query.Node_UpdateStmt:
+		return statementInfo{action: actionRoute, command: "UPDATE"}, nil
+
+	case *pg_query.Node_DeleteStmt:
+		return statementInfo{action: actionRoute, command: "DELETE"}, nil
+
+	case *pg_query.Node_MergeStmt:
+		return statementInfo{action: actionRoute, command: "MERGE"}, nil
+
+	default:
+		// the router rejects anything else with a proper reason
+		return statementInfo{action: actionRoute, command: "UTILITY"}, nil
+	}
+}
+
+// commandTag builds the CommandComplete tag for a routed statement
+func commandTag(command string, rows int64) string {
+	switch command {
+	case "INSERT":
+		return fmt.Sprintf("INSERT 0 %d", rows)
+	case "SELECT", "UPDATE", "DELETE", "MERGE":
+		return fmt.Sprintf("%s %d", command, rows)
+	default:
+		return command
+	}
+}
@@ -0,0 +1,330 @@
+// Package querystats aggregates executed statements per fingerprint, like
+// pg_stat_statements at the sharding layer, and keeps a slow query log.
+package querystats
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+)
+
+// sort orders for Stats
+const (
+	SortTotal = "total"
+	SortMean  = "mean"
+	SortCalls = "calls"
+	SortP99   = "p99"
+)
+
+// Settings controls aggregation and the slow query log
+type Settings struct {
+	// statements at least this slow are logged, 0 disables the slow log
+	SlowThreshold time.Duration
+
+	// slow queries kept in memory per collector
+	SlowLogSize int
+
+	// fingerprints tracked per project, the least called one is evicted beyond this
+	MaxFingerprints int
+
+	// events queued for aggregation, events beyond this are dropped
+	BufferSize int
+}
+
+// Event is one executed statement
+type Event struct {
+	ProjectID   string
+	SQL         string
+	RoutingMode string
+	Shards      int
+	Rows        int64
+	Failed      bool
+	Started     time.Time
+	Duration    time.Duration
+}
+
+// FingerprintStats aggregates the statements sharing a fingerprint
+type FingerprintStats struct {
+	Fingerprint  string           `json:"fingerprint"`
+	Query        string           `json:"query"`
+	Calls        int64            `json:"calls"`
+	Errors       int64            `json:"errors"`
+	Rows         int64            `json:"rows"`
+	TotalMs      float64          `json:"total_ms"`
+	MeanMs       float64          `json:"mean_ms"`
+	MinMs        float64          `json:"min_ms"`
+	MaxMs        float64          `json:"max_ms"`
+	P99Ms        float64          `json:"p99_ms"`
+	RoutingModes map[string]int64 `json:"routing_modes"`
+	FirstSeen    time.Time        `json:"first_seen"`
+	LastSeen     time.Time        `json:"last_seen"`
+}
+
+// SlowQuery is an entry of the slow query log
+type SlowQuery struct {
+	ProjectID   string    `json:"project_id"`
+	Fingerprint string    `json:"fingerprint"`
+	Query       string    `json:"query"`
+	RoutingMode string    `json:"routing_mode"`
+	Shards      int       `json:"shards"`
+	Rows        int64     `json:"rows"`
+	Failed      bool      `json:"failed"`
+	DurationMs  float64   `json:"duration_ms"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+type entry struct {
+	stats     FingerprintStats
+	latencies histogram
+}
+
+// Collector aggregates events queued by Observe. Fingerprinting and
+// aggregation happen in Run, off the request path.
+type Collector struct {
+	settings Settings
+	slowLog  *slog.Logger
+
+	queue chan Event
+
+	mu       sync.RWMutex
+	projects map[string]map[string]*entry
+	slow     []SlowQuery
+	slowNext int
+}
+
+func NewCollector(settings Settings, slowLog *slog.Logger) *Collector {
+
+	if settings.SlowLogSize <= 0 {
+		settings.SlowLogSize = 500
+	}
+	if settings.MaxFingerprints <= 0 {
+		settings.MaxFingerprints = 5000
+	}
+	if settings.BufferSize <= 0 {
+		settings.BufferSize = 10000
+	}
+
+	return &Collector{
+		settings: settings,
+		slowLog:  slowLog,
+		queue:    make(chan Event, settings.BufferSize),
+		projects: make(map[string]map[string]*entry),
+	}
+}
+
+// Observe queues an event without blocking, events are dropped when the queue is full
+func (c *Collector) Observe(event Event) {
+	select {
+	case c.queue <- event:
+	default:
+	}
+}
+
+// Run aggregates queued events until ctx is cancelled
+func (c *Collector) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-c.queue:
+			c.add(event)
+		}
+	}
+}
+
+// add folds an event into the stats of its fingerprint
+func (c *Collector) add(event Event) {
+
+	fingerprint, err := pg_query.Fingerprint(event.SQL)
+	if err != nil {
+		fingerprint = "unparsable"
+	}
+
+	query, err := pg_query.Normalize(event.SQL)
+	if err != nil {
+		query = ""
+	}
+
+	ms := float64(event.Duration.Microseconds()) / 1000
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fingerprints, ok := c.projects[event.ProjectID]
+	if !ok {
+		fingerprints = make(map[string]*entry)
+		c.projects[event.ProjectID] = fingerprints
+	}
+
+	e, ok := fingerprints[fingerprint]
+	if !ok {
+		if len(fingerprints) >= c.settings.MaxFingerprints {
+			evictLeastCalled(fingerprints)
+		}
+		e = &entry{stats: FingerprintStats{
+			Fingerprint:  fingerprint,
+			Query:        query,
+			MinMs:        ms,
+			RoutingModes: make(map[string]int64),
+			FirstSeen:    event.Started,
+		}}
+		fingerprints[fingerprint] = e
+	}
+
+	s := &e.stats
+	s.Calls++
+	s.Rows += event.Rows
+	s.TotalMs += ms
+	s.MinMs = min(s.MinMs, ms)
+	s.MaxMs = max(s.MaxMs, ms)
+	s.RoutingModes[event.RoutingMode]++
+	s.LastSeen = event.Started
+	if event.Failed {
+		s.Errors++
+	}
+	e.latencies.observe(ms)
+
+	if c.settings.SlowThreshold > 0 && event.Duration >= c.settings.SlowThreshold {
+		c.logSlow(SlowQuery{
+			ProjectID:   event.ProjectID,
+			Fingerprint: fingerprint,
+			Query:       query,
+			RoutingMode: event.RoutingMode,
+			Shards:      event.Shards,
+			Rows:        event.Rows,
+			Failed:      event.Failed,
+			DurationMs:  ms,
+			OccurredAt:  event.Started,
+		})
+	}
+}
+
+// logSlow writes a slow query to the slow log and the in-memory ring.
+// Caller must hold c.mu.
+func (c *Collector) logSlow(query SlowQuery) {
+
+	c.slowLog.Warn(
+		"slow query",
+		"project_id", query.ProjectID,
+		"fingerprint", query.Fingerprint,
+		"query", query.Query,
+		"routing_mode", query.RoutingMode,
+		"shards", query.Shards,
+		"rows", query.Rows,
+		"failed", query.Failed,
+		"duration_ms", query.DurationMs,
+	)
+
+	if len(c.slow) < c.settings.SlowLogSize {
+		c.slow = append(c.slow, query)
+		return
+	}
+
+	c.slow[c.slowNext] = query
+	c.slowNext = (c.slowNext + 1) % len(c.slow)
+}
+
+// Stats returns the fingerprints of a project ordered by sortBy, limit 0 returns all
+func (c *Collector) Stats(projectID string, sortBy string, limit int) []FingerprintStats {
+
+	c.mu.RLock()
+
+	stats := make([]FingerprintStats, 0, len(c.projects[projectID]))
+	for _, e := range c.projects[projectID] {
+		s := e.stats
+		s.MeanMs = s.TotalMs / float64(s.Calls)
+		s.P99Ms = min(e.latencies.quantile(0.99), s.MaxMs)
+		s.RoutingModes = make(map[string]int64, len(e.stats.RoutingModes))
+		for mode, count := range e.stats.RoutingModes {
+			s.RoutingModes[mode] = count
+		}
+		stats = append(stats, s)
+	}
+
+	c.mu.RUnlock()
+
+	key := func(s FingerprintStats) float64 {
+		switch sortBy {
+		case SortMean:
+			return s.MeanMs
+		case SortCalls:
+			return float64(s.Calls)
+		case SortP99:
+			return s.P99Ms
+		default:
+			return s.TotalMs
+		}
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return key(stats[i]) > key(stats[j])
+	})
+
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+
+	return stats
+}
+
+// SlowQueries returns the slow queries of a project, newest first
+func (c *Collector) SlowQueries(projectID string, limit int) []SlowQuery {
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	queries := make([]SlowQuery, 0)
+
+	// walk the ring from the newest entry backwards
+	for i := range c.slow {
+		index := (c.slowNext - 1 - i + 2*len(c.slow)) % len(c.slow)
+		if c.slow[index].ProjectID != projectID {
+			continue
+		}
+		queries = append(queries, c.slow[index])
+		if limit > 0 && len(queries) == limit {
+			break
+		}
+	}
+
+	return queries
+}
+
+// Reset clears the stats and slow queries of a project
+func (c *Collector) Reset(projectID string) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.projects, projectID)
+
+	kept := make([]SlowQuery, 0, len(c.slow))
+	for i := range c.slow {
+		// keep ring order, oldest first
+		query := c.slow[(c.slowNext+i)%len(c.slow)]
+		if query.ProjectID != projectID {
+			kept = append(kept, query)
+		}
+	}
+	c.slow = kept
+	c.slowNext = 0
+}
+
+// evictLeastCalled removes the fingerprint with the fewest calls
+func evictLeastCalled(fingerprints map[string]*entry) {
+
+	var victim string
+	var calls int64 = -1
+
+	for fingerprint, e := range fingerprints {
+		if calls < 0 || e.stats.Calls < calls {
+			victim, calls = fingerprint, e.stats.Calls
+		}
+	}
+
+	delete(fingerprints, victim)
+}
@@ -0,0 +1,259 @@
+package querystats
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func discardLog() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestCollectorAggregatesPerFingerprint(t *testing.T) {
+
+	c := NewCollector(Settings{}, discardLog())
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	events := []Event{
+		{SQL: "SELECT * FROM orders WHERE id = 1", RoutingMode: "single", Shards: 1, Rows: 1, Duration: 2 * time.Millisecond},
+		{SQL: "SELECT * FROM orders WHERE id = 2", RoutingMode: "single", Shards: 1, Rows: 0, Duration: 4 * time.Millisecond},
+		{SQL: "select * from orders where id = 3", RoutingMode: "broadcast", Shards: 2, Rows: 3, Duration: 6 * time.Millisecond, Failed: true},
+		{SQL: "SELECT * FROM users", RoutingMode: "broadcast", Shards: 2, Rows: 10, Duration: time.Millisecond},
+	}
+
+	for i, event := range events {
+		event.ProjectID = "p"
+		event.Started = start.Add(time.Duration(i) * time.Second)
+		c.add(event)
+	}
+	c.add(Event{ProjectID: "other", SQL: "SELECT 1", Duration: time.Second})
+
+	stats := c.Stats("p", SortCalls, 0)
+	if len(stats) != 2 {
+		t.Fatalf("got %d fingerprints, want 2", len(stats))
+	}
+
+	orders := stats[0]
+
+	if orders.Query != "SELECT * FROM orders WHERE id = $1" {
+		t.Fatalf("query %q", orders.Query)
+	}
+	if orders.Calls != 3 || orders.Errors != 1 || orders.Rows != 4 {
+		t.Fatalf("calls %d errors %d rows %d, want 3 1 4", orders.Calls, orders.Errors, orders.Rows)
+	}
+	if orders.TotalMs != 12 || orders.MeanMs != 4 || orders.MinMs != 2 || orders.MaxMs != 6 {
+		t.Fatalf("total %v mean %v min %v max %v, want 12 4 2 6", orders.TotalMs, orders.MeanMs, orders.MinMs, orders.MaxMs)
+	}
+	if orders.RoutingModes["single"] != 2 || orders.RoutingModes["broadcast"] != 1 {
+		t.Fatalf("routing modes %v", orders.RoutingModes)
+	}
+	if !orders.FirstSeen.Equal(start) || !orders.LastSeen.Equal(start.Add(2*time.Second)) {
+		t.Fatalf("first seen %v last seen %v", orders.FirstSeen, orders.LastSeen)
+	}
+
+	// the returned stats are copies
+	orders.RoutingModes["single"] = 100
+	if c.Stats("p", SortCalls, 0)[0].RoutingModes["single"] != 2 {
+		t.Fatal("stats share routing modes with the collector")
+	}
+}
+
+func TestCollectorSortAndLimit(t *testing.T) {
+
+	c := NewCollector(Settings{}, discardLog())
+
+	// many fast calls against one slow call
+	for i := 0; i < 5; i++ {
+		c.add(Event{ProjectID: "p", SQL: "SELECT * FROM orders", Duration: time.Millisecond})
+	}
+	c.add(Event{ProjectID: "p", SQL: "SELECT * FROM users", Duration: 20 * time.Millisecond})
+
+	tests := []struct {
+		sortBy string
+		want   string
+	}{
+		{SortTotal, "SELECT * FROM users"},
+		{SortMean, "SELECT * FROM users"},
+		{SortP99, "SELECT * FROM users"},
+		{SortCalls, "SELECT * FROM orders"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+
+			stats := c.Stats("p", tt.sortBy, 1)
+			if len(stats) != 1 {
+				t.Fatalf("got %d fingerprints, want 1", len(stats))
+			}
+			if stats[0].Query != tt.want {
+				t.Fatalf("first %q, want %q", stats[0].Query, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectorEvictsLeastCalled(t *testing.T) {
+
+	c := NewCollector(Settings{MaxFingerprints: 2}, discardLog())
+
+	c.add(Event{ProjectID: "p", SQL: "SELECT * FROM orders"})
+	c.add(Event{ProjectID: "p", SQL: "SELECT * FROM orders"})
+	c.add(Event{ProjectID: "p", SQL: "SELECT * FROM users"})
+	c.add(Event{ProjectID: "p", SQL: "SELECT * FROM items"})
+
+	stats := c.Stats("p", SortCalls, 0)
+	if len(stats) != 2 || stats[0].Query != "SELECT * FROM orders" || stats[1].Query != "SELECT * FROM items" {
+		t.Fatalf("got %+v, want orders and items", stats)
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+
+	var empty histogram
+	if got := empty.quantile(0.99); got != 0 {
+		t.Fatalf("quantile of an empty histogram %v, want 0", got)
+	}
+
+	var h histogram
+	for ms := 1; ms <= 1000; ms++ {
+		h.observe(float64(ms))
+	}
+
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{0.5, 500},
+		{0.9, 900},
+		{0.99, 990},
+	}
+
+	for _, tt := range tests {
+		// buckets are upper bounds within the bucket growth of the value
+		got := h.quantile(tt.q)
+		if got < tt.want || got > tt.want*bucketGrowth {
+			t.Fatalf("quantile %v = %v, want within [%v, %v]", tt.q, got, tt.want, tt.want*bucketGrowth)
+		}
+	}
+
+	// latencies beyond the last bucket land in it
+	var slow histogram
+	slow.observe(float64(24 * time.Hour / time.Millisecond))
+	if got, want := slow.quantile(1), bucketStart*math.Pow(bucketGrowth, bucketCount-1); got != want {
+		t.Fatalf("quantile of an overflow %v, want %v", got, want)
+	}
+}
+
+func TestCollectorP99IsCappedByMax(t *testing.T) {
+
+	c := NewCollector(Settings{}, discardLog())
+	c.add(Event{ProjectID: "p", SQL: "SELECT 1", Duration: 3 * time.Millisecond})
+
+	stats := c.Stats("p", SortTotal, 0)
+	if stats[0].P99Ms != 3 {
+		t.Fatalf("p99 %v, want 3", stats[0].P99Ms)
+	}
+}
+
+func TestSlowLogThreshold(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		durations []time.Duration
+		want      []float64
+	}{
+		{
+			name:      "disabled",
+			durations: []time.Duration{time.Second},
+		},
+		{
+			name:      "at and above the threshold, newest first",
+			threshold: 10 * time.Millisecond,
+			durations: []time.Duration{5 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond},
+			want:      []float64{20, 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			var out bytes.Buffer
+			c := NewCollector(Settings{SlowThreshold: tt.threshold}, slog.New(slog.NewTextHandler(&out, nil)))
+
+			for _, d := range tt.durations {
+				c.add(Event{ProjectID: "p", SQL: "SELECT * FROM orders WHERE id = 1", RoutingMode: "single", Duration: d})
+			}
+
+			slow := c.SlowQueries("p", 0)
+			if len(slow) != len(tt.want) {
+				t.Fatalf("got %d slow queries, want %d", len(slow), len(tt.want))
+			}
+			for i, q := range slow {
+				if q.DurationMs != tt.want[i] {
+					t.Fatalf("slow query %d took %v, want %v", i, q.DurationMs, tt.want[i])
+				}
+				if q.Query != "SELECT * FROM orders WHERE id = $1" || q.RoutingMode != "single" {
+					t.Fatalf("slow query %+v", q)
+				}
+			}
+
+			if logged := strings.Count(out.String(), "slow query"); logged != len(tt.want) {
+				t.Fatalf("logged %d slow queries, want %d", logged, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestSlowLogRingAndReset(t *testing.T) {
+
+	c := NewCollector(Settings{SlowThreshold: time.Millisecond, SlowLogSize: 3}, discardLog())
+
+	// a1 is overwritten once the ring is full
+	for _, e := range []struct {
+		project string
+		ms      int
+	}{{"a", 1}, {"b", 2}, {"a", 3}, {"b", 4}} {
+		c.add(Event{ProjectID: e.project, SQL: "SELECT 1", Duration: time.Duration(e.ms) * time.Millisecond})
+	}
+
+	durations := func(project string, limit int) []float64 {
+		var got []float64
+		for _, q := range c.SlowQueries(project, limit) {
+			got = append(got, q.DurationMs)
+		}
+		return got
+	}
+
+	if got := durations("a", 0); len(got) != 1 || got[0] != 3 {
+		t.Fatalf("project a %v, want [3]", got)
+	}
+	if got := durations("b", 1); len(got) != 1 || got[0] != 4 {
+		t.Fatalf("project b limited %v, want [4]", got)
+	}
+
+	c.Reset("a")
+
+	if got := durations("a", 0); len(got) != 0 {
+		t.Fatalf("project a after reset %v, want none", got)
+	}
+	if got := durations("b", 0); len(got) != 2 || got[0] != 4 || got[1] != 2 {
+		t.Fatalf("project b after reset %v, want [4 2]", got)
+	}
+	if len(c.Stats("a", SortTotal, 0)) != 0 {
+		t.Fatal("project a keeps stats after reset")
+	}
+
+	// the ring keeps filling after a reset
+	c.add(Event{ProjectID: "b", SQL: "SELECT 1", Duration: 5 * time.Millisecond})
+	c.add(Event{ProjectID: "b", SQL: "SELECT 1", Duration: 6 * time.Millisecond})
+	if got := durations("b", 0); len(got) != 3 || got[0] != 6 || got[2] != 4 {
+		t.Fatalf("project b refilled %v, want [6 5 4]", got)
+	}
+}
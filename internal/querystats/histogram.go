@@ -0,0 +1,52 @@
+package querystats
+
+import "math"
+
+// latency buckets grow by bucketGrowth from bucketStart milliseconds, which
+// covers 10µs to over ten minutes with a relative error below 20%
+const (
+	bucketStart  = 0.01
+	bucketGrowth = 1.2
+	bucketCount  = 100
+)
+
+// histogram counts latencies in exponential buckets so percentiles can be
+// estimated in constant memory per fingerprint
+type histogram struct {
+	counts [bucketCount]int64
+	total  int64
+}
+
+func (h *histogram) observe(ms float64) {
+
+	index := 0
+	if ms > bucketStart {
+		index = int(math.Ceil(math.Log(ms/bucketStart) / math.Log(bucketGrowth)))
+	}
+	if index >= bucketCount {
+		index = bucketCount - 1
+	}
+
+	h.counts[index]++
+	h.total++
+}
+
+// quantile returns the upper bound of the bucket holding quantile q
+func (h *histogram) quantile(q float64) float64 {
+
+	if h.total == 0 {
+		return 0
+	}
+
+	rank := int64(math.Ceil(q * float64(h.total)))
+
+	var seen int64
+	for i, count := range h.counts {
+		seen += count
+		if seen >= rank {
+			return bucketStart * math.Pow(bucketGrowth, float64(i))
+		}
+	}
+
+	return bucketStart * math.Pow(bucketGrowth, bucketCount-1)
+}
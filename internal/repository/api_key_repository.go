@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// represents api_keys table, the key hash is never serialized
+type APIKey struct {
+	ID         string     `json:"id"`
+	ProjectID  *string    `json:"project_id"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`
+	KeyPrefix  string     `json:"key_prefix"`
+	KeyHash    string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+
+	// statement-level access policy
+	AllowedTables       []string `json:"allowed_tables"`
+	DenyBroadcastWrites bool     `json:"deny_broadcast_writes"`
+
+	// tenant the key is bound to, nil when the caller passes one per request
+	TenantID *string `json:"tenant_id"`
+}
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `
+	id, project_id, name, role, key_prefix, key_hash,
+	created_at, rotated_at, last_used_at, revoked_at,
+	allowed_tables, deny_broadcast_writes, tenant_id
+`
+
+// func to store a new key
+func (r *APIKeyRepository) APIKeyCreate(ctx context.Context, key APIKey) (*APIKey, error) {
+
+	key.ID = uuid.New().String()
+	key.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO api_keys
+		(id, project_id, name, role, key_prefix, key_hash, created_at,
+		 allowed_tables, deny_broadcast_writes, tenant_id)
+		VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		key.ID,
+		key.ProjectID,
+		key.Name,
+		key.Role,
+		key.KeyPrefix,
+		key.KeyHash,
+		key.CreatedAt,
+		nullableArray(key.AllowedTables),
+		key.DenyBroadcastWrites,
+		key.TenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// func to list keys of a project, or all keys when projectID is empty
+func (r *APIKeyRepository) APIKeyList(ctx context.Context, projectID string) ([]APIKey, error) {
+
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE $1 = '' OR project_id::text = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+// func to fetch a key by id
+func (r *APIKeyRepository) APIKeyFetchByID(ctx context.Context, keyID string) (*APIKey, error) {
+
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE id = $1
+	`
+
+	return scanAPIKey(r.db.QueryRowContext(ctx, query, keyID))
+}
+
+// func to fetch a non revoked key by its hash
+func (r *APIKeyRepository) APIKeyFetchActiveByHash(ctx context.Context, keyHash string) (*APIKey, error) {
+
+	query := `
+		SELECT ` + apiKeyColumns + `
+		FROM api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL
+	`
+
+	return scanAPIKey(r.db.QueryRowContext(ctx, query, keyHash))
+}
+
+// func to replace the secret of a non revoked key
+func (r *APIKeyRepository) APIKeyRotate(ctx context.Context, keyID string, keyPrefix string, keyHash string) error {
+
+	query := `
+		UPDATE api_keys
+		SET key_prefix = $1, key_hash = $2, rotated_at = NOW()
+		WHERE id = $3 AND revoked_at IS NULL
+	`
+
+	return execOneRow(ctx, r.db, query, keyPrefix, keyHash, keyID)
+}
+
+// func to revoke a key
+func (r *APIKeyRepository) APIKeyRevoke(ctx context.Context, keyID string) error {
+
+	query := `
+		UPDATE api_keys
+		SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+
+	return execOneRow(ctx, r.db, query, keyID)
+}
+
+// func to replace the access policy of a key
+func (r *APIKeyRepository) APIKeyUpdatePolicy(
+	ctx context.Context,
+	keyID string,
+	allowedTables []string,
+	denyBroadcastWrites bool,
+	tenantID *string,
+) error {
+
+	query := `
+		UPDATE api_keys
+		SET allowed_tables = $1, deny_broadcast_writes = $2, tenant_id = $3
+		WHERE id = $4 AND revoked_at IS NULL
+	`
+
+	return execOneRow(ctx, r.db, query, nullableArray(allowedTables), denyBroadcastWrites, tenantID, keyID)
+}
+
+// func to record key usage
+func (r *APIKeyRepository) APIKeyTouch(ctx context.Context, keyID string) error {
+
+	query := `
+		UPDATE api_keys
+		SET last_used_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, keyID)
+	return err
+}
+
+// helper to scan a key from a row
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (*APIKey, error) {
+
+	var key APIKey
+
+	err := row.Scan(
+		&key.ID,
+		&key.ProjectID,
+		&key.Name,
+		&key.Role,
+		&key.KeyPrefix,
+		&key.KeyHash,
+		&key.CreatedAt,
+		&key.RotatedAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		pq.Array(&key.AllowedTables),
+		&key.DenyBroadcastWrites,
+		&key.TenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// helper to run an update that must affect exactly one row
+func execOneRow(ctx context.Context, db *sql.DB, query string, args ...any) error {
+
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// helper to store an empty list as NULL
+func nullableArray(values []string) any {
+	if len(values) == 0 {
+		return nil
+	}
+	return pq.Array(values)
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// represents audit_log table
+type AuditRecord struct {
+	ID         int64     `json:"id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	EventType  string    `json:"event_type"`
+	ProjectID  *string   `json:"project_id"`
+	Actor      string    `json:"actor"`
+	Statement  string    `json:"statement"`
+	Detail     string    `json:"detail"`
+
+	// query events
+	Fingerprint  string   `json:"fingerprint"`
+	Normalized   string   `json:"normalized"`
+	RoutingMode  string   `json:"routing_mode"`
+	TargetShards []string `json:"target_shards"`
+	DurationMs   *float64 `json:"duration_ms"`
+	RowsAffected *int64   `json:"rows_affected"`
+	Error        string   `json:"error"`
+}
+
+// filters for browsing the audit log, zero values match everything
+type AuditFilter struct {
+	ProjectID   string
+	EventType   string
+	Fingerprint string
+	Since       time.Time
+	Until       time.Time
+
+	// page through older records by passing the smallest ID seen
+	BeforeID int64
+	Limit    int
+}
+
+type AuditLogRepository struct {
+	db *sql.DB
+}
+
+func NewAuditLogRepository(db *sql.DB) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+const auditLogColumns = `
+	id, occurred_at, event_type, project_id, actor, statement, detail,
+	fingerprint, normalized, routing_mode, target_shards,
+	duration_ms, rows_affected, error
+`
+
+// number of values bound per record in AuditLogInsertBatch
+const auditLogInsertArgs = 13
+
+// func to append an audit record
+func (r *AuditLogRepository) AuditLogInsert(ctx context.Context, record AuditRecord) error {
+	return r.AuditLogInsertBatch(ctx, []AuditRecord{record})
+}
+
+// func to append several audit records with a single statement
+func (r *AuditLogRepository) AuditLogInsertBatch(ctx context.Context, records []AuditRecord) error {
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	var query strings.Builder
+	query.WriteString(`
+		INSERT INTO audit_log
+		(occurred_at, event_type, project_id, actor, statement, detail,
+		 fingerprint, normalized, routing_mode, target_shards,
+		 duration_ms, rows_affected, error)
+		VALUES
+	`)
+
+	args := make([]any, 0, len(records)*auditLogInsertArgs)
+
+	for i, record := range records {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+
+		placeholders := make([]string, auditLogInsertArgs)
+		for j := range placeholders {
+			placeholders[j] = fmt.Sprintf("$%d", len(args)+j+1)
+		}
+		query.WriteString("(" + strings.Join(placeholders, ", ") + ")")
+
+		args = append(args,
+			record.OccurredAt,
+			record.EventType,
+			record.ProjectID,
+			record.Actor,
+			record.Statement,
+			record.Detail,
+			record.Fingerprint,
+			record.Normalized,
+			record.RoutingMode,
+			nullableArray(record.TargetShards),
+			record.DurationMs,
+			record.RowsAffected,
+			record.Error,
+		)
+	}
+
+	_, err := r.db.ExecContext(ctx, query.String(), args...)
+	return err
+}
+
+// func to list audit records, newest first
+func (r *AuditLogRepository) AuditLogList(ctx context.Context, filter AuditFilter) ([]AuditRecord, error) {
+
+	var since, until any
+	if !filter.Since.IsZero() {
+		since = filter.Since
+	}
+	if !filter.Until.IsZero() {
+		until = filter.Until
+	}
+
+	query := `
+		SELECT ` + auditLogColumns + `
+		FROM audit_log
+		WHERE ($1 = '' OR project_id::text = $1)
+		  AND ($2 = '' OR event_type = $2)
+		  AND ($3 = '' OR fingerprint = $3)
+		  AND ($4::timestamptz IS NULL OR occurred_at >= $4)
+		  AND ($5::timestamptz IS NULL OR occurred_at < $5)
+		  AND ($6 = 0 OR id < $6)
+		ORDER BY id DESC
+		LIMIT $7
+	`
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		query,
+		filter.ProjectID,
+		filter.EventType,
+		filter.Fingerprint,
+		since,
+		until,
+		filter.BeforeID,
+		filter.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []AuditRecord
+
+	for rows.Next() {
+		var record AuditRecord
+
+		err := rows.Scan(
+			&record.ID,
+			&record.OccurredAt,
+			&record.EventType,
+			&record.ProjectID,
+			&record.Actor,
+			&record.Statement,
+			&record.Detail,
+			&record.Fingerprint,
+			&record.Normalized,
+			&record.RoutingMode,
+			pq.Array(&record.TargetShards),
+			&record.DurationMs,
+			&record.RowsAffected,
+			&record.Error,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// func to delete records older than a point in time
+func (r *AuditLogRepository) AuditLogPrune(ctx context.Context, before time.Time) (int64, error) {
+
+	query := `
+		DELETE FROM audit_log
+		WHERE occurred_at < $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// represents the data distribution of a column
+type ColumnStatistic struct {
+	TableName    string    `json:"table_name"`
+	ColumnName   string    `json:"column_name"`
+	NDistinct    float64   `json:"n_distinct"`
+	NullFrac     float64   `json:"null_frac"`
+	TopFrequency float64   `json:"top_frequency"`
+	RowCount     int64     `json:"row_count"`
+	Source       string    `json:"source"`
+	ShardCount   int       `json:"shard_count"`
+	CollectedAt  time.Time `json:"collected_at"`
+}
+
+type ColumnStatisticsRepository struct {
+	db *sql.DB
+}
+
+func NewColumnStatisticsRepository(db *sql.DB) *ColumnStatisticsRepository {
+	return &ColumnStatisticsRepository{db: db}
+}
+
+// func to replace the statistics of a project with a new collection
+func (r *ColumnStatisticsRepository) StatisticsReplace(ctx context.Context, projectID string, stats []ColumnStatistic) error {
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM column_statistics WHERE project_id = $1`, projectID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO column_statistics
+		(project_id, table_name, column_name, n_distinct, null_frac, top_frequency,
+		 row_count, source, shard_count, collected_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	now := time.Now()
+
+	for _, s := range stats {
+		if _, err := tx.ExecContext(
+			ctx,
+			query,
+			projectID,
+			s.TableName,
+			s.ColumnName,
+			s.NDistinct,
+			s.NullFrac,
+			s.TopFrequency,
+			s.RowCount,
+			s.Source,
+			s.ShardCount,
+			now,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// func to list the statistics of a project
+func (r *ColumnStatisticsRepository) StatisticsList(ctx context.Context, projectID string) ([]ColumnStatistic, error) {
+
+	query := `
+		SELECT table_name, column_name, n_distinct, null_frac, top_frequency,
+		       row_count, source, shard_count, collected_at
+		FROM column_statistics
+		WHERE project_id = $1
+		ORDER BY table_name, column_name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make([]ColumnStatistic, 0)
+
+	for rows.Next() {
+		var s ColumnStatistic
+		if err := rows.Scan(
+			&s.TableName,
+			&s.ColumnName,
+			&s.NDistinct,
+			&s.NullFrac,
+			&s.TopFrequency,
+			&s.RowCount,
+			&s.Source,
+			&s.ShardCount,
+			&s.CollectedAt,
+		); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+
+	return stats, rows.Err()
+}
+
+// func to drop the statistics of a project, inference falls back to name heuristics
+func (r *ColumnStatisticsRepository) StatisticsClear(ctx context.Context, projectID string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM column_statistics WHERE project_id = $1`, projectID)
+	return err
+}
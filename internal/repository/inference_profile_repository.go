@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// represents the score weights of shard key inference, penalties are positive magnitudes
+type InferenceWeights struct {
+	IncomingFK        int `json:"incoming_fk"`
+	ReferencingTable  int `json:"referencing_table"`
+	ForeignKey        int `json:"foreign_key"`
+	RootAffinity      int `json:"root_affinity"`
+	PrimaryKey        int `json:"primary_key"`
+	TextualPenalty    int `json:"textual_penalty"`
+	Local             int `json:"local"`
+	WorkloadPredicate int `json:"workload_predicate"`
+	WorkloadJoin      int `json:"workload_join"`
+	SkewPenalty       int `json:"skew_penalty"`
+	PreferredColumn   int `json:"preferred_column"`
+}
+
+// represents the shard key inference profile of a project
+type InferenceProfile struct {
+	Weights            InferenceWeights `json:"weights"`
+	EliminatedPatterns []string         `json:"eliminated_patterns"`
+	PreferredColumns   []string         `json:"preferred_columns"`
+
+	// nil for the built-in defaults
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+type InferenceProfileRepository struct {
+	db *sql.DB
+}
+
+func NewInferenceProfileRepository(db *sql.DB) *InferenceProfileRepository {
+	return &InferenceProfileRepository{db: db}
+}
+
+// func to fetch the profile of a project, sql.ErrNoRows when it uses the defaults
+func (r *InferenceProfileRepository) InferenceProfileGet(ctx context.Context, projectID string) (*InferenceProfile, error) {
+
+	query := `
+		SELECT
+			incoming_fk_weight,
+			referencing_table_weight,
+			foreign_key_weight,
+			root_affinity_weight,
+			primary_key_weight,
+			textual_penalty,
+			local_weight,
+			workload_predicate_weight,
+			workload_join_weight,
+			skew_penalty,
+			preferred_column_weight,
+			eliminated_patterns,
+			preferred_columns,
+			updated_at
+		FROM inference_profiles
+		WHERE project_id = $1
+	`
+
+	var p InferenceProfile
+	var updatedAt time.Time
+
+	if err := r.db.QueryRowContext(ctx, query, projectID).Scan(
+		&p.Weights.IncomingFK,
+		&p.Weights.ReferencingTable,
+		&p.Weights.ForeignKey,
+		&p.Weights.RootAffinity,
+		&p.Weights.PrimaryKey,
+		&p.Weights.TextualPenalty,
+		&p.Weights.Local,
+		&p.Weights.WorkloadPredicate,
+		&p.Weights.WorkloadJoin,
+		&p.Weights.SkewPenalty,
+		&p.Weights.PreferredColumn,
+		pq.Array(&p.EliminatedPatterns),
+		pq.Array(&p.PreferredColumns),
+		&updatedAt,
+	); err != nil {
+		return nil, err
+	}
+	p.UpdatedAt = &updatedAt
+
+	return &p, nil
+}
+
+// func to store the profile of a project
+func (r *InferenceProfileRepository) InferenceProfileUpsert(ctx context.Context, projectID string, p *InferenceProfile) error {
+
+	query := `
+		INSERT INTO inference_profiles
+		(project_id, incoming_fk_weight, referencing_table_weight, foreign_key_weight,
+		 root_affinity_weight, primary_key_weight, textual_penalty, local_weight,
+		 workload_predicate_weight, workload_join_weight, skew_penalty,
+		 preferred_column_weight, eliminated_patterns, preferred_columns, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (project_id) DO UPDATE SET
+			incoming_fk_weight = EXCLUDED.incoming_fk_weight,
+			referencing_table_weight = EXCLUDED.referencing_table_weight,
+			foreign_key_weight = EXCLUDED.foreign_key_weight,
+			root_affinity_weight = EXCLUDED.root_affinity_weight,
+			primary_key_weight = EXCLUDED.primary_key_weight,
+			textual_penalty = EXCLUDED.textual_penalty,
+			local_weight = EXCLUDED.local_weight,
+			workload_predicate_weight = EXCLUDED.workload_predicate_weight,
+			workload_join_weight = EXCLUDED.workload_join_weight,
+			skew_penalty = EXCLUDED.skew_penalty,
+			preferred_column_weight = EXCLUDED.preferred_column_weight,
+			eliminated_patterns = EXCLUDED.eliminated_patterns,
+			preferred_columns = EXCLUDED.preferred_columns,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	now := time.Now()
+
+	if _, err := r.db.ExecContext(
+		ctx,
+		query,
+		projectID,
+		p.Weights.IncomingFK,
+		p.Weights.ReferencingTable,
+		p.Weights.ForeignKey,
+		p.Weights.RootAffinity,
+		p.Weights.PrimaryKey,
+		p.Weights.TextualPenalty,
+		p.Weights.Local,
+		p.Weights.WorkloadPredicate,
+		p.Weights.WorkloadJoin,
+		p.Weights.SkewPenalty,
+		p.Weights.PreferredColumn,
+		pq.Array(p.EliminatedPatterns),
+		pq.Array(p.PreferredColumns),
+		now,
+	); err != nil {
+		return err
+	}
+	p.UpdatedAt = &now
+
+	return nil
+}
+
+// func to drop the profile of a project, inference goes back to the defaults
+func (r *InferenceProfileRepository) InferenceProfileDelete(ctx context.Context, projectID string) error {
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM inference_profiles WHERE project_id = $1`, projectID)
+	return err
+}
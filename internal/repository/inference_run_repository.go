@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// runs kept per project, older runs are deleted when a new one is stored
+const maxInferenceRuns = 100
+
+// represents a shard key inference run
+type InferenceRun struct {
+	ID                 string    `json:"id"`
+	ProjectID          string    `json:"project_id"`
+	WorkloadStatements int64     `json:"workload_statements"`
+	CreatedAt          time.Time `json:"created_at"`
+
+	// tables the run ranked candidates for
+	Tables int `json:"tables"`
+
+	// profile the run scored with, nil for runs stored before profiles
+	Profile *InferenceProfile `json:"profile,omitempty"`
+
+	Candidates []InferenceCandidate `json:"candidates,omitempty"`
+}
+
+// represents a ranked shard key candidate of a run
+type InferenceCandidate struct {
+	TableName  string   `json:"table_name"`
+	Rank       int      `json:"rank"`
+	ColumnName string   `json:"column_name"`
+	Score      int      `json:"score"`
+	Reasons    []string `json:"reasons"`
+	Selected   bool     `json:"selected"`
+}
+
+type InferenceRunRepository struct {
+	db *sql.DB
+}
+
+func NewInferenceRunRepository(db *sql.DB) *InferenceRunRepository {
+	return &InferenceRunRepository{db: db}
+}
+
+// func to store a run with its candidates and drop the oldest runs beyond the limit
+func (r *InferenceRunRepository) InferenceRunCreate(ctx context.Context, run *InferenceRun) error {
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	run.ID = uuid.New().String()
+	run.CreatedAt = time.Now()
+
+	// sent as text, lib/pq would encode bytes as bytea
+	var profile sql.NullString
+	if run.Profile != nil {
+		data, err := json.Marshal(run.Profile)
+		if err != nil {
+			return err
+		}
+		profile = sql.NullString{String: string(data), Valid: true}
+	}
+
+	runQuery := `
+		INSERT INTO inference_runs
+		(id, project_id, workload_statements, profile, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	if _, err := tx.ExecContext(ctx, runQuery, run.ID, run.ProjectID, run.WorkloadStatements, profile, run.CreatedAt); err != nil {
+		return err
+	}
+
+	candidateQuery := `
+		INSERT INTO inference_candidates
+		(run_id, table_name, rank, column_name, score, reasons, selected)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	tables := make(map[string]struct{})
+
+	for _, c := range run.Candidates {
+		if _, err := tx.ExecContext(
+			ctx,
+			candidateQuery,
+			run.ID,
+			c.TableName,
+			c.Rank,
+			c.ColumnName,
+			c.Score,
+			pq.Array(c.Reasons),
+			c.Selected,
+		); err != nil {
+			return err
+		}
+		tables[c.TableName] = struct{}{}
+	}
+	run.Tables = len(tables)
+
+	pruneQuery := `
+		DELETE FROM inference_runs
+		WHERE project_id = $1
+		  AND id NOT IN (
+			SELECT id FROM inference_runs
+			WHERE project_id = $1
+			ORDER BY created_at DESC
+			LIMIT $2
+		  )
+	`
+	if _, err := tx.ExecContext(ctx, pruneQuery, run.ProjectID, maxInferenceRuns); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// func to list the runs of a project, newest first, without candidates
+func (r *InferenceRunRepository) InferenceRunList(ctx context.Context, projectID string, limit int) ([]InferenceRun, error) {
+
+	query := `
+		SELECT
+			r.id,
+			r.project_id,
+			r.workload_statements,
+			r.created_at,
+			(SELECT COUNT(DISTINCT c.table_name) FROM inference_candidates c WHERE c.run_id = r.id)
+		FROM inference_runs r
+		WHERE r.project_id = $1
+		ORDER BY r.created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, projectID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := make([]InferenceRun, 0)
+
+	for rows.Next() {
+		var run InferenceRun
+		if err := rows.Scan(
+			&run.ID,
+			&run.ProjectID,
+			&run.WorkloadStatements,
+			&run.CreatedAt,
+			&run.Tables,
+		); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+// func to fetch a run of a project with all its candidates
+func (r *InferenceRunRepository) InferenceRunGet(ctx context.Context, projectID string, runID string) (*InferenceRun, error) {
+
+	runQuery := `
+		SELECT id, project_id, workload_statements, profile, created_at
+		FROM inference_runs
+		WHERE project_id = $1
+		  AND id = $2
+	`
+
+	var run InferenceRun
+	var profile sql.NullString
+	if err := r.db.QueryRowContext(ctx, runQuery, projectID, runID).Scan(
+		&run.ID,
+		&run.ProjectID,
+		&run.WorkloadStatements,
+		&profile,
+		&run.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if profile.Valid {
+		run.Profile = &InferenceProfile{}
+		if err := json.Unmarshal([]byte(profile.String), run.Profile); err != nil {
+			return nil, err
+		}
+	}
+
+	candidateQuery := `
+		SELECT table_name, rank, column_name, score, reasons, selected
+		FROM inference_candidates
+		WHERE run_id = $1
+		ORDER BY table_name, rank
+	`
+
+	rows, err := r.db.QueryContext(ctx, candidateQuery, run.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make(map[string]struct{})
+
+	for rows.Next() {
+		var c InferenceCandidate
+		if err := rows.Scan(
+			&c.TableName,
+			&c.Rank,
+			&c.ColumnName,
+			&c.Score,
+			pq.Array(&c.Reasons),
+			&c.Selected,
+		); err != nil {
+			return nil, err
+		}
+		run.Candidates = append(run.Candidates, c)
+		tables[c.TableName] = struct{}{}
+	}
+	run.Tables = len(tables)
+
+	return &run, rows.Err()
+}
+
+// func to fetch the latest run of a project
+func (r *InferenceRunRepository) InferenceRunLatest(ctx context.Context, projectID string) (*InferenceRun, error) {
+
+	var runID string
+
+	query := `
+		SELECT id
+		FROM inference_runs
+		WHERE project_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	if err := r.db.QueryRowContext(ctx, query, projectID).Scan(&runID); err != nil {
+		return nil, err
+	}
+
+	return r.InferenceRunGet(ctx, projectID, runID)
+}
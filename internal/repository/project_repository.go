@@ -236,6 +236,48 @@ func (r *ProjectRepository) GetProjectByID(ctx context.Context, id string) (Proj
 	return p, nil
 }
 
+// fetches the tenant column of a project, empty when tenant isolation is off
+func (r *ProjectRepository) FetchTenantColumn(ctx context.Context, projectID string) (string, error) {
+
+	query := `
+		SELECT COALESCE(tenant_column, '') FROM projects WHERE id = $1
+	`
+
+	var column string
+
+	err := r.db.QueryRowContext(ctx, query, projectID).Scan(&column)
+	if err != nil {
+		return "", err
+	}
+
+	return column, nil
+}
+
+// sets the tenant column of a project, empty turns tenant isolation off
+func (r *ProjectRepository) SetTenantColumn(ctx context.Context, projectID string, column string) error {
+
+	query := `
+		UPDATE projects
+		SET tenant_column = NULLIF($1, '')
+		WHERE id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, column, projectID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
 func (r *ProjectRepository) FetchActiveProject(ctx context.Context) (string, error) {
 
 	query := `
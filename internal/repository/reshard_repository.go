@@ -0,0 +1,518 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrShardMapChanged is returned when a cutover finds the shard map changed
+// since the job was planned
+var ErrShardMapChanged = errors.New("shard map changed since the job was planned")
+
+// represents a resharding job
+type ReshardJob struct {
+	ID        string `json:"id"`
+	ProjectID string `json:"project_id"`
+	Kind      string `json:"kind"`
+
+	// the shard keys move to or from
+	ShardID string `json:"shard_id"`
+
+	Status     string `json:"status"`
+	Phase      string `json:"phase"`
+	MapVersion int64  `json:"map_version"`
+	Error      string `json:"error,omitempty"`
+
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// represents a hash slot changing owner
+type SlotMove struct {
+	Slot          int    `json:"slot"`
+	SourceShardID string `json:"source_shard_id"`
+	TargetShardID string `json:"target_shard_id"`
+}
+
+// represents the progress of one table pass on one shard
+type ReshardCheckpoint struct {
+	ShardID     string    `json:"shard_id"`
+	TableName   string    `json:"table_name"`
+	Phase       string    `json:"phase"`
+	LastKey     []string  `json:"last_key,omitempty"`
+	RowsScanned int64     `json:"rows_scanned"`
+	RowsWritten int64     `json:"rows_written"`
+	Done        bool      `json:"done"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ReshardRepository struct {
+	db *sql.DB
+}
+
+func NewReshardRepository(db *sql.DB) *ReshardRepository {
+	return &ReshardRepository{db: db}
+}
+
+const reshardJobColumns = `
+	id, project_id, kind, shard_id, status, phase, map_version,
+	COALESCE(error, ''), created_at, updated_at, completed_at
+`
+
+func scanReshardJob(row interface{ Scan(...any) error }) (*ReshardJob, error) {
+
+	var job ReshardJob
+	var completedAt sql.NullTime
+
+	err := row.Scan(
+		&job.ID,
+		&job.ProjectID,
+		&job.Kind,
+		&job.ShardID,
+		&job.Status,
+		&job.Phase,
+		&job.MapVersion,
+		&job.Error,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&completedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if completedAt.Valid {
+		job.CompletedAt = &completedAt.Time
+	}
+
+	return &job, nil
+}
+
+// func to store a planned job with the slots it moves
+func (r *ReshardRepository) ReshardJobCreate(ctx context.Context, job *ReshardJob, moves []SlotMove) error {
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	job.ID = uuid.New().String()
+	job.Status = "pending"
+	job.Phase = "planned"
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = job.CreatedAt
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO reshard_jobs
+		 (id, project_id, kind, shard_id, status, phase, map_version, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)`,
+		job.ID,
+		job.ProjectID,
+		job.Kind,
+		job.ShardID,
+		job.Status,
+		job.Phase,
+		job.MapVersion,
+		job.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range moves {
+		_, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO reshard_moves (job_id, slot, source_shard_id, target_shard_id)
+			 VALUES ($1, $2, $3, $4)`,
+			job.ID,
+			m.Slot,
+			m.SourceShardID,
+			m.TargetShardID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// func to fetch a job
+func (r *ReshardRepository) ReshardJobGet(ctx context.Context, jobID string) (*ReshardJob, error) {
+
+	query := `SELECT ` + reshardJobColumns + ` FROM reshard_jobs WHERE id = $1`
+
+	return scanReshardJob(r.db.QueryRowContext(ctx, query, jobID))
+}
+
+// func to fetch the pending, running or failed job of a project, sql.ErrNoRows when there is none
+func (r *ReshardRepository) ReshardJobUnfinished(ctx context.Context, projectID string) (*ReshardJob, error) {
+
+	query := `
+		SELECT ` + reshardJobColumns + `
+		FROM reshard_jobs
+		WHERE project_id = $1
+		  AND status IN ('pending', 'running', 'failed')
+	`
+
+	return scanReshardJob(r.db.QueryRowContext(ctx, query, projectID))
+}
+
+// func to fetch the latest job of the given kinds for a shard, sql.ErrNoRows when there is none
+func (r *ReshardRepository) ReshardJobLatest(ctx context.Context, shardID string, kinds []string) (*ReshardJob, error) {
+
+	query := `
+		SELECT ` + reshardJobColumns + `
+		FROM reshard_jobs
+		WHERE shard_id = $1
+		  AND kind = ANY($2)
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	return scanReshardJob(r.db.QueryRowContext(ctx, query, shardID, pq.Array(kinds)))
+}
+
+// func to list the jobs of a project, newest first
+func (r *ReshardRepository) ReshardJobList(ctx context.Context, projectID string) ([]ReshardJob, error) {
+
+	query := `
+		SELECT ` + reshardJobColumns + `
+		FROM reshard_jobs
+		WHERE project_id = $1
+		ORDER BY created_at DESC
+	`
+
+	return r.listJobs(ctx, query, projectID)
+}
+
+// func to list jobs left running, used to resume them after a restart
+func (r *ReshardRepository) ReshardJobListRunning(ctx context.Context) ([]ReshardJob, error) {
+
+	query := `
+		SELECT ` + reshardJobColumns + `
+		FROM reshard_jobs
+		WHERE status = 'running'
+		ORDER BY created_at
+	`
+
+	return r.listJobs(ctx, query)
+}
+
+func (r *ReshardRepository) listJobs(ctx context.Context, query string, args ...any) ([]ReshardJob, error) {
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]ReshardJob, 0)
+
+	for rows.Next() {
+		job, err := scanReshardJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// func to mark a pending or failed job running, false when it is in another state
+func (r *ReshardRepository) ReshardJobClaim(ctx context.Context, jobID string) (bool, error) {
+
+	result, err := r.db.ExecContext(
+		ctx,
+		`UPDATE reshard_jobs
+		 SET status = 'running', error = NULL, updated_at = NOW()
+		 WHERE id = $1 AND status IN ('pending', 'failed')`,
+		jobID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+// func to move a job to its next phase
+func (r *ReshardRepository) ReshardJobSetPhase(ctx context.Context, jobID string, phase string) error {
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE reshard_jobs SET phase = $2, updated_at = NOW() WHERE id = $1`,
+		jobID,
+		phase,
+	)
+
+	return err
+}
+
+// func to mark a job failed, it keeps its phase and checkpoints to resume from
+func (r *ReshardRepository) ReshardJobFail(ctx context.Context, jobID string, message string) error {
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE reshard_jobs SET status = 'failed', error = $2, updated_at = NOW() WHERE id = $1`,
+		jobID,
+		message,
+	)
+
+	return err
+}
+
+// func to end a job as completed or cancelled
+func (r *ReshardRepository) ReshardJobFinish(ctx context.Context, jobID string, status string) error {
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE reshard_jobs
+		 SET status = $2, phase = 'done', error = NULL, updated_at = NOW(), completed_at = NOW()
+		 WHERE id = $1`,
+		jobID,
+		status,
+	)
+
+	return err
+}
+
+// func to list the slots a job moves
+func (r *ReshardRepository) ReshardMoves(ctx context.Context, jobID string) ([]SlotMove, error) {
+
+	query := `
+		SELECT slot, source_shard_id, target_shard_id
+		FROM reshard_moves
+		WHERE job_id = $1
+		ORDER BY slot
+	`
+
+	return r.listMoves(ctx, query, jobID)
+}
+
+// func to list the slots moving in the running job of a project and the
+// phase of that job, no moves while no job copies or cuts over
+func (r *ReshardRepository) ReshardActiveMoves(ctx context.Context, projectID string) (string, []SlotMove, error) {
+
+	var jobID, phase string
+
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT id, phase FROM reshard_jobs
+		 WHERE project_id = $1
+		   AND status = 'running'
+		   AND phase IN ('copying', 'catching_up', 'cutover')`,
+		projectID,
+	).Scan(&jobID, &phase)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	moves, err := r.ReshardMoves(ctx, jobID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return phase, moves, nil
+}
+
+func (r *ReshardRepository) listMoves(ctx context.Context, query string, args ...any) ([]SlotMove, error) {
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	moves := make([]SlotMove, 0)
+
+	for rows.Next() {
+		var m SlotMove
+		if err := rows.Scan(&m.Slot, &m.SourceShardID, &m.TargetShardID); err != nil {
+			return nil, err
+		}
+		moves = append(moves, m)
+	}
+
+	return moves, rows.Err()
+}
+
+// func to hand the moved slots to their new owners and move the job to
+// cleanup in one transaction
+func (r *ReshardRepository) ReshardCutover(
+	ctx context.Context,
+	job *ReshardJob,
+	owners []string,
+) (int64, error) {
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var version int64
+
+	err = tx.QueryRowContext(
+		ctx,
+		`UPDATE shard_maps
+		 SET owners = $2, version = version + 1, updated_at = NOW()
+		 WHERE project_id = $1 AND version = $3
+		 RETURNING version`,
+		job.ProjectID,
+		pq.Array(owners),
+		job.MapVersion,
+	).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrShardMapChanged
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE reshard_jobs SET phase = 'cleanup', map_version = $2, updated_at = NOW() WHERE id = $1`,
+		job.ID,
+		version,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, tx.Commit()
+}
+
+// func to fetch the checkpoint of a table pass, sql.ErrNoRows before the pass started
+func (r *ReshardRepository) ReshardCheckpointGet(
+	ctx context.Context,
+	jobID string,
+	shardID string,
+	table string,
+	phase string,
+) (*ReshardCheckpoint, error) {
+
+	query := `
+		SELECT shard_id, table_name, phase, last_key, rows_scanned, rows_written, done, updated_at
+		FROM reshard_checkpoints
+		WHERE job_id = $1 AND shard_id = $2 AND table_name = $3 AND phase = $4
+	`
+
+	var cp ReshardCheckpoint
+
+	err := r.db.QueryRowContext(ctx, query, jobID, shardID, table, phase).Scan(
+		&cp.ShardID,
+		&cp.TableName,
+		&cp.Phase,
+		pq.Array(&cp.LastKey),
+		&cp.RowsScanned,
+		&cp.RowsWritten,
+		&cp.Done,
+		&cp.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cp, nil
+}
+
+// func to store the progress of a table pass
+func (r *ReshardRepository) ReshardCheckpointSave(ctx context.Context, jobID string, cp *ReshardCheckpoint) error {
+
+	query := `
+		INSERT INTO reshard_checkpoints
+		(job_id, shard_id, table_name, phase, last_key, rows_scanned, rows_written, done, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		ON CONFLICT (job_id, shard_id, table_name, phase) DO UPDATE SET
+			last_key = EXCLUDED.last_key,
+			rows_scanned = EXCLUDED.rows_scanned,
+			rows_written = EXCLUDED.rows_written,
+			done = EXCLUDED.done,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.ExecContext(
+		ctx,
+		query,
+		jobID,
+		cp.ShardID,
+		cp.TableName,
+		cp.Phase,
+		pq.Array(cp.LastKey),
+		cp.RowsScanned,
+		cp.RowsWritten,
+		cp.Done,
+	)
+
+	return err
+}
+
+// func to drop the checkpoints of a phase so its passes start over
+func (r *ReshardRepository) ReshardCheckpointReset(ctx context.Context, jobID string, phase string) error {
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM reshard_checkpoints WHERE job_id = $1 AND phase = $2`,
+		jobID,
+		phase,
+	)
+
+	return err
+}
+
+// func to list every checkpoint of a job
+func (r *ReshardRepository) ReshardCheckpointList(ctx context.Context, jobID string) ([]ReshardCheckpoint, error) {
+
+	query := `
+		SELECT shard_id, table_name, phase, last_key, rows_scanned, rows_written, done, updated_at
+		FROM reshard_checkpoints
+		WHERE job_id = $1
+		ORDER BY updated_at
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checkpoints := make([]ReshardCheckpoint, 0)
+
+	for rows.Next() {
+		var cp ReshardCheckpoint
+		err := rows.Scan(
+			&cp.ShardID,
+			&cp.TableName,
+			&cp.Phase,
+			pq.Array(&cp.LastKey),
+			&cp.RowsScanned,
+			&cp.RowsWritten,
+			&cp.Done,
+			&cp.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+
+	return checkpoints, rows.Err()
+}
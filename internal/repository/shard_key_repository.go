@@ -12,6 +12,13 @@ type ShardKeys struct {
 	ShardKeyColumn   string    `json:"shard_key_column"`
 	IsManualOverride bool      `json:"is_manual_override"`
 	UpdatedAt        time.Time `json:"updated_at"`
+
+	// column the latest inference run chose for the table
+	InferredColumn *string    `json:"inferred_column,omitempty"`
+	InferredAt     *time.Time `json:"inferred_at,omitempty"`
+
+	// manual override inference now disagrees with
+	Conflict bool `json:"conflict"`
 }
 
 type ShardKeyRecord struct {
@@ -39,9 +46,12 @@ func (s *ShardKeysRepository) FetchShardKeysByProjectID(
 			table_name,
 			shard_key_column,
 			is_manual_override,
-			updated_at
+			updated_at,
+			inferred_column,
+			inferred_at
 		FROM table_shard_keys
 		WHERE project_id = $1
+		ORDER BY table_name
 	`
 
 	rows, err := s.db.QueryContext(ctx, query, projectID)
@@ -60,9 +70,14 @@ func (s *ShardKeysRepository) FetchShardKeysByProjectID(
 			&key.ShardKeyColumn,
 			&key.IsManualOverride,
 			&key.UpdatedAt,
+			&key.InferredColumn,
+			&key.InferredAt,
 		); err != nil {
 			return nil, err
 		}
+		key.Conflict = key.IsManualOverride &&
+			key.InferredColumn != nil &&
+			*key.InferredColumn != key.ShardKeyColumn
 		keys = append(keys, key)
 	}
 
@@ -119,3 +134,122 @@ func (s *ShardKeysRepository) ReplaceShardKeysForProject(
 
 	return tx.Commit()
 }
+
+// func to merge an inference run into the stored keys, manual overrides keep
+// their column and only record what inference chose
+func (s *ShardKeysRepository) MergeInferredShardKeys(
+	ctx context.Context,
+	projectID string,
+	records []ShardKeyRecord,
+) error {
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	deleteQuery := `
+		DELETE FROM table_shard_keys
+		WHERE project_id = $1
+		  AND is_manual_override = FALSE
+	`
+	if _, err := tx.ExecContext(ctx, deleteQuery, projectID); err != nil {
+		return err
+	}
+
+	// overrides of tables inference no longer decides have nothing to conflict with
+	clearQuery := `
+		UPDATE table_shard_keys
+		SET inferred_column = NULL,
+		    inferred_at = NULL
+		WHERE project_id = $1
+	`
+	if _, err := tx.ExecContext(ctx, clearQuery, projectID); err != nil {
+		return err
+	}
+
+	insertQuery := `
+		INSERT INTO table_shard_keys
+		(project_id, table_name, shard_key_column, is_manual_override, updated_at, inferred_column, inferred_at)
+		VALUES ($1, $2, $3, FALSE, $4, $3, $4)
+		ON CONFLICT (project_id, table_name)
+		DO UPDATE SET
+		  inferred_column = EXCLUDED.inferred_column,
+		  inferred_at = EXCLUDED.inferred_at
+	`
+
+	now := time.Now()
+
+	for _, r := range records {
+		if _, err := tx.ExecContext(
+			ctx,
+			insertQuery,
+			projectID,
+			r.TableName,
+			r.ShardKeyColumn,
+			now,
+		); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// func to pin the key of a table as a manual override, an empty column keeps
+// the current one
+func (s *ShardKeysRepository) LockShardKey(
+	ctx context.Context,
+	projectID string,
+	tableName string,
+	column string,
+) error {
+
+	query := `
+		INSERT INTO table_shard_keys
+		(project_id, table_name, shard_key_column, is_manual_override, updated_at)
+		VALUES ($1, $2, $3, TRUE, $4)
+		ON CONFLICT (project_id, table_name)
+		DO UPDATE SET
+		  shard_key_column = COALESCE(NULLIF(EXCLUDED.shard_key_column, ''), table_shard_keys.shard_key_column),
+		  is_manual_override = TRUE,
+		  updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := s.db.ExecContext(ctx, query, projectID, tableName, column, time.Now())
+	return err
+}
+
+// func to release a manual override, the table goes back to the inferred
+// column when inference decided one
+func (s *ShardKeysRepository) UnlockShardKey(
+	ctx context.Context,
+	projectID string,
+	tableName string,
+) error {
+
+	query := `
+		UPDATE table_shard_keys
+		SET is_manual_override = FALSE,
+		    shard_key_column = COALESCE(inferred_column, shard_key_column),
+		    updated_at = $3
+		WHERE project_id = $1
+		  AND table_name = $2
+	`
+
+	res, err := s.db.ExecContext(ctx, query, projectID, tableName, time.Now())
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
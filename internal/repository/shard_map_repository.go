@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// represents the hash slot ownership of a project, Owners[slot] is the
+// shard holding keys hashing to that slot
+type ShardMap struct {
+	ProjectID string    `json:"project_id"`
+	Owners    []string  `json:"owners"`
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ShardMapRepository struct {
+	db *sql.DB
+}
+
+func NewShardMapRepository(db *sql.DB) *ShardMapRepository {
+	return &ShardMapRepository{db: db}
+}
+
+// func to fetch the shard map of a project, sql.ErrNoRows while the
+// project still routes by modulo over its active shards
+func (r *ShardMapRepository) ShardMapGet(ctx context.Context, projectID string) (*ShardMap, error) {
+
+	query := `
+		SELECT project_id, owners, version, updated_at
+		FROM shard_maps
+		WHERE project_id = $1
+	`
+
+	var m ShardMap
+
+	err := r.db.QueryRowContext(ctx, query, projectID).Scan(
+		&m.ProjectID,
+		pq.Array(&m.Owners),
+		&m.Version,
+		&m.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// func to store the first shard map of a project, an existing map is kept
+// and returned instead
+func (r *ShardMapRepository) ShardMapCreate(ctx context.Context, projectID string, owners []string) (*ShardMap, error) {
+
+	query := `
+		INSERT INTO shard_maps (project_id, owners)
+		VALUES ($1, $2)
+		ON CONFLICT (project_id) DO NOTHING
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, projectID, pq.Array(owners)); err != nil {
+		return nil, err
+	}
+
+	return r.ShardMapGet(ctx, projectID)
+}
@@ -194,6 +194,21 @@ func (s *ShardRepository) ShardActivate(ctx context.Context, shardID string) err
 
 }
 
+// func to mark a shard draining, it keeps serving the hash slots it owns
+// until they have moved to other shards
+func (s *ShardRepository) ShardDrain(ctx context.Context, shardID string) error {
+	query := `
+		UPDATE shards SET status = 'draining' WHERE id = $1
+	`
+	_, err := s.shd.ExecContext(
+		ctx,
+		query,
+		shardID,
+	)
+
+	return err
+}
+
 // func to fecth status of a shard using its id
 func (s *ShardRepository) FetchShardStatus(ctx context.Context, shardID string) (string, error) {
 	query := `
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// represents a statement of a project workload and how often it ran
+type WorkloadQuery struct {
+	Fingerprint string `json:"fingerprint"`
+	Query       string `json:"query"`
+	Calls       int64  `json:"calls"`
+}
+
+type WorkloadRepository struct {
+	db *sql.DB
+}
+
+func NewWorkloadRepository(db *sql.DB) *WorkloadRepository {
+	return &WorkloadRepository{db: db}
+}
+
+// func to import statements, calls add up for statements already imported
+func (r *WorkloadRepository) WorkloadImport(ctx context.Context, projectID string, queries []WorkloadQuery) error {
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO workload_queries
+		(project_id, fingerprint, query, calls, imported_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (project_id, fingerprint)
+		DO UPDATE SET
+		  calls = workload_queries.calls + EXCLUDED.calls,
+		  imported_at = EXCLUDED.imported_at
+	`
+
+	now := time.Now()
+
+	for _, q := range queries {
+		if _, err := tx.ExecContext(ctx, query, projectID, q.Fingerprint, q.Query, q.Calls, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// func to list the workload of a project: imported statements plus
+// statements captured in the audit log since captureSince
+func (r *WorkloadRepository) WorkloadList(ctx context.Context, projectID string, captureSince time.Time) ([]WorkloadQuery, error) {
+
+	query := `
+		SELECT fingerprint, MIN(query), SUM(calls)::BIGINT
+		FROM (
+			SELECT fingerprint, query, calls
+			FROM workload_queries
+			WHERE project_id = $1
+
+			UNION ALL
+
+			SELECT fingerprint, MIN(normalized), COUNT(*)
+			FROM audit_log
+			WHERE project_id = $1
+			  AND event_type = 'query'
+			  AND normalized <> ''
+			  AND occurred_at >= $2
+			GROUP BY fingerprint
+		) workload
+		GROUP BY fingerprint
+		ORDER BY SUM(calls) DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, projectID, captureSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var queries []WorkloadQuery
+
+	for rows.Next() {
+		var q WorkloadQuery
+		if err := rows.Scan(&q.Fingerprint, &q.Query, &q.Calls); err != nil {
+			return nil, err
+		}
+		queries = append(queries, q)
+	}
+
+	return queries, rows.Err()
+}
+
+// func to delete the imported statements of a project
+func (r *WorkloadRepository) WorkloadClear(ctx context.Context, projectID string) error {
+
+	query := `
+		DELETE FROM workload_queries
+		WHERE project_id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, projectID)
+	return err
+}
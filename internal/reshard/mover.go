@@ -0,0 +1,735 @@
+package reshard
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"sql-sharding-v2/internal/connections"
+	"sql-sharding-v2/internal/metrics"
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/pkg/logger"
+)
+
+// job kinds
+const (
+	KindRebalance = "rebalance"
+	KindDrain     = "drain"
+	KindSplit     = "split"
+	KindMerge     = "merge"
+)
+
+// EmptiesShard reports whether a job kind moves every slot off its shard,
+// leaving it draining until it is deleted
+func EmptiesShard(kind string) bool {
+	return kind == KindDrain || kind == KindMerge
+}
+
+// job statuses
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+	StatusCompleted = "completed"
+)
+
+// job phases, in order
+const (
+	PhasePlanned    = "planned"
+	PhaseCopying    = "copying"
+	PhaseCatchingUp = "catching_up"
+	PhaseCutover    = "cutover"
+	PhaseCleanup    = "cleanup"
+	PhaseDone       = "done"
+
+	// checkpoint phase of rows removed from targets by a cancelled job
+	phaseCancel = "cancel"
+
+	// checkpoint phases counting the rows of moving slots at cutover
+	PhaseVerifySource = "verify_source"
+	PhaseVerifyTarget = "verify_target"
+)
+
+// how long statements routed just before a cutover may still be running
+const inflightGrace = 2 * time.Second
+
+// Settings tune how jobs move rows
+type Settings struct {
+	// rows read per batch
+	BatchSize int
+
+	// longest wait for transactions opened before a cutover
+	CutoverTimeout time.Duration
+}
+
+// Barrier waits for transactions that may write moving keys without
+// copying them to their new owner
+type Barrier interface {
+	WaitOpenedBefore(ctx context.Context, projectID string, t time.Time) error
+}
+
+// Mover copies the rows of moving hash slots between shards and hands the
+// slots over, persisting progress so a failed job resumes where it stopped
+type Mover struct {
+	repo     *repository.ReshardRepository
+	maps     *repository.ShardMapRepository
+	conns    *connections.ConnectionStore
+	barrier  Barrier
+	settings Settings
+}
+
+func NewMover(
+	repo *repository.ReshardRepository,
+	maps *repository.ShardMapRepository,
+	conns *connections.ConnectionStore,
+	barrier Barrier,
+	settings Settings,
+) *Mover {
+
+	if settings.BatchSize <= 0 {
+		settings.BatchSize = 500
+	}
+	if settings.CutoverTimeout <= 0 {
+		settings.CutoverTimeout = time.Minute
+	}
+
+	return &Mover{
+		repo:     repo,
+		maps:     maps,
+		conns:    conns,
+		barrier:  barrier,
+		settings: settings,
+	}
+}
+
+// work is what a run of a job needs, loaded once per run
+type work struct {
+	job   *repository.ReshardJob
+	moves map[int]repository.SlotMove
+	slots int
+
+	sources []string
+	targets []string
+
+	// parents before children
+	tables []*Table
+}
+
+// Run drives a running job from its phase to completion. On error the job
+// keeps its phase and checkpoints, the caller marks it failed.
+func (m *Mover) Run(ctx context.Context, job *repository.ReshardJob, keys []ShardKey) error {
+
+	w, err := m.prepare(ctx, job, keys)
+	if err != nil {
+		return err
+	}
+
+	for {
+		logger.Logger.Info("reshard job phase", "job_id", job.ID, "phase", job.Phase)
+
+		var next string
+
+		switch job.Phase {
+
+		case PhasePlanned:
+			next = PhaseCopying
+
+		case PhaseCopying:
+			if err := m.copyRows(ctx, w, PhaseCopying, false); err != nil {
+				return err
+			}
+			next = PhaseCatchingUp
+
+		case PhaseCatchingUp:
+			if err := m.syncRows(ctx, w, PhaseCatchingUp); err != nil {
+				return err
+			}
+			next = PhaseCutover
+
+		case PhaseCutover:
+			if err := m.cutover(ctx, w); err != nil {
+				return err
+			}
+			continue
+
+		case PhaseCleanup:
+			err := m.deleteRows(ctx, w, w.sources, PhaseCleanup, func(move repository.SlotMove, shardID string) bool {
+				return move.SourceShardID == shardID
+			})
+			if err != nil {
+				return err
+			}
+			if EmptiesShard(job.Kind) {
+				if err := m.checkEmpty(ctx, w, job.ShardID); err != nil {
+					return err
+				}
+			}
+			return m.repo.ReshardJobFinish(ctx, job.ID, StatusCompleted)
+
+		default:
+			return fmt.Errorf("reshard job %s cannot run in phase %s", job.ID, job.Phase)
+		}
+
+		if err := m.repo.ReshardJobSetPhase(ctx, job.ID, next); err != nil {
+			return err
+		}
+		job.Phase = next
+	}
+}
+
+// Cancel removes the rows a job copied to its targets and ends it, only
+// possible before the cutover
+func (m *Mover) Cancel(ctx context.Context, job *repository.ReshardJob, keys []ShardKey) error {
+
+	switch job.Phase {
+	case PhaseCutover, PhaseCleanup, PhaseDone:
+		return fmt.Errorf("reshard job in phase %s can no longer be cancelled", job.Phase)
+	}
+
+	if job.Phase != PhasePlanned {
+
+		w, err := m.prepare(ctx, job, keys)
+		if err != nil {
+			return err
+		}
+
+		err = m.deleteRows(ctx, w, w.targets, phaseCancel, func(move repository.SlotMove, shardID string) bool {
+			return move.TargetShardID == shardID
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return m.repo.ReshardJobFinish(ctx, job.ID, StatusCancelled)
+}
+
+// prepare loads the moves of a job and the tables it copies, every table
+// must exist on every shard involved
+func (m *Mover) prepare(ctx context.Context, job *repository.ReshardJob, keys []ShardKey) (*work, error) {
+
+	moves, err := m.repo.ReshardMoves(ctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	shardMap, err := m.maps.ShardMapGet(ctx, job.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &work{
+		job:   job,
+		moves: make(map[int]repository.SlotMove, len(moves)),
+		slots: len(shardMap.Owners),
+	}
+
+	sources := make(map[string]struct{})
+	targets := make(map[string]struct{})
+
+	for _, move := range moves {
+		w.moves[move.Slot] = move
+		sources[move.SourceShardID] = struct{}{}
+		targets[move.TargetShardID] = struct{}{}
+	}
+
+	w.sources = sortedKeys(sources)
+	w.targets = sortedKeys(targets)
+
+	if len(w.sources) == 0 {
+		return w, nil
+	}
+
+	for _, key := range keys {
+
+		var table *Table
+
+		for _, shardID := range append(append([]string{}, w.sources...), w.targets...) {
+
+			db, err := m.conns.Get(job.ProjectID, shardID)
+			if err != nil {
+				return nil, fmt.Errorf("shard %s: %w", shardID, err)
+			}
+
+			t, err := LoadTable(ctx, db, key)
+			if err != nil {
+				return nil, fmt.Errorf("shard %s: %w", shardID, err)
+			}
+
+			if table == nil {
+				table = t
+			}
+		}
+
+		w.tables = append(w.tables, table)
+	}
+
+	return w, nil
+}
+
+// copyRows copies the rows of moving slots from every source to their target
+func (m *Mover) copyRows(ctx context.Context, w *work, phase string, overwrite bool) error {
+
+	for _, t := range w.tables {
+
+		positions := t.rowColumns()
+		keyIndex := indexOf(positions, t.key)
+
+		for _, source := range w.sources {
+
+			err := m.pass(ctx, w, t, source, phase, positions, func(_ *sql.DB, rows []row) (int64, error) {
+
+				byTarget := make(map[string][]row)
+
+				for _, r := range rows {
+					slot, err := t.slotOf(r, keyIndex, w.slots)
+					if err != nil {
+						return 0, err
+					}
+
+					move, ok := w.moves[slot]
+					if !ok || move.SourceShardID != source {
+						continue
+					}
+					byTarget[move.TargetShardID] = append(byTarget[move.TargetShardID], r)
+				}
+
+				var written int64
+
+				for target, batch := range byTarget {
+					db, err := m.conns.Get(w.job.ProjectID, target)
+					if err != nil {
+						return written, err
+					}
+
+					existing, err := t.lookup(ctx, db, t.keyColumns(), batch)
+					if err != nil {
+						return written, err
+					}
+
+					key, err := w.conflict(t, source, target, existing)
+					if err != nil {
+						return written, err
+					}
+					if key != nil {
+						return written, fmt.Errorf(
+							"shard %s already holds a row with primary key (%s) outside the moving slots, the primary key must be unique across shards",
+							target, strings.Join(key, ", "))
+					}
+
+					n, err := t.insert(ctx, db, batch, overwrite)
+					written += n
+					if err != nil {
+						return written, err
+					}
+				}
+
+				return written, nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// conflict returns the primary key of a target row, read with keyColumns,
+// that is not a copy of a row moving from source to target. Copying over it
+// would drop the moved row or overwrite the row the target owns.
+func (w *work) conflict(t *Table, source string, target string, existing []row) ([]string, error) {
+
+	keyIndex := len(t.primaryKey)
+
+	for _, r := range existing {
+		slot, err := t.slotOf(r, keyIndex, w.slots)
+		if err != nil {
+			return nil, err
+		}
+
+		move, ok := w.moves[slot]
+		if ok && move.SourceShardID == source && move.TargetShardID == target {
+			continue
+		}
+		return t.primaryKeyOf(r), nil
+	}
+
+	return nil, nil
+}
+
+// syncRows makes the targets match the sources for every moving slot,
+// overwriting copied rows and deleting those gone from the source
+func (m *Mover) syncRows(ctx context.Context, w *work, phase string) error {
+
+	if err := m.copyRows(ctx, w, phase, true); err != nil {
+		return err
+	}
+
+	// children before parents
+	for i := len(w.tables) - 1; i >= 0; i-- {
+
+		t := w.tables[i]
+		positions := t.keyColumns()
+		keyIndex := len(positions) - 1
+
+		for _, target := range w.targets {
+
+			err := m.pass(ctx, w, t, target, phase, positions, func(db *sql.DB, rows []row) (int64, error) {
+
+				bySource := make(map[string][]row)
+
+				for _, r := range rows {
+					slot, err := t.slotOf(r, keyIndex, w.slots)
+					if err != nil {
+						return 0, err
+					}
+
+					move, ok := w.moves[slot]
+					if !ok || move.TargetShardID != target {
+						continue
+					}
+					bySource[move.SourceShardID] = append(bySource[move.SourceShardID], r)
+				}
+
+				var deleted int64
+
+				for source, batch := range bySource {
+					sourceDB, err := m.conns.Get(w.job.ProjectID, source)
+					if err != nil {
+						return deleted, err
+					}
+
+					existing, err := t.existingKeys(ctx, sourceDB, batch)
+					if err != nil {
+						return deleted, err
+					}
+
+					gone := make([]row, 0)
+					for _, r := range batch {
+						if !existing[joinKey(t.primaryKeyOf(r))] {
+							gone = append(gone, r)
+						}
+					}
+
+					if len(gone) == 0 {
+						continue
+					}
+
+					n, err := t.deleteKeys(ctx, db, gone)
+					deleted += n
+					if err != nil {
+						return deleted, err
+					}
+				}
+
+				return deleted, nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// cutover holds back writes to the moving slots, syncs them a last time
+// and hands them to their targets
+func (m *Mover) cutover(ctx context.Context, w *work) error {
+
+	job := w.job
+
+	// writes were not held back while the job was not running
+	for _, phase := range []string{PhaseCutover, PhaseVerifySource, PhaseVerifyTarget} {
+		if err := m.repo.ReshardCheckpointReset(ctx, job.ID, phase); err != nil {
+			return err
+		}
+	}
+
+	frozen := time.Now()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(inflightGrace):
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, m.settings.CutoverTimeout)
+	err := m.barrier.WaitOpenedBefore(waitCtx, job.ProjectID, frozen)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("transactions opened before the cutover are still open: %w", err)
+	}
+
+	if err := m.syncRows(ctx, w, PhaseCutover); err != nil {
+		return err
+	}
+
+	if err := m.verifyRows(ctx, w); err != nil {
+		return err
+	}
+
+	shardMap, err := m.maps.ShardMapGet(ctx, job.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	owners := append([]string{}, shardMap.Owners...)
+	for slot, move := range w.moves {
+		owners[slot] = move.TargetShardID
+	}
+
+	version, err := m.repo.ReshardCutover(ctx, job, owners)
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.Info("reshard job cut over", "job_id", job.ID, "slots", len(w.moves), "map_version", version)
+
+	job.Phase = PhaseCleanup
+	job.MapVersion = version
+
+	return nil
+}
+
+// verifyRows counts the rows of every moving slot on its source and its
+// target, writes are held back so both sides must hold the same rows
+func (m *Mover) verifyRows(ctx context.Context, w *work) error {
+
+	count := func(t *Table, shardID string, phase string, match func(move repository.SlotMove) bool) error {
+
+		positions := t.keyColumns()
+		keyIndex := len(positions) - 1
+
+		return m.pass(ctx, w, t, shardID, phase, positions, func(_ *sql.DB, rows []row) (int64, error) {
+
+			var n int64
+			for _, r := range rows {
+				slot, err := t.slotOf(r, keyIndex, w.slots)
+				if err != nil {
+					return n, err
+				}
+				if move, ok := w.moves[slot]; ok && match(move) {
+					n++
+				}
+			}
+			return n, nil
+		})
+	}
+
+	for _, t := range w.tables {
+
+		for _, source := range w.sources {
+			err := count(t, source, PhaseVerifySource, func(move repository.SlotMove) bool {
+				return move.SourceShardID == source
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, target := range w.targets {
+			err := count(t, target, PhaseVerifyTarget, func(move repository.SlotMove) bool {
+				return move.TargetShardID == target
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	checkpoints, err := m.repo.ReshardCheckpointList(ctx, w.job.ID)
+	if err != nil {
+		return err
+	}
+
+	onSources := make(map[string]int64)
+	onTargets := make(map[string]int64)
+
+	for _, cp := range checkpoints {
+		switch cp.Phase {
+		case PhaseVerifySource:
+			onSources[cp.TableName] += cp.RowsWritten
+		case PhaseVerifyTarget:
+			onTargets[cp.TableName] += cp.RowsWritten
+		}
+	}
+
+	for _, t := range w.tables {
+		if onSources[t.Name] != onTargets[t.Name] {
+			return fmt.Errorf("table %s has %d rows of moving slots on the sources but %d on the targets",
+				t.Name, onSources[t.Name], onTargets[t.Name])
+		}
+	}
+
+	return nil
+}
+
+// checkEmpty makes sure a drained shard holds no rows of sharded tables
+func (m *Mover) checkEmpty(ctx context.Context, w *work, shardID string) error {
+
+	db, err := m.conns.Get(w.job.ProjectID, shardID)
+	if err != nil {
+		return fmt.Errorf("shard %s: %w", shardID, err)
+	}
+
+	for _, t := range w.tables {
+
+		n, err := t.count(ctx, db)
+		if err != nil {
+			return fmt.Errorf("table %s on shard %s: %w", t.Name, shardID, err)
+		}
+
+		if n > 0 {
+			return fmt.Errorf("table %s still has %d rows on drained shard %s outside the moved slots", t.Name, n, shardID)
+		}
+	}
+
+	return nil
+}
+
+// deleteRows deletes the rows of moving slots from the given shards when
+// match accepts the move of their slot, children before parents
+func (m *Mover) deleteRows(
+	ctx context.Context,
+	w *work,
+	shardIDs []string,
+	phase string,
+	match func(move repository.SlotMove, shardID string) bool,
+) error {
+
+	for i := len(w.tables) - 1; i >= 0; i-- {
+
+		t := w.tables[i]
+		positions := t.keyColumns()
+		keyIndex := len(positions) - 1
+
+		for _, shardID := range shardIDs {
+
+			err := m.pass(ctx, w, t, shardID, phase, positions, func(db *sql.DB, rows []row) (int64, error) {
+
+				moved := make([]row, 0)
+
+				for _, r := range rows {
+					slot, err := t.slotOf(r, keyIndex, w.slots)
+					if err != nil {
+						return 0, err
+					}
+
+					if move, ok := w.moves[slot]; ok && match(move, shardID) {
+						moved = append(moved, r)
+					}
+				}
+
+				if len(moved) == 0 {
+					return 0, nil
+				}
+
+				return t.deleteKeys(ctx, db, moved)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// pass scans a table on a shard in batches from its checkpoint, handing
+// each batch to fn which returns the rows it wrote or deleted
+func (m *Mover) pass(
+	ctx context.Context,
+	w *work,
+	t *Table,
+	shardID string,
+	phase string,
+	positions []int,
+	fn func(db *sql.DB, rows []row) (int64, error),
+) error {
+
+	job := w.job
+
+	cp, err := m.repo.ReshardCheckpointGet(ctx, job.ID, shardID, t.Name, phase)
+	if errors.Is(err, sql.ErrNoRows) {
+		cp = &repository.ReshardCheckpoint{
+			ShardID:   shardID,
+			TableName: t.Name,
+			Phase:     phase,
+		}
+	} else if err != nil {
+		return err
+	}
+
+	if cp.Done {
+		return nil
+	}
+
+	db, err := m.conns.Get(job.ProjectID, shardID)
+	if err != nil {
+		return fmt.Errorf("shard %s: %w", shardID, err)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rows, err := t.scan(ctx, db, positions, cp.LastKey, m.settings.BatchSize)
+		if err != nil {
+			return fmt.Errorf("table %s on shard %s: %w", t.Name, shardID, err)
+		}
+
+		if len(rows) == 0 {
+			break
+		}
+
+		n, err := fn(db, rows)
+		if n > 0 {
+			metrics.ObserveReshardRows(job.ProjectID, job.Kind, phase, n)
+		}
+		if err != nil {
+			return fmt.Errorf("table %s from shard %s: %w", t.Name, shardID, err)
+		}
+
+		cp.LastKey = t.primaryKeyOf(rows[len(rows)-1])
+		cp.RowsScanned += int64(len(rows))
+		cp.RowsWritten += n
+
+		if err := m.repo.ReshardCheckpointSave(ctx, job.ID, cp); err != nil {
+			return err
+		}
+
+		if len(rows) < m.settings.BatchSize {
+			break
+		}
+	}
+
+	cp.Done = true
+
+	return m.repo.ReshardCheckpointSave(ctx, job.ID, cp)
+}
+
+func indexOf(positions []int, position int) int {
+	for i, p := range positions {
+		if p == position {
+			return i
+		}
+	}
+	return -1
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,142 @@
+package reshard
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/router"
+)
+
+// orders has a serial id primary key, every shard numbers its rows from 1
+func ordersTable() *Table {
+	return &Table{
+		Name: "orders",
+		Columns: []Column{
+			{Name: "id", Type: "integer", TypeName: "int4"},
+			{Name: "customer_id", Type: "integer", TypeName: "int4"},
+		},
+		primaryKey: []int{0},
+		key:        1,
+	}
+}
+
+// customerIn returns a customer id whose rows hash to the given slot
+func customerIn(t *testing.T, slot int, slots int) string {
+	t.Helper()
+
+	for i := 1; i < 10000; i++ {
+		value, err := router.KeyValue("int4", fmt.Sprint(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if router.KeySlot(value, slots) == slot {
+			return fmt.Sprint(i)
+		}
+	}
+
+	t.Fatalf("no customer id in slot %d", slot)
+	return ""
+}
+
+func keyRow(values ...string) row {
+	r := make(row, len(values))
+	for i, v := range values {
+		r[i] = sql.NullString{String: v, Valid: true}
+	}
+	return r
+}
+
+func TestConflict(t *testing.T) {
+
+	const slots = 4
+
+	// slot 0 moves from a to b, slot 1 from c to b, b keeps slot 2
+	w := &work{
+		slots: slots,
+		moves: map[int]repository.SlotMove{
+			0: {Slot: 0, SourceShardID: "a", TargetShardID: "b"},
+			1: {Slot: 1, SourceShardID: "c", TargetShardID: "b"},
+		},
+	}
+
+	moving := customerIn(t, 0, slots)
+	fromOther := customerIn(t, 1, slots)
+	owned := customerIn(t, 2, slots)
+
+	tests := []struct {
+		name     string
+		existing []row
+		want     []string
+	}{
+		{
+			name: "no rows on the target",
+		},
+		{
+			name:     "rows copied by an earlier pass",
+			existing: []row{keyRow("1", moving), keyRow("2", moving)},
+		},
+		{
+			name:     "serial id the target already uses",
+			existing: []row{keyRow("1", moving), keyRow("2", owned)},
+			want:     []string{"2"},
+		},
+		{
+			name:     "serial id copied from another source",
+			existing: []row{keyRow("3", fromOther)},
+			want:     []string{"3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			got, err := w.conflict(ordersTable(), "a", "b", tt.existing)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConflictNeedsShardKey(t *testing.T) {
+
+	w := &work{slots: 4}
+
+	existing := []row{{{String: "1", Valid: true}, {}}}
+
+	if _, err := w.conflict(ordersTable(), "a", "b", existing); err == nil {
+		t.Fatal("expected an error for a row without shard key")
+	}
+}
+
+func TestConflictMergingIntoPopulatedShard(t *testing.T) {
+
+	const slots = 4
+
+	owners := []string{"a", "b", "a", "b"}
+
+	w := &work{slots: slots, moves: make(map[int]repository.SlotMove)}
+	for _, m := range PlanMerge(owners, "a", "b") {
+		w.moves[m.Slot] = m
+	}
+
+	// both shards numbered their orders from 1
+	existing := []row{
+		keyRow("1", customerIn(t, 0, slots)),
+		keyRow("2", customerIn(t, 1, slots)),
+	}
+
+	got, err := w.conflict(ordersTable(), "a", "b", existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,219 @@
+package reshard
+
+import (
+	"sort"
+
+	"sql-sharding-v2/internal/repository"
+)
+
+// PlanRebalance moves slots to a shard owning none until it holds an even
+// share, taking them from the shards owning the most slots first
+func PlanRebalance(owners []string, target string) []repository.SlotMove {
+
+	owned := slotsByOwner(owners)
+	delete(owned, target)
+
+	share := len(owners) / (len(owned) + 1)
+
+	moves := make([]repository.SlotMove, 0, share)
+
+	for len(moves) < share {
+
+		source := largestOwner(owned)
+		if source == "" {
+			break
+		}
+
+		slots := owned[source]
+		slot := slots[len(slots)-1]
+		owned[source] = slots[:len(slots)-1]
+
+		moves = append(moves, repository.SlotMove{
+			Slot:          slot,
+			SourceShardID: source,
+			TargetShardID: target,
+		})
+	}
+
+	sort.Slice(moves, func(i, j int) bool {
+		return moves[i].Slot < moves[j].Slot
+	})
+
+	return moves
+}
+
+// PlanDrain moves every slot of a shard to the given targets, each slot
+// going to the target owning the fewest slots at that point
+func PlanDrain(owners []string, shardID string, targets []string) []repository.SlotMove {
+
+	owned := slotsByOwner(owners)
+
+	counts := make(map[string]int, len(targets))
+	for _, target := range targets {
+		if target != shardID {
+			counts[target] = len(owned[target])
+		}
+	}
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	moves := make([]repository.SlotMove, 0, len(owned[shardID]))
+
+	for _, slot := range owned[shardID] {
+
+		target := smallestOwner(counts)
+		counts[target]++
+
+		moves = append(moves, repository.SlotMove{
+			Slot:          slot,
+			SourceShardID: shardID,
+			TargetShardID: target,
+		})
+	}
+
+	return moves
+}
+
+// PlanSplit moves the upper half of a shard's slots, in slot order, to a
+// shard owning none
+func PlanSplit(owners []string, source string, target string) []repository.SlotMove {
+
+	slots := slotsByOwner(owners)[source]
+
+	moves := make([]repository.SlotMove, 0, len(slots)/2)
+
+	for _, slot := range slots[len(slots)-len(slots)/2:] {
+		moves = append(moves, repository.SlotMove{
+			Slot:          slot,
+			SourceShardID: source,
+			TargetShardID: target,
+		})
+	}
+
+	return moves
+}
+
+// PlanMerge moves every slot of a shard to another one
+func PlanMerge(owners []string, source string, target string) []repository.SlotMove {
+
+	if source == target {
+		return nil
+	}
+
+	return PlanDrain(owners, source, []string{target})
+}
+
+// OrderTables sorts shard keys parents first along foreign keys, tables
+// in cycles or unrelated tables follow by name
+func OrderTables(keys []ShardKey, edges []repository.FKEdges) []ShardKey {
+
+	byTable := make(map[string]ShardKey, len(keys))
+	for _, k := range keys {
+		byTable[k.Table] = k
+	}
+
+	parents := make(map[string]map[string]struct{}, len(keys))
+	for _, k := range keys {
+		parents[k.Table] = make(map[string]struct{})
+	}
+	for _, e := range edges {
+		_, child := byTable[e.ChildTable]
+		_, parent := byTable[e.ParentTable]
+		if child && parent && e.ChildTable != e.ParentTable {
+			parents[e.ChildTable][e.ParentTable] = struct{}{}
+		}
+	}
+
+	names := make([]string, 0, len(keys))
+	for name := range byTable {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ordered := make([]ShardKey, 0, len(keys))
+	placed := make(map[string]bool, len(keys))
+
+	for len(ordered) < len(names) {
+
+		progress := false
+
+		for _, name := range names {
+			if placed[name] {
+				continue
+			}
+
+			ready := true
+			for parent := range parents[name] {
+				if !placed[parent] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				ordered = append(ordered, byTable[name])
+				placed[name] = true
+				progress = true
+			}
+		}
+
+		// a cycle, place the first remaining table
+		if !progress {
+			for _, name := range names {
+				if !placed[name] {
+					ordered = append(ordered, byTable[name])
+					placed[name] = true
+					break
+				}
+			}
+		}
+	}
+
+	return ordered
+}
+
+// slotsByOwner lists the slots of every owner in ascending order
+func slotsByOwner(owners []string) map[string][]int {
+
+	owned := make(map[string][]int)
+	for slot, owner := range owners {
+		owned[owner] = append(owned[owner], slot)
+	}
+
+	return owned
+}
+
+// largestOwner returns the owner of the most slots, ties by shard ID
+func largestOwner(owned map[string][]int) string {
+
+	best := ""
+	for owner, slots := range owned {
+		if len(slots) == 0 {
+			continue
+		}
+		if best == "" ||
+			len(slots) > len(owned[best]) ||
+			(len(slots) == len(owned[best]) && owner < best) {
+			best = owner
+		}
+	}
+
+	return best
+}
+
+// smallestOwner returns the shard with the fewest slots, ties by shard ID
+func smallestOwner(counts map[string]int) string {
+
+	best := ""
+	for owner, n := range counts {
+		if best == "" ||
+			n < counts[best] ||
+			(n == counts[best] && owner < best) {
+			best = owner
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,182 @@
+package reshard
+
+import (
+	"reflect"
+	"testing"
+
+	"sql-sharding-v2/internal/repository"
+)
+
+func move(slot int, source string, target string) repository.SlotMove {
+	return repository.SlotMove{Slot: slot, SourceShardID: source, TargetShardID: target}
+}
+
+func TestPlanDrain(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		owners  []string
+		shardID string
+		targets []string
+		want    []repository.SlotMove
+	}{
+		{
+			name:    "slots go to the target owning the fewest",
+			owners:  []string{"a", "b", "c", "a", "b", "a"},
+			shardID: "a",
+			targets: []string{"b", "c"},
+			want:    []repository.SlotMove{move(0, "a", "c"), move(3, "a", "b"), move(5, "a", "c")},
+		},
+		{
+			name:    "ties go to the lowest shard id",
+			owners:  []string{"a", "a", "b", "c"},
+			shardID: "a",
+			targets: []string{"c", "b"},
+			want:    []repository.SlotMove{move(0, "a", "b"), move(1, "a", "c")},
+		},
+		{
+			name:    "the drained shard is no target",
+			owners:  []string{"a", "b"},
+			shardID: "a",
+			targets: []string{"a"},
+		},
+		{
+			name:    "a shard owning no slots moves nothing",
+			owners:  []string{"b", "b"},
+			shardID: "a",
+			targets: []string{"b"},
+			want:    []repository.SlotMove{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PlanDrain(tt.owners, tt.shardID, tt.targets)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanSplit(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		owners []string
+		want   []repository.SlotMove
+	}{
+		{
+			name:   "upper half in slot order",
+			owners: []string{"a", "b", "a", "a", "b", "a"},
+			want:   []repository.SlotMove{move(3, "a", "c"), move(5, "a", "c")},
+		},
+		{
+			name:   "an odd count keeps the extra slot",
+			owners: []string{"a", "a", "a"},
+			want:   []repository.SlotMove{move(2, "a", "c")},
+		},
+		{
+			name:   "a single slot stays",
+			owners: []string{"a", "b"},
+			want:   []repository.SlotMove{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PlanSplit(tt.owners, "a", "c")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanMerge(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		source string
+		target string
+		want   []repository.SlotMove
+	}{
+		{
+			name:   "every slot goes to the target",
+			source: "a",
+			target: "c",
+			want:   []repository.SlotMove{move(0, "a", "c"), move(2, "a", "c")},
+		},
+		{
+			name:   "a shard cannot merge into itself",
+			source: "a",
+			target: "a",
+		},
+	}
+
+	owners := []string{"a", "b", "a", "c"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PlanMerge(owners, tt.source, tt.target)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderTables(t *testing.T) {
+
+	key := func(table string) ShardKey {
+		return ShardKey{Table: table, Column: "customer_id"}
+	}
+	edge := func(child string, parent string) repository.FKEdges {
+		return repository.FKEdges{ChildTable: child, ParentTable: parent}
+	}
+
+	tests := []struct {
+		name  string
+		keys  []ShardKey
+		edges []repository.FKEdges
+		want  []string
+	}{
+		{
+			name:  "parents before children",
+			keys:  []ShardKey{key("order_items"), key("orders"), key("customers")},
+			edges: []repository.FKEdges{edge("order_items", "orders"), edge("orders", "customers")},
+			want:  []string{"customers", "orders", "order_items"},
+		},
+		{
+			name: "unrelated tables by name",
+			keys: []ShardKey{key("invoices"), key("events")},
+			want: []string{"events", "invoices"},
+		},
+		{
+			name:  "edges to unsharded tables and self references are ignored",
+			keys:  []ShardKey{key("orders"), key("employees")},
+			edges: []repository.FKEdges{edge("orders", "countries"), edge("employees", "employees")},
+			want:  []string{"employees", "orders"},
+		},
+		{
+			name:  "a cycle is broken by name",
+			keys:  []ShardKey{key("b"), key("a"), key("c")},
+			edges: []repository.FKEdges{edge("a", "b"), edge("b", "a"), edge("c", "a")},
+			want:  []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered := OrderTables(tt.keys, tt.edges)
+
+			got := make([]string, 0, len(ordered))
+			for _, k := range ordered {
+				got = append(got, k.Table)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
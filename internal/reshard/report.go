@@ -0,0 +1,136 @@
+package reshard
+
+import (
+	"sort"
+	"time"
+
+	"sql-sharding-v2/internal/repository"
+)
+
+// JobReport is a job with the slots it moves and the progress of its passes
+type JobReport struct {
+	repository.ReshardJob
+
+	Moves       []MoveSummary                  `json:"moves"`
+	Checkpoints []repository.ReshardCheckpoint `json:"checkpoints"`
+
+	// rows written to targets by the copy and catch-up passes
+	RowsCopied int64 `json:"rows_copied"`
+
+	// rows deleted from sources after the cutover
+	RowsCleaned int64 `json:"rows_cleaned"`
+
+	// rows of moving slots found on both sides at the cutover
+	RowsVerified int64 `json:"rows_verified"`
+}
+
+// DrainReport is the drain state of a shard
+type DrainReport struct {
+	ShardID string `json:"shard_id"`
+	Status  string `json:"status"`
+
+	// hash slots the shard still owns
+	Slots int `json:"slots"`
+
+	// a shard owning no slots holds no routed rows and may be deleted
+	Deletable bool `json:"deletable"`
+
+	// latest drain job of the shard, absent when it was never drained
+	Job *JobReport `json:"job,omitempty"`
+}
+
+// MoveSummary counts the slots moving from one shard to another
+type MoveSummary struct {
+	SourceShardID string `json:"source_shard_id"`
+	TargetShardID string `json:"target_shard_id"`
+	Slots         int    `json:"slots"`
+}
+
+// MapReport is the slot ownership of a project
+type MapReport struct {
+	ProjectID string       `json:"project_id"`
+	Version   int64        `json:"version"`
+	Slots     int          `json:"slots"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	Shards    []ShardSlots `json:"shards"`
+}
+
+// ShardSlots is the number of slots a shard owns and their share of the map
+type ShardSlots struct {
+	ShardID string  `json:"shard_id"`
+	Slots   int     `json:"slots"`
+	Share   float64 `json:"share"`
+}
+
+// DescribeJob builds the report of a job
+func DescribeJob(
+	job *repository.ReshardJob,
+	moves []repository.SlotMove,
+	checkpoints []repository.ReshardCheckpoint,
+) *JobReport {
+
+	report := &JobReport{
+		ReshardJob:  *job,
+		Moves:       make([]MoveSummary, 0),
+		Checkpoints: checkpoints,
+	}
+
+	index := make(map[[2]string]int)
+	for _, m := range moves {
+		key := [2]string{m.SourceShardID, m.TargetShardID}
+		i, ok := index[key]
+		if !ok {
+			i = len(report.Moves)
+			index[key] = i
+			report.Moves = append(report.Moves, MoveSummary{
+				SourceShardID: m.SourceShardID,
+				TargetShardID: m.TargetShardID,
+			})
+		}
+		report.Moves[i].Slots++
+	}
+
+	sources := make(map[string]bool)
+	for _, m := range moves {
+		sources[m.SourceShardID] = true
+	}
+
+	for _, cp := range checkpoints {
+		switch {
+		case cp.Phase == PhaseCleanup:
+			report.RowsCleaned += cp.RowsWritten
+		case sources[cp.ShardID] && (cp.Phase == PhaseCopying || cp.Phase == PhaseCatchingUp):
+			report.RowsCopied += cp.RowsWritten
+		case cp.Phase == PhaseVerifyTarget:
+			report.RowsVerified += cp.RowsWritten
+		}
+	}
+
+	return report
+}
+
+// DescribeMap builds the report of a shard map
+func DescribeMap(m *repository.ShardMap) *MapReport {
+
+	report := &MapReport{
+		ProjectID: m.ProjectID,
+		Version:   m.Version,
+		Slots:     len(m.Owners),
+		UpdatedAt: m.UpdatedAt,
+		Shards:    make([]ShardSlots, 0),
+	}
+
+	for owner, slots := range slotsByOwner(m.Owners) {
+		report.Shards = append(report.Shards, ShardSlots{
+			ShardID: owner,
+			Slots:   len(slots),
+			Share:   float64(len(slots)) / float64(len(m.Owners)),
+		})
+	}
+
+	sort.Slice(report.Shards, func(i, j int) bool {
+		return report.Shards[i].ShardID < report.Shards[j].ShardID
+	})
+
+	return report
+}
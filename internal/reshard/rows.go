@@ -0,0 +1,285 @@
+package reshard
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"sql-sharding-v2/internal/router"
+)
+
+// postgres accepts at most 65535 parameters per statement
+const maxParams = 65535
+
+// row is a row read as text, in the order of the positions it was read with
+type row []sql.NullString
+
+// scan reads up to limit rows after the given primary key in primary key
+// order. positions must start with the primary key.
+func (t *Table) scan(
+	ctx context.Context,
+	db *sql.DB,
+	positions []int,
+	after []string,
+	limit int,
+) ([]row, error) {
+
+	pk := t.primaryKey
+
+	var b strings.Builder
+	args := make([]any, 0, len(after))
+
+	fmt.Fprintf(&b, "SELECT %s FROM %s", t.columnList(positions, true), t.quotedName())
+
+	if len(after) == len(pk) {
+		fmt.Fprintf(&b, " WHERE (%s) > %s", t.columnList(pk, false), t.placeholders(pk, 1))
+		for _, v := range after {
+			args = append(args, v)
+		}
+	}
+
+	fmt.Fprintf(&b, " ORDER BY %s LIMIT %d", t.columnList(pk, false), limit)
+
+	rows, err := db.QueryContext(ctx, b.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]row, 0, limit)
+
+	for rows.Next() {
+		r := make(row, len(positions))
+		dest := make([]any, len(positions))
+		for i := range r {
+			dest[i] = &r[i]
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+
+	return result, rows.Err()
+}
+
+// count returns the number of rows of the table
+func (t *Table) count(ctx context.Context, db *sql.DB) (int64, error) {
+
+	var n int64
+	err := db.QueryRowContext(ctx, "SELECT count(*) FROM "+t.quotedName()).Scan(&n)
+
+	return n, err
+}
+
+// primaryKeyOf returns the primary key of a row read with primary key first
+func (t *Table) primaryKeyOf(r row) []string {
+	key := make([]string, 0, len(t.primaryKey))
+	for i := range t.primaryKey {
+		key = append(key, r[i].String)
+	}
+	return key
+}
+
+// slotOf returns the hash slot of a row, index is the position of the shard
+// key within the row
+func (t *Table) slotOf(r row, index int, slots int) (int, error) {
+
+	if !r[index].Valid {
+		return -1, fmt.Errorf("table %s has a row without shard key", t.Name)
+	}
+
+	value, err := router.KeyValue(t.Columns[t.key].TypeName, r[index].String)
+	if err != nil {
+		return -1, fmt.Errorf("table %s: %w", t.Name, err)
+	}
+
+	return router.KeySlot(value, slots), nil
+}
+
+// insert writes rows read with rowColumns, rows already present are kept
+// or, with overwrite, replaced
+func (t *Table) insert(ctx context.Context, db *sql.DB, rows []row, overwrite bool) (int64, error) {
+
+	positions := t.rowColumns()
+
+	conflict := "DO NOTHING"
+	if overwrite && len(positions) > len(t.primaryKey) {
+		sets := make([]string, 0, len(positions))
+		for _, i := range positions[len(t.primaryKey):] {
+			name := pq.QuoteIdentifier(t.Columns[i].Name)
+			sets = append(sets, name+" = EXCLUDED."+name)
+		}
+		conflict = "DO UPDATE SET " + strings.Join(sets, ", ")
+	}
+
+	var written int64
+
+	for _, chunk := range chunks(rows, maxParams/len(positions)) {
+
+		var b strings.Builder
+		args := make([]any, 0, len(chunk)*len(positions))
+
+		fmt.Fprintf(&b, "INSERT INTO %s (%s) OVERRIDING SYSTEM VALUE VALUES ",
+			t.quotedName(), t.columnList(positions, false))
+
+		for i, r := range chunk {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(t.placeholders(positions, len(args)+1))
+			for _, v := range r {
+				args = append(args, v)
+			}
+		}
+
+		fmt.Fprintf(&b, " ON CONFLICT (%s) %s", t.columnList(t.primaryKey, false), conflict)
+
+		result, err := db.ExecContext(ctx, b.String(), args...)
+		if err != nil {
+			return written, err
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return written, err
+		}
+		written += n
+	}
+
+	return written, nil
+}
+
+// deleteKeys deletes the rows with the given primary keys
+func (t *Table) deleteKeys(ctx context.Context, db *sql.DB, keys []row) (int64, error) {
+
+	var deleted int64
+
+	for _, chunk := range chunks(keys, maxParams/len(t.primaryKey)) {
+
+		query, args := t.keyFilter("DELETE FROM "+t.quotedName(), chunk)
+
+		result, err := db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return deleted, err
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += n
+	}
+
+	return deleted, nil
+}
+
+// existingKeys returns which of the given primary keys have a row
+func (t *Table) existingKeys(ctx context.Context, db *sql.DB, keys []row) (map[string]bool, error) {
+
+	rows, err := t.lookup(ctx, db, t.primaryKey, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool, len(rows))
+	for _, r := range rows {
+		existing[joinKey(t.primaryKeyOf(r))] = true
+	}
+
+	return existing, nil
+}
+
+// lookup reads the rows with the given primary keys, positions must start
+// with the primary key
+func (t *Table) lookup(ctx context.Context, db *sql.DB, positions []int, keys []row) ([]row, error) {
+
+	result := make([]row, 0, len(keys))
+
+	for _, chunk := range chunks(keys, maxParams/len(t.primaryKey)) {
+
+		query, args := t.keyFilter(
+			fmt.Sprintf("SELECT %s FROM %s", t.columnList(positions, true), t.quotedName()),
+			chunk,
+		)
+
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			r := make(row, len(positions))
+			dest := make([]any, len(r))
+			for i := range r {
+				dest[i] = &r[i]
+			}
+			if err := rows.Scan(dest...); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			result = append(result, r)
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// keyFilter appends a primary key IN list to a statement, keys start with
+// the primary key
+func (t *Table) keyFilter(statement string, keys []row) (string, []any) {
+
+	pk := t.primaryKey
+
+	var b strings.Builder
+	args := make([]any, 0, len(keys)*len(pk))
+
+	fmt.Fprintf(&b, "%s WHERE (%s) IN (", statement, t.columnList(pk, false))
+
+	for i, r := range keys {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(t.placeholders(pk, len(args)+1))
+		for j := range pk {
+			args = append(args, r[j])
+		}
+	}
+
+	b.WriteString(")")
+
+	return b.String(), args
+}
+
+func joinKey(key []string) string {
+	return strings.Join(key, "\x00")
+}
+
+// chunks splits rows into slices of at most size rows
+func chunks(rows []row, size int) [][]row {
+
+	if size < 1 {
+		size = 1
+	}
+
+	out := make([][]row, 0, len(rows)/size+1)
+	for len(rows) > size {
+		out = append(out, rows[:size])
+		rows = rows[size:]
+	}
+	if len(rows) > 0 {
+		out = append(out, rows)
+	}
+
+	return out
+}
@@ -0,0 +1,187 @@
+package reshard
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// keyTypes are the shard key types a job can move rows of. The text form a
+// shard returns for them is what statements write as literals, so rows hash
+// to the slot statements on them are routed to. Numeric scales, timestamps
+// and padded chars render differently and are rejected.
+var keyTypes = map[string]bool{
+	"int2":    true,
+	"int4":    true,
+	"int8":    true,
+	"text":    true,
+	"varchar": true,
+	"uuid":    true,
+}
+
+// ShardKey is a table a job moves rows of and its shard key column
+type ShardKey struct {
+	Table  string
+	Column string
+}
+
+// Column is a column as the shard catalog describes it
+type Column struct {
+	Name string
+
+	// format_type of the column, used to cast text parameters
+	Type string
+
+	// pg_type name, used to convert shard key values before hashing
+	TypeName string
+}
+
+// Table is a table with the columns needed to copy its rows
+type Table struct {
+	Name    string
+	Columns []Column
+
+	// positions of the primary key columns and the shard key column in Columns
+	primaryKey []int
+	key        int
+}
+
+// LoadTable reads the columns and primary key of a table from a shard
+func LoadTable(ctx context.Context, db *sql.DB, shardKey ShardKey) (*Table, error) {
+
+	query := `
+		SELECT
+			a.attname,
+			format_type(a.atttypid, a.atttypmod),
+			t.typname,
+			COALESCE(a.attnum = ANY(i.indkey), false)
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_type t ON t.oid = a.atttypid
+		LEFT JOIN pg_index i ON i.indrelid = c.oid AND i.indisprimary
+		WHERE n.nspname = current_schema()
+		  AND c.relname = $1
+		  AND a.attnum > 0
+		  AND NOT a.attisdropped
+		  AND a.attgenerated = ''
+		ORDER BY a.attnum
+	`
+
+	rows, err := db.QueryContext(ctx, query, shardKey.Table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	table := &Table{Name: shardKey.Table, key: -1}
+
+	for rows.Next() {
+		var col Column
+		var isPK bool
+
+		if err := rows.Scan(&col.Name, &col.Type, &col.TypeName, &isPK); err != nil {
+			return nil, err
+		}
+
+		if isPK {
+			table.primaryKey = append(table.primaryKey, len(table.Columns))
+		}
+		if strings.EqualFold(col.Name, shardKey.Column) {
+			table.key = len(table.Columns)
+		}
+
+		table.Columns = append(table.Columns, col)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(table.Columns) == 0:
+		return nil, fmt.Errorf("table %s does not exist on the shard", shardKey.Table)
+	case len(table.primaryKey) == 0:
+		return nil, fmt.Errorf("table %s has no primary key, rows cannot be copied in batches", shardKey.Table)
+	case table.key < 0:
+		return nil, fmt.Errorf("table %s has no shard key column %s", shardKey.Table, shardKey.Column)
+	case !keyTypes[table.Columns[table.key].TypeName]:
+		return nil, fmt.Errorf(
+			"shard key %s.%s has type %s, only integer, text, varchar and uuid keys can be resharded",
+			shardKey.Table, shardKey.Column, table.Columns[table.key].Type,
+		)
+	}
+
+	return table, nil
+}
+
+// PrimaryKey returns the names of the primary key columns
+func (t *Table) PrimaryKey() []string {
+	names := make([]string, 0, len(t.primaryKey))
+	for _, i := range t.primaryKey {
+		names = append(names, t.Columns[i].Name)
+	}
+	return names
+}
+
+func (t *Table) quotedName() string {
+	return pq.QuoteIdentifier(t.Name)
+}
+
+// columnList quotes the columns at the given positions
+func (t *Table) columnList(positions []int, asText bool) string {
+
+	parts := make([]string, 0, len(positions))
+	for _, i := range positions {
+		name := pq.QuoteIdentifier(t.Columns[i].Name)
+		if asText {
+			name += "::text"
+		}
+		parts = append(parts, name)
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// placeholders returns a row of typed parameters for the columns at the
+// given positions starting at parameter n
+func (t *Table) placeholders(positions []int, n int) string {
+
+	parts := make([]string, 0, len(positions))
+	for i, pos := range positions {
+		parts = append(parts, fmt.Sprintf("$%d::%s", n+i, t.Columns[pos].Type))
+	}
+
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// rowColumns returns the primary key followed by every other column
+func (t *Table) rowColumns() []int {
+
+	positions := append([]int{}, t.primaryKey...)
+
+	for i := range t.Columns {
+		if !t.isPrimaryKey(i) {
+			positions = append(positions, i)
+		}
+	}
+
+	return positions
+}
+
+// keyColumns returns the primary key followed by the shard key
+func (t *Table) keyColumns() []int {
+	return append(append([]int{}, t.primaryKey...), t.key)
+}
+
+func (t *Table) isPrimaryKey(position int) bool {
+	for _, i := range t.primaryKey {
+		if i == position {
+			return true
+		}
+	}
+	return false
+}
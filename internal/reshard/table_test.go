@@ -0,0 +1,96 @@
+package reshard
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+
+	"sql-sharding-v2/internal/router"
+)
+
+// slot of a literal as the router routes a statement on it
+func routedSlot(t *testing.T, literal string, ring *router.Ring) router.ShardID {
+	t.Helper()
+
+	tree, err := pg_query.Parse("SELECT * FROM items WHERE key = " + literal)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pred, rerr := router.ExtractShardPredicate(tree.Stmts[0].Stmt, "items", "key")
+	if rerr != nil {
+		t.Fatal(rerr)
+	}
+	if len(pred.Values) != 1 {
+		t.Fatalf("literal %s extracted %d values", literal, len(pred.Values))
+	}
+
+	return ring.LocateShard(router.NewHasher().Hash(pred.Values[0]))
+}
+
+func TestSlotOfMatchesRouter(t *testing.T) {
+
+	const slots = 64
+
+	owners := make([]router.ShardID, slots)
+	for i := range owners {
+		owners[i] = router.ShardID(fmt.Sprint(i))
+	}
+	ring := router.NewSlotRing(owners)
+
+	tests := []struct {
+		typeName string
+		literal  string
+
+		// the ::text rendering a shard returns for the stored value
+		text string
+	}{
+		{"int2", "7", "7"},
+		{"int2", "-32768", "-32768"},
+		{"int4", "42", "42"},
+		{"int4", "2147483647", "2147483647"},
+		{"int4", "-2147483648", "-2147483648"},
+		{"int8", "9999999999", "9999999999"},
+		{"int8", "-9223372036854775808", "-9223372036854775808"},
+		{"text", "'abc'", "abc"},
+		{"text", "'it''s'", "it's"},
+		{"text", "''", ""},
+		{"varchar", "'customer-17'", "customer-17"},
+		{"uuid", "'0f8fad5b-d9cb-469f-a165-70867728950e'", "0f8fad5b-d9cb-469f-a165-70867728950e"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typeName+" "+tt.literal, func(t *testing.T) {
+
+			if !keyTypes[tt.typeName] {
+				t.Fatalf("%s keys cannot be resharded", tt.typeName)
+			}
+
+			table := &Table{
+				Name:    "items",
+				Columns: []Column{{Name: "key", Type: tt.typeName, TypeName: tt.typeName}},
+				key:     0,
+			}
+
+			slot, err := table.slotOf(row{sql.NullString{String: tt.text, Valid: true}}, 0, slots)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if routed := routedSlot(t, tt.literal, ring); routed != owners[slot] {
+				t.Fatalf("mover puts %q in slot %d, router routes %s to slot %s", tt.text, slot, tt.literal, routed)
+			}
+		})
+	}
+}
+
+func TestKeyTypes(t *testing.T) {
+
+	for _, typeName := range []string{"numeric", "float8", "bool", "bpchar", "date", "timestamptz", "bytea"} {
+		if keyTypes[typeName] {
+			t.Errorf("%s keys render differently from their literals and must not be resharded", typeName)
+		}
+	}
+}
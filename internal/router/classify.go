@@ -0,0 +1,126 @@
+package router
+
+import (
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// ClassifyStatement determines the statement kind from its AST node.
+func ClassifyStatement(node *pg_query.Node) StatementKind {
+
+	if node == nil {
+		return StatementKindUnknown
+	}
+
+	switch n := node.Node.(type) {
+
+	case *pg_query.Node_SelectStmt:
+		if selectWrites(n.SelectStmt) {
+			return StatementKindWrite
+		}
+		return StatementKindRead
+
+	case *pg_query.Node_InsertStmt,
+		*pg_query.Node_UpdateStmt,
+		*pg_query.Node_DeleteStmt,
+		*pg_query.Node_MergeStmt:
+		return StatementKindWrite
+
+	case *pg_query.Node_CreateStmt,
+		*pg_query.Node_CreateTableAsStmt,
+		*pg_query.Node_AlterTableStmt,
+		*pg_query.Node_DropStmt,
+		*pg_query.Node_TruncateStmt,
+		*pg_query.Node_IndexStmt,
+		*pg_query.Node_RenameStmt,
+		*pg_query.Node_ViewStmt,
+		*pg_query.Node_CreateSeqStmt,
+		*pg_query.Node_AlterSeqStmt,
+		*pg_query.Node_CreateEnumStmt,
+		*pg_query.Node_CreateSchemaStmt,
+		*pg_query.Node_CommentStmt:
+		return StatementKindDDL
+
+	default:
+		return StatementKindUtility
+	}
+}
+
+// selectWrites reports whether a SELECT modifies data: SELECT ... INTO
+// creates a table, FOR UPDATE/SHARE locks rows and a CTE may hold an
+// INSERT, UPDATE, DELETE or MERGE, at any depth of the statement
+func selectWrites(stmt *pg_query.SelectStmt) bool {
+
+	writes := false
+
+	WalkMessages(stmt.ProtoReflect(), func(msg proto.Message) {
+		switch m := msg.(type) {
+		case *pg_query.SelectStmt:
+			if m.IntoClause != nil || len(m.LockingClause) > 0 {
+				writes = true
+			}
+		case *pg_query.InsertStmt,
+			*pg_query.UpdateStmt,
+			*pg_query.DeleteStmt,
+			*pg_query.MergeStmt:
+			writes = true
+		}
+	})
+
+	return writes
+}
+
+// nestedWrite reports whether a statement holds an INSERT, UPDATE, DELETE
+// or MERGE below its top level, e.g. in a CTE. Such writes target their own
+// table, which the statement is not routed by.
+func nestedWrite(node *pg_query.Node) bool {
+
+	writes := 0
+
+	WalkMessages(node.ProtoReflect(), func(msg proto.Message) {
+		switch msg.(type) {
+		case *pg_query.InsertStmt,
+			*pg_query.UpdateStmt,
+			*pg_query.DeleteStmt,
+			*pg_query.MergeStmt:
+			writes++
+		}
+	})
+
+	switch node.Node.(type) {
+	case *pg_query.Node_InsertStmt,
+		*pg_query.Node_UpdateStmt,
+		*pg_query.Node_DeleteStmt,
+		*pg_query.Node_MergeStmt:
+		writes--
+	}
+
+	return writes > 0
+}
+
+// HasReturning reports whether a write statement carries a RETURNING clause.
+func HasReturning(node *pg_query.Node) bool {
+
+	if node == nil {
+		return false
+	}
+
+	switch n := node.Node.(type) {
+
+	case *pg_query.Node_InsertStmt:
+		return len(n.InsertStmt.ReturningList) > 0
+
+	case *pg_query.Node_UpdateStmt:
+		return len(n.UpdateStmt.ReturningList) > 0
+
+	case *pg_query.Node_DeleteStmt:
+		return len(n.DeleteStmt.ReturningList) > 0
+
+	// a SELECT classified as a write still returns its rows
+	case *pg_query.Node_SelectStmt:
+		return true
+
+	default:
+		return false
+	}
+}
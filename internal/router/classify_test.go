@@ -0,0 +1,78 @@
+package router
+
+import (
+	"testing"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+)
+
+func parseStmt(t *testing.T, sql string) *pg_query.Node {
+	t.Helper()
+
+	result, err := pg_query.Parse(sql)
+	if err != nil {
+		t.Fatalf("parse %q: %v", sql, err)
+	}
+	if len(result.Stmts) != 1 {
+		t.Fatalf("parse %q: %d statements", sql, len(result.Stmts))
+	}
+
+	return result.Stmts[0].Stmt
+}
+
+func TestClassifyStatement(t *testing.T) {
+
+	tests := []struct {
+		sql  string
+		want StatementKind
+	}{
+		{"SELECT * FROM orders WHERE id = 1", StatementKindRead},
+		{"WITH o AS (SELECT * FROM orders) SELECT * FROM o", StatementKindRead},
+		{"SELECT * FROM orders UNION SELECT * FROM archived_orders", StatementKindRead},
+		{"SELECT * INTO copy FROM orders", StatementKindWrite},
+		{"SELECT * FROM orders WHERE id = 1 FOR UPDATE", StatementKindWrite},
+		{"SELECT * FROM orders FOR SHARE", StatementKindWrite},
+		{"SELECT * FROM (SELECT * FROM orders FOR UPDATE) o", StatementKindWrite},
+		{"WITH d AS (DELETE FROM orders RETURNING *) SELECT * FROM d", StatementKindWrite},
+		{"WITH u AS (UPDATE orders SET total = 0 RETURNING id) SELECT count(*) FROM u", StatementKindWrite},
+		{"WITH i AS (INSERT INTO orders (id) VALUES (1) RETURNING id) SELECT * FROM i", StatementKindWrite},
+		{"WITH r AS (SELECT 1), d AS (DELETE FROM orders RETURNING 1) SELECT * FROM r", StatementKindWrite},
+		{"INSERT INTO orders (id) VALUES (1)", StatementKindWrite},
+		{"UPDATE orders SET total = 1 WHERE id = 1", StatementKindWrite},
+		{"DELETE FROM orders WHERE id = 1", StatementKindWrite},
+		{"CREATE TABLE t (id int)", StatementKindDDL},
+		{"DROP TABLE orders", StatementKindDDL},
+		{"SET search_path TO public", StatementKindUtility},
+		{"VACUUM orders", StatementKindUtility},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			if got := ClassifyStatement(parseStmt(t, tt.sql)); got != tt.want {
+				t.Errorf("ClassifyStatement() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasReturning(t *testing.T) {
+
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{"INSERT INTO orders (id) VALUES (1)", false},
+		{"INSERT INTO orders (id) VALUES (1) RETURNING id", true},
+		{"UPDATE orders SET total = 1 RETURNING *", true},
+		{"DELETE FROM orders", false},
+		{"WITH d AS (DELETE FROM orders RETURNING *) SELECT * FROM d", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			if got := HasReturning(parseStmt(t, tt.sql)); got != tt.want {
+				t.Errorf("HasReturning() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
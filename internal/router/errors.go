@@ -9,8 +9,34 @@ const (
 	ErrUnsupportedPredicate
 	ErrPolicyViolation
 	ErrFanoutExceeded
+	ErrUnsupportedStatement
+	ErrAccessDenied
+	ErrShardMoving
 )
 
+func (c RoutingErrorCode) String() string {
+	switch c {
+	case ErrNoShardKey:
+		return "no_shard_key"
+	case ErrShardKeyNotInQuery:
+		return "shard_key_not_in_query"
+	case ErrUnsupportedPredicate:
+		return "unsupported_predicate"
+	case ErrPolicyViolation:
+		return "policy_violation"
+	case ErrFanoutExceeded:
+		return "fanout_exceeded"
+	case ErrUnsupportedStatement:
+		return "unsupported_statement"
+	case ErrAccessDenied:
+		return "access_denied"
+	case ErrShardMoving:
+		return "shard_moving"
+	default:
+		return "invalid"
+	}
+}
+
 type RoutingError struct {
 	Code    RoutingErrorCode
 	Message string
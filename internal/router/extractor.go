@@ -4,7 +4,7 @@ import (
 	pg_query "github.com/pganalyze/pg_query_go/v5"
 )
 
-func ExtractShardPredicate(node pg_query.Node, table string, shardKey string) (*ExtractedPredicate, *RoutingError) {
+func ExtractShardPredicate(node *pg_query.Node, table string, shardKey string) (*ExtractedPredicate, *RoutingError) {
 
 	switch n := node.Node.(type) {
 
@@ -233,6 +233,9 @@ func extractColumn(node *pg_query.Node) (string, bool) {
 	return str.String_.Sval, true
 }
 
+// extractConst returns the parser value of a literal. Shard keys hash
+// these values, so they must not change or keys move to other shards;
+// KeyValue builds the same values from rows read from shards.
 func extractConst(node *pg_query.Node) (any, bool) {
 	ac, ok := node.Node.(*pg_query.Node_AConst)
 	if !ok {
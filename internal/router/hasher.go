@@ -4,6 +4,11 @@ import (
 	"encoding/binary"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
 )
 
 // computes hashes for shard-key values.
@@ -47,3 +52,54 @@ func (h *Hasher) Hash(value any) HashValue {
 
 	return HashValue(hasher.Sum64())
 }
+
+// KeyValue converts the text form of a shard key read from a shard into the
+// value the planner extracts from a literal of that value, so rows hash to
+// the slot statements on them are routed to. Like the parser, integers that
+// fit 32 bits are integer literals and other numbers are float literals.
+func KeyValue(typeName string, text string) (any, error) {
+
+	switch strings.ToLower(typeName) {
+
+	case "int2", "int4", "int8", "smallint", "integer", "int", "bigint",
+		"serial", "serial4", "bigserial", "serial8", "smallserial", "serial2",
+		"numeric", "decimal", "float4", "float8", "real", "double precision":
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return nil, err
+		}
+		// the parser reads -2147483648 as a negated float literal
+		if n, err := strconv.ParseInt(text, 10, 32); err == nil && n != math.MinInt32 {
+			return &pg_query.Integer{Ival: int32(n)}, nil
+		}
+		return &pg_query.Float{Fval: text}, nil
+
+	case "bool", "boolean":
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, err
+		}
+		return &pg_query.Boolean{Boolval: b}, nil
+
+	default:
+		return &pg_query.String{Sval: text}, nil
+	}
+}
+
+// KeyText renders a shard key value as it was written, e.g. for reports
+func KeyText(value any) string {
+
+	switch v := value.(type) {
+	case *pg_query.Integer:
+		return strconv.FormatInt(int64(v.Ival), 10)
+	case *pg_query.Float:
+		return v.Fval
+	case *pg_query.Boolean:
+		return strconv.FormatBool(v.Boolval)
+	case *pg_query.String:
+		return v.Sval
+	case *pg_query.BitString:
+		return v.Bsval
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,112 @@
+package router
+
+import (
+	"hash/fnv"
+	"testing"
+)
+
+// baselineHash is how shard keys have always been hashed: FNV-1a over the
+// text form of the parser value of the literal
+func baselineHash(text string) HashValue {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(text))
+	return HashValue(h.Sum64())
+}
+
+// literalValue extracts the shard key value of the literal in "WHERE id = <literal>"
+func literalValue(t *testing.T, literal string) any {
+	t.Helper()
+
+	node := parseStmt(t, "SELECT * FROM orders WHERE id = "+literal)
+
+	value, ok := extractConst(node.GetSelectStmt().WhereClause.GetAExpr().Rexpr)
+	if !ok {
+		t.Fatalf("literal %s has no constant value", literal)
+	}
+
+	return value
+}
+
+func TestHashLiteralsLikeBaseline(t *testing.T) {
+
+	tests := []struct {
+		literal string
+		text    string
+	}{
+		{"42", "ival:42"},
+		{"-7", "ival:-7"},
+		{"0", ""},
+		{"9999999999", `fval:"9999999999"`},
+		{"1.5", `fval:"1.5"`},
+		{"true", "boolval:true"},
+		{"'abc'", `sval:"abc"`},
+		{"'a\"b'", `sval:"a\"b"`},
+		{"''", ""},
+	}
+
+	hasher := NewHasher()
+
+	for _, tt := range tests {
+		t.Run(tt.literal, func(t *testing.T) {
+			if got, want := hasher.Hash(literalValue(t, tt.literal)), baselineHash(tt.text); got != want {
+				t.Errorf("Hash(%s) = %d, want %d, the hash of %q", tt.literal, got, want, tt.text)
+			}
+		})
+	}
+}
+
+func TestKeyValueHashesLikeLiteral(t *testing.T) {
+
+	tests := []struct {
+		typeName string
+		text     string
+		literal  string
+	}{
+		{"int4", "42", "42"},
+		{"integer", "-7", "-7"},
+		{"int8", "0", "0"},
+		{"bigint", "9999999999", "9999999999"},
+		{"int4", "-2147483648", "-2147483648"},
+		{"int4", "2147483647", "2147483647"},
+		{"numeric", "1.5", "1.5"},
+		{"numeric", "12", "12"},
+		{"bool", "true", "true"},
+		{"boolean", "t", "true"},
+		{"text", "abc", "'abc'"},
+		{"uuid", "8e2b0c9a-3c4f-4a8e-9d36-0c6f2b1d7e55", "'8e2b0c9a-3c4f-4a8e-9d36-0c6f2b1d7e55'"},
+		{"varchar", "", "''"},
+	}
+
+	hasher := NewHasher()
+
+	for _, tt := range tests {
+		t.Run(tt.typeName+" "+tt.text, func(t *testing.T) {
+
+			value, err := KeyValue(tt.typeName, tt.text)
+			if err != nil {
+				t.Fatalf("KeyValue: %v", err)
+			}
+
+			if got, want := hasher.Hash(value), hasher.Hash(literalValue(t, tt.literal)); got != want {
+				t.Errorf("row value %q hashes to %d, literal %s to %d", tt.text, got, tt.literal, want)
+			}
+		})
+	}
+
+	if _, err := KeyValue("int4", "abc"); err == nil {
+		t.Error("KeyValue accepted a non-numeric integer")
+	}
+}
+
+func TestKeyText(t *testing.T) {
+
+	for _, literal := range []string{"42", "9999999999", "1.5", "true", "'abc'"} {
+		want := literal
+		if literal == "'abc'" {
+			want = "abc"
+		}
+		if got := KeyText(literalValue(t, literal)); got != want {
+			t.Errorf("KeyText(%s) = %q, want %q", literal, got, want)
+		}
+	}
+}
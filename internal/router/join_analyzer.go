@@ -1,8 +1,6 @@
 package router
 
 import (
-	"fmt"
-
 	pg_query "github.com/pganalyze/pg_query_go/v5"
 )
 
@@ -26,27 +24,49 @@ func ExtractJoinInfo(stmt *pg_query.SelectStmt) (*JoinInfo, bool) {
 
 	join := joinNode.JoinExpr
 
-	left := join.Larg.Node.(*pg_query.Node_RangeVar).RangeVar.Relname
-	right := join.Rarg.Node.(*pg_query.Node_RangeVar).RangeVar.Relname
-
-	expr := join.Quals.Node.(*pg_query.Node_AExpr).AExpr
-
-	fmt.Printf("Expression: %s", expr)
-
-	leftCol, ok1 := extractColumn(expr.Lexpr)
-	rightCol, ok2 := extractColumn(expr.Rexpr)
-
-	if !ok1 || !ok2 {
-		fmt.Println("Failed to extract columns from join condition")
+	left, ok1 := join.Larg.Node.(*pg_query.Node_RangeVar)
+	right, ok2 := join.Rarg.Node.(*pg_query.Node_RangeVar)
+	if !ok1 || !ok2 || join.Quals == nil {
 		return nil, false
 	}
 
-	// fmt.Println("left Table", left, "Right Table: ", right, "left column: ", leftCol, "Right column: ", rightCol)
+	leftCol, rightCol, ok := joinColumns(join.Quals)
+	if !ok {
+		return nil, false
+	}
 
 	return &JoinInfo{
-		LeftTable:   left,
-		RightTable:  right,
+		LeftTable:   left.RangeVar.Relname,
+		RightTable:  right.RangeVar.Relname,
 		LeftColumn:  leftCol,
 		RightColumn: rightCol,
 	}, true
 }
+
+// joinColumns finds the column comparison of a join condition. Conditions
+// ANDed with it, e.g. a tenant filter on the nullable side, are skipped.
+func joinColumns(quals *pg_query.Node) (string, string, bool) {
+
+	switch n := quals.Node.(type) {
+
+	case *pg_query.Node_AExpr:
+		if n.AExpr.Lexpr == nil || n.AExpr.Rexpr == nil {
+			return "", "", false
+		}
+		leftCol, ok1 := extractColumn(n.AExpr.Lexpr)
+		rightCol, ok2 := extractColumn(n.AExpr.Rexpr)
+		return leftCol, rightCol, ok1 && ok2
+
+	case *pg_query.Node_BoolExpr:
+		if n.BoolExpr.Boolop != pg_query.BoolExprType_AND_EXPR {
+			return "", "", false
+		}
+		for _, arg := range n.BoolExpr.Args {
+			if leftCol, rightCol, ok := joinColumns(arg); ok {
+				return leftCol, rightCol, true
+			}
+		}
+	}
+
+	return "", "", false
+}
@@ -25,7 +25,7 @@ func NewPlanner(
 
 // Plan builds a RoutingPlan for a single SQL statement.
 func (p *Planner) Plan(
-	node pg_query.Node,
+	node *pg_query.Node,
 	table string,
 	shardKey string,
 ) *RoutingPlan {
@@ -60,6 +60,18 @@ func (p *Planner) Plan(
 		}
 	}
 
+	// every target would insert every row, not only the rows hashing to it
+	if _, insert := node.Node.(*pg_query.Node_InsertStmt); insert && len(shards) > 1 {
+		return &RoutingPlan{
+			Mode:   RoutingModeRejected,
+			Reason: "insert rows hash to several shards",
+			RejectError: &RoutingError{
+				Code:    ErrUnsupportedStatement,
+				Message: "rows of a multi-row insert must hash to one shard, insert them separately",
+			},
+		}
+	}
+
 	// scatter gather optimizer ->  no. of keys >= no. of shards -> do bradcast instead of targeted routing
 	if len(pred.Values) >= p.ring.Size() && len(shards) > 1 {
 
@@ -72,9 +84,12 @@ func (p *Planner) Plan(
 		}
 
 		return &RoutingPlan{
-			Mode:    RoutingModeBroadcast,
-			Targets: targets,
-			Reason:  "scatter-gather selected due to large multi-key fanout",
+			Mode:      RoutingModeBroadcast,
+			Targets:   targets,
+			Reason:    "scatter-gather selected due to large multi-key fanout",
+			Table:     table,
+			ShardKey:  shardKey,
+			KeyValues: pred.Values,
 		}
 	}
 
@@ -104,8 +119,11 @@ func (p *Planner) Plan(
 	}
 
 	return &RoutingPlan{
-		Mode:    mode,
-		Targets: targets,
-		Reason:  "shard key resolved successfully",
+		Mode:      mode,
+		Targets:   targets,
+		Reason:    "shard key resolved successfully",
+		Table:     table,
+		ShardKey:  shardKey,
+		KeyValues: pred.Values,
 	}
 }
@@ -0,0 +1,301 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// AccessPolicy restricts which statements a caller may run.
+// The zero value allows everything the router can route.
+type AccessPolicy struct {
+	// Subject identifies the caller in audit records, e.g. an API key ID
+	Subject string
+
+	// ReadOnly limits the caller to SELECT statements
+	ReadOnly bool
+
+	// AllowedTables limits the tables a statement may reference, empty allows all.
+	// Entries match a table name or a schema-qualified name, case-insensitively.
+	AllowedTables []string
+
+	// DenyBroadcastWrites rejects writes that target more than one shard
+	DenyBroadcastWrites bool
+
+	// Tenant confines statements to one tenant's rows, nil disables isolation
+	Tenant *TenantScope
+}
+
+// AccessDenial describes a statement rejected by an access policy
+type AccessDenial struct {
+	ProjectID string
+	Subject   string
+	SQL       string
+	Kind      StatementKind
+	Reason    string
+}
+
+// DenialRecorder receives every access policy denial
+type DenialRecorder interface {
+	RecordDenial(ctx context.Context, denial AccessDenial)
+}
+
+type policyKey struct{}
+
+// WithAccessPolicy returns a context whose statements are checked against policy
+func WithAccessPolicy(ctx context.Context, policy *AccessPolicy) context.Context {
+	return context.WithValue(ctx, policyKey{}, policy)
+}
+
+func policyFrom(ctx context.Context) *AccessPolicy {
+	policy, _ := ctx.Value(policyKey{}).(*AccessPolicy)
+	return policy
+}
+
+// checkStatement applies the statement kind and table rules of a policy
+func (p *AccessPolicy) checkStatement(kind StatementKind, node *pg_query.Node) string {
+
+	if p.ReadOnly && kind != StatementKindRead {
+		return fmt.Sprintf("%s statements are not allowed for read-only callers", kind)
+	}
+
+	if len(p.AllowedTables) == 0 {
+		return ""
+	}
+
+	for _, table := range ReferencedTables(node) {
+		if !p.allowsTable(table) {
+			return fmt.Sprintf("table %s is not allowed", table)
+		}
+	}
+
+	return ""
+}
+
+// checkPlan applies rules that depend on the routing outcome
+func (p *AccessPolicy) checkPlan(kind StatementKind, plan *RoutingPlan) string {
+
+	if p.DenyBroadcastWrites && kind == StatementKindWrite && len(plan.Targets) > 1 {
+		return fmt.Sprintf("write would run on %d shards, broadcast writes are not allowed", len(plan.Targets))
+	}
+
+	return ""
+}
+
+func (p *AccessPolicy) allowsTable(table string) bool {
+
+	_, bare, _ := strings.Cut(table, ".")
+
+	for _, allowed := range p.AllowedTables {
+		if strings.EqualFold(allowed, table) || (bare != "" && strings.EqualFold(allowed, bare)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deny builds the rejected plan for a policy violation and records it
+func (s *RouterService) deny(
+	ctx context.Context,
+	projectID string,
+	sql string,
+	policy *AccessPolicy,
+	kind StatementKind,
+	reason string,
+) *RoutingPlan {
+
+	if s.denials != nil {
+		s.denials.RecordDenial(ctx, AccessDenial{
+			ProjectID: projectID,
+			Subject:   policy.Subject,
+			SQL:       sql,
+			Kind:      kind,
+			Reason:    reason,
+		})
+	}
+
+	return deniedPlan(kind, reason)
+}
+
+// deniedPlan builds the rejected plan for a policy violation
+func deniedPlan(kind StatementKind, reason string) *RoutingPlan {
+	return &RoutingPlan{
+		Mode:   RoutingModeRejected,
+		Kind:   kind,
+		Reason: reason,
+		RejectError: &RoutingError{
+			Code:    ErrAccessDenied,
+			Message: "access denied: " + reason,
+		},
+	}
+}
+
+// ReferencedTables returns the relations a statement reads or writes,
+// schema-qualified where the statement qualifies them. References to CTEs
+// in scope are excluded.
+func ReferencedTables(node *pg_query.Node) []string {
+
+	if node == nil {
+		return nil
+	}
+
+	ctes := cteReferences(node)
+	var relations []*pg_query.RangeVar
+
+	WalkMessages(node.ProtoReflect(), func(msg proto.Message) {
+		if rv, ok := msg.(*pg_query.RangeVar); ok && !ctes[rv] {
+			relations = append(relations, rv)
+		}
+	})
+
+	seen := map[string]bool{}
+	var tables []string
+
+	for _, rv := range relations {
+		name := rv.Relname
+		if rv.Schemaname != "" {
+			name = rv.Schemaname + "." + rv.Relname
+		}
+
+		if !seen[name] {
+			seen[name] = true
+			tables = append(tables, name)
+		}
+	}
+
+	return tables
+}
+
+// cteReferences returns the range vars of a statement that name a CTE
+// rather than a table. A CTE is visible to the query of its WITH clause, to
+// the queries nested in it and to the later CTEs of the clause; inside its
+// own body the name is a table unless the WITH is RECURSIVE. The target of
+// an INSERT, UPDATE, DELETE or MERGE is always a table.
+func cteReferences(node *pg_query.Node) map[*pg_query.RangeVar]bool {
+
+	refs := map[*pg_query.RangeVar]bool{}
+	walkScoped(node.ProtoReflect(), map[string]bool{}, refs)
+
+	return refs
+}
+
+func walkScoped(msg protoreflect.Message, scope map[string]bool, refs map[*pg_query.RangeVar]bool) {
+
+	var with *pg_query.WithClause
+	skip := map[protoreflect.Name]bool{}
+
+	switch m := msg.Interface().(type) {
+
+	case *pg_query.RangeVar:
+		if m.Schemaname == "" && scope[m.Relname] {
+			refs[m] = true
+		}
+		return
+
+	case *pg_query.SelectStmt:
+		with = m.WithClause
+	case *pg_query.InsertStmt:
+		with = m.WithClause
+		skip["relation"] = true
+	case *pg_query.UpdateStmt:
+		with = m.WithClause
+		skip["relation"] = true
+	case *pg_query.DeleteStmt:
+		with = m.WithClause
+		skip["relation"] = true
+	case *pg_query.MergeStmt:
+		with = m.WithClause
+		skip["relation"] = true
+	}
+
+	if with != nil {
+		scope = withScope(with, scope, refs)
+		skip["with_clause"] = true
+	}
+
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+
+		if fd.Kind() != protoreflect.MessageKind || skip[fd.Name()] {
+			return true
+		}
+
+		switch {
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				walkScoped(list.Get(i).Message(), scope, refs)
+			}
+		case fd.IsMap():
+			// parse trees carry no map fields
+		default:
+			walkScoped(v.Message(), scope, refs)
+		}
+
+		return true
+	})
+}
+
+// withScope walks the CTE bodies of a WITH clause and returns the scope of
+// the query it belongs to
+func withScope(with *pg_query.WithClause, outer map[string]bool, refs map[*pg_query.RangeVar]bool) map[string]bool {
+
+	scope := make(map[string]bool, len(outer)+len(with.Ctes))
+	for name := range outer {
+		scope[name] = true
+	}
+
+	ctes := make([]*pg_query.CommonTableExpr, 0, len(with.Ctes))
+	for _, node := range with.Ctes {
+		if cte := node.GetCommonTableExpr(); cte != nil {
+			ctes = append(ctes, cte)
+		}
+	}
+
+	if with.Recursive {
+		for _, cte := range ctes {
+			scope[cte.Ctename] = true
+		}
+	}
+
+	for _, cte := range ctes {
+		// a body sees the CTEs before it, it is walked before its own is added
+		if cte.Ctequery != nil {
+			walkScoped(cte.Ctequery.ProtoReflect(), scope, refs)
+		}
+		scope[cte.Ctename] = true
+	}
+
+	return scope
+}
+
+// WalkMessages visits every message in a parse tree, depth first
+func WalkMessages(msg protoreflect.Message, visit func(proto.Message)) {
+
+	visit(msg.Interface())
+
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+
+		if fd.Kind() != protoreflect.MessageKind {
+			return true
+		}
+
+		switch {
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				WalkMessages(list.Get(i).Message(), visit)
+			}
+		case fd.IsMap():
+			// parse trees carry no map fields
+		default:
+			WalkMessages(v.Message(), visit)
+		}
+
+		return true
+	})
+}
@@ -0,0 +1,116 @@
+package router
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAccessPolicyCheckStatement(t *testing.T) {
+
+	readOnly := &AccessPolicy{ReadOnly: true}
+	ordersOnly := &AccessPolicy{AllowedTables: []string{"orders", "billing.invoices"}}
+
+	tests := []struct {
+		name   string
+		policy *AccessPolicy
+		sql    string
+		denied bool
+	}{
+		{"zero policy allows writes", &AccessPolicy{}, "DELETE FROM users", false},
+		{"read only allows select", readOnly, "SELECT * FROM orders WHERE id = 1", false},
+		{"read only allows read cte", readOnly, "WITH o AS (SELECT * FROM orders) SELECT * FROM o", false},
+		{"read only denies insert", readOnly, "INSERT INTO orders (id) VALUES (1)", true},
+		{"read only denies update", readOnly, "UPDATE orders SET total = 0", true},
+		{"read only denies delete cte", readOnly, "WITH d AS (DELETE FROM orders RETURNING *) SELECT * FROM d", true},
+		{"read only denies update cte", readOnly, "WITH u AS (UPDATE orders SET total = 0 RETURNING id) SELECT * FROM u", true},
+		{"read only denies select for update", readOnly, "SELECT * FROM orders WHERE id = 1 FOR UPDATE", true},
+		{"read only denies select into", readOnly, "SELECT * INTO copy FROM orders", true},
+		{"allowed table", ordersOnly, "SELECT * FROM orders", false},
+		{"allowed table case-insensitive", ordersOnly, "SELECT * FROM ORDERS", false},
+		{"allowed qualified table", ordersOnly, "SELECT * FROM billing.invoices", false},
+		{"bare entry matches qualified table", ordersOnly, "SELECT * FROM public.orders", false},
+		{"qualified entry does not match other schema", ordersOnly, "SELECT * FROM public.invoices", true},
+		{"denied table", ordersOnly, "SELECT * FROM users", true},
+		{"denied table in join", ordersOnly, "SELECT * FROM orders o JOIN users u ON o.user_id = u.id", true},
+		{"denied table in subquery", ordersOnly, "SELECT * FROM orders WHERE user_id IN (SELECT id FROM users)", true},
+		{"denied table in cte", ordersOnly, "WITH d AS (DELETE FROM users RETURNING *) SELECT * FROM orders", true},
+		{"cte name is not a table", ordersOnly, "WITH recent AS (SELECT * FROM orders) SELECT * FROM recent", false},
+		{"cte body reads the table it shadows", ordersOnly, "WITH secret AS (SELECT * FROM secret) SELECT * FROM secret WHERE id = 1", true},
+		{"cte named like a delete target", ordersOnly, "WITH users AS (SELECT 1) DELETE FROM users WHERE id = 1", true},
+		{"cte named like an update target", ordersOnly, "WITH users AS (SELECT 1) UPDATE users SET name = 'x'", true},
+		{"cte named like an insert target", ordersOnly, "WITH users AS (SELECT 1) INSERT INTO users (id) VALUES (1)", true},
+		{"cte scoped to its subquery", ordersOnly, "SELECT * FROM (WITH users AS (SELECT * FROM orders) SELECT * FROM users) u JOIN users ON true", true},
+		{"later cte sees earlier one", ordersOnly, "WITH a AS (SELECT * FROM orders), b AS (SELECT * FROM a) SELECT * FROM b", false},
+		{"earlier cte does not see later one", ordersOnly, "WITH a AS (SELECT * FROM b), b AS (SELECT * FROM orders) SELECT * FROM a", true},
+		{"recursive cte sees itself", ordersOnly, "WITH RECURSIVE tree AS (SELECT * FROM orders UNION ALL SELECT o.* FROM orders o JOIN tree t ON o.parent_id = t.id) SELECT * FROM tree", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := parseStmt(t, tt.sql)
+
+			reason := tt.policy.checkStatement(ClassifyStatement(node), node)
+			if (reason != "") != tt.denied {
+				t.Errorf("checkStatement(%q) = %q, want denied %t", tt.sql, reason, tt.denied)
+			}
+		})
+	}
+}
+
+func TestAccessPolicyCheckPlan(t *testing.T) {
+
+	one := []ShardTarget{{ShardID: "a"}}
+	two := []ShardTarget{{ShardID: "a"}, {ShardID: "b"}}
+
+	tests := []struct {
+		name    string
+		policy  *AccessPolicy
+		sql     string
+		targets []ShardTarget
+		denied  bool
+	}{
+		{"single shard write", &AccessPolicy{DenyBroadcastWrites: true}, "DELETE FROM orders WHERE id = 1", one, false},
+		{"broadcast read", &AccessPolicy{DenyBroadcastWrites: true}, "SELECT * FROM orders", two, false},
+		{"broadcast write", &AccessPolicy{DenyBroadcastWrites: true}, "DELETE FROM orders", two, true},
+		{"broadcast write in cte", &AccessPolicy{DenyBroadcastWrites: true}, "WITH d AS (DELETE FROM orders RETURNING *) SELECT * FROM d", two, true},
+		{"broadcast locking select", &AccessPolicy{DenyBroadcastWrites: true}, "SELECT * FROM orders FOR UPDATE", two, true},
+		{"broadcast write allowed", &AccessPolicy{}, "DELETE FROM orders", two, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := parseStmt(t, tt.sql)
+			plan := &RoutingPlan{Mode: RoutingModeBroadcast, Targets: tt.targets}
+
+			reason := tt.policy.checkPlan(ClassifyStatement(node), plan)
+			if (reason != "") != tt.denied {
+				t.Errorf("checkPlan(%q) = %q, want denied %t", tt.sql, reason, tt.denied)
+			}
+		})
+	}
+}
+
+func TestReferencedTables(t *testing.T) {
+
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{"SELECT * FROM orders", []string{"orders"}},
+		{"SELECT * FROM public.orders o JOIN users u ON o.user_id = u.id", []string{"public.orders", "users"}},
+		{"INSERT INTO orders (id) SELECT id FROM carts", []string{"orders", "carts"}},
+		{"WITH c AS (SELECT * FROM carts) SELECT * FROM c JOIN c AS d ON true", []string{"carts"}},
+		{"WITH secret AS (SELECT * FROM secret) SELECT * FROM secret", []string{"secret"}},
+		{"WITH users AS (SELECT 1) DELETE FROM users WHERE id = 1", []string{"users"}},
+		{"SELECT * FROM (WITH c AS (SELECT 1) SELECT * FROM c) x JOIN c ON true", []string{"c"}},
+		{"SELECT 1", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			if got := ReferencedTables(parseStmt(t, tt.sql)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReferencedTables() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,9 @@ package router
 // ring maps hash values to shard IDs.
 type Ring struct {
 	shards []ShardID
+
+	// owner of every hash slot, empty for modulo rings
+	slots []ShardID
 }
 
 // constructs a routing ring from active shard IDs.
@@ -12,8 +15,33 @@ func NewRing(shards []ShardID) *Ring {
 	}
 }
 
+// constructs a routing ring from the owners of a shard map, a key hashes
+// to slot hash % len(slots). Shards owning no slot receive no statements.
+func NewSlotRing(slots []ShardID) *Ring {
+
+	seen := make(map[ShardID]struct{})
+	shards := make([]ShardID, 0)
+
+	for _, sid := range slots {
+		if _, ok := seen[sid]; ok {
+			continue
+		}
+		seen[sid] = struct{}{}
+		shards = append(shards, sid)
+	}
+
+	return &Ring{
+		shards: shards,
+		slots:  slots,
+	}
+}
+
 // maps a hash value to a single shard.
 func (r *Ring) LocateShard(hash HashValue) ShardID {
+	if len(r.slots) > 0 {
+		return r.slots[r.Slot(hash)]
+	}
+
 	if len(r.shards) == 0 {
 		panic("ring has no shards")
 	}
@@ -22,6 +50,14 @@ func (r *Ring) LocateShard(hash HashValue) ShardID {
 	return r.shards[pos]
 }
 
+// Slot returns the hash slot of a hash value, -1 for modulo rings.
+func (r *Ring) Slot(hash HashValue) int {
+	if len(r.slots) == 0 {
+		return -1
+	}
+	return int(hash % HashValue(len(r.slots)))
+}
+
 // maps multiple hash values to shard IDs.
 // ensures uniqueness of returned shards.
 func (r *Ring) LocateShards(hashes []HashValue) []ShardID {
@@ -4,42 +4,200 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"time"
 
 	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"go.opentelemetry.io/otel/attribute"
 
+	"sql-sharding-v2/internal/metrics"
 	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/tracing"
 	"sql-sharding-v2/pkg/logger"
 )
 
 type RouterService struct {
 	shardKeysRepo *repository.ShardKeysRepository
 	shardRepo     *repository.ShardRepository
+	shardMapRepo  *repository.ShardMapRepository
+	reshardRepo   *repository.ReshardRepository
 	cfg           RouterConfig
+	denials       DenialRecorder
 }
 
 func NewRouterService(
 	shardKeysRepo *repository.ShardKeysRepository,
 	shardRepo *repository.ShardRepository,
+	shardMapRepo *repository.ShardMapRepository,
+	reshardRepo *repository.ReshardRepository,
 	cfg RouterConfig,
 ) *RouterService {
 	return &RouterService{
 		shardKeysRepo: shardKeysRepo,
 		shardRepo:     shardRepo,
+		shardMapRepo:  shardMapRepo,
+		reshardRepo:   reshardRepo,
 		cfg:           cfg,
 	}
 }
 
-// RouteSQL is the router service entry point
+// SetDenialRecorder registers where access policy denials are recorded
+func (s *RouterService) SetDenialRecorder(recorder DenialRecorder) {
+	s.denials = recorder
+}
+
+// RouteSQL is the router service entry point. Statements are checked against
+// the access policy carried by ctx, if any, before and after routing.
 func (s *RouterService) RouteSQL(
 	ctx context.Context,
 	projectID string,
 	sql string,
 ) (*RoutingPlan, error) {
 
+	started := time.Now()
+
+	ctx, span := tracing.Start(ctx, "router.route", attribute.String("project_id", projectID))
+
+	plan, err := s.routeSQL(ctx, projectID, sql)
+
+	if plan != nil {
+		span.SetAttributes(
+			attribute.String("routing.mode", plan.Mode.String()),
+			attribute.String("statement.kind", plan.Kind.String()),
+			attribute.Int("routing.shards", len(plan.Targets)),
+		)
+	}
+	tracing.End(span, err)
+
+	observeRoute(plan, err, time.Since(started))
+
+	return plan, err
+}
+
+// observeRoute reports a routing outcome to metrics, statements that failed
+// to route count as rejected with code error
+func observeRoute(plan *RoutingPlan, err error, duration time.Duration) {
+
+	if err != nil || plan == nil {
+		metrics.ObserveRoute(RoutingModeRejected.String(), StatementKindUnknown.String(), "error", 0, duration)
+		return
+	}
+
+	code := ""
+	if plan.Mode == RoutingModeRejected {
+		code = ErrInvalid.String()
+		if plan.RejectError != nil {
+			code = plan.RejectError.Code.String()
+		}
+	}
+
+	metrics.ObserveRoute(plan.Mode.String(), plan.Kind.String(), code, len(plan.Targets), duration)
+}
+
+func (s *RouterService) routeSQL(
+	ctx context.Context,
+	projectID string,
+	sql string,
+) (*RoutingPlan, error) {
+
 	logger.Logger.Info("router entry reached")
 
+	stmt, err := s.checkSQL(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+
+	if stmt.denial != "" {
+		return s.deny(ctx, projectID, sql, stmt.policy, stmt.kind, stmt.denial), nil
+	}
+
+	if stmt.rejected != nil {
+		return stmt.rejected, nil
+	}
+
+	rawStmt := stmt.tree.Stmts[0]
+	kind := stmt.kind
+
+	plan, err := s.routeStatement(ctx, projectID, rawStmt, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	plan.Kind = kind
+	plan.HasReturning = HasReturning(rawStmt.Stmt)
+	plan.SQL = stmt.rewritten
+
+	if stmt.policy != nil && plan.Mode != RoutingModeRejected {
+		if reason := stmt.policy.checkPlan(kind, plan); reason != "" {
+			return s.deny(ctx, projectID, sql, stmt.policy, kind, reason), nil
+		}
+	}
+
+	return plan, nil
+}
+
+// PrepareSQL checks a statement against the access policy carried by ctx and
+// applies its tenant rewrite without resolving shards, for statements whose
+// parameters are not bound yet. The plan holds the kind and the statement to
+// run, always deparsed, and is rejected when the statement is denied.
+// Denials are recorded once the statement is routed.
+func (s *RouterService) PrepareSQL(
+	ctx context.Context,
+	sql string,
+) (*RoutingPlan, error) {
+
+	stmt, err := s.checkSQL(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+
+	if stmt.denial != "" {
+		return deniedPlan(stmt.kind, stmt.denial), nil
+	}
+
+	if stmt.rejected != nil {
+		return stmt.rejected, nil
+	}
+
+	rewritten := stmt.rewritten
+	if rewritten == "" {
+		rewritten, err = pg_query.Deparse(stmt.tree)
+		if err != nil {
+			return nil, fmt.Errorf("deparse: %w", err)
+		}
+	}
+
+	return &RoutingPlan{
+		Kind:         stmt.kind,
+		HasReturning: HasReturning(stmt.tree.Stmts[0].Stmt),
+		SQL:          rewritten,
+	}, nil
+}
+
+// checkedStatement is a parsed statement checked against the statement
+// rules of an access policy
+type checkedStatement struct {
+	tree   *pg_query.ParseResult
+	kind   StatementKind
+	policy *AccessPolicy
+
+	// reason of a policy denial, empty if allowed
+	denial string
+
+	// plan rejecting a statement that cannot be routed, nil if routable
+	rejected *RoutingPlan
+
+	// statement after the tenant rewrite, empty if unchanged
+	rewritten string
+}
+
+// checkSQL parses a single statement, classifies it and applies the statement
+// rules and tenant rewrite of the access policy carried by ctx, if any
+func (s *RouterService) checkSQL(ctx context.Context, sql string) (*checkedStatement, error) {
+
 	// 1. Parse SQL
+	_, parseSpan := tracing.Start(ctx, "router.parse")
 	parseResult, err := pg_query.Parse(sql)
+	tracing.End(parseSpan, err)
 	if err != nil {
 		return nil, fmt.Errorf("sql parse error: %w", err)
 	}
@@ -49,21 +207,89 @@ func (s *RouterService) RouteSQL(
 	}
 
 	rawStmt := parseResult.Stmts[0]
-	node := rawStmt.Stmt
 
-	// detect joins
-	var joinInfo *JoinInfo
-	var isJoin bool
+	stmt := &checkedStatement{
+		tree:   parseResult,
+		kind:   ClassifyStatement(rawStmt.Stmt),
+		policy: policyFrom(ctx),
+	}
 
-	if selectNode, ok := node.Node.(*pg_query.Node_SelectStmt); ok {
-		joinInfo, isJoin = ExtractJoinInfo(selectNode.SelectStmt)
+	if stmt.policy != nil {
+		if reason := stmt.policy.checkStatement(stmt.kind, rawStmt.Stmt); reason != "" {
+			stmt.denial = reason
+			return stmt, nil
+		}
+	}
+
+	if plan := rejectKind(stmt.kind); plan != nil {
+		stmt.rejected = plan
+		return stmt, nil
 	}
 
+	if stmt.policy != nil && stmt.policy.Tenant != nil {
+		changed, reason := stmt.policy.Tenant.rewrite(rawStmt.Stmt)
+		if reason != "" {
+			stmt.denial = reason
+			return stmt, nil
+		}
+
+		if changed {
+			stmt.rewritten, err = pg_query.Deparse(parseResult)
+			if err != nil {
+				return nil, fmt.Errorf("tenant rewrite: %w", err)
+			}
+		}
+	}
+
+	return stmt, nil
+}
+
+// rejectKind rejects statements that are neither reads nor writes
+func rejectKind(kind StatementKind) *RoutingPlan {
+
+	switch kind {
+
+	case StatementKindDDL:
+		return &RoutingPlan{
+			Mode:   RoutingModeRejected,
+			Kind:   kind,
+			Reason: "DDL must be applied through schema drafts",
+			RejectError: &RoutingError{
+				Code:    ErrUnsupportedStatement,
+				Message: "ddl statements cannot be routed",
+			},
+		}
+
+	case StatementKindUtility, StatementKindUnknown:
+		return &RoutingPlan{
+			Mode:   RoutingModeRejected,
+			Kind:   kind,
+			Reason: "utility statements cannot be routed",
+			RejectError: &RoutingError{
+				Code:    ErrUnsupportedStatement,
+				Message: "unsupported statement kind",
+			},
+		}
+	}
+
+	return nil
+}
+
+// routeStatement resolves shard targets for a single read or write statement
+func (s *RouterService) routeStatement(
+	ctx context.Context,
+	projectID string,
+	rawStmt *pg_query.RawStmt,
+	kind StatementKind,
+) (*RoutingPlan, error) {
+
 	// 2. Fetch shard keys for project
+	keysCtx, keysSpan := tracing.Start(ctx, "router.fetch_shard_keys")
 	shardKeys, err := s.shardKeysRepo.FetchShardKeysByProjectID(
-		ctx,
+		keysCtx,
 		projectID,
 	)
+	tracing.End(keysSpan, err)
 	if err != nil {
 		return nil, err
 	}
@@ -74,14 +300,83 @@ func (s *RouterService) RouteSQL(
 		shardKeyMap[k.TableName] = k.ShardKeyColumn
 	}
 
+	ring, err := s.loadRing(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, planSpan := tracing.Start(ctx, "router.plan")
+	plan, err := planStatement(s.cfg, rawStmt, shardKeyMap, ring)
+	tracing.End(planSpan, err)
+	if err != nil {
+		return nil, err
+	}
+
+	// keys only move between shards of a shard map
+	if kind != StatementKindWrite || ring.slots == nil {
+		return plan, nil
+	}
+
+	phase, moves, err := s.reshardRepo.ReshardActiveMoves(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, insert := rawStmt.Stmt.Node.(*pg_query.Node_InsertStmt)
+
+	return applyMoves(plan, ring, phase, moves, insert), nil
+}
+
+// reason of plans for joins on the shard keys of both sides
+const reasonColocatedJoin = "colocated join detected"
+
+// planStatement routes a statement against a shard key assignment and ring
+// without touching the database, shared by the router and the simulator
+func planStatement(
+	cfg RouterConfig,
+	rawStmt *pg_query.RawStmt,
+	shardKeyMap map[string]string,
+	ring *Ring,
+) (*RoutingPlan, error) {
+
+	node := rawStmt.Stmt
+
+	if _, ok := node.Node.(*pg_query.Node_MergeStmt); ok {
+		return &RoutingPlan{
+			Mode:   RoutingModeRejected,
+			Reason: "MERGE statements cannot be routed",
+			RejectError: &RoutingError{
+				Code:    ErrUnsupportedStatement,
+				Message: "merge not supported",
+			},
+		}, nil
+	}
+
+	if nestedWrite(node) {
+		return &RoutingPlan{
+			Mode:   RoutingModeRejected,
+			Reason: "data-modifying statements in WITH cannot be routed",
+			RejectError: &RoutingError{
+				Code:    ErrUnsupportedStatement,
+				Message: "nested writes not supported",
+			},
+		}, nil
+	}
+
+	// detect joins
+	var joinInfo *JoinInfo
+	var isJoin bool
+
+	if selectNode, ok := node.Node.(*pg_query.Node_SelectStmt); ok {
+		joinInfo, isJoin = ExtractJoinInfo(selectNode.SelectStmt)
+	}
+
 	// handle join queries
 	if isJoin {
 
 		leftKey, ok1 := shardKeyMap[joinInfo.LeftTable]
 		rightKey, ok2 := shardKeyMap[joinInfo.RightTable]
 
-		// fmt.Println("left key: ", leftKey, "Right key:", rightKey)
-
 		if !ok1 || !ok2 {
 			return &RoutingPlan{
 				Mode:   RoutingModeRejected,
@@ -96,26 +391,17 @@ func (s *RouterService) RouteSQL(
 		// Check colocated join condition
 		if joinInfo.LeftColumn == leftKey && joinInfo.RightColumn == rightKey {
 
-			// Fetch shards
-			shards, err := s.shardRepo.ShardList(ctx, projectID)
-			if err != nil {
-				return nil, err
-			}
-
-			targets := make([]ShardTarget, 0)
-
-			for _, sh := range shards {
-				if sh.Status == "active" {
-					targets = append(targets, ShardTarget{
-						ShardID: ShardID(sh.ID),
-					})
-				}
+			targets := make([]ShardTarget, 0, ring.Size())
+			for _, sid := range ring.shards {
+				targets = append(targets, ShardTarget{
+					ShardID: sid,
+				})
 			}
 
 			return &RoutingPlan{
 				Mode:    RoutingModeBroadcast,
 				Targets: targets,
-				Reason:  "colocated join detected",
+				Reason:  reasonColocatedJoin,
 			}, nil
 		}
 
@@ -151,12 +437,26 @@ func (s *RouterService) RouteSQL(
 		}, nil
 	}
 
-	// Fetch shards
-	shards, err := s.shardRepo.ShardList(ctx, projectID)
-	if err != nil {
-		return nil, err
+	if ring.Size() == 0 {
+		return nil, fmt.Errorf("no active shards for project")
 	}
 
+	planner := NewPlanner(
+		cfg,
+		NewHasher(),
+		ring,
+	)
+
+	return planner.Plan(
+		node,
+		tableName,
+		shardKeyColumn,
+	), nil
+}
+
+// activeShardIDs lists active shards in ring order
+func activeShardIDs(shards []repository.Shard) []ShardID {
+
 	activeShards := make([]repository.Shard, 0)
 	for _, sh := range shards {
 		if sh.Status == "active" {
@@ -164,10 +464,6 @@ func (s *RouterService) RouteSQL(
 		}
 	}
 
-	if len(activeShards) == 0 {
-		return nil, fmt.Errorf("no active shards for project")
-	}
-
 	sort.Slice(activeShards, func(i, j int) bool {
 		return activeShards[i].ShardIndex < activeShards[j].ShardIndex
 	})
@@ -177,23 +473,21 @@ func (s *RouterService) RouteSQL(
 		shardIDs = append(shardIDs, ShardID(sh.ID))
 	}
 
-	ring := NewRing(shardIDs)
-	hasher := NewHasher()
+	return shardIDs
+}
 
-	planner := NewPlanner(
-		s.cfg,
-		hasher,
-		ring,
-	)
+// listShards fetches the shards of a project
+func (s *RouterService) listShards(ctx context.Context, projectID string) ([]repository.Shard, error) {
 
-	plan := planner.Plan(
-		*node,
-		tableName,
-		shardKeyColumn,
-	)
+	ctx, span := tracing.Start(ctx, "router.list_shards")
 
-	return plan, nil
+	shards, err := s.shardRepo.ShardList(ctx, projectID)
+
+	tracing.End(span, err)
+
+	return shards, err
 }
+
 func extractTableAndNode(
 	stmt *pg_query.RawStmt,
 ) (string, *pg_query.Node, error) {
@@ -207,7 +501,10 @@ func extractTableAndNode(
 		if len(from) != 1 {
 			return "", nil, fmt.Errorf("joins not supported in v1")
 		}
-		rv := from[0].Node.(*pg_query.Node_RangeVar)
+		rv, ok := from[0].Node.(*pg_query.Node_RangeVar)
+		if !ok {
+			return "", nil, fmt.Errorf("unsupported FROM clause")
+		}
 		return rv.RangeVar.Relname, node, nil
 
 	case *pg_query.Node_InsertStmt:
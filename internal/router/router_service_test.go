@@ -0,0 +1,186 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+)
+
+// denialLog records denials in memory
+type denialLog []AccessDenial
+
+func (l *denialLog) RecordDenial(_ context.Context, denial AccessDenial) {
+	*l = append(*l, denial)
+}
+
+func TestPrepareSQL(t *testing.T) {
+
+	tenant := &AccessPolicy{
+		Tenant: &TenantScope{Column: "tenant_id", Value: "acme", Tables: []string{"orders"}},
+	}
+	readOnly := &AccessPolicy{ReadOnly: true}
+
+	tests := []struct {
+		name     string
+		policy   *AccessPolicy
+		sql      string
+		rejected bool
+		wantKind StatementKind
+		wantSQL  string
+	}{
+		{"trailing semicolon", nil, "SELECT id FROM orders WHERE id = $1;", false, StatementKindRead, "SELECT id FROM orders WHERE id = $1"},
+		{"tenant rewrite", tenant, "SELECT id FROM orders WHERE id = $1", false, StatementKindRead, "SELECT id FROM orders WHERE id = $1 AND orders.tenant_id = 'acme'"},
+		{"write", nil, "UPDATE orders SET total = $1 WHERE id = $2", false, StatementKindWrite, "UPDATE orders SET total = $1 WHERE id = $2"},
+		{"read only denies write cte", readOnly, "WITH d AS (DELETE FROM orders RETURNING *) SELECT * FROM d", true, StatementKindWrite, ""},
+		{"tenant denies other tenant", tenant, "SELECT * FROM orders WHERE tenant_id = 'other'", true, StatementKindRead, ""},
+		{"ddl", nil, "DROP TABLE orders", true, StatementKindDDL, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			denials := &denialLog{}
+			s := &RouterService{denials: denials}
+
+			ctx := context.Background()
+			if tt.policy != nil {
+				ctx = WithAccessPolicy(ctx, tt.policy)
+			}
+
+			plan, err := s.PrepareSQL(ctx, tt.sql)
+			if err != nil {
+				t.Fatalf("PrepareSQL: %v", err)
+			}
+
+			if (plan.Mode == RoutingModeRejected) != tt.rejected || plan.Kind != tt.wantKind {
+				t.Errorf("plan = %s %s, want rejected %t, kind %s", plan.Mode, plan.Kind, tt.rejected, tt.wantKind)
+			}
+			if plan.SQL != tt.wantSQL {
+				t.Errorf("SQL = %q, want %q", plan.SQL, tt.wantSQL)
+			}
+			if len(*denials) != 0 {
+				t.Errorf("recorded %d denials, want none", len(*denials))
+			}
+		})
+	}
+
+	if _, err := (&RouterService{}).PrepareSQL(context.Background(), "SELECT 1; SELECT 2"); err == nil {
+		t.Error("PrepareSQL accepted two statements")
+	}
+}
+
+func TestPlanStatement(t *testing.T) {
+
+	keys := map[string]string{"orders": "id", "users": "id"}
+	ring := NewRing([]ShardID{"a", "b"})
+
+	tests := []struct {
+		name     string
+		sql      string
+		rejected bool
+		reason   string
+	}{
+		{name: "read by key", sql: "SELECT * FROM orders WHERE id = 7"},
+		{name: "write by key", sql: "DELETE FROM orders WHERE id = 7"},
+		{name: "read CTE", sql: "WITH o AS (SELECT * FROM orders WHERE id = 7) DELETE FROM orders WHERE id = 7"},
+		{
+			name:     "write in CTE of a read",
+			sql:      "WITH d AS (DELETE FROM users WHERE id = 5 RETURNING *) SELECT * FROM orders WHERE id = 7",
+			rejected: true,
+			reason:   "data-modifying statements in WITH cannot be routed",
+		},
+		{
+			name:     "write in CTE of a write",
+			sql:      "WITH d AS (DELETE FROM users WHERE id = 5 RETURNING id) UPDATE orders SET total = 0 WHERE id = 7",
+			rejected: true,
+			reason:   "data-modifying statements in WITH cannot be routed",
+		},
+		{
+			name:     "merge",
+			sql:      "MERGE INTO orders o USING users u ON o.id = u.id WHEN MATCHED THEN DELETE",
+			rejected: true,
+			reason:   "MERGE statements cannot be routed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			tree, err := pg_query.Parse(tt.sql)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			plan, err := planStatement(DefaultRouterConfig(), tree.Stmts[0], keys, ring)
+			if err != nil {
+				t.Fatalf("planStatement: %v", err)
+			}
+
+			if rejected := plan.Mode == RoutingModeRejected; rejected != tt.rejected {
+				t.Fatalf("plan = %s %q, want rejected %t", plan.Mode, plan.Reason, tt.rejected)
+			}
+			if tt.rejected && plan.Reason != tt.reason {
+				t.Fatalf("reason = %q, want %q", plan.Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestPlanMultiRowInsert(t *testing.T) {
+
+	ring := NewRing([]ShardID{"a", "b"})
+	hasher := NewHasher()
+
+	// two ids on one shard and one on the other
+	byShard := make(map[ShardID][]int)
+	for i := 1; len(byShard["a"]) < 2 || len(byShard["b"]) < 1; i++ {
+		sid := ring.LocateShard(hasher.Hash(literalValue(t, fmt.Sprint(i))))
+		byShard[sid] = append(byShard[sid], i)
+	}
+	a1, a2, b1 := byShard["a"][0], byShard["a"][1], byShard["b"][0]
+
+	tests := []struct {
+		name     string
+		sql      string
+		rejected bool
+	}{
+		{
+			name: "rows on one shard",
+			sql:  fmt.Sprintf("INSERT INTO orders (id, total) VALUES (%d, 1), (%d, 2)", a1, a2),
+		},
+		{
+			name:     "rows on several shards",
+			sql:      fmt.Sprintf("INSERT INTO orders (id, total) VALUES (%d, 1), (%d, 2)", a1, b1),
+			rejected: true,
+		},
+		{
+			name:     "more rows than shards",
+			sql:      fmt.Sprintf("INSERT INTO orders (id, total) VALUES (%d, 1), (%d, 2), (%d, 3)", a1, a2, b1),
+			rejected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			tree, err := pg_query.Parse(tt.sql)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			plan, err := planStatement(DefaultRouterConfig(), tree.Stmts[0], map[string]string{"orders": "id"}, ring)
+			if err != nil {
+				t.Fatalf("planStatement: %v", err)
+			}
+
+			if rejected := plan.Mode == RoutingModeRejected; rejected != tt.rejected {
+				t.Fatalf("plan = %s %q, want rejected %t", plan.Mode, plan.Reason, tt.rejected)
+			}
+			if !tt.rejected && (len(plan.Targets) != 1 || plan.Targets[0].ShardID != "a") {
+				t.Fatalf("targets = %v, want a", plan.Targets)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package router
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/tracing"
+)
+
+// SlotsPerShard is the number of hash slots per shard when a shard map is
+// first frozen, a multiple of the shard count keeps every key on the shard
+// the modulo ring placed it on
+const SlotsPerShard = 256
+
+// phase in which writes to moving slots are held back
+const phaseCutover = "cutover"
+
+// FreezeOwners returns the slot owners reproducing modulo routing over the
+// given shards in ring order
+func FreezeOwners(shards []repository.Shard) []string {
+
+	shardIDs := activeShardIDs(shards)
+
+	owners := make([]string, 0, len(shardIDs)*SlotsPerShard)
+	for i := 0; i < len(shardIDs)*SlotsPerShard; i++ {
+		owners = append(owners, string(shardIDs[i%len(shardIDs)]))
+	}
+
+	return owners
+}
+
+// KeySlot returns the hash slot of a shard key value in a map of the given size
+func KeySlot(value any, slots int) int {
+	return int(NewHasher().Hash(value) % HashValue(slots))
+}
+
+// loadRing builds the ring of a project from its shard map, or from its
+// active shards by modulo while it has none
+func (s *RouterService) loadRing(ctx context.Context, projectID string) (*Ring, error) {
+
+	ctx, span := tracing.Start(ctx, "router.build_ring")
+
+	ring, err := s.buildRing(ctx, projectID)
+	if ring != nil {
+		span.SetAttributes(
+			attribute.Int("routing.shards", ring.Size()),
+			attribute.Int("routing.slots", len(ring.slots)),
+		)
+	}
+	tracing.End(span, err)
+
+	return ring, err
+}
+
+func (s *RouterService) buildRing(ctx context.Context, projectID string) (*Ring, error) {
+
+	shardMap, err := s.shardMapRepo.ShardMapGet(ctx, projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		shards, err := s.listShards(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		return NewRing(activeShardIDs(shards)), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	slots := make([]ShardID, 0, len(shardMap.Owners))
+	for _, owner := range shardMap.Owners {
+		slots = append(slots, ShardID(owner))
+	}
+
+	return NewSlotRing(slots), nil
+}
+
+// applyMoves sends writes on keys being copied to another shard to that
+// shard as well, and holds them back while the job cuts over. Writes not
+// resolved to keys may touch any moving row. A shadow runs the whole
+// statement, so an insert is only copied when all its rows move.
+func applyMoves(
+	plan *RoutingPlan,
+	ring *Ring,
+	phase string,
+	moves []repository.SlotMove,
+	insert bool,
+) *RoutingPlan {
+
+	if len(moves) == 0 || plan.Mode == RoutingModeRejected {
+		return plan
+	}
+
+	moving := make(map[int]ShardID, len(moves))
+	for _, m := range moves {
+		moving[m.Slot] = ShardID(m.TargetShardID)
+	}
+
+	affected := make([]ShardID, 0)
+	seen := make(map[ShardID]struct{})
+	staying := 0
+
+	add := func(sid ShardID) {
+		if _, ok := seen[sid]; ok {
+			return
+		}
+		seen[sid] = struct{}{}
+		affected = append(affected, sid)
+	}
+
+	if plan.Mode == RoutingModeBroadcast || len(plan.KeyValues) == 0 {
+		for _, m := range moves {
+			add(ShardID(m.TargetShardID))
+		}
+	} else {
+		hasher := NewHasher()
+		for _, v := range plan.KeyValues {
+			if target, ok := moving[ring.Slot(hasher.Hash(v))]; ok {
+				add(target)
+				continue
+			}
+			staying++
+		}
+	}
+
+	if len(affected) == 0 {
+		return plan
+	}
+
+	if phase == phaseCutover {
+		return &RoutingPlan{
+			Mode:   RoutingModeRejected,
+			Reason: "shard keys are moving between shards",
+			RejectError: &RoutingError{
+				Code:    ErrShardMoving,
+				Message: "shard keys of this statement are moving between shards, retry shortly",
+			},
+		}
+	}
+
+	if insert && (len(affected) > 1 || staying > 0) {
+		return &RoutingPlan{
+			Mode:   RoutingModeRejected,
+			Reason: "insert rows are split by moving shard keys",
+			RejectError: &RoutingError{
+				Code:    ErrUnsupportedStatement,
+				Message: "rows of this insert move to different shards, insert them separately",
+			},
+		}
+	}
+
+	// shards the statement already runs on need no copy
+	for _, sid := range affected {
+		inTargets := false
+		for _, target := range plan.Targets {
+			if target.ShardID == sid {
+				inTargets = true
+				break
+			}
+		}
+		if !inTargets {
+			plan.Shadows = append(plan.Shadows, ShardTarget{ShardID: sid})
+		}
+	}
+
+	return plan
+}
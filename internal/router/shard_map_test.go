@@ -0,0 +1,177 @@
+package router
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"sql-sharding-v2/internal/repository"
+)
+
+func TestFreezeOwnersReproducesModulo(t *testing.T) {
+
+	shards := []repository.Shard{
+		{ID: "c", ShardIndex: 2, Status: "active"},
+		{ID: "a", ShardIndex: 0, Status: "active"},
+		{ID: "x", ShardIndex: 3, Status: "inactive"},
+		{ID: "b", ShardIndex: 1, Status: "active"},
+	}
+
+	owners := FreezeOwners(shards)
+	if len(owners) != 3*SlotsPerShard {
+		t.Fatalf("got %d slots, want %d", len(owners), 3*SlotsPerShard)
+	}
+
+	slots := make([]ShardID, 0, len(owners))
+	for _, owner := range owners {
+		slots = append(slots, ShardID(owner))
+	}
+
+	modulo := NewRing(activeShardIDs(shards))
+	frozen := NewSlotRing(slots)
+	hasher := NewHasher()
+
+	for i := 0; i < 1000; i++ {
+		for _, value := range []any{literalValue(t, fmt.Sprint(i)), literalValue(t, fmt.Sprintf("'user-%d'", i))} {
+			h := hasher.Hash(value)
+			if got, want := frozen.LocateShard(h), modulo.LocateShard(h); got != want {
+				t.Fatalf("value %v on shard %s, modulo placed it on %s", value, got, want)
+			}
+		}
+	}
+}
+
+func TestApplyMoves(t *testing.T) {
+
+	slots := []ShardID{"a", "b", "a", "b"}
+	ring := NewSlotRing(slots)
+	hasher := NewHasher()
+
+	// find a key in slot 0, owned by a, and one in slot 1, owned by b
+	var moving, staying any
+	for i := 0; moving == nil || staying == nil; i++ {
+		value := literalValue(t, fmt.Sprint(i))
+		switch ring.Slot(hasher.Hash(value)) {
+		case 0:
+			moving = value
+		case 1:
+			staying = value
+		}
+	}
+
+	moves := []repository.SlotMove{{Slot: 0, SourceShardID: "a", TargetShardID: "c"}}
+
+	single := func(value any) *RoutingPlan {
+		return &RoutingPlan{
+			Mode:      RoutingModeSingle,
+			Targets:   []ShardTarget{{ShardID: ring.LocateShard(hasher.Hash(value))}},
+			KeyValues: []any{value},
+		}
+	}
+	broadcast := func() *RoutingPlan {
+		return &RoutingPlan{
+			Mode:    RoutingModeBroadcast,
+			Targets: []ShardTarget{{ShardID: "a"}, {ShardID: "b"}},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		plan         *RoutingPlan
+		phase        string
+		moves        []repository.SlotMove
+		wantShadows  []ShardTarget
+		wantRejected bool
+	}{
+		{"no moves", single(moving), "copying", nil, nil, false},
+		{"key in a moving slot", single(moving), "copying", moves, []ShardTarget{{ShardID: "c"}}, false},
+		{"key in another slot", single(staying), "catching_up", moves, nil, false},
+		{"broadcast write", broadcast(), "copying", moves, []ShardTarget{{ShardID: "c"}}, false},
+		{"key in a moving slot at cutover", single(moving), "cutover", moves, nil, true},
+		{"broadcast write at cutover", broadcast(), "cutover", moves, nil, true},
+		{"key in another slot at cutover", single(staying), "cutover", moves, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			plan := applyMoves(tt.plan, ring, tt.phase, tt.moves, false)
+
+			if rejected := plan.Mode == RoutingModeRejected; rejected != tt.wantRejected {
+				t.Fatalf("rejected = %t, want %t", rejected, tt.wantRejected)
+			}
+			if tt.wantRejected {
+				if plan.RejectError == nil || plan.RejectError.Code != ErrShardMoving {
+					t.Errorf("reject error = %v, want shard moving", plan.RejectError)
+				}
+				return
+			}
+			if !reflect.DeepEqual(plan.Shadows, tt.wantShadows) {
+				t.Errorf("shadows = %v, want %v", plan.Shadows, tt.wantShadows)
+			}
+		})
+	}
+}
+
+func TestApplyMovesInsert(t *testing.T) {
+
+	slots := []ShardID{"a", "b", "a", "b"}
+	ring := NewSlotRing(slots)
+	hasher := NewHasher()
+
+	// two keys in slot 0 and one in slot 2, both slots owned by a
+	bySlot := make(map[int][]any)
+	for i := 0; len(bySlot[0]) < 2 || len(bySlot[2]) < 1; i++ {
+		value := literalValue(t, fmt.Sprint(i))
+		slot := ring.Slot(hasher.Hash(value))
+		bySlot[slot] = append(bySlot[slot], value)
+	}
+	slot0, slot0b, slot2 := bySlot[0][0], bySlot[0][1], bySlot[2][0]
+
+	insert := func(values ...any) *RoutingPlan {
+		return &RoutingPlan{
+			Mode:      RoutingModeSingle,
+			Targets:   []ShardTarget{{ShardID: "a"}},
+			KeyValues: values,
+		}
+	}
+
+	oneMove := []repository.SlotMove{{Slot: 0, SourceShardID: "a", TargetShardID: "c"}}
+	twoMoves := []repository.SlotMove{
+		{Slot: 0, SourceShardID: "a", TargetShardID: "c"},
+		{Slot: 2, SourceShardID: "a", TargetShardID: "d"},
+	}
+
+	tests := []struct {
+		name         string
+		plan         *RoutingPlan
+		moves        []repository.SlotMove
+		wantShadows  []ShardTarget
+		wantRejected bool
+	}{
+		{"all rows move to one shard", insert(slot0, slot0b), oneMove, []ShardTarget{{ShardID: "c"}}, false},
+		{"no row moves", insert(slot2), oneMove, nil, false},
+		{"rows move and stay", insert(slot0, slot2), oneMove, nil, true},
+		{"rows move to different shards", insert(slot0, slot2), twoMoves, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			plan := applyMoves(tt.plan, ring, "copying", tt.moves, true)
+
+			if rejected := plan.Mode == RoutingModeRejected; rejected != tt.wantRejected {
+				t.Fatalf("rejected = %t, want %t", rejected, tt.wantRejected)
+			}
+			if tt.wantRejected {
+				if plan.RejectError == nil || plan.RejectError.Code != ErrUnsupportedStatement {
+					t.Errorf("reject error = %v, want unsupported statement", plan.RejectError)
+				}
+				return
+			}
+			if !reflect.DeepEqual(plan.Shadows, tt.wantShadows) {
+				t.Errorf("shadows = %v, want %v", plan.Shadows, tt.wantShadows)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"google.golang.org/protobuf/proto"
+
+	"sql-sharding-v2/internal/repository"
+)
+
+// ErrNoShardsToSimulate is returned when a project has no active shards and
+// no shard count was given
+var ErrNoShardsToSimulate = errors.New("no active shards to simulate against, pass a shard count")
+
+// Simulation reports how a workload would route under a shard key assignment,
+// counts are weighted by how often each statement ran
+type Simulation struct {
+	ShardCount int               `json:"shard_count"`
+	ShardKeys  map[string]string `json:"shard_keys"`
+
+	Statements int64 `json:"statements"`
+	Single     int64 `json:"single"`
+	Multi      int64 `json:"multi"`
+	Broadcast  int64 `json:"broadcast"`
+	Rejected   int64 `json:"rejected"`
+
+	SinglePct    float64 `json:"single_pct"`
+	MultiPct     float64 `json:"multi_pct"`
+	BroadcastPct float64 `json:"broadcast_pct"`
+	RejectedPct  float64 `json:"rejected_pct"`
+
+	// join statements and how many of them run colocated
+	Joins          int64   `json:"joins"`
+	ColocatedJoins int64   `json:"colocated_joins"`
+	JoinCoverage   float64 `json:"join_coverage"`
+
+	Rejections []SimulatedRejection `json:"rejections"`
+}
+
+// SimulatedRejection is a statement the router would reject
+type SimulatedRejection struct {
+	Query  string `json:"query"`
+	Calls  int64  `json:"calls"`
+	Reason string `json:"reason"`
+}
+
+// Simulate routes statements through the planner of a project without
+// executing them. keys override the stored shard keys per table and a
+// shard count above zero replaces the active shards with that many.
+func (s *RouterService) Simulate(
+	ctx context.Context,
+	projectID string,
+	keys map[string]string,
+	shardCount int,
+	statements []repository.WorkloadQuery,
+) (*Simulation, error) {
+
+	stored, err := s.shardKeysRepo.FetchShardKeysByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	shardKeyMap := make(map[string]string, len(stored)+len(keys))
+	for _, k := range stored {
+		shardKeyMap[k.TableName] = k.ShardKeyColumn
+	}
+	for table, column := range keys {
+		shardKeyMap[table] = column
+	}
+
+	var ring *Ring
+
+	if shardCount > 0 {
+		shardIDs := make([]ShardID, 0, shardCount)
+		for i := 0; i < shardCount; i++ {
+			shardIDs = append(shardIDs, ShardID(fmt.Sprintf("simulated-%d", i)))
+		}
+		ring = NewRing(shardIDs)
+	} else {
+		ring, err = s.loadRing(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ring.Size() == 0 {
+		return nil, ErrNoShardsToSimulate
+	}
+
+	return Simulate(s.cfg, shardKeyMap, ring, statements), nil
+}
+
+// Simulate routes every statement against a shard key assignment and ring
+func Simulate(
+	cfg RouterConfig,
+	shardKeyMap map[string]string,
+	ring *Ring,
+	statements []repository.WorkloadQuery,
+) *Simulation {
+
+	sim := &Simulation{
+		ShardCount: ring.Size(),
+		ShardKeys:  shardKeyMap,
+		Rejections: make([]SimulatedRejection, 0),
+	}
+
+	for _, stmt := range statements {
+
+		calls := stmt.Calls
+		if calls <= 0 {
+			calls = 1
+		}
+		sim.Statements += calls
+
+		plan, isJoin, err := simulateStatement(cfg, stmt.Query, shardKeyMap, ring)
+
+		if isJoin {
+			sim.Joins += calls
+		}
+
+		switch {
+		case err != nil:
+			sim.reject(stmt.Query, calls, err.Error())
+		case plan.Mode == RoutingModeRejected:
+			sim.reject(stmt.Query, calls, plan.Reason)
+		case plan.Mode == RoutingModeSingle:
+			sim.Single += calls
+		case plan.Mode == RoutingModeMulti:
+			sim.Multi += calls
+		case plan.Mode == RoutingModeBroadcast:
+			sim.Broadcast += calls
+			if plan.Reason == reasonColocatedJoin {
+				sim.ColocatedJoins += calls
+			}
+		}
+	}
+
+	sim.SinglePct = percent(sim.Single, sim.Statements)
+	sim.MultiPct = percent(sim.Multi, sim.Statements)
+	sim.BroadcastPct = percent(sim.Broadcast, sim.Statements)
+	sim.RejectedPct = percent(sim.Rejected, sim.Statements)
+	sim.JoinCoverage = percent(sim.ColocatedJoins, sim.Joins)
+
+	sort.SliceStable(sim.Rejections, func(i, j int) bool {
+		return sim.Rejections[i].Calls > sim.Rejections[j].Calls
+	})
+
+	return sim
+}
+
+func (sim *Simulation) reject(query string, calls int64, reason string) {
+	sim.Rejected += calls
+	sim.Rejections = append(sim.Rejections, SimulatedRejection{
+		Query:  query,
+		Calls:  calls,
+		Reason: reason,
+	})
+}
+
+// simulateStatement parses and plans one statement the way RouteSQL does
+func simulateStatement(
+	cfg RouterConfig,
+	sql string,
+	shardKeyMap map[string]string,
+	ring *Ring,
+) (*RoutingPlan, bool, error) {
+
+	parseResult, err := pg_query.Parse(sql)
+	if err != nil {
+		return nil, false, fmt.Errorf("sql parse error: %w", err)
+	}
+
+	if len(parseResult.Stmts) != 1 {
+		return nil, false, fmt.Errorf("only single-statement queries supported")
+	}
+
+	rawStmt := parseResult.Stmts[0]
+
+	if plan := rejectKind(ClassifyStatement(rawStmt.Stmt)); plan != nil {
+		return plan, false, nil
+	}
+
+	// captured and imported statements are normalized, so parameters stand
+	// in for the constants the planner needs
+	bindParams(rawStmt.Stmt)
+
+	plan, err := planStatement(cfg, rawStmt, shardKeyMap, ring)
+
+	return plan, isJoinStatement(rawStmt.Stmt), err
+}
+
+// bindParams replaces every $n with a constant, equal parameters hash alike
+func bindParams(node *pg_query.Node) {
+
+	WalkMessages(node.ProtoReflect(), func(msg proto.Message) {
+		n, ok := msg.(*pg_query.Node)
+		if !ok {
+			return
+		}
+
+		param, ok := n.Node.(*pg_query.Node_ParamRef)
+		if !ok {
+			return
+		}
+
+		n.Node = &pg_query.Node_AConst{AConst: &pg_query.A_Const{
+			Val: &pg_query.A_Const_Sval{Sval: &pg_query.String{
+				Sval: fmt.Sprintf("$%d", param.ParamRef.Number),
+			}},
+		}}
+	})
+}
+
+// isJoinStatement reports a select reading more than one relation
+func isJoinStatement(node *pg_query.Node) bool {
+
+	sel, ok := node.Node.(*pg_query.Node_SelectStmt)
+	if !ok {
+		return false
+	}
+
+	from := sel.SelectStmt.FromClause
+	if len(from) > 1 {
+		return true
+	}
+
+	if len(from) == 1 {
+		_, ok := from[0].Node.(*pg_query.Node_JoinExpr)
+		return ok
+	}
+
+	return false
+}
+
+func percent(part int64, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
@@ -0,0 +1,394 @@
+package router
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// TenantScope restricts statements to the rows of a single tenant by
+// rewriting them: a tenant predicate is added for every tenant table a
+// statement reads, updates or deletes, and the tenant value is added to
+// inserted rows.
+type TenantScope struct {
+	// Column holds the tenant of a row, e.g. tenant_id
+	Column string
+
+	// Value is the caller's tenant, empty if the caller has none
+	Value string
+
+	// Numeric is set when Column holds numbers, Value is then emitted unquoted
+	Numeric bool
+
+	// Tables lists the tables carrying Column, others are left untouched
+	Tables []string
+}
+
+var numericTenant = regexp.MustCompile(`^-?[0-9]+$`)
+
+// tenantRewriter holds the state of rewriting one statement
+type tenantRewriter struct {
+	column  string
+	tables  map[string]bool
+	ctes    map[*pg_query.RangeVar]bool
+	value   *pg_query.Node
+	valueID string
+	changed bool
+}
+
+// rewrite injects the tenant into a statement in place. It reports whether
+// the statement changed, or a reason when the statement must be rejected.
+func (t *TenantScope) rewrite(stmt *pg_query.Node) (bool, string) {
+
+	if t.Value == "" {
+		return false, "a tenant is required for this project"
+	}
+
+	value, err := t.constNode()
+	if err != nil {
+		return false, err.Error()
+	}
+
+	constVal, _ := extractConst(value)
+
+	rw := &tenantRewriter{
+		column:  strings.ToLower(t.Column),
+		tables:  map[string]bool{},
+		ctes:    cteReferences(stmt),
+		value:   value,
+		valueID: fmt.Sprintf("%v", constVal),
+	}
+	for _, table := range t.Tables {
+		rw.tables[strings.ToLower(table)] = true
+	}
+
+	var (
+		selects []*pg_query.SelectStmt
+		updates []*pg_query.UpdateStmt
+		deletes []*pg_query.DeleteStmt
+		inserts []*pg_query.InsertStmt
+		exprs   []*pg_query.A_Expr
+	)
+
+	// collect first, the tree is modified afterwards
+	WalkMessages(stmt.ProtoReflect(), func(msg proto.Message) {
+		switch m := msg.(type) {
+		case *pg_query.SelectStmt:
+			selects = append(selects, m)
+		case *pg_query.UpdateStmt:
+			updates = append(updates, m)
+		case *pg_query.DeleteStmt:
+			deletes = append(deletes, m)
+		case *pg_query.InsertStmt:
+			inserts = append(inserts, m)
+		case *pg_query.A_Expr:
+			exprs = append(exprs, m)
+		}
+	})
+
+	if _, isMerge := stmt.Node.(*pg_query.Node_MergeStmt); isMerge {
+		return false, "MERGE is not supported for tenant isolated projects"
+	}
+
+	for _, expr := range exprs {
+		if reason := rw.checkComparison(expr); reason != "" {
+			return false, reason
+		}
+	}
+
+	for _, sel := range selects {
+		preds, reason := rw.fromPredicates(sel.FromClause)
+		if reason != "" {
+			return false, reason
+		}
+		sel.WhereClause = rw.and(sel.WhereClause, preds)
+	}
+
+	for _, upd := range updates {
+		if reason := rw.checkAssignments(upd.TargetList); reason != "" {
+			return false, reason
+		}
+
+		preds, reason := rw.fromPredicates(upd.FromClause)
+		if reason != "" {
+			return false, reason
+		}
+		preds = append(preds, rw.relationPredicates(upd.Relation)...)
+		upd.WhereClause = rw.and(upd.WhereClause, preds)
+	}
+
+	for _, del := range deletes {
+		preds, reason := rw.fromPredicates(del.UsingClause)
+		if reason != "" {
+			return false, reason
+		}
+		preds = append(preds, rw.relationPredicates(del.Relation)...)
+		del.WhereClause = rw.and(del.WhereClause, preds)
+	}
+
+	for _, ins := range inserts {
+		if reason := rw.rewriteInsert(ins); reason != "" {
+			return false, reason
+		}
+	}
+
+	return rw.changed, ""
+}
+
+// constNode parses the tenant literal so it has exactly the shape, and
+// therefore the shard hash, of the same literal written in a statement
+func (t *TenantScope) constNode() (*pg_query.Node, error) {
+
+	literal := pq.QuoteLiteral(t.Value)
+	if t.Numeric {
+		if !numericTenant.MatchString(t.Value) {
+			return nil, fmt.Errorf("tenant %q is not numeric", t.Value)
+		}
+		literal = t.Value
+	}
+
+	tree, err := pg_query.Parse("SELECT " + literal)
+	if err != nil {
+		return nil, err
+	}
+
+	sel := tree.Stmts[0].Stmt.GetSelectStmt()
+	return sel.TargetList[0].GetResTarget().Val, nil
+}
+
+// isTenantTable reports whether a range var refers to a tenant table rather than a CTE
+func (rw *tenantRewriter) isTenantTable(rv *pg_query.RangeVar) bool {
+
+	if rv == nil {
+		return false
+	}
+	if rw.ctes[rv] {
+		return false
+	}
+
+	name := strings.ToLower(rv.Relname)
+	qualified := strings.ToLower(rv.Schemaname) + "." + name
+
+	return rw.tables[name] || rw.tables[qualified]
+}
+
+// relationPredicates returns the tenant predicate for a table, if it is a tenant table
+func (rw *tenantRewriter) relationPredicates(rv *pg_query.RangeVar) []*pg_query.Node {
+
+	if !rw.isTenantTable(rv) {
+		return nil
+	}
+
+	var fields []*pg_query.Node
+	switch {
+	case rv.Alias != nil:
+		fields = append(fields, pg_query.MakeStrNode(rv.Alias.Aliasname))
+	case rv.Schemaname != "":
+		fields = append(fields, pg_query.MakeStrNode(rv.Schemaname), pg_query.MakeStrNode(rv.Relname))
+	default:
+		fields = append(fields, pg_query.MakeStrNode(rv.Relname))
+	}
+	fields = append(fields, pg_query.MakeStrNode(rw.column))
+
+	return []*pg_query.Node{
+		pg_query.MakeAExprNode(
+			pg_query.A_Expr_Kind_AEXPR_OP,
+			[]*pg_query.Node{pg_query.MakeStrNode("=")},
+			pg_query.MakeColumnRefNode(fields, -1),
+			proto.Clone(rw.value).(*pg_query.Node),
+			-1,
+		),
+	}
+}
+
+// fromPredicates returns the predicates for the WHERE clause of a FROM list.
+// Tables on the nullable side of an outer join are filtered in the join condition
+// so the join keeps its meaning.
+func (rw *tenantRewriter) fromPredicates(items []*pg_query.Node) ([]*pg_query.Node, string) {
+
+	var preds []*pg_query.Node
+
+	for _, item := range items {
+		itemPreds, reason := rw.itemPredicates(item)
+		if reason != "" {
+			return nil, reason
+		}
+		preds = append(preds, itemPreds...)
+	}
+
+	return preds, ""
+}
+
+func (rw *tenantRewriter) itemPredicates(item *pg_query.Node) ([]*pg_query.Node, string) {
+
+	switch n := item.Node.(type) {
+
+	case *pg_query.Node_RangeVar:
+		return rw.relationPredicates(n.RangeVar), ""
+
+	case *pg_query.Node_JoinExpr:
+		join := n.JoinExpr
+
+		left, reason := rw.itemPredicates(join.Larg)
+		if reason != "" {
+			return nil, reason
+		}
+		right, reason := rw.itemPredicates(join.Rarg)
+		if reason != "" {
+			return nil, reason
+		}
+
+		var outer, inner []*pg_query.Node
+
+		switch join.Jointype {
+		case pg_query.JoinType_JOIN_LEFT:
+			outer, inner = left, right
+		case pg_query.JoinType_JOIN_RIGHT:
+			outer, inner = right, left
+		case pg_query.JoinType_JOIN_FULL:
+			if len(left)+len(right) > 0 {
+				return nil, "FULL JOIN on tenant tables is not supported"
+			}
+			return nil, ""
+		default:
+			return append(left, right...), ""
+		}
+
+		if len(inner) > 0 {
+			if join.Quals == nil {
+				return nil, "outer joins on tenant tables need an ON condition"
+			}
+			join.Quals = rw.and(join.Quals, inner)
+		}
+
+		return outer, ""
+
+	default:
+		// subqueries are rewritten on their own
+		return nil, ""
+	}
+}
+
+// and combines a clause with predicates
+func (rw *tenantRewriter) and(clause *pg_query.Node, preds []*pg_query.Node) *pg_query.Node {
+
+	if len(preds) == 0 {
+		return clause
+	}
+	rw.changed = true
+
+	args := preds
+	if clause != nil {
+		args = append([]*pg_query.Node{clause}, preds...)
+	}
+	if len(args) == 1 {
+		return args[0]
+	}
+
+	return pg_query.MakeBoolExprNode(pg_query.BoolExprType_AND_EXPR, args, -1)
+}
+
+// checkComparison rejects comparisons of the tenant column with another tenant
+func (rw *tenantRewriter) checkComparison(expr *pg_query.A_Expr) string {
+
+	col, ok := extractColumn(expr.Lexpr)
+	other := expr.Rexpr
+	if !ok || !strings.EqualFold(col, rw.column) {
+		col, ok = extractColumn(expr.Rexpr)
+		other = expr.Lexpr
+		if !ok || !strings.EqualFold(col, rw.column) {
+			return ""
+		}
+	}
+
+	var values []*pg_query.Node
+
+	switch expr.Kind {
+	case pg_query.A_Expr_Kind_AEXPR_OP:
+		// other operators are narrowed by the injected predicate
+		if len(expr.Name) == 1 && expr.Name[0].GetString_().GetSval() == "=" {
+			values = []*pg_query.Node{other}
+		}
+	case pg_query.A_Expr_Kind_AEXPR_IN:
+		if list, ok := other.Node.(*pg_query.Node_List); ok {
+			values = list.List.Items
+		}
+	}
+
+	for _, v := range values {
+		if val, ok := extractConst(v); ok && fmt.Sprintf("%v", val) != rw.valueID {
+			return "statement references another tenant"
+		}
+	}
+
+	return ""
+}
+
+// checkAssignments rejects moving rows to another tenant
+func (rw *tenantRewriter) checkAssignments(targets []*pg_query.Node) string {
+
+	for _, target := range targets {
+		if res := target.GetResTarget(); res != nil && strings.EqualFold(res.Name, rw.column) {
+			return fmt.Sprintf("%s cannot be updated", rw.column)
+		}
+	}
+
+	return ""
+}
+
+// rewriteInsert checks or adds the tenant value of every inserted row
+func (rw *tenantRewriter) rewriteInsert(ins *pg_query.InsertStmt) string {
+
+	if !rw.isTenantTable(ins.Relation) {
+		return ""
+	}
+
+	if ins.OnConflictClause != nil {
+		if reason := rw.checkAssignments(ins.OnConflictClause.TargetList); reason != "" {
+			return reason
+		}
+	}
+
+	sel := ins.SelectStmt.GetSelectStmt()
+	if sel == nil || len(sel.ValuesLists) == 0 {
+		return "INSERT into tenant tables requires a VALUES list"
+	}
+
+	if len(ins.Cols) == 0 {
+		return "INSERT into tenant tables requires a column list"
+	}
+
+	index := findShardKeyIndex(ins.Cols, rw.column)
+
+	for _, row := range sel.ValuesLists {
+		list := row.GetList()
+		if list == nil {
+			return "invalid VALUES list"
+		}
+
+		if index < 0 {
+			list.Items = append(list.Items, proto.Clone(rw.value).(*pg_query.Node))
+			continue
+		}
+
+		if index >= len(list.Items) {
+			return fmt.Sprintf("%s value missing", rw.column)
+		}
+
+		val, ok := extractConst(list.Items[index])
+		if !ok || fmt.Sprintf("%v", val) != rw.valueID {
+			return "statement references another tenant"
+		}
+	}
+
+	if index < 0 {
+		ins.Cols = append(ins.Cols, pg_query.MakeResTargetNodeWithName(rw.column, -1))
+		rw.changed = true
+	}
+
+	return ""
+}
@@ -0,0 +1,228 @@
+package router
+
+import (
+	"testing"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+)
+
+// rewriteTenant rewrites sql for scope and deparses the result
+func rewriteTenant(t *testing.T, scope *TenantScope, sql string) (*pg_query.ParseResult, string, string) {
+	t.Helper()
+
+	tree, err := pg_query.Parse(sql)
+	if err != nil {
+		t.Fatalf("parse %q: %v", sql, err)
+	}
+
+	_, reason := scope.rewrite(tree.Stmts[0].Stmt)
+
+	out, err := pg_query.Deparse(tree)
+	if err != nil {
+		t.Fatalf("deparse %q: %v", sql, err)
+	}
+
+	return tree, out, reason
+}
+
+func TestTenantScopeRewrite(t *testing.T) {
+
+	scope := &TenantScope{Column: "tenant_id", Value: "acme", Tables: []string{"orders", "users", "items"}}
+
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			"select",
+			"SELECT * FROM orders WHERE id = 1",
+			"SELECT * FROM orders WHERE id = 1 AND orders.tenant_id = 'acme'",
+		},
+		{
+			"schema qualified table",
+			"SELECT * FROM public.orders",
+			"SELECT * FROM public.orders WHERE public.orders.tenant_id = 'acme'",
+		},
+		{
+			"table without tenant column",
+			"SELECT * FROM products",
+			"SELECT * FROM products",
+		},
+		{
+			"inner join",
+			"SELECT * FROM orders o JOIN users u ON o.user_id = u.id",
+			"SELECT * FROM orders o JOIN users u ON o.user_id = u.id WHERE o.tenant_id = 'acme' AND u.tenant_id = 'acme'",
+		},
+		{
+			"left join filters nullable side in ON",
+			"SELECT * FROM users u LEFT JOIN orders o ON o.user_id = u.id",
+			"SELECT * FROM users u LEFT JOIN orders o ON o.user_id = u.id AND o.tenant_id = 'acme' WHERE u.tenant_id = 'acme'",
+		},
+		{
+			"right join filters nullable side in ON",
+			"SELECT * FROM users u RIGHT JOIN orders o ON o.user_id = u.id",
+			"SELECT * FROM users u RIGHT JOIN orders o ON o.user_id = u.id AND u.tenant_id = 'acme' WHERE o.tenant_id = 'acme'",
+		},
+		{
+			"IN subquery",
+			"SELECT * FROM orders WHERE user_id IN (SELECT id FROM users WHERE name = 'x')",
+			"SELECT * FROM orders WHERE user_id IN (SELECT id FROM users WHERE name = 'x' AND users.tenant_id = 'acme') AND orders.tenant_id = 'acme'",
+		},
+		{
+			"EXISTS subquery",
+			"SELECT * FROM orders WHERE EXISTS (SELECT 1 FROM items i WHERE i.order_id = orders.id)",
+			"SELECT * FROM orders WHERE EXISTS (SELECT 1 FROM items i WHERE i.order_id = orders.id AND i.tenant_id = 'acme') AND orders.tenant_id = 'acme'",
+		},
+		{
+			"FROM subquery",
+			"SELECT * FROM (SELECT * FROM orders) o",
+			"SELECT * FROM (SELECT * FROM orders WHERE orders.tenant_id = 'acme') o",
+		},
+		{
+			"CTE",
+			"WITH o AS (SELECT * FROM orders) SELECT * FROM o",
+			"WITH o AS (SELECT * FROM orders WHERE orders.tenant_id = 'acme') SELECT * FROM o",
+		},
+		{
+			"CTE named like a delete target",
+			"WITH orders AS (SELECT 1) DELETE FROM orders WHERE id = 5",
+			"WITH orders AS (SELECT 1) DELETE FROM orders WHERE id = 5 AND orders.tenant_id = 'acme'",
+		},
+		{
+			"CTE named like an update target",
+			"WITH orders AS (SELECT 1) UPDATE orders SET total = 0 WHERE id = 5",
+			"WITH orders AS (SELECT 1) UPDATE orders SET total = 0 WHERE id = 5 AND orders.tenant_id = 'acme'",
+		},
+		{
+			"CTE named like an insert target",
+			"WITH orders AS (SELECT 1) INSERT INTO orders (id) VALUES (1)",
+			"WITH orders AS (SELECT 1) INSERT INTO orders (id, tenant_id) VALUES (1, 'acme')",
+		},
+		{
+			"CTE body reads the table it shadows",
+			"WITH orders AS (SELECT * FROM orders) SELECT (SELECT string_agg(o.secret, ',') FROM orders o) FROM users WHERE tenant_id = 'acme'",
+			"WITH orders AS (SELECT * FROM orders WHERE orders.tenant_id = 'acme') SELECT (SELECT string_agg(o.secret, ',') FROM orders o) FROM users WHERE tenant_id = 'acme' AND users.tenant_id = 'acme'",
+		},
+		{
+			"CTE scoped to its subquery",
+			"SELECT * FROM (WITH orders AS (SELECT 1) SELECT * FROM orders) x, orders",
+			"SELECT * FROM (WITH orders AS (SELECT 1) SELECT * FROM orders) x, orders WHERE orders.tenant_id = 'acme'",
+		},
+		{
+			"recursive CTE refers to itself",
+			"WITH RECURSIVE orders AS (SELECT 1 AS n UNION ALL SELECT n + 1 FROM orders WHERE n < 3) SELECT * FROM orders",
+			"WITH RECURSIVE orders AS (SELECT 1 AS n UNION ALL SELECT n + 1 FROM orders WHERE n < 3) SELECT * FROM orders",
+		},
+		{
+			"insert adds tenant",
+			"INSERT INTO orders (id, total) VALUES (1, 10), (2, 20)",
+			"INSERT INTO orders (id, total, tenant_id) VALUES (1, 10, 'acme'), (2, 20, 'acme')",
+		},
+		{
+			"insert carrying tenant",
+			"INSERT INTO orders (id, tenant_id) VALUES (1, 'acme')",
+			"INSERT INTO orders (id, tenant_id) VALUES (1, 'acme')",
+		},
+		{
+			"update",
+			"UPDATE orders SET total = 0 WHERE id = 1",
+			"UPDATE orders SET total = 0 WHERE id = 1 AND orders.tenant_id = 'acme'",
+		},
+		{
+			"update from",
+			"UPDATE orders o SET total = 0 FROM users u WHERE o.user_id = u.id",
+			"UPDATE orders o SET total = 0 FROM users u WHERE o.user_id = u.id AND u.tenant_id = 'acme' AND o.tenant_id = 'acme'",
+		},
+		{
+			"delete using",
+			"DELETE FROM orders USING users WHERE orders.user_id = users.id",
+			"DELETE FROM orders USING users WHERE orders.user_id = users.id AND users.tenant_id = 'acme' AND orders.tenant_id = 'acme'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got, reason := rewriteTenant(t, scope, tt.sql)
+			if reason != "" {
+				t.Fatalf("rewrite rejected: %s", reason)
+			}
+			if got != tt.want {
+				t.Errorf("rewrite =\n  %s\nwant\n  %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenantScopeRewriteRejects(t *testing.T) {
+
+	scope := &TenantScope{Column: "tenant_id", Value: "acme", Tables: []string{"orders", "users"}}
+
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT * FROM orders WHERE tenant_id = 'other'", "statement references another tenant"},
+		{"SELECT * FROM orders WHERE tenant_id IN ('acme', 'other')", "statement references another tenant"},
+		{"INSERT INTO orders (id, tenant_id) VALUES (1, 'other')", "statement references another tenant"},
+		{"INSERT INTO orders VALUES (1)", "INSERT into tenant tables requires a column list"},
+		{"INSERT INTO orders (id) SELECT id FROM carts", "INSERT into tenant tables requires a VALUES list"},
+		{"UPDATE orders SET tenant_id = 'other'", "tenant_id cannot be updated"},
+		{"INSERT INTO orders (id) VALUES (1) ON CONFLICT (id) DO UPDATE SET tenant_id = 'other'", "tenant_id cannot be updated"},
+		{"SELECT * FROM users u FULL JOIN orders o ON o.user_id = u.id", "FULL JOIN on tenant tables is not supported"},
+		{"MERGE INTO orders o USING users u ON o.user_id = u.id WHEN MATCHED THEN DELETE", "MERGE is not supported for tenant isolated projects"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sql, func(t *testing.T) {
+			if _, _, reason := rewriteTenant(t, scope, tt.sql); reason != tt.want {
+				t.Errorf("rewrite reason = %q, want %q", reason, tt.want)
+			}
+		})
+	}
+
+	if _, _, reason := rewriteTenant(t, &TenantScope{Column: "tenant_id"}, "SELECT 1"); reason == "" {
+		t.Error("rewrite without a tenant was accepted")
+	}
+}
+
+func TestTenantScopeRewriteNumeric(t *testing.T) {
+
+	scope := &TenantScope{Column: "tenant_id", Value: "42", Numeric: true, Tables: []string{"orders"}}
+
+	_, got, reason := rewriteTenant(t, scope, "SELECT * FROM orders WHERE tenant_id = 42")
+	if reason != "" {
+		t.Fatalf("rewrite rejected: %s", reason)
+	}
+	if want := "SELECT * FROM orders WHERE tenant_id = 42 AND orders.tenant_id = 42"; got != want {
+		t.Errorf("rewrite = %s, want %s", got, want)
+	}
+
+	if _, _, reason := rewriteTenant(t, scope, "SELECT * FROM orders WHERE tenant_id = 7"); reason == "" {
+		t.Error("statement naming another numeric tenant was accepted")
+	}
+
+	invalid := &TenantScope{Column: "tenant_id", Value: "acme", Numeric: true, Tables: []string{"orders"}}
+	if _, _, reason := rewriteTenant(t, invalid, "SELECT * FROM orders"); reason == "" {
+		t.Error("non-numeric tenant for a numeric column was accepted")
+	}
+}
+
+func TestTenantScopedLeftJoinRoutes(t *testing.T) {
+
+	scope := &TenantScope{Column: "tenant_id", Value: "acme", Tables: []string{"orders", "users"}}
+	tree, _, reason := rewriteTenant(t, scope, "SELECT * FROM users u LEFT JOIN orders o ON u.id = o.user_id")
+	if reason != "" {
+		t.Fatalf("rewrite rejected: %s", reason)
+	}
+
+	keys := map[string]string{"users": "id", "orders": "user_id"}
+	plan, err := planStatement(DefaultRouterConfig(), tree.Stmts[0], keys, NewRing([]ShardID{"a", "b"}))
+	if err != nil {
+		t.Fatalf("planStatement: %v", err)
+	}
+
+	if plan.Mode != RoutingModeBroadcast || plan.Reason != reasonColocatedJoin {
+		t.Errorf("plan = %s %q, want colocated broadcast", plan.Mode, plan.Reason)
+	}
+}
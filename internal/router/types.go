@@ -12,15 +12,72 @@ const (
 	RoutingModeRejected
 )
 
+func (m RoutingMode) String() string {
+	switch m {
+	case RoutingModeSingle:
+		return "single"
+	case RoutingModeMulti:
+		return "multi"
+	case RoutingModeBroadcast:
+		return "broadcast"
+	case RoutingModeRejected:
+		return "rejected"
+	default:
+		return "invalid"
+	}
+}
+
 type ShardID string
 
+// ---------- Statement kinds ----------
+
+type StatementKind int
+
+const (
+	StatementKindUnknown StatementKind = iota
+	StatementKindRead
+	StatementKindWrite
+	StatementKindDDL
+	StatementKindUtility
+)
+
+func (k StatementKind) String() string {
+	switch k {
+	case StatementKindRead:
+		return "read"
+	case StatementKindWrite:
+		return "write"
+	case StatementKindDDL:
+		return "ddl"
+	case StatementKindUtility:
+		return "utility"
+	default:
+		return "unknown"
+	}
+}
+
 // Routing plan
 
 type RoutingPlan struct {
-	Mode        RoutingMode
-	Targets     []ShardTarget
-	Reason      string
-	RejectError *RoutingError
+	Mode         RoutingMode
+	Kind         StatementKind
+	HasReturning bool
+	Targets      []ShardTarget
+	Reason       string
+	RejectError  *RoutingError
+
+	// SQL is the statement to execute when the router rewrote it, empty otherwise
+	SQL string
+
+	// Table, ShardKey and KeyValues are the shard key the plan was resolved
+	// by and the values hashed, empty unless the planner resolved a key
+	Table     string
+	ShardKey  string
+	KeyValues []any
+
+	// Shadows receive a copy of a write while its keys are copied to them,
+	// their results are discarded
+	Shadows []ShardTarget
 }
 
 type ShardTarget struct {
@@ -43,7 +43,7 @@ func ExecuteProjectSchema(
 			return err
 		}
 
-		if shard.Status != "active" {
+		if shard.Status == "inactive" {
 			msg := "shard inactive"
 			_ = execRepo.ExxecutionRecordsUpdateState(
 				ctx,
@@ -1,31 +1,48 @@
 package shardkey
 
 import (
+	"fmt"
+
+	"sql-sharding-v2/internal/repository"
 	"sql-sharding-v2/internal/schema"
 )
 
-// BuildShardKeyPlan runs the full shard key inference pipeline
-// using schema-only analysis.
-func BuildShardKeyPlan(s *schema.LogicalSchema) InferenceResult {
+// BuildShardKeyPlan runs the full shard key inference pipeline using
+// schema analysis, weighted by the observed workload and data distribution
+// when the signals carry them, scored with the weights of the profile.
+// Each root table picks its best ranked key and the tables owned by it
+// through FK chains inherit that key so they stay co-located.
+func BuildShardKeyPlan(s *schema.LogicalSchema, profile repository.InferenceProfile, signals Signals) InferenceResult {
 
 	result := InferenceResult{
 		ProjectID: s.ProjectID,
+		Profile:   profile,
 	}
 
-	candidates := ExtractCandidates(s)
+	candidates := ExtractCandidates(s, profile, signals.Statistics)
 
 	fanout := ComputeFanout(s, candidates)
 
-	for tableName, localCandidates := range candidates {
+	ranked := make(map[string][]RankedCandidate, len(candidates))
 
-		ranked := RankTableCandidates(
+	for tableName, localCandidates := range candidates {
+		ranked[tableName] = RankTableCandidates(
 			tableName,
 			localCandidates,
 			fanout,
 			s,
+			profile,
+			signals,
 		)
+	}
 
-		decision := selectBestCandidate(tableName, ranked)
+	groups, placements := buildColocationGroups(s, candidates, ranked)
+	result.Groups = groups
+	result.Candidates = ranked
+
+	for tableName, tableRanked := range ranked {
+
+		decision := selectBestCandidate(tableName, tableRanked, placements[tableName])
 		if decision == nil {
 			continue
 		}
@@ -36,7 +53,9 @@ func BuildShardKeyPlan(s *schema.LogicalSchema) InferenceResult {
 	return result
 }
 
-func selectBestCandidate(table string, ranked []RankedCandidate) *ShardKeyDecision {
+// selectBestCandidate takes the key placement propagated to the table,
+// which is its best ranked candidate unless it inherits a root key
+func selectBestCandidate(table string, ranked []RankedCandidate, p *placement) *ShardKeyDecision {
 
 	if len(ranked) == 0 {
 		return nil
@@ -44,10 +63,32 @@ func selectBestCandidate(table string, ranked []RankedCandidate) *ShardKeyDecisi
 
 	best := ranked[0]
 
+	if p != nil && p.Column != "" {
+		for _, r := range ranked {
+			if r.Column.Column == p.Column {
+				best = r
+				break
+			}
+		}
+	}
+
+	reasons := append([]string(nil), best.Reasons...)
+
+	if p != nil {
+		if p.Root != table {
+			reasons = append(reasons, fmt.Sprintf("co-located with %s via %s", p.Root, p.Via))
+		}
+		for _, m := range p.missing {
+			reasons = append(reasons, fmt.Sprintf(
+				"needs a denormalized %s column to co-locate with %s", m.Missing, m.Table,
+			))
+		}
+	}
+
 	return &ShardKeyDecision{
 		Table:   table,
 		Column:  best.Column,
 		Score:   best.Score,
-		Reasons: best.Reasons,
+		Reasons: reasons,
 	}
 }
@@ -0,0 +1,279 @@
+package shardkey
+
+import (
+	"fmt"
+	"sort"
+
+	"sql-sharding-v2/internal/schema"
+)
+
+// ColocatedTable describes how a table is distributed within a group
+type ColocatedTable struct {
+	Table string `json:"table"`
+
+	// column carrying the distribution key of the group root
+	Column string `json:"column,omitempty"`
+
+	// foreign key the key is inherited through, empty for the root
+	Via string `json:"via,omitempty"`
+
+	// column the table would need to be co-located with the group
+	Missing string `json:"missing,omitempty"`
+}
+
+// ColocationGroup is a root table and every table distributed by its key,
+// rows sharing a key value live on the same shard across all members
+type ColocationGroup struct {
+	Root    string           `json:"root"`
+	Key     string           `json:"key"`
+	Members []ColocatedTable `json:"members"`
+
+	// tables referencing the group that lack a column carrying its key,
+	// they are distributed by a key of their own
+	Unresolved []ColocatedTable `json:"unresolved,omitempty"`
+}
+
+// placement is where a table ends up after propagation
+type placement struct {
+	ColocatedTable
+	Root string
+
+	// co-location the table would get by adding the missing column
+	missing []ColocatedTable
+}
+
+type colocation struct {
+	schema     *schema.LogicalSchema
+	candidates map[ColumnRef]struct{}
+	ranked     map[string][]RankedCandidate
+
+	placed   map[string]*placement
+	visiting map[string]bool
+}
+
+// buildColocationGroups walks the FK DAG from the root tables down and
+// propagates the distribution key of each root to the tables owned by it.
+// A table inherits the key through a non-nullable FK to the column its
+// parent is distributed by, or through a column named like the parent key.
+// Tables without either are reported and keep a key of their own, which
+// makes them the root of a group of their own.
+func buildColocationGroups(
+	s *schema.LogicalSchema,
+	candidates CandidateSet,
+	ranked map[string][]RankedCandidate,
+) ([]ColocationGroup, map[string]*placement) {
+
+	c := &colocation{
+		schema:     s,
+		candidates: indexCandidates(candidates),
+		ranked:     ranked,
+		placed:     make(map[string]*placement),
+		visiting:   make(map[string]bool),
+	}
+
+	tables := make([]string, 0, len(s.Tables))
+	for name := range s.Tables {
+		tables = append(tables, name)
+	}
+	sort.Strings(tables)
+
+	for _, name := range tables {
+		c.resolve(name)
+	}
+
+	return c.groups(tables), c.placed
+}
+
+// resolve places a table after its parents, nil while the table is on the
+// current path so FK cycles are cut instead of followed
+func (c *colocation) resolve(tableName string) *placement {
+
+	if p, ok := c.placed[tableName]; ok {
+		return p
+	}
+
+	if c.visiting[tableName] {
+		return nil
+	}
+
+	c.visiting[tableName] = true
+	defer delete(c.visiting, tableName)
+
+	var options []placement
+
+	for _, fk := range sortedFKs(c.schema.Tables[tableName]) {
+
+		// self references and optional relationships do not imply ownership
+		if fk.ParentTable == tableName {
+			continue
+		}
+		if !c.isCandidate(tableName, fk.ChildColumn) {
+			continue
+		}
+
+		parent := c.resolve(fk.ParentTable)
+		if parent == nil || parent.Column == "" {
+			continue
+		}
+
+		option := placement{
+			ColocatedTable: ColocatedTable{
+				Table: tableName,
+				Via: fmt.Sprintf("%s.%s -> %s.%s",
+					tableName, fk.ChildColumn, fk.ParentTable, fk.ParentColumn),
+			},
+			Root: parent.Root,
+		}
+
+		switch {
+		case parent.Column == fk.ParentColumn:
+			option.Column = fk.ChildColumn
+		case c.isCandidate(tableName, parent.Column):
+			// the parent key is already denormalized into the table
+			option.Column = parent.Column
+		default:
+			option.Missing = parent.Column
+		}
+
+		options = append(options, option)
+	}
+
+	p := c.choose(tableName, options)
+	c.placed[tableName] = p
+
+	return p
+}
+
+// choose keeps the co-located option whose key ranks best for the table,
+// falling back to the table's own best candidate as a new root
+func (c *colocation) choose(tableName string, options []placement) *placement {
+
+	var best *placement
+	bestScore := 0
+
+	for i := range options {
+		option := &options[i]
+		if option.Missing != "" {
+			continue
+		}
+
+		score := c.score(tableName, option.Column)
+		if best == nil || score > bestScore {
+			best, bestScore = option, score
+		}
+	}
+
+	if best != nil {
+		return best
+	}
+
+	root := &placement{
+		ColocatedTable: ColocatedTable{Table: tableName},
+		Root:           tableName,
+	}
+
+	if ranked := c.ranked[tableName]; len(ranked) > 0 {
+		root.Column = ranked[0].Column.Column
+	}
+
+	for _, option := range options {
+		root.missing = append(root.missing, ColocatedTable{
+			Table:   option.Root,
+			Via:     option.Via,
+			Missing: option.Missing,
+		})
+	}
+
+	return root
+}
+
+// groups collects placements per root, roots without members are left out
+func (c *colocation) groups(tables []string) []ColocationGroup {
+
+	byRoot := make(map[string]*ColocationGroup)
+
+	group := func(root string) *ColocationGroup {
+		g, ok := byRoot[root]
+		if !ok {
+			g = &ColocationGroup{
+				Root:    root,
+				Key:     root + "." + c.placed[root].Column,
+				Members: make([]ColocatedTable, 0),
+			}
+			byRoot[root] = g
+		}
+		return g
+	}
+
+	for _, name := range tables {
+		p := c.placed[name]
+
+		if p.Root != name {
+			g := group(p.Root)
+			g.Members = append(g.Members, p.ColocatedTable)
+		}
+
+		for _, m := range p.missing {
+			g := group(m.Table)
+			g.Unresolved = append(g.Unresolved, ColocatedTable{
+				Table:   name,
+				Via:     m.Via,
+				Missing: m.Missing,
+			})
+		}
+	}
+
+	result := make([]ColocationGroup, 0, len(byRoot))
+	for _, g := range byRoot {
+		result = append(result, *g)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i].Members) != len(result[j].Members) {
+			return len(result[i].Members) > len(result[j].Members)
+		}
+		return result[i].Root < result[j].Root
+	})
+
+	return result
+}
+
+func (c *colocation) isCandidate(tableName string, column string) bool {
+	_, ok := c.candidates[ColumnRef{Table: tableName, Column: column}]
+	return ok
+}
+
+// score of a column in the table's own ranking
+func (c *colocation) score(tableName string, column string) int {
+	for _, r := range c.ranked[tableName] {
+		if r.Column.Column == column {
+			return r.Score
+		}
+	}
+	return 0
+}
+
+// sortedFKs orders the FKs of a table so propagation is deterministic
+func sortedFKs(table *schema.Table) []*schema.FK {
+
+	if table == nil {
+		return nil
+	}
+
+	fks := make([]*schema.FK, 0, len(table.FKs))
+	for _, fk := range table.FKs {
+		fks = append(fks, fk)
+	}
+
+	sort.Slice(fks, func(i, j int) bool {
+		if fks[i].ChildColumn != fks[j].ChildColumn {
+			return fks[i].ChildColumn < fks[j].ChildColumn
+		}
+		if fks[i].ParentTable != fks[j].ParentTable {
+			return fks[i].ParentTable < fks[j].ParentTable
+		}
+		return fks[i].ParentColumn < fks[j].ParentColumn
+	})
+
+	return fks
+}
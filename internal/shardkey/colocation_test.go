@@ -0,0 +1,126 @@
+package shardkey
+
+import (
+	"reflect"
+	"testing"
+
+	"sql-sharding-v2/internal/schema"
+)
+
+func TestBuildColocationGroups(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		schema *schema.LogicalSchema
+
+		// candidate columns per table, best ranked first
+		candidates map[string][]string
+
+		want []ColocationGroup
+	}{
+		{
+			name:   "key propagates down the FK chain",
+			schema: shopSchema(),
+			candidates: map[string][]string{
+				"customers":   {"id"},
+				"orders":      {"customer_id"},
+				"order_items": {"order_id", "customer_id"},
+			},
+			want: []ColocationGroup{
+				{
+					Root: "customers",
+					Key:  "customers.id",
+					Members: []ColocatedTable{
+						{Table: "order_items", Column: "customer_id", Via: "order_items.order_id -> orders.id"},
+						{Table: "orders", Column: "customer_id", Via: "orders.customer_id -> customers.id"},
+					},
+				},
+			},
+		},
+		{
+			name: "child without the root key stays a root of its own",
+			schema: testSchema(
+				map[string][]string{
+					"customers":   {"id"},
+					"orders":      {"id", "customer_id"},
+					"order_items": {"id", "order_id"},
+				},
+				fk{"orders", "customer_id", "customers", "id"},
+				fk{"order_items", "order_id", "orders", "id"},
+			),
+			candidates: map[string][]string{
+				"customers":   {"id"},
+				"orders":      {"customer_id"},
+				"order_items": {"order_id"},
+			},
+			want: []ColocationGroup{
+				{
+					Root:    "customers",
+					Key:     "customers.id",
+					Members: []ColocatedTable{{Table: "orders", Column: "customer_id", Via: "orders.customer_id -> customers.id"}},
+					Unresolved: []ColocatedTable{
+						{Table: "order_items", Via: "order_items.order_id -> orders.id", Missing: "customer_id"},
+					},
+				},
+			},
+		},
+		{
+			name: "optional FK does not imply ownership",
+			schema: testSchema(
+				map[string][]string{
+					"customers": {"id"},
+					"orders":    {"id", "customer_id"},
+				},
+				fk{"orders", "customer_id", "customers", "id"},
+			),
+			candidates: map[string][]string{
+				"customers": {"id"},
+				"orders":    {"id"},
+			},
+			want: []ColocationGroup{},
+		},
+		{
+			name: "FK cycle is cut",
+			schema: testSchema(
+				map[string][]string{
+					"a": {"id", "b_id"},
+					"b": {"id", "a_id"},
+				},
+				fk{"a", "b_id", "b", "id"},
+				fk{"b", "a_id", "a", "id"},
+			),
+			candidates: map[string][]string{
+				"a": {"b_id"},
+				"b": {"id", "a_id"},
+			},
+			want: []ColocationGroup{
+				{
+					Root:    "b",
+					Key:     "b.id",
+					Members: []ColocatedTable{{Table: "a", Column: "b_id", Via: "a.b_id -> b.id"}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			candidates := make(CandidateSet)
+			ranked := make(map[string][]RankedCandidate)
+
+			for table, columns := range tt.candidates {
+				for i, column := range columns {
+					ref := ColumnRef{Table: table, Column: column}
+					candidates[table] = append(candidates[table], ref)
+					ranked[table] = append(ranked[table], RankedCandidate{Column: ref, Score: 10 - i})
+				}
+			}
+
+			got, _ := buildColocationGroups(tt.schema, candidates, ranked)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,12 +3,15 @@ package shardkey
 import (
 	"strings"
 
+	"sql-sharding-v2/internal/repository"
 	"sql-sharding-v2/internal/schema"
 )
 
 // ExtractCandidates performs HARD elimination only.
 // This stage must remove only fundamentally invalid shard keys.
-func ExtractCandidates(s *schema.LogicalSchema) CandidateSet {
+// Column statistics, when taken, replace the cardinality name heuristics,
+// and the profile may eliminate further column name patterns.
+func ExtractCandidates(s *schema.LogicalSchema, profile repository.InferenceProfile, stats *Statistics) CandidateSet {
 
 	candidates := make(CandidateSet)
 
@@ -18,15 +21,23 @@ func ExtractCandidates(s *schema.LogicalSchema) CandidateSet {
 
 		for _, column := range table.Columns {
 
-			eliminated, _ := isEliminated(column)
+			ref := ColumnRef{
+				Table:  tableName,
+				Column: column.Name,
+			}
+
+			if _, excluded := matchColumn(profile.EliminatedPatterns, ref); excluded {
+				continue
+			}
+
+			colStats, hasStats := stats.Column(ref)
+
+			eliminated, _ := isEliminated(column, colStats, hasStats)
 			if eliminated {
 				continue
 			}
 
-			tableCandidates = append(tableCandidates, ColumnRef{
-				Table:  tableName,
-				Column: column.Name,
-			})
+			tableCandidates = append(tableCandidates, ref)
 		}
 
 		if len(tableCandidates) > 0 {
@@ -38,7 +49,7 @@ func ExtractCandidates(s *schema.LogicalSchema) CandidateSet {
 }
 
 // isEliminated applies ONLY structural hard-elimination rules
-func isEliminated(col *schema.Column) (bool, string) {
+func isEliminated(col *schema.Column, colStats repository.ColumnStatistic, hasStats bool) (bool, string) {
 
 	if isNullable(col) {
 		return true, "column is nullable"
@@ -48,6 +59,13 @@ func isEliminated(col *schema.Column) (bool, string) {
 		return true, "technical metadata column"
 	}
 
+	if hasStats {
+		if isLowCardinalityData(colStats) {
+			return true, "low cardinality column (statistics)"
+		}
+		return false, ""
+	}
+
 	if isLowCardinality(col) {
 		return true, "low cardinality column"
 	}
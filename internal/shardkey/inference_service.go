@@ -2,27 +2,45 @@ package shardkey
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"time"
 
 	"sql-sharding-v2/internal/repository"
 	"sql-sharding-v2/internal/schema"
 	"sql-sharding-v2/pkg/logger"
 )
 
+// WorkloadWindow is how far back statements captured in the audit log count as workload
+const WorkloadWindow = 30 * 24 * time.Hour
+
 type InferenceService struct {
 	columnRepo   *repository.ColumnRepository
 	fkRepo       *repository.FKEdgesRepository
 	shardKeyRepo *repository.ShardKeysRepository
+	workloadRepo *repository.WorkloadRepository
+	runRepo      *repository.InferenceRunRepository
+	statsRepo    *repository.ColumnStatisticsRepository
+	profileRepo  *repository.InferenceProfileRepository
 }
 
 func NewInferenceService(
 	columnRepo *repository.ColumnRepository,
 	fkRepo *repository.FKEdgesRepository,
 	shardKeyRepo *repository.ShardKeysRepository,
+	workloadRepo *repository.WorkloadRepository,
+	runRepo *repository.InferenceRunRepository,
+	statsRepo *repository.ColumnStatisticsRepository,
+	profileRepo *repository.InferenceProfileRepository,
 ) *InferenceService {
 	return &InferenceService{
 		columnRepo:   columnRepo,
 		fkRepo:       fkRepo,
 		shardKeyRepo: shardKeyRepo,
+		workloadRepo: workloadRepo,
+		runRepo:      runRepo,
+		statsRepo:    statsRepo,
+		profileRepo:  profileRepo,
 	}
 }
 
@@ -33,29 +51,172 @@ func (s *InferenceService) ApplyShardKeyInference(
 
 	logger.Logger.Info("inference entry reached")
 
-	columns, err := s.columnRepo.GetColumnsByProjectID(ctx, projectID)
+	logicalSchema, err := s.loadLogicalSchema(ctx, projectID)
 	if err != nil {
 		return err
 	}
 
-	fkEdges, err := s.fkRepo.GetEdgesByProjectID(ctx, projectID)
+	profile, err := s.LoadProfile(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	signals, err := s.loadSignals(ctx, projectID, logicalSchema)
 	if err != nil {
 		return err
 	}
 
-	logicalSchema, err := schema.BuildLogicalSchemaFromMetadata(
+	inferenceResult := BuildShardKeyPlan(logicalSchema, profile, signals)
+	inferred := convertDecisionsToShardKeyRecords(inferenceResult.Decisions)
+
+	// manual overrides survive, inference only records what it would choose
+	if err := s.shardKeyRepo.MergeInferredShardKeys(ctx, projectID, inferred); err != nil {
+		return err
+	}
+
+	run := convertResultToRun(inferenceResult, signals.Workload)
+	if err := s.runRepo.InferenceRunCreate(ctx, run); err != nil {
+		return err
+	}
+
+	logger.Logger.Info("inference run stored", "project_id", projectID, "run_id", run.ID, "tables", run.Tables)
+
+	return nil
+}
+
+// AnalyzeColocation returns the co-location groups inference derives for a project
+func (s *InferenceService) AnalyzeColocation(
+	ctx context.Context,
+	projectID string,
+) ([]ColocationGroup, error) {
+
+	logicalSchema, err := s.loadLogicalSchema(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := s.LoadProfile(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	signals, err := s.loadSignals(ctx, projectID, logicalSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildShardKeyPlan(logicalSchema, profile, signals).Groups, nil
+}
+
+// AnalyzeProjectWorkload returns the workload inference would use for a project
+func (s *InferenceService) AnalyzeProjectWorkload(
+	ctx context.Context,
+	projectID string,
+) (*Workload, error) {
+
+	logicalSchema, err := s.loadLogicalSchema(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	workload, err := s.loadWorkload(ctx, projectID, logicalSchema)
+	if err != nil || workload != nil {
+		return workload, err
+	}
+
+	return newWorkload(), nil
+}
+
+// loadLogicalSchema builds the schema of a project from its stored metadata
+func (s *InferenceService) loadLogicalSchema(
+	ctx context.Context,
+	projectID string,
+) (*schema.LogicalSchema, error) {
+
+	columns, err := s.columnRepo.GetColumnsByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	fkEdges, err := s.fkRepo.GetEdgesByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return schema.BuildLogicalSchemaFromMetadata(
 		projectID,
 		columns,
 		fkEdges,
 	)
+}
+
+// LoadProfile returns the inference profile of a project, the defaults
+// when it did not set one
+func (s *InferenceService) LoadProfile(
+	ctx context.Context,
+	projectID string,
+) (repository.InferenceProfile, error) {
+
+	profile, err := s.profileRepo.InferenceProfileGet(ctx, projectID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultProfile(), nil
+	}
 	if err != nil {
-		return err
+		return repository.InferenceProfile{}, err
 	}
 
-	inferenceResult := BuildShardKeyPlan(logicalSchema)
-	inferred := convertDecisionsToShardKeyRecords(inferenceResult.Decisions)
+	return *profile, nil
+}
 
-	return s.shardKeyRepo.ReplaceShardKeysForProject(ctx, projectID, inferred)
+// loadSignals gathers the workload and column statistics of a project
+func (s *InferenceService) loadSignals(
+	ctx context.Context,
+	projectID string,
+	logicalSchema *schema.LogicalSchema,
+) (Signals, error) {
+
+	workload, err := s.loadWorkload(ctx, projectID, logicalSchema)
+	if err != nil {
+		return Signals{}, err
+	}
+
+	stats, err := s.statsRepo.StatisticsList(ctx, projectID)
+	if err != nil {
+		return Signals{}, err
+	}
+
+	return Signals{
+		Workload:   workload,
+		Statistics: NewStatistics(stats),
+	}, nil
+}
+
+// loadWorkload analyses imported and captured statements, nil when there are none
+func (s *InferenceService) loadWorkload(
+	ctx context.Context,
+	projectID string,
+	logicalSchema *schema.LogicalSchema,
+) (*Workload, error) {
+
+	queries, err := s.workloadRepo.WorkloadList(ctx, projectID, time.Now().Add(-WorkloadWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(queries) == 0 {
+		return nil, nil
+	}
+
+	workload := AnalyzeWorkload(logicalSchema, queries)
+
+	logger.Logger.Info(
+		"workload analysed for inference",
+		"project_id", projectID,
+		"fingerprints", len(queries),
+		"statements", workload.Statements,
+	)
+
+	return workload, nil
 }
 
 func convertDecisionsToShardKeyRecords(
@@ -74,3 +235,49 @@ func convertDecisionsToShardKeyRecords(
 
 	return records
 }
+
+// convertResultToRun keeps every ranked candidate, the selected one carries
+// the reasons of the decision including co-location
+func convertResultToRun(result InferenceResult, workload *Workload) *repository.InferenceRun {
+
+	run := &repository.InferenceRun{
+		ProjectID: result.ProjectID,
+		Profile:   &result.Profile,
+	}
+
+	if workload != nil {
+		run.WorkloadStatements = workload.Statements
+	}
+
+	decisions := make(map[string]ShardKeyDecision, len(result.Decisions))
+	for _, d := range result.Decisions {
+		decisions[d.Table] = d
+	}
+
+	for table, ranked := range result.Candidates {
+		decision, decided := decisions[table]
+
+		for i, c := range ranked {
+			candidate := repository.InferenceCandidate{
+				TableName:  table,
+				Rank:       i + 1,
+				ColumnName: c.Column.Column,
+				Score:      c.Score,
+				Reasons:    c.Reasons,
+			}
+
+			if decided && decision.Column == c.Column {
+				candidate.Selected = true
+				candidate.Reasons = decision.Reasons
+			}
+
+			if candidate.Reasons == nil {
+				candidate.Reasons = []string{}
+			}
+
+			run.Candidates = append(run.Candidates, candidate)
+		}
+	}
+
+	return run
+}
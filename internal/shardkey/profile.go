@@ -0,0 +1,111 @@
+package shardkey
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"sql-sharding-v2/internal/repository"
+)
+
+// highest weight a profile may set, keeps scores far from overflow and
+// stops a single signal from drowning every other one by accident
+const maxProfileWeight = 1000
+
+// DefaultWeights are the built-in score weights
+func DefaultWeights() repository.InferenceWeights {
+	return repository.InferenceWeights{
+		IncomingFK:        incomingFKWeight,
+		ReferencingTable:  referencingTableWeight,
+		ForeignKey:        foreignKeyWeight,
+		RootAffinity:      rootAffinityWeight,
+		PrimaryKey:        primaryKeyWeight,
+		TextualPenalty:    textualPenalty,
+		Local:             localWeight,
+		WorkloadPredicate: workloadPredicateWeight,
+		WorkloadJoin:      workloadJoinWeight,
+		SkewPenalty:       statsSkewWeight,
+		PreferredColumn:   preferredColumnWeight,
+	}
+}
+
+// DefaultProfile is the profile of projects that did not set one
+func DefaultProfile() repository.InferenceProfile {
+	return repository.InferenceProfile{
+		Weights:            DefaultWeights(),
+		EliminatedPatterns: []string{},
+		PreferredColumns:   []string{},
+	}
+}
+
+// ValidateProfile checks weights are in range and every pattern is a
+// valid glob. Patterns match column names case-insensitively, a pattern
+// with a dot matches table.column instead.
+func ValidateProfile(p repository.InferenceProfile) error {
+
+	weights := map[string]int{
+		"incoming_fk":        p.Weights.IncomingFK,
+		"referencing_table":  p.Weights.ReferencingTable,
+		"foreign_key":        p.Weights.ForeignKey,
+		"root_affinity":      p.Weights.RootAffinity,
+		"primary_key":        p.Weights.PrimaryKey,
+		"textual_penalty":    p.Weights.TextualPenalty,
+		"local":              p.Weights.Local,
+		"workload_predicate": p.Weights.WorkloadPredicate,
+		"workload_join":      p.Weights.WorkloadJoin,
+		"skew_penalty":       p.Weights.SkewPenalty,
+		"preferred_column":   p.Weights.PreferredColumn,
+	}
+
+	for name, w := range weights {
+		if w < 0 || w > maxProfileWeight {
+			return fmt.Errorf("weight %s must be between 0 and %d", name, maxProfileWeight)
+		}
+	}
+
+	for _, list := range [][]string{p.EliminatedPatterns, p.PreferredColumns} {
+		for _, pattern := range list {
+			if strings.TrimSpace(pattern) == "" {
+				return fmt.Errorf("empty column pattern")
+			}
+			if _, err := path.Match(strings.ToLower(pattern), ""); err != nil {
+				return fmt.Errorf("invalid column pattern %q", pattern)
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchColumn returns the first pattern matching a column
+func matchColumn(patterns []string, col ColumnRef) (string, bool) {
+
+	column := strings.ToLower(col.Column)
+	qualified := strings.ToLower(col.Table) + "." + column
+
+	for _, pattern := range patterns {
+		p := strings.ToLower(pattern)
+
+		name := column
+		if strings.Contains(p, ".") {
+			name = qualified
+		}
+
+		if ok, _ := path.Match(p, name); ok {
+			return pattern, true
+		}
+	}
+
+	return "", false
+}
+
+// preferredBonus rewards columns named like the profile prefers
+func preferredBonus(col ColumnRef, profile repository.InferenceProfile) (int, string) {
+
+	pattern, ok := matchColumn(profile.PreferredColumns, col)
+	if !ok || profile.Weights.PreferredColumn == 0 {
+		return 0, ""
+	}
+
+	return profile.Weights.PreferredColumn, fmt.Sprintf("preferred column name (%s)", pattern)
+}
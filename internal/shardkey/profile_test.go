@@ -0,0 +1,79 @@
+package shardkey
+
+import (
+	"testing"
+
+	"sql-sharding-v2/internal/repository"
+)
+
+func TestValidateProfile(t *testing.T) {
+
+	profile := func(edit func(p *repository.InferenceProfile)) repository.InferenceProfile {
+		p := DefaultProfile()
+		edit(&p)
+		return p
+	}
+
+	tests := []struct {
+		name    string
+		profile repository.InferenceProfile
+		valid   bool
+	}{
+		{name: "defaults", profile: DefaultProfile(), valid: true},
+		{
+			name:    "patterns",
+			profile: profile(func(p *repository.InferenceProfile) { p.PreferredColumns = []string{"tenant_*", "orders.customer_id"} }),
+			valid:   true,
+		},
+		{
+			name:    "negative weight",
+			profile: profile(func(p *repository.InferenceProfile) { p.Weights.SkewPenalty = -1 }),
+		},
+		{
+			name:    "weight above the maximum",
+			profile: profile(func(p *repository.InferenceProfile) { p.Weights.Local = maxProfileWeight + 1 }),
+		},
+		{
+			name:    "blank pattern",
+			profile: profile(func(p *repository.InferenceProfile) { p.EliminatedPatterns = []string{" "} }),
+		},
+		{
+			name:    "malformed glob",
+			profile: profile(func(p *repository.InferenceProfile) { p.PreferredColumns = []string{"tenant_["} }),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProfile(tt.profile)
+			if (err == nil) != tt.valid {
+				t.Fatalf("got error %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestMatchColumn(t *testing.T) {
+
+	patterns := []string{"orders.ID", "*_uuid", "tenant_?d"}
+
+	tests := []struct {
+		column ColumnRef
+		want   string
+	}{
+		{column: ColumnRef{Table: "orders", Column: "id"}, want: "orders.ID"},
+		{column: ColumnRef{Table: "customers", Column: "id"}},
+		{column: ColumnRef{Table: "events", Column: "Device_UUID"}, want: "*_uuid"},
+		{column: ColumnRef{Table: "events", Column: "tenant_id"}, want: "tenant_?d"},
+		{column: ColumnRef{Table: "events", Column: "tenant_name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.column.Table+"."+tt.column.Column, func(t *testing.T) {
+			got, ok := matchColumn(patterns, tt.column)
+			if ok != (tt.want != "") || got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
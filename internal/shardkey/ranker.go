@@ -2,19 +2,26 @@ package shardkey
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"strings"
 
+	"sql-sharding-v2/internal/repository"
 	"sql-sharding-v2/internal/schema"
 )
 
 // RankTableCandidates ranks shard key candidates for a single table
-// using fanout + ownership + root affinity + identity + content signals.
+// using fanout + ownership + root affinity + identity + content signals,
+// weighted by observed predicates and joins when a workload is given and
+// by the data distribution when column statistics were taken. The profile
+// sets the weight of every signal and the preferred column names.
 func RankTableCandidates(
 	tableName string,
 	local []ColumnRef,
 	fanout map[ColumnRef]FanoutStats,
 	s *schema.LogicalSchema,
+	profile repository.InferenceProfile,
+	signals Signals,
 ) []RankedCandidate {
 
 	var ranked []RankedCandidate
@@ -30,7 +37,21 @@ func RankTableCandidates(
 
 		column := table.Columns[col.Column]
 
-		score, reasons := scoreColumn(col, column, stats, table, fanout)
+		score, reasons := scoreColumn(col, column, stats, table, fanout, profile.Weights)
+
+		if bonus, reason := preferredBonus(col, profile); bonus > 0 {
+			score += bonus
+			reasons = append(reasons, reason)
+		}
+
+		if bonus, workloadReasons := workloadBonus(col, signals.Workload, profile.Weights); bonus > 0 {
+			score += bonus
+			reasons = append(reasons, workloadReasons...)
+		}
+
+		penalty, statsReasons := statisticsPenalty(col, signals.Statistics, profile.Weights)
+		score += penalty
+		reasons = append(reasons, statsReasons...)
 
 		ranked = append(ranked, RankedCandidate{
 			Column:  col,
@@ -56,13 +77,14 @@ func scoreColumn(
 	stats FanoutStats,
 	table *schema.Table,
 	fanout map[ColumnRef]FanoutStats,
+	weights repository.InferenceWeights,
 ) (int, []string) {
 
 	score := 0
 	var reasons []string
 
 	if stats.IncomingFKs > 0 {
-		value := stats.IncomingFKs * 10
+		value := stats.IncomingFKs * weights.IncomingFK
 		score += value
 		reasons = append(reasons,
 			fmt.Sprintf("referenced by %d foreign keys", stats.IncomingFKs),
@@ -70,7 +92,7 @@ func scoreColumn(
 	}
 
 	if stats.ReferencingTables > 0 {
-		value := stats.ReferencingTables * 5
+		value := stats.ReferencingTables * weights.ReferencingTable
 		score += value
 		reasons = append(reasons,
 			fmt.Sprintf("shared across %d tables", stats.ReferencingTables),
@@ -78,37 +100,71 @@ func scoreColumn(
 	}
 
 	if isForeignKey(col.Column, table) {
-		score += 20
+		score += weights.ForeignKey
 		reasons = append(reasons, "foreign key (ownership column)")
 
-		if bonus, reason := rootAffinityBonus(col.Column, table, fanout); bonus > 0 {
+		if bonus, reason := rootAffinityBonus(col.Column, table, fanout, weights.RootAffinity); bonus > 0 {
 			score += bonus
 			reasons = append(reasons, reason)
 		}
 	}
 
 	if column.IsPrimaryKey {
-		score += 10
+		score += weights.PrimaryKey
 		reasons = append(reasons, "primary key (identity column)")
 	}
 
 	if isTextualColumn(column) {
-		score -= 15
+		score -= weights.TextualPenalty
 		reasons = append(reasons, "textual/content column")
 	}
 
-	score += 1
+	score += weights.Local
 	reasons = append(reasons, "local column")
 
 	return score, reasons
 }
 
+// workloadBonus prefers columns the observed statements filter or join on.
+// A statement filtering by equality on the shard key runs on one shard and
+// a join on the shard keys of both sides runs colocated, so the bonus grows
+// with the share of statements on the table that use the column that way.
+func workloadBonus(col ColumnRef, workload *Workload, weights repository.InferenceWeights) (int, []string) {
+
+	if workload == nil {
+		return 0, nil
+	}
+
+	score := 0
+	var reasons []string
+	statements := workload.TableStatements[col.Table]
+
+	if share := workload.PredicateShare(col); share > 0 {
+		score += int(math.Round(share * float64(weights.WorkloadPredicate)))
+		reasons = append(reasons, fmt.Sprintf(
+			"equality predicate in %.0f%% of %d observed statements",
+			share*100, statements,
+		))
+	}
+
+	if share, partner := workload.JoinShare(col); share > 0 {
+		score += int(math.Round(share * float64(weights.WorkloadJoin)))
+		reasons = append(reasons, fmt.Sprintf(
+			"joined with %s.%s in %.0f%% of observed statements",
+			partner.Table, partner.Column, share*100,
+		))
+	}
+
+	return score, reasons
+}
+
 // rootAffinityBonus prefers FKs that point to root tables
 // (tables with high incoming fanout).
 func rootAffinityBonus(
 	columnName string,
 	table *schema.Table,
 	fanout map[ColumnRef]FanoutStats,
+	weight int,
 ) (int, string) {
 
 	for _, fk := range table.FKs {
@@ -127,7 +183,7 @@ func rootAffinityBonus(
 		}
 
 		if stats.IncomingFKs > 0 {
-			bonus := stats.IncomingFKs * 5
+			bonus := stats.IncomingFKs * weight
 			return bonus, fmt.Sprintf(
 				"points to root table (%d incoming references)",
 				stats.IncomingFKs,
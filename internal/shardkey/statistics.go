@@ -0,0 +1,129 @@
+package shardkey
+
+import (
+	"fmt"
+	"math"
+
+	"sql-sharding-v2/internal/repository"
+)
+
+// Statistics is the observed data distribution of the columns of a project
+type Statistics struct {
+	// active shards when the statistics were taken
+	ShardCount int
+
+	Columns map[ColumnRef]repository.ColumnStatistic
+}
+
+// NewStatistics indexes stored column statistics, nil when there are none
+func NewStatistics(stats []repository.ColumnStatistic) *Statistics {
+
+	if len(stats) == 0 {
+		return nil
+	}
+
+	st := &Statistics{
+		Columns: make(map[ColumnRef]repository.ColumnStatistic, len(stats)),
+	}
+
+	for _, s := range stats {
+		st.Columns[ColumnRef{Table: s.TableName, Column: s.ColumnName}] = s
+		if s.ShardCount > st.ShardCount {
+			st.ShardCount = s.ShardCount
+		}
+	}
+
+	return st
+}
+
+// Column returns the statistics of a column, if any were taken
+func (st *Statistics) Column(col ColumnRef) (repository.ColumnStatistic, bool) {
+	if st == nil {
+		return repository.ColumnStatistic{}, false
+	}
+	s, ok := st.Columns[col]
+	return s, ok
+}
+
+// isLowCardinalityData reports columns whose values repeat too much to
+// spread rows over shards
+func isLowCardinalityData(s repository.ColumnStatistic) bool {
+	return s.NDistinct < statsMinDistinct && s.NDistinct < float64(s.RowCount)/2
+}
+
+// statisticsPenalty lowers skewed candidates, a value holding a large share
+// of the rows puts that share on a single shard whatever the shard count
+func statisticsPenalty(col ColumnRef, st *Statistics, weights repository.InferenceWeights) (int, []string) {
+
+	s, ok := st.Column(col)
+	if !ok {
+		return 0, nil
+	}
+
+	score := 0
+	reasons := []string{
+		fmt.Sprintf("%.0f distinct values in %d rows (%s)", s.NDistinct, s.RowCount, s.Source),
+	}
+
+	if s.TopFrequency > statsSkewThreshold {
+		score -= int(math.Round(s.TopFrequency * float64(weights.SkewPenalty)))
+		reasons = append(reasons, fmt.Sprintf(
+			"skewed: most common value holds %.0f%% of rows", s.TopFrequency*100,
+		))
+	}
+
+	if st.ShardCount > 1 {
+		reasons = append(reasons, fmt.Sprintf(
+			"hottest of %d shards would hold ~%.0f%% of rows",
+			st.ShardCount, EstimateMaxShardShare(s, st.ShardCount)*100,
+		))
+	}
+
+	return score, reasons
+}
+
+// EstimateMaxShardShare estimates the share of rows on the fullest shard
+// when a table is distributed by the column: the most common value lands
+// on one shard next to its even part of the remaining rows, and fewer
+// distinct values than shards leave shards empty.
+func EstimateMaxShardShare(s repository.ColumnStatistic, shardCount int) float64 {
+
+	if shardCount <= 1 {
+		return 1
+	}
+
+	share := s.TopFrequency + (1-s.TopFrequency)/float64(shardCount)
+
+	if s.NDistinct >= 1 && s.NDistinct < float64(shardCount) {
+		share = math.Max(share, 1/s.NDistinct)
+	}
+
+	return math.Min(share, 1)
+}
+
+// ColumnDistribution is a column statistic with what inference reads from it
+type ColumnDistribution struct {
+	repository.ColumnStatistic
+
+	// eliminated as shard key candidate
+	LowCardinality bool `json:"low_cardinality"`
+
+	// estimated share of rows on the fullest shard if the table were distributed by the column
+	MaxShardShare float64 `json:"max_shard_share"`
+}
+
+// DescribeStatistics explains stored column statistics
+func DescribeStatistics(stats []repository.ColumnStatistic) []ColumnDistribution {
+
+	result := make([]ColumnDistribution, 0, len(stats))
+
+	for _, s := range stats {
+		result = append(result, ColumnDistribution{
+			ColumnStatistic: s,
+			LowCardinality:  isLowCardinalityData(s),
+			MaxShardShare:   EstimateMaxShardShare(s, s.ShardCount),
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,91 @@
+package shardkey
+
+import (
+	"math"
+	"testing"
+
+	"sql-sharding-v2/internal/repository"
+)
+
+func TestEstimateMaxShardShare(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		stat      repository.ColumnStatistic
+		shards    int
+		wantShare float64
+	}{
+		{
+			name:      "uniform values spread evenly",
+			stat:      repository.ColumnStatistic{NDistinct: 10000, TopFrequency: 0},
+			shards:    4,
+			wantShare: 0.25,
+		},
+		{
+			name:      "most common value lands on one shard",
+			stat:      repository.ColumnStatistic{NDistinct: 10000, TopFrequency: 0.6},
+			shards:    4,
+			wantShare: 0.7,
+		},
+		{
+			name:      "fewer values than shards leave shards empty",
+			stat:      repository.ColumnStatistic{NDistinct: 2, TopFrequency: 0.5},
+			shards:    8,
+			wantShare: 0.5625,
+		},
+		{
+			name:      "a single value holds every row",
+			stat:      repository.ColumnStatistic{NDistinct: 1, TopFrequency: 0},
+			shards:    4,
+			wantShare: 1,
+		},
+		{
+			name:      "one shard holds every row",
+			stat:      repository.ColumnStatistic{NDistinct: 10000},
+			shards:    1,
+			wantShare: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EstimateMaxShardShare(tt.stat, tt.shards)
+			if math.Abs(got-tt.wantShare) > 1e-9 {
+				t.Fatalf("got %v, want %v", got, tt.wantShare)
+			}
+		})
+	}
+}
+
+func TestStatisticsPenalty(t *testing.T) {
+
+	weights := DefaultWeights()
+
+	st := NewStatistics([]repository.ColumnStatistic{
+		{TableName: "orders", ColumnName: "customer_id", NDistinct: 5000, TopFrequency: 0.01, RowCount: 100000, ShardCount: 4},
+		{TableName: "orders", ColumnName: "status", NDistinct: 3, TopFrequency: 0.9, RowCount: 100000, ShardCount: 4},
+	})
+
+	tests := []struct {
+		name    string
+		column  string
+		penalty bool
+		reasons int
+	}{
+		{name: "evenly spread column", column: "customer_id", reasons: 2},
+		{name: "skewed column", column: "status", penalty: true, reasons: 3},
+		{name: "column without statistics", column: "id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score, reasons := statisticsPenalty(ColumnRef{Table: "orders", Column: tt.column}, st, weights)
+			if (score < 0) != tt.penalty {
+				t.Fatalf("got score %d, want penalty %v", score, tt.penalty)
+			}
+			if len(reasons) != tt.reasons {
+				t.Fatalf("got reasons %q, want %d", reasons, tt.reasons)
+			}
+		})
+	}
+}
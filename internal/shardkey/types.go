@@ -1,5 +1,46 @@
 package shardkey
 
+import "sql-sharding-v2/internal/repository"
+
+// default schema signal weights, a project profile may override them
+const (
+	incomingFKWeight       = 10
+	referencingTableWeight = 5
+	foreignKeyWeight       = 20
+	rootAffinityWeight     = 5
+	primaryKeyWeight       = 10
+	textualPenalty         = 15
+	localWeight            = 1
+
+	// score of a column matching a preferred name of the profile
+	preferredColumnWeight = 30
+)
+
+// default workload signal weights, the score a column gets when every
+// observed statement on its table uses it
+const (
+	workloadPredicateWeight = 40
+	workloadJoinWeight      = 20
+)
+
+// data distribution thresholds, used when column statistics were taken
+const (
+	// fewer distinct values cannot spread rows over shards evenly
+	statsMinDistinct = 32
+
+	// share of rows held by the most common value above which a column is skewed
+	statsSkewThreshold = 0.05
+
+	// default penalty of a column whose most common value holds every row
+	statsSkewWeight = 60
+)
+
+// Signals are the optional observations inference weighs on top of the schema
+type Signals struct {
+	Workload   *Workload
+	Statistics *Statistics
+}
+
 // Identifies a column uniquely across schema
 type ColumnRef struct {
 	Table  string
@@ -35,5 +76,10 @@ type ShardKeyDecision struct {
 // Whole-project output
 type InferenceResult struct {
 	ProjectID string
+	Profile   repository.InferenceProfile
 	Decisions []ShardKeyDecision
+	Groups    []ColocationGroup
+
+	// every candidate per table, best first
+	Candidates map[string][]RankedCandidate
 }
@@ -0,0 +1,416 @@
+package shardkey
+
+import (
+	"sort"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"google.golang.org/protobuf/proto"
+
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/router"
+	"sql-sharding-v2/internal/schema"
+)
+
+// Workload counts how observed statements use columns, weighted by calls.
+// A statement filtering a table by equality on its shard key runs on one
+// shard, so columns used that way on most statements minimize fan-out.
+type Workload struct {
+	// statements analysed
+	Statements int64
+
+	// statements reading, updating or deleting from each table
+	TableStatements map[string]int64
+
+	// statements with an equality or IN predicate on a column
+	Predicates map[ColumnRef]int64
+
+	// statements joining two columns by equality, stored in both directions
+	Joins map[ColumnRef]map[ColumnRef]int64
+}
+
+func newWorkload() *Workload {
+	return &Workload{
+		TableStatements: make(map[string]int64),
+		Predicates:      make(map[ColumnRef]int64),
+		Joins:           make(map[ColumnRef]map[ColumnRef]int64),
+	}
+}
+
+// AnalyzeWorkload parses the statements of a workload and counts predicate
+// columns and join pairs. Unparsable statements and inserts are skipped.
+func AnalyzeWorkload(s *schema.LogicalSchema, queries []repository.WorkloadQuery) *Workload {
+
+	w := newWorkload()
+
+	for _, q := range queries {
+
+		tree, err := pg_query.Parse(q.Query)
+		if err != nil {
+			continue
+		}
+
+		for _, raw := range tree.Stmts {
+			if w.analyzeStatement(s, raw.Stmt, q.Calls) {
+				w.Statements += q.Calls
+			}
+		}
+	}
+
+	return w
+}
+
+// analyzeStatement counts every query block of a statement, e.g. subqueries
+// and CTEs, as its own scope. It reports whether any table was touched.
+func (w *Workload) analyzeStatement(s *schema.LogicalSchema, stmt *pg_query.Node, calls int64) bool {
+
+	usage := newStatementUsage()
+
+	router.WalkMessages(stmt.ProtoReflect(), func(msg proto.Message) {
+		switch m := msg.(type) {
+		case *pg_query.SelectStmt:
+			usage.addScope(s, m.FromClause, nil, m.WhereClause)
+		case *pg_query.UpdateStmt:
+			usage.addScope(s, m.FromClause, m.Relation, m.WhereClause)
+		case *pg_query.DeleteStmt:
+			usage.addScope(s, m.UsingClause, m.Relation, m.WhereClause)
+		}
+	})
+
+	// a statement counts once per table, column and pair however often it repeats them
+	for table := range usage.tables {
+		w.TableStatements[table] += calls
+	}
+	for col := range usage.predicates {
+		w.Predicates[col] += calls
+	}
+	for pair := range usage.joins {
+		w.addJoin(pair[0], pair[1], calls)
+		w.addJoin(pair[1], pair[0], calls)
+	}
+
+	return len(usage.tables) > 0
+}
+
+func (w *Workload) addJoin(from ColumnRef, to ColumnRef, calls int64) {
+	if w.Joins[from] == nil {
+		w.Joins[from] = make(map[ColumnRef]int64)
+	}
+	w.Joins[from][to] += calls
+}
+
+// PredicateShare is the fraction of statements on a column's table that
+// filter the column by equality
+func (w *Workload) PredicateShare(col ColumnRef) float64 {
+
+	if w == nil || w.TableStatements[col.Table] == 0 {
+		return 0
+	}
+
+	return float64(w.Predicates[col]) / float64(w.TableStatements[col.Table])
+}
+
+// JoinShare is the fraction of statements on a column's table that join
+// the column, along with the column it is most often joined with
+func (w *Workload) JoinShare(col ColumnRef) (float64, ColumnRef) {
+
+	if w == nil || w.TableStatements[col.Table] == 0 {
+		return 0, ColumnRef{}
+	}
+
+	var total int64
+	var partner ColumnRef
+	var partnerCalls int64
+
+	for other, calls := range w.Joins[col] {
+		total += calls
+		if calls > partnerCalls || (calls == partnerCalls && tieBreak(other, partner)) {
+			partner, partnerCalls = other, calls
+		}
+	}
+
+	share := float64(total) / float64(w.TableStatements[col.Table])
+	return min(share, 1), partner
+}
+
+// statementUsage collects what one statement touches
+type statementUsage struct {
+	tables     map[string]struct{}
+	predicates map[ColumnRef]struct{}
+	joins      map[[2]ColumnRef]struct{}
+}
+
+func newStatementUsage() *statementUsage {
+	return &statementUsage{
+		tables:     make(map[string]struct{}),
+		predicates: make(map[ColumnRef]struct{}),
+		joins:      make(map[[2]ColumnRef]struct{}),
+	}
+}
+
+// scope maps the names visible in a query block to tables
+type scope struct {
+	schema *schema.LogicalSchema
+	names  map[string]string
+	tables []string
+}
+
+// addScope records the tables of a query block and the conditions of its
+// WHERE clause and join conditions
+func (u *statementUsage) addScope(
+	s *schema.LogicalSchema,
+	from []*pg_query.Node,
+	relation *pg_query.RangeVar,
+	where *pg_query.Node,
+) {
+
+	sc := &scope{schema: s, names: make(map[string]string)}
+	conditions := []*pg_query.Node{where}
+
+	if relation != nil {
+		sc.add(relation)
+	}
+	for _, item := range from {
+		conditions = append(conditions, sc.addFromItem(item)...)
+	}
+
+	if len(sc.tables) == 0 {
+		return
+	}
+
+	for _, table := range sc.tables {
+		u.tables[table] = struct{}{}
+	}
+
+	for _, condition := range conditions {
+		u.addConditions(sc, condition)
+	}
+}
+
+// addFromItem adds the tables of a FROM item and returns its join conditions
+func (sc *scope) addFromItem(item *pg_query.Node) []*pg_query.Node {
+
+	switch n := item.Node.(type) {
+
+	case *pg_query.Node_RangeVar:
+		sc.add(n.RangeVar)
+
+	case *pg_query.Node_JoinExpr:
+		conditions := []*pg_query.Node{n.JoinExpr.Quals}
+		conditions = append(conditions, sc.addFromItem(n.JoinExpr.Larg)...)
+		conditions = append(conditions, sc.addFromItem(n.JoinExpr.Rarg)...)
+		return conditions
+	}
+
+	// subqueries are scopes of their own
+	return nil
+}
+
+// add makes a table visible by its alias, or by its name without one
+func (sc *scope) add(rv *pg_query.RangeVar) {
+
+	if sc.schema != nil {
+		if _, ok := sc.schema.Tables[rv.Relname]; !ok {
+			// CTEs and tables outside the project schema
+			return
+		}
+	}
+
+	name := rv.Relname
+	if rv.Alias != nil {
+		name = rv.Alias.Aliasname
+	}
+
+	sc.names[name] = rv.Relname
+	sc.tables = append(sc.tables, rv.Relname)
+}
+
+// resolve maps a column reference to a table column of the scope
+func (sc *scope) resolve(node *pg_query.Node) (ColumnRef, bool) {
+
+	ref := node.GetColumnRef()
+	if ref == nil {
+		return ColumnRef{}, false
+	}
+
+	var parts []string
+	for _, field := range ref.Fields {
+		str := field.GetString_()
+		if str == nil {
+			return ColumnRef{}, false
+		}
+		parts = append(parts, str.Sval)
+	}
+
+	switch len(parts) {
+
+	case 1:
+		// unqualified, the only table of the scope having the column
+		var found []string
+		for _, table := range sc.tables {
+			if sc.schema == nil || sc.hasColumn(table, parts[0]) {
+				found = append(found, table)
+			}
+		}
+		if len(found) != 1 {
+			return ColumnRef{}, false
+		}
+		return ColumnRef{Table: found[0], Column: parts[0]}, true
+
+	default:
+		qualifier := parts[len(parts)-2]
+		table, ok := sc.names[qualifier]
+		if !ok {
+			return ColumnRef{}, false
+		}
+		return ColumnRef{Table: table, Column: parts[len(parts)-1]}, true
+	}
+}
+
+func (sc *scope) hasColumn(table string, column string) bool {
+	t, ok := sc.schema.Tables[table]
+	if !ok {
+		return false
+	}
+	_, ok = t.Columns[column]
+	return ok
+}
+
+// addConditions walks the AND-connected comparisons of a condition. Branches
+// of an OR cannot route a statement to one shard and are not counted.
+func (u *statementUsage) addConditions(sc *scope, node *pg_query.Node) {
+
+	if node == nil {
+		return
+	}
+
+	switch n := node.Node.(type) {
+
+	case *pg_query.Node_BoolExpr:
+		if n.BoolExpr.Boolop != pg_query.BoolExprType_AND_EXPR {
+			return
+		}
+		for _, arg := range n.BoolExpr.Args {
+			u.addConditions(sc, arg)
+		}
+
+	case *pg_query.Node_AExpr:
+		u.addComparison(sc, n.AExpr)
+	}
+}
+
+func (u *statementUsage) addComparison(sc *scope, expr *pg_query.A_Expr) {
+
+	switch expr.Kind {
+
+	case pg_query.A_Expr_Kind_AEXPR_IN:
+		if col, ok := sc.resolve(expr.Lexpr); ok {
+			u.predicates[col] = struct{}{}
+		}
+
+	case pg_query.A_Expr_Kind_AEXPR_OP:
+		if len(expr.Name) != 1 || expr.Name[0].GetString_().GetSval() != "=" {
+			return
+		}
+
+		left, leftOK := sc.resolve(expr.Lexpr)
+		right, rightOK := sc.resolve(expr.Rexpr)
+
+		switch {
+		case leftOK && rightOK:
+			if left.Table != right.Table {
+				if tieBreak(right, left) {
+					left, right = right, left
+				}
+				u.joins[[2]ColumnRef{left, right}] = struct{}{}
+			}
+		case leftOK && isValue(expr.Rexpr):
+			u.predicates[left] = struct{}{}
+		case rightOK && isValue(expr.Lexpr):
+			u.predicates[right] = struct{}{}
+		}
+	}
+}
+
+// isValue reports whether a node is a constant or a bind parameter, the
+// values normalized and prepared statements carry
+func isValue(node *pg_query.Node) bool {
+
+	switch n := node.Node.(type) {
+	case *pg_query.Node_AConst, *pg_query.Node_ParamRef:
+		return true
+	case *pg_query.Node_TypeCast:
+		return isValue(n.TypeCast.Arg)
+	}
+
+	return false
+}
+
+// WorkloadColumn summarizes how the workload uses a column
+type WorkloadColumn struct {
+	Table          string  `json:"table"`
+	Column         string  `json:"column"`
+	Predicates     int64   `json:"predicates"`
+	PredicateShare float64 `json:"predicate_share"`
+	Joins          int64   `json:"joins"`
+	JoinShare      float64 `json:"join_share"`
+	JoinedWith     string  `json:"joined_with,omitempty"`
+}
+
+// WorkloadSummary reports what the workload tells about each table
+type WorkloadSummary struct {
+	Statements      int64            `json:"statements"`
+	TableStatements map[string]int64 `json:"table_statements"`
+	Columns         []WorkloadColumn `json:"columns"`
+}
+
+// Summary lists every column used in predicates or joins, most used first
+func (w *Workload) Summary() WorkloadSummary {
+
+	summary := WorkloadSummary{
+		Statements:      w.Statements,
+		TableStatements: w.TableStatements,
+		Columns:         make([]WorkloadColumn, 0),
+	}
+
+	columns := make(map[ColumnRef]struct{})
+	for col := range w.Predicates {
+		columns[col] = struct{}{}
+	}
+	for col := range w.Joins {
+		columns[col] = struct{}{}
+	}
+
+	for col := range columns {
+		joinShare, partner := w.JoinShare(col)
+
+		var joins int64
+		for _, calls := range w.Joins[col] {
+			joins += calls
+		}
+
+		entry := WorkloadColumn{
+			Table:          col.Table,
+			Column:         col.Column,
+			Predicates:     w.Predicates[col],
+			PredicateShare: w.PredicateShare(col),
+			Joins:          joins,
+			JoinShare:      joinShare,
+		}
+		if partner.Table != "" {
+			entry.JoinedWith = partner.Table + "." + partner.Column
+		}
+
+		summary.Columns = append(summary.Columns, entry)
+	}
+
+	sort.Slice(summary.Columns, func(i, j int) bool {
+		a, b := summary.Columns[i], summary.Columns[j]
+		if a.Predicates+a.Joins != b.Predicates+b.Joins {
+			return a.Predicates+a.Joins > b.Predicates+b.Joins
+		}
+		return strings.Compare(a.Table+"."+a.Column, b.Table+"."+b.Column) < 0
+	})
+
+	return summary
+}
@@ -0,0 +1,175 @@
+package shardkey
+
+import (
+	"reflect"
+	"testing"
+
+	"sql-sharding-v2/internal/repository"
+	"sql-sharding-v2/internal/schema"
+)
+
+// fk is a foreign key child.column -> parent.column
+type fk struct {
+	child, childColumn, parent, parentColumn string
+}
+
+// testSchema builds a schema from table columns, "id" being the primary key
+func testSchema(tables map[string][]string, fks ...fk) *schema.LogicalSchema {
+
+	s := &schema.LogicalSchema{Tables: make(map[string]*schema.Table)}
+
+	for name, columns := range tables {
+		t := &schema.Table{
+			Columns: make(map[string]*schema.Column),
+			FKs:     make(map[schema.FKKey]*schema.FK),
+		}
+		for _, col := range columns {
+			t.Columns[col] = &schema.Column{Name: col, DataType: "bigint", IsPrimaryKey: col == "id"}
+		}
+		s.Tables[name] = t
+	}
+
+	for _, f := range fks {
+		s.Tables[f.child].FKs[schema.FKKey{ChildColumn: f.childColumn, ParentTable: f.parent, ParentColumn: f.parentColumn}] = &schema.FK{
+			ChildTable:   f.child,
+			ChildColumn:  f.childColumn,
+			ParentTable:  f.parent,
+			ParentColumn: f.parentColumn,
+		}
+	}
+
+	return s
+}
+
+func shopSchema() *schema.LogicalSchema {
+	return testSchema(
+		map[string][]string{
+			"customers":   {"id", "name"},
+			"orders":      {"id", "customer_id", "status"},
+			"order_items": {"id", "order_id", "customer_id", "sku"},
+		},
+		fk{"orders", "customer_id", "customers", "id"},
+		fk{"order_items", "order_id", "orders", "id"},
+	)
+}
+
+func TestAnalyzeWorkload(t *testing.T) {
+
+	col := func(table string, column string) ColumnRef {
+		return ColumnRef{Table: table, Column: column}
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		statements int64
+		tables     map[string]int64
+		predicates map[ColumnRef]int64
+		joins      map[ColumnRef]map[ColumnRef]int64
+	}{
+		{
+			name:       "equality on a parameter",
+			query:      "SELECT * FROM orders WHERE customer_id = $1 AND status = 'paid'",
+			statements: 10,
+			tables:     map[string]int64{"orders": 10},
+			predicates: map[ColumnRef]int64{col("orders", "customer_id"): 10, col("orders", "status"): 10},
+		},
+		{
+			name:       "join condition and aliases",
+			query:      "SELECT * FROM orders o JOIN customers c ON c.id = o.customer_id WHERE c.id = 5",
+			statements: 10,
+			tables:     map[string]int64{"orders": 10, "customers": 10},
+			predicates: map[ColumnRef]int64{col("customers", "id"): 10},
+			joins: map[ColumnRef]map[ColumnRef]int64{
+				col("customers", "id"):       {col("orders", "customer_id"): 10},
+				col("orders", "customer_id"): {col("customers", "id"): 10},
+			},
+		},
+		{
+			name:       "branches of an OR are not counted",
+			query:      "SELECT * FROM orders WHERE customer_id = 1 OR status = 'paid'",
+			statements: 10,
+			tables:     map[string]int64{"orders": 10},
+		},
+		{
+			name:       "IN list on a delete",
+			query:      "DELETE FROM orders WHERE id IN (1, 2)",
+			statements: 10,
+			tables:     map[string]int64{"orders": 10},
+			predicates: map[ColumnRef]int64{col("orders", "id"): 10},
+		},
+		{
+			name:       "ambiguous unqualified column",
+			query:      "SELECT * FROM orders, order_items WHERE customer_id = 1",
+			statements: 10,
+			tables:     map[string]int64{"orders": 10, "order_items": 10},
+		},
+		{
+			name:       "subquery is a scope of its own and no value list",
+			query:      "SELECT * FROM customers WHERE id IN (SELECT customer_id FROM orders WHERE status = 'open')",
+			statements: 10,
+			tables:     map[string]int64{"customers": 10, "orders": 10},
+			predicates: map[ColumnRef]int64{col("orders", "status"): 10},
+		},
+		{
+			name:  "inserts are skipped",
+			query: "INSERT INTO orders (id, customer_id) VALUES (1, 2)",
+		},
+		{
+			name:  "tables outside the schema are skipped",
+			query: "WITH recent AS (SELECT 1 AS id) SELECT * FROM recent WHERE id = 1",
+		},
+		{
+			name:  "unparsable statements are skipped",
+			query: "SELEC * FROM orders",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			w := AnalyzeWorkload(shopSchema(), []repository.WorkloadQuery{{Query: tt.query, Calls: 10}})
+
+			want := newWorkload()
+			want.Statements = tt.statements
+			for k, v := range tt.tables {
+				want.TableStatements[k] = v
+			}
+			for k, v := range tt.predicates {
+				want.Predicates[k] = v
+			}
+			for k, v := range tt.joins {
+				want.Joins[k] = v
+			}
+
+			if !reflect.DeepEqual(w, want) {
+				t.Fatalf("got %+v, want %+v", w, want)
+			}
+		})
+	}
+}
+
+func TestWorkloadShares(t *testing.T) {
+
+	w := AnalyzeWorkload(shopSchema(), []repository.WorkloadQuery{
+		{Query: "SELECT * FROM orders WHERE customer_id = $1", Calls: 30},
+		{Query: "SELECT * FROM orders o JOIN customers c ON c.id = o.customer_id", Calls: 10},
+		{Query: "SELECT * FROM orders WHERE status = $1", Calls: 60},
+	})
+
+	customerID := ColumnRef{Table: "orders", Column: "customer_id"}
+
+	if got := w.PredicateShare(customerID); got != 0.3 {
+		t.Fatalf("predicate share %v, want 0.3", got)
+	}
+
+	share, partner := w.JoinShare(customerID)
+	if share != 0.1 || partner != (ColumnRef{Table: "customers", Column: "id"}) {
+		t.Fatalf("join share %v with %v, want 0.1 with customers.id", share, partner)
+	}
+
+	var none *Workload
+	if got := none.PredicateShare(customerID); got != 0 {
+		t.Fatalf("predicate share without workload %v, want 0", got)
+	}
+}
@@ -0,0 +1,182 @@
+package shardstats
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"sql-sharding-v2/internal/repository"
+)
+
+// statistic sources
+const (
+	SourcePgStats = "pg_stats"
+	SourceSample  = "sample"
+)
+
+// TableSample is a sample of rows of a table, column name to value
+type TableSample struct {
+	Table string           `json:"table"`
+	Rows  []map[string]any `json:"rows"`
+}
+
+// CollectColumnStats reads the planner statistics of a shard. Only tables
+// analysed on the shard, by autovacuum or ANALYZE, have statistics.
+func CollectColumnStats(ctx context.Context, db *sql.DB) ([]repository.ColumnStatistic, error) {
+
+	query := `
+		SELECT
+			s.tablename,
+			s.attname,
+			s.n_distinct,
+			s.null_frac,
+			COALESCE(s.most_common_freqs[1], 0),
+			GREATEST(c.reltuples, 0)::BIGINT
+		FROM pg_stats s
+		JOIN pg_namespace n ON n.nspname = s.schemaname
+		JOIN pg_class c ON c.relnamespace = n.oid AND c.relname = s.tablename
+		WHERE s.schemaname = current_schema()
+		  AND NOT s.inherited
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make([]repository.ColumnStatistic, 0)
+
+	for rows.Next() {
+		var s repository.ColumnStatistic
+		if err := rows.Scan(
+			&s.TableName,
+			&s.ColumnName,
+			&s.NDistinct,
+			&s.NullFrac,
+			&s.TopFrequency,
+			&s.RowCount,
+		); err != nil {
+			return nil, err
+		}
+
+		// negative n_distinct is a fraction of the rows
+		if s.NDistinct < 0 {
+			s.NDistinct = -s.NDistinct * float64(s.RowCount)
+		}
+
+		s.Source = SourcePgStats
+		stats = append(stats, s)
+	}
+
+	return stats, rows.Err()
+}
+
+// MergeColumnStats combines the statistics of every shard into one per
+// column. Rows add up, fractions are weighted by rows and distinct values
+// take the largest shard, a lower bound when values repeat across shards.
+func MergeColumnStats(perShard [][]repository.ColumnStatistic, shardCount int) []repository.ColumnStatistic {
+
+	type key struct{ table, column string }
+
+	merged := make(map[key]*repository.ColumnStatistic)
+	order := make([]key, 0)
+
+	for _, shard := range perShard {
+		for _, s := range shard {
+			k := key{s.TableName, s.ColumnName}
+
+			m, ok := merged[k]
+			if !ok {
+				m = &repository.ColumnStatistic{
+					TableName:  s.TableName,
+					ColumnName: s.ColumnName,
+					Source:     s.Source,
+				}
+				merged[k] = m
+				order = append(order, k)
+			}
+
+			rows := float64(s.RowCount)
+			total := float64(m.RowCount) + rows
+			if total > 0 {
+				m.NullFrac = (m.NullFrac*float64(m.RowCount) + s.NullFrac*rows) / total
+				m.TopFrequency = (m.TopFrequency*float64(m.RowCount) + s.TopFrequency*rows) / total
+			}
+
+			m.RowCount += s.RowCount
+			if s.NDistinct > m.NDistinct {
+				m.NDistinct = s.NDistinct
+			}
+		}
+	}
+
+	result := make([]repository.ColumnStatistic, 0, len(order))
+	for _, k := range order {
+		m := merged[k]
+		m.ShardCount = shardCount
+		result = append(result, *m)
+	}
+
+	return result
+}
+
+// SampleColumnStats computes statistics from sampled rows
+func SampleColumnStats(samples []TableSample, shardCount int) []repository.ColumnStatistic {
+
+	stats := make([]repository.ColumnStatistic, 0)
+
+	for _, sample := range samples {
+
+		if len(sample.Rows) == 0 {
+			continue
+		}
+
+		counts := make(map[string]map[string]int64)
+		nulls := make(map[string]int64)
+
+		for _, row := range sample.Rows {
+			for column, value := range row {
+				if counts[column] == nil {
+					counts[column] = make(map[string]int64)
+				}
+				if value == nil {
+					nulls[column]++
+					continue
+				}
+				counts[column][fmt.Sprint(value)]++
+			}
+		}
+
+		columns := make([]string, 0, len(counts))
+		for column := range counts {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+
+		total := float64(len(sample.Rows))
+
+		for _, column := range columns {
+			var top int64
+			for _, n := range counts[column] {
+				if n > top {
+					top = n
+				}
+			}
+
+			stats = append(stats, repository.ColumnStatistic{
+				TableName:    sample.Table,
+				ColumnName:   column,
+				NDistinct:    float64(len(counts[column])),
+				NullFrac:     float64(nulls[column]) / total,
+				TopFrequency: float64(top) / total,
+				RowCount:     int64(len(sample.Rows)),
+				Source:       SourceSample,
+				ShardCount:   shardCount,
+			})
+		}
+	}
+
+	return stats
+}
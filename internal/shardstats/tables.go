@@ -0,0 +1,52 @@
+package shardstats
+
+import (
+	"context"
+	"database/sql"
+)
+
+// TableSize is the size of a table on one shard
+type TableSize struct {
+	Table string `json:"table"`
+
+	// planner estimate, 0 until the table was analysed
+	Rows int64 `json:"rows"`
+
+	// heap, indexes and toast
+	Bytes int64 `json:"bytes"`
+}
+
+// CollectTableSizes reads the row estimate and total size of every table
+// of a shard from pg_class
+func CollectTableSizes(ctx context.Context, db *sql.DB) ([]TableSize, error) {
+
+	query := `
+		SELECT
+			c.relname,
+			GREATEST(c.reltuples, 0)::BIGINT,
+			pg_total_relation_size(c.oid)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = current_schema()
+		  AND c.relkind = 'r'
+		ORDER BY c.relname
+	`
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sizes := make([]TableSize, 0)
+
+	for rows.Next() {
+		var t TableSize
+		if err := rows.Scan(&t.Table, &t.Rows, &t.Bytes); err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, t)
+	}
+
+	return sizes, rows.Err()
+}
@@ -0,0 +1,311 @@
+// Package skew measures how evenly the data and the traffic of a project
+// spread over its shards and finds the shard key values drawing the most
+// routed statements.
+package skew
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Settings controls traffic tracking and alerting
+type Settings struct {
+	// coefficient of variation across shards above which rows, size,
+	// statements or a table are reported as skewed
+	Threshold float64
+
+	// share of key-routed statements above which a key value is reported as hot
+	HotKeyShare float64
+
+	// key values tracked per project and window, beyond this a new value
+	// replaces the least called one and inherits its calls (Space-Saving)
+	MaxKeys int
+
+	// events queued for aggregation, events beyond this are dropped
+	BufferSize int
+}
+
+// Event is a routed statement with the shards it ran on
+type Event struct {
+	ProjectID string
+	Shards    []string
+
+	// shard key the router resolved the statement by, empty for broadcasts
+	Table  string
+	Column string
+	Values []string
+}
+
+// HotKey is a shard key value and the statements routed by it
+type HotKey struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	Value  string `json:"value"`
+
+	// shard the value lives on
+	Shard string `json:"shard,omitempty"`
+
+	// once more values were seen than are tracked, calls may be overestimated
+	// by at most the calls of the value this one replaced
+	Calls int64 `json:"calls"`
+
+	// of the statements routed by a key value
+	Share float64 `json:"share"`
+}
+
+// Traffic is what was routed for a project in one window
+type Traffic struct {
+	Since time.Time
+	Until time.Time
+
+	Statements int64
+
+	// statements resolved through shard key values
+	Keyed int64
+
+	// statements per shard
+	Shards map[string]int64
+
+	// most routed key values first
+	Keys []HotKey
+}
+
+type window struct {
+	since      time.Time
+	statements int64
+	keyed      int64
+	shards     map[string]int64
+	keys       map[string]*trackedKey
+	counts     keyHeap
+}
+
+// trackedKey is a key value counted in a window
+type trackedKey struct {
+	HotKey
+	id    string
+	index int
+}
+
+// keyHeap orders tracked key values by calls, least called first
+type keyHeap []*trackedKey
+
+func (h keyHeap) Len() int           { return len(h) }
+func (h keyHeap) Less(i, j int) bool { return h[i].Calls < h[j].Calls }
+
+func (h keyHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *keyHeap) Push(x any) {
+	k := x.(*trackedKey)
+	k.index = len(*h)
+	*h = append(*h, k)
+}
+
+func (h *keyHeap) Pop() any {
+	old := *h
+	k := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return k
+}
+
+// Detector aggregates routed statements queued by Observe into per-project
+// windows and keeps the latest report of every project
+type Detector struct {
+	settings Settings
+
+	queue chan Event
+
+	mu      sync.Mutex
+	started time.Time
+	windows map[string]*window
+	rotated map[string]time.Time
+	reports map[string]*Report
+}
+
+func NewDetector(settings Settings) *Detector {
+
+	if settings.Threshold <= 0 {
+		settings.Threshold = 0.3
+	}
+	if settings.HotKeyShare <= 0 {
+		settings.HotKeyShare = 0.2
+	}
+	if settings.MaxKeys <= 0 {
+		settings.MaxKeys = 10000
+	}
+	if settings.BufferSize <= 0 {
+		settings.BufferSize = 10000
+	}
+
+	return &Detector{
+		settings: settings,
+		queue:    make(chan Event, settings.BufferSize),
+		started:  time.Now(),
+		windows:  make(map[string]*window),
+		rotated:  make(map[string]time.Time),
+		reports:  make(map[string]*Report),
+	}
+}
+
+// Observe queues an event without blocking, events are dropped when the queue is full
+func (d *Detector) Observe(event Event) {
+	select {
+	case d.queue <- event:
+	default:
+	}
+}
+
+// Run aggregates queued events until ctx is cancelled
+func (d *Detector) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-d.queue:
+			d.add(event)
+		}
+	}
+}
+
+// add folds an event into the current window of its project
+func (d *Detector) add(event Event) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	w, ok := d.windows[event.ProjectID]
+	if !ok {
+		w = d.newWindow(event.ProjectID)
+		d.windows[event.ProjectID] = w
+	}
+
+	w.statements++
+	for _, shard := range event.Shards {
+		w.shards[shard]++
+	}
+
+	if len(event.Values) == 0 {
+		return
+	}
+	w.keyed++
+
+	shard := ""
+	if len(event.Shards) == 1 {
+		shard = event.Shards[0]
+	}
+
+	for _, value := range event.Values {
+		id := event.Table + "." + event.Column + "=" + value
+
+		k, ok := w.keys[id]
+		if !ok {
+			k = w.track(id, HotKey{Table: event.Table, Column: event.Column, Value: value}, d.settings.MaxKeys)
+		}
+
+		k.Calls++
+		if shard != "" {
+			k.Shard = shard
+		}
+		heap.Fix(&w.counts, k.index)
+	}
+}
+
+// track starts counting a key value. A full window replaces its least
+// called value, whose calls the new value inherits, so a value turning
+// hot late climbs past the values tracked before it.
+func (w *window) track(id string, key HotKey, maxKeys int) *trackedKey {
+
+	if len(w.keys) < maxKeys {
+		k := &trackedKey{HotKey: key, id: id}
+		w.keys[id] = k
+		heap.Push(&w.counts, k)
+		return k
+	}
+
+	k := w.counts[0]
+	delete(w.keys, k.id)
+
+	key.Calls = k.Calls
+	k.HotKey, k.id = key, id
+	w.keys[id] = k
+
+	return k
+}
+
+// Rotate closes the current window of a project and starts a new one
+func (d *Detector) Rotate(projectID string) Traffic {
+
+	now := time.Now()
+
+	d.mu.Lock()
+	w, ok := d.windows[projectID]
+	if !ok {
+		w = d.newWindow(projectID)
+	}
+	delete(d.windows, projectID)
+	d.rotated[projectID] = now
+	d.mu.Unlock()
+
+	traffic := Traffic{
+		Since:      w.since,
+		Until:      now,
+		Statements: w.statements,
+		Keyed:      w.keyed,
+		Shards:     w.shards,
+		Keys:       make([]HotKey, 0, len(w.keys)),
+	}
+
+	for _, k := range w.keys {
+		key := k.HotKey
+		if w.keyed > 0 {
+			key.Share = float64(key.Calls) / float64(w.keyed)
+		}
+		traffic.Keys = append(traffic.Keys, key)
+	}
+
+	sort.Slice(traffic.Keys, func(i, j int) bool {
+		if traffic.Keys[i].Calls != traffic.Keys[j].Calls {
+			return traffic.Keys[i].Calls > traffic.Keys[j].Calls
+		}
+		return traffic.Keys[i].Value < traffic.Keys[j].Value
+	})
+
+	return traffic
+}
+
+// Store keeps a report as the latest of its project
+func (d *Detector) Store(report *Report) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reports[report.ProjectID] = report
+}
+
+// Latest returns the latest report of a project
+func (d *Detector) Latest(projectID string) (*Report, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	report, ok := d.reports[projectID]
+	return report, ok
+}
+
+// newWindow starts where the previous window of the project ended.
+// Caller must hold d.mu.
+func (d *Detector) newWindow(projectID string) *window {
+
+	since, ok := d.rotated[projectID]
+	if !ok {
+		since = d.started
+	}
+
+	return &window{
+		since:  since,
+		shards: make(map[string]int64),
+		keys:   make(map[string]*trackedKey),
+	}
+}
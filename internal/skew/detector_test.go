@@ -0,0 +1,112 @@
+package skew
+
+import (
+	"fmt"
+	"testing"
+)
+
+// keyEvent is a statement routed by one key value to one shard
+func keyEvent(value string) Event {
+	return Event{
+		ProjectID: "p1",
+		Shards:    []string{"s1"},
+		Table:     "orders",
+		Column:    "user_id",
+		Values:    []string{value},
+	}
+}
+
+func TestDetectorCountsKeys(t *testing.T) {
+
+	d := NewDetector(Settings{MaxKeys: 10})
+
+	for i := 0; i < 3; i++ {
+		d.add(keyEvent("a"))
+	}
+	d.add(keyEvent("b"))
+	d.add(Event{ProjectID: "p1", Shards: []string{"s1", "s2"}})
+
+	traffic := d.Rotate("p1")
+
+	if traffic.Statements != 5 || traffic.Keyed != 4 {
+		t.Fatalf("statements = %d, keyed = %d, want 5 and 4", traffic.Statements, traffic.Keyed)
+	}
+	if traffic.Shards["s1"] != 5 || traffic.Shards["s2"] != 1 {
+		t.Errorf("shards = %v", traffic.Shards)
+	}
+
+	want := []HotKey{
+		{Table: "orders", Column: "user_id", Value: "a", Shard: "s1", Calls: 3, Share: 0.75},
+		{Table: "orders", Column: "user_id", Value: "b", Shard: "s1", Calls: 1, Share: 0.25},
+	}
+	if len(traffic.Keys) != len(want) {
+		t.Fatalf("keys = %v, want %v", traffic.Keys, want)
+	}
+	for i := range want {
+		if traffic.Keys[i] != want[i] {
+			t.Errorf("keys[%d] = %+v, want %+v", i, traffic.Keys[i], want[i])
+		}
+	}
+
+	if next := d.Rotate("p1"); next.Statements != 0 || len(next.Keys) != 0 {
+		t.Errorf("window after rotate = %+v, want empty", next)
+	}
+}
+
+func TestDetectorReportsLateHotKey(t *testing.T) {
+
+	const maxKeys = 8
+
+	d := NewDetector(Settings{MaxKeys: maxKeys})
+
+	// fill the table with values called a few times each
+	for i := 0; i < maxKeys; i++ {
+		for j := 0; j < 5; j++ {
+			d.add(keyEvent(fmt.Sprintf("warm-%d", i)))
+		}
+	}
+
+	// a value that turns hot late, interleaved with a stream of one-off values
+	for i := 0; i < 200; i++ {
+		d.add(keyEvent("hot"))
+		d.add(keyEvent(fmt.Sprintf("cold-%d", i)))
+	}
+
+	traffic := d.Rotate("p1")
+
+	if len(traffic.Keys) > maxKeys {
+		t.Fatalf("tracked %d key values, want at most %d", len(traffic.Keys), maxKeys)
+	}
+
+	top := traffic.Keys[0]
+	if top.Value != "hot" {
+		t.Fatalf("most called value = %q, want hot, keys %v", top.Value, traffic.Keys)
+	}
+	if top.Calls < 200 {
+		t.Errorf("hot calls = %d, want at least 200", top.Calls)
+	}
+}
+
+func TestDetectorEvictsLeastCalled(t *testing.T) {
+
+	d := NewDetector(Settings{MaxKeys: 2})
+
+	d.add(keyEvent("a"))
+	d.add(keyEvent("a"))
+	d.add(keyEvent("a"))
+	d.add(keyEvent("b"))
+	d.add(keyEvent("c"))
+
+	traffic := d.Rotate("p1")
+
+	got := map[string]int64{}
+	for _, k := range traffic.Keys {
+		got[k.Value] = k.Calls
+	}
+
+	// c replaced b and inherited its single call
+	want := map[string]int64{"a": 3, "c": 2}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+}
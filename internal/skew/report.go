@@ -0,0 +1,274 @@
+package skew
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"sql-sharding-v2/internal/shardstats"
+)
+
+// below these volumes imbalance is noise and raises no alert
+const (
+	minAlertRows       = 10000
+	minAlertStatements = 100
+	minHotKeyCalls     = 50
+)
+
+// hot key values kept in a report
+const maxReportedKeys = 20
+
+// alert kinds
+const (
+	AlertRows       = "rows"
+	AlertSize       = "size"
+	AlertStatements = "statements"
+	AlertTable      = "table"
+	AlertHotKey     = "hot_key"
+)
+
+// Report is the data and traffic distribution of a project over its shards
+type Report struct {
+	ProjectID   string    `json:"project_id"`
+	CollectedAt time.Time `json:"collected_at"`
+
+	// traffic window the statement counts cover
+	WindowSeconds float64 `json:"window_seconds"`
+
+	Shards []ShardLoad `json:"shards"`
+
+	// coefficients of variation across shards, 0 is perfectly even
+	RowSkew       float64 `json:"row_skew"`
+	SizeSkew      float64 `json:"size_skew"`
+	StatementSkew float64 `json:"statement_skew"`
+
+	Tables  []TableSkew `json:"tables"`
+	HotKeys []HotKey    `json:"hot_keys"`
+	Alerts  []Alert     `json:"alerts"`
+
+	// shards whose tables could not be read, left out of the data skew
+	Unreachable []string `json:"unreachable,omitempty"`
+}
+
+// ShardLoad is the data and traffic of one shard
+type ShardLoad struct {
+	ShardID          string                 `json:"shard_id"`
+	Rows             int64                  `json:"rows"`
+	Bytes            int64                  `json:"bytes"`
+	Statements       int64                  `json:"statements"`
+	StatementsPerSec float64                `json:"statements_per_sec"`
+	Tables           []shardstats.TableSize `json:"tables"`
+}
+
+// TableSkew is how evenly the rows of one table spread
+type TableSkew struct {
+	Table string  `json:"table"`
+	Rows  int64   `json:"rows"`
+	Skew  float64 `json:"skew"`
+
+	// shard holding the most rows and its share
+	LargestShard string  `json:"largest_shard"`
+	LargestShare float64 `json:"largest_share"`
+}
+
+// Alert is a skewed dimension or a hot key value
+type Alert struct {
+	Kind    string  `json:"kind"`
+	Subject string  `json:"subject"`
+	Value   float64 `json:"value"`
+	Message string  `json:"message"`
+}
+
+// Analyze builds the report of a project from the table sizes read on each
+// shard and the traffic of the last window. Shards missing from sizes are
+// reported unreachable.
+func (d *Detector) Analyze(
+	projectID string,
+	shardIDs []string,
+	sizes map[string][]shardstats.TableSize,
+	traffic Traffic,
+) *Report {
+
+	report := &Report{
+		ProjectID:     projectID,
+		CollectedAt:   time.Now(),
+		WindowSeconds: traffic.Until.Sub(traffic.Since).Seconds(),
+		Tables:        make([]TableSkew, 0),
+		HotKeys:       make([]HotKey, 0),
+		Alerts:        make([]Alert, 0),
+	}
+
+	var rows, bytes, statements []float64
+	tableRows := make(map[string]map[string]int64)
+
+	for _, shardID := range shardIDs {
+
+		load := ShardLoad{
+			ShardID:    shardID,
+			Statements: traffic.Shards[shardID],
+			Tables:     sizes[shardID],
+		}
+		if report.WindowSeconds > 0 {
+			load.StatementsPerSec = float64(load.Statements) / report.WindowSeconds
+		}
+		statements = append(statements, float64(load.Statements))
+
+		tables, ok := sizes[shardID]
+		if !ok {
+			report.Unreachable = append(report.Unreachable, shardID)
+			load.Tables = make([]shardstats.TableSize, 0)
+			report.Shards = append(report.Shards, load)
+			continue
+		}
+
+		for _, t := range tables {
+			load.Rows += t.Rows
+			load.Bytes += t.Bytes
+
+			if tableRows[t.Table] == nil {
+				tableRows[t.Table] = make(map[string]int64)
+			}
+			tableRows[t.Table][shardID] = t.Rows
+		}
+
+		rows = append(rows, float64(load.Rows))
+		bytes = append(bytes, float64(load.Bytes))
+		report.Shards = append(report.Shards, load)
+	}
+
+	report.RowSkew = coefficientOfVariation(rows)
+	report.SizeSkew = coefficientOfVariation(bytes)
+	report.StatementSkew = coefficientOfVariation(statements)
+
+	if sum(rows) >= minAlertRows {
+		d.alertSkew(report, AlertRows, "rows", report.RowSkew)
+		d.alertSkew(report, AlertSize, "table sizes", report.SizeSkew)
+	}
+	if traffic.Statements >= minAlertStatements {
+		d.alertSkew(report, AlertStatements, "statements", report.StatementSkew)
+	}
+
+	report.Tables = tableSkews(tableRows, len(rows))
+	for _, t := range report.Tables {
+		if t.Rows >= minAlertRows && t.Skew > d.settings.Threshold {
+			report.Alerts = append(report.Alerts, Alert{
+				Kind:    AlertTable,
+				Subject: t.Table,
+				Value:   t.Skew,
+				Message: fmt.Sprintf(
+					"table %s is skewed (%.2f): shard %s holds %.0f%% of its rows",
+					t.Table, t.Skew, t.LargestShard, t.LargestShare*100,
+				),
+			})
+		}
+	}
+
+	for i, k := range traffic.Keys {
+		if i == maxReportedKeys {
+			break
+		}
+		report.HotKeys = append(report.HotKeys, k)
+
+		if k.Calls >= minHotKeyCalls && k.Share > d.settings.HotKeyShare {
+			report.Alerts = append(report.Alerts, Alert{
+				Kind:    AlertHotKey,
+				Subject: fmt.Sprintf("%s.%s=%s", k.Table, k.Column, k.Value),
+				Value:   k.Share,
+				Message: fmt.Sprintf(
+					"hot key %s.%s=%s drew %.0f%% of key-routed statements",
+					k.Table, k.Column, k.Value, k.Share*100,
+				),
+			})
+		}
+	}
+
+	return report
+}
+
+func (d *Detector) alertSkew(report *Report, kind string, what string, skew float64) {
+
+	if skew <= d.settings.Threshold {
+		return
+	}
+
+	report.Alerts = append(report.Alerts, Alert{
+		Kind:    kind,
+		Subject: report.ProjectID,
+		Value:   skew,
+		Message: fmt.Sprintf("%s are skewed across shards (%.2f, threshold %.2f)", what, skew, d.settings.Threshold),
+	})
+}
+
+// tableSkews computes the skew of every table over the reachable shards,
+// most skewed first
+func tableSkews(tableRows map[string]map[string]int64, shardCount int) []TableSkew {
+
+	result := make([]TableSkew, 0, len(tableRows))
+
+	for table, perShard := range tableRows {
+
+		t := TableSkew{Table: table}
+		values := make([]float64, 0, shardCount)
+
+		for shardID, rows := range perShard {
+			t.Rows += rows
+			values = append(values, float64(rows))
+			largest := perShard[t.LargestShard]
+			if t.LargestShard == "" || rows > largest || (rows == largest && shardID < t.LargestShard) {
+				t.LargestShard = shardID
+			}
+		}
+
+		// shards without the table hold none of its rows
+		for len(values) < shardCount {
+			values = append(values, 0)
+		}
+
+		t.Skew = coefficientOfVariation(values)
+		if t.Rows > 0 {
+			t.LargestShare = float64(perShard[t.LargestShard]) / float64(t.Rows)
+		}
+
+		result = append(result, t)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Skew != result[j].Skew {
+			return result[i].Skew > result[j].Skew
+		}
+		return result[i].Table < result[j].Table
+	})
+
+	return result
+}
+
+// coefficientOfVariation is the standard deviation relative to the mean,
+// 0 for fewer than two values or no load at all
+func coefficientOfVariation(values []float64) float64 {
+
+	if len(values) < 2 {
+		return 0
+	}
+
+	mean := sum(values) / float64(len(values))
+	if mean == 0 {
+		return 0
+	}
+
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(values))
+
+	return math.Sqrt(variance) / mean
+}
+
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
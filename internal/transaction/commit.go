@@ -0,0 +1,114 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+
+	"sql-sharding-v2/pkg/logger"
+)
+
+// twoPhaseCommit prepares the transaction on every shard and commits only
+// once all shards voted yes. Requires max_prepared_transactions > 0 on shards.
+func twoPhaseCommit(ctx context.Context, session *Session) error {
+
+	prepared := make([]string, 0, len(session.order))
+
+	for _, shardID := range session.order {
+
+		gid := preparedID(session.ID, shardID)
+
+		_, err := session.conns[shardID].ExecContext(
+			ctx,
+			"PREPARE TRANSACTION "+pq.QuoteLiteral(gid),
+		)
+		if err != nil {
+			logger.Logger.Error("prepare failed, aborting distributed transaction",
+				"tx_id", session.ID,
+				"shard_id", shardID,
+				"error", err,
+			)
+
+			abortPrepared(ctx, session, prepared)
+			return fmt.Errorf("prepare on shard %s: %w", shardID, err)
+		}
+
+		prepared = append(prepared, shardID)
+	}
+
+	var firstErr error
+
+	for _, shardID := range prepared {
+
+		gid := preparedID(session.ID, shardID)
+
+		_, err := session.conns[shardID].ExecContext(
+			ctx,
+			"COMMIT PREPARED "+pq.QuoteLiteral(gid),
+		)
+		if err != nil {
+			// the shard holds an in-doubt transaction that must be resolved manually
+			logger.Logger.Error("commit prepared failed",
+				"tx_id", session.ID,
+				"shard_id", shardID,
+				"gid", gid,
+				"error", err,
+			)
+
+			if firstErr == nil {
+				firstErr = fmt.Errorf("commit prepared on shard %s: %w", shardID, err)
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// abortPrepared rolls back prepared shards and plain-rolls back the rest
+func abortPrepared(ctx context.Context, session *Session, prepared []string) {
+
+	isPrepared := make(map[string]struct{}, len(prepared))
+
+	for _, shardID := range prepared {
+		isPrepared[shardID] = struct{}{}
+
+		gid := preparedID(session.ID, shardID)
+		if _, err := session.conns[shardID].ExecContext(
+			ctx,
+			"ROLLBACK PREPARED "+pq.QuoteLiteral(gid),
+		); err != nil {
+			logger.Logger.Error("rollback prepared failed", "tx_id", session.ID, "shard_id", shardID, "gid", gid, "error", err)
+		}
+	}
+
+	for _, shardID := range session.order {
+		if _, ok := isPrepared[shardID]; ok {
+			continue
+		}
+		_, _ = session.conns[shardID].ExecContext(ctx, "ROLLBACK")
+	}
+}
+
+// rollbackAll issues ROLLBACK on every pinned connection
+func rollbackAll(ctx context.Context, session *Session) {
+	for _, shardID := range session.order {
+		if _, err := session.conns[shardID].ExecContext(ctx, "ROLLBACK"); err != nil {
+			logger.Logger.Warn("rollback failed", "tx_id", session.ID, "shard_id", shardID, "error", err)
+		}
+	}
+}
+
+// releaseConns returns pinned connections to their pools
+func releaseConns(session *Session) {
+	for _, shardID := range session.order {
+		_ = session.conns[shardID].Close()
+	}
+	session.conns = nil
+	session.order = nil
+}
+
+// preparedID builds the global transaction identifier for a shard
+func preparedID(txID string, shardID string) string {
+	return fmt.Sprintf("sqlshard_%s_%s", txID, shardID)
+}
@@ -0,0 +1,186 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// shardLog records the statements run on fake shard connections in order
+type shardLog struct {
+	statements []string
+
+	// statement prefixes failing per shard
+	failures map[string]string
+}
+
+type fakeConnector struct {
+	shardID string
+	log     *shardLog
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return fakeConn(c), nil
+}
+
+func (c fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn fakeConnector
+
+func (c fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+
+	c.log.statements = append(c.log.statements, c.shardID+": "+query)
+
+	if prefix, ok := c.log.failures[c.shardID]; ok && strings.HasPrefix(query, prefix) {
+		return nil, errors.New("shard unavailable")
+	}
+
+	return driver.RowsAffected(0), nil
+}
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c fakeConn) Close() error {
+	return nil
+}
+
+func (c fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+// openSession pins a fake connection per shard, in the given order
+func openSession(t *testing.T, log *shardLog, shardIDs ...string) *Session {
+	t.Helper()
+
+	session := &Session{ID: "tx", conns: make(map[string]*sql.Conn)}
+
+	for _, shardID := range shardIDs {
+		db := sql.OpenDB(fakeConnector{shardID: shardID, log: log})
+		t.Cleanup(func() { db.Close() })
+
+		conn, err := db.Conn(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		session.conns[shardID] = conn
+		session.order = append(session.order, shardID)
+	}
+
+	return session
+}
+
+func TestCommit(t *testing.T) {
+
+	prepare := func(shardID string) string {
+		return shardID + ": PREPARE TRANSACTION '" + preparedID("tx", shardID) + "'"
+	}
+	commitPrepared := func(shardID string) string {
+		return shardID + ": COMMIT PREPARED '" + preparedID("tx", shardID) + "'"
+	}
+	rollbackPrepared := func(shardID string) string {
+		return shardID + ": ROLLBACK PREPARED '" + preparedID("tx", shardID) + "'"
+	}
+
+	tests := []struct {
+		name     string
+		shards   []string
+		failed   bool
+		failures map[string]string
+		want     []string
+		wantErr  error
+		fails    bool
+	}{
+		{
+			name: "no shard touched",
+		},
+		{
+			name:   "one shard commits without prepare",
+			shards: []string{"a"},
+			want:   []string{"a: COMMIT"},
+		},
+		{
+			name:   "several shards prepare before any commit",
+			shards: []string{"a", "b"},
+			want:   []string{prepare("a"), prepare("b"), commitPrepared("a"), commitPrepared("b")},
+		},
+		{
+			name:     "a failed prepare aborts every shard",
+			shards:   []string{"a", "b", "c"},
+			failures: map[string]string{"b": "PREPARE"},
+			want:     []string{prepare("a"), prepare("b"), rollbackPrepared("a"), "b: ROLLBACK", "c: ROLLBACK"},
+			fails:    true,
+		},
+		{
+			name:     "a failed commit prepared still commits the other shards",
+			shards:   []string{"a", "b"},
+			failures: map[string]string{"a": "COMMIT PREPARED"},
+			want:     []string{prepare("a"), prepare("b"), commitPrepared("a"), commitPrepared("b")},
+			fails:    true,
+		},
+		{
+			name:    "an aborted transaction rolls back",
+			shards:  []string{"a", "b"},
+			failed:  true,
+			want:    []string{"a: ROLLBACK", "b: ROLLBACK"},
+			wantErr: ErrTxAborted,
+			fails:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+
+			log := &shardLog{failures: tt.failures}
+
+			session := openSession(t, log, tt.shards...)
+			session.failed = tt.failed
+
+			m := &Manager{sessions: map[string]*Session{session.ID: session}}
+
+			err := m.Commit(context.Background(), session.ID)
+			if (err != nil) != tt.fails {
+				t.Fatalf("got error %v, want failure %v", err, tt.fails)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(log.statements, tt.want) {
+				t.Fatalf("got statements %q, want %q", log.statements, tt.want)
+			}
+
+			if _, err := m.Get(session.ID); !errors.Is(err, ErrTxNotFound) {
+				t.Fatalf("session still registered after commit: %v", err)
+			}
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+
+	log := &shardLog{}
+	session := openSession(t, log, "a", "b")
+
+	m := &Manager{sessions: map[string]*Session{session.ID: session}}
+
+	if err := m.Rollback(context.Background(), session.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"a: ROLLBACK", "b: ROLLBACK"}; !reflect.DeepEqual(log.statements, want) {
+		t.Fatalf("got statements %q, want %q", log.statements, want)
+	}
+
+	if err := m.Rollback(context.Background(), session.ID); !errors.Is(err, ErrTxNotFound) {
+		t.Fatalf("second rollback: got %v, want %v", err, ErrTxNotFound)
+	}
+}
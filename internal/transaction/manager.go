@@ -0,0 +1,339 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"sql-sharding-v2/internal/connections"
+	"sql-sharding-v2/internal/executor"
+	"sql-sharding-v2/internal/router"
+	"sql-sharding-v2/pkg/logger"
+)
+
+// DefaultIdleTimeout is how long a transaction may stay idle before it is rolled back
+const DefaultIdleTimeout = 60 * time.Second
+
+// Manager tracks interactive transactions across shards.
+type Manager struct {
+	connStore   *connections.ConnectionStore
+	router      *router.RouterService
+	idleTimeout time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+func NewManager(
+	connStore *connections.ConnectionStore,
+	routerService *router.RouterService,
+	idleTimeout time.Duration,
+) *Manager {
+
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+
+	return &Manager{
+		connStore:   connStore,
+		router:      routerService,
+		idleTimeout: idleTimeout,
+		sessions:    make(map[string]*Session),
+	}
+}
+
+// Begin opens a new transaction session for a project on behalf of owner.
+// Shard connections are pinned lazily once a statement touches them.
+func (m *Manager) Begin(projectID string, owner string) *Session {
+
+	session := &Session{
+		ID:           uuid.New().String(),
+		ProjectID:    projectID,
+		Owner:        owner,
+		conns:        make(map[string]*sql.Conn),
+		startedAt:    time.Now(),
+		lastActivity: time.Now(),
+	}
+
+	m.mu.Lock()
+	m.sessions[session.ID] = session
+	m.mu.Unlock()
+
+	logger.Logger.Info("transaction started", "tx_id", session.ID, "project_id", projectID)
+
+	return session
+}
+
+// WaitOpenedBefore blocks until every transaction of a project opened
+// before t has committed, rolled back or expired
+func (m *Manager) WaitOpenedBefore(ctx context.Context, projectID string, t time.Time) error {
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if m.openedBefore(projectID, t) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) openedBefore(projectID string, t time.Time) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for _, session := range m.sessions {
+		if session.ProjectID == projectID && session.startedAt.Before(t) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Get returns an open session by ID
+func (m *Manager) Get(txID string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[txID]
+	if !ok {
+		return nil, ErrTxNotFound
+	}
+
+	return session, nil
+}
+
+// Execute routes a statement and runs it inside the session. The routing
+// plan is returned for auditing, also when the router rejected the statement.
+func (m *Manager) Execute(
+	ctx context.Context,
+	txID string,
+	sqlText string,
+) ([]executor.ExecutionResult, *router.RoutingPlan, error) {
+
+	session, err := m.Get(txID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	defer func() {
+		session.lastActivity = time.Now()
+	}()
+
+	// completed concurrently by commit, rollback or idle timeout
+	if session.conns == nil {
+		return nil, nil, ErrTxNotFound
+	}
+
+	if session.failed {
+		return nil, nil, ErrTxAborted
+	}
+
+	plan, err := m.router.RouteSQL(ctx, session.ProjectID, sqlText)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if plan.Mode == router.RoutingModeRejected {
+		return nil, plan, plan.RejectError
+	}
+
+	results := make([]executor.ExecutionResult, 0, len(plan.Targets))
+
+	// shadow targets are skipped, a reshard job waits for transactions
+	// opened before its cutover and syncs the moving keys afterwards
+	for _, target := range plan.Targets {
+
+		shardID := string(target.ShardID)
+
+		conn, err := m.pin(ctx, session, shardID)
+		if err != nil {
+			session.failed = true
+			results = append(results, executor.ExecutionResult{
+				ShardID: shardID,
+				Kind:    plan.Kind,
+				Err:     err,
+			})
+			continue
+		}
+
+		result := executor.ExecuteOn(ctx, conn, shardID, sqlText, plan)
+		if result.Err != nil {
+			// postgres aborts the shard transaction on any error
+			session.failed = true
+		}
+
+		results = append(results, result)
+	}
+
+	return results, plan, nil
+}
+
+// pin acquires a dedicated connection for a shard and opens a transaction on it.
+// Caller must hold session.mu.
+func (m *Manager) pin(ctx context.Context, session *Session, shardID string) (*sql.Conn, error) {
+
+	if conn, ok := session.conns[shardID]; ok {
+		return conn, nil
+	}
+
+	db, err := m.connStore.Get(session.ProjectID, shardID)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.ExecContext(ctx, "BEGIN"); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("begin on shard %s: %w", shardID, err)
+	}
+
+	session.conns[shardID] = conn
+	session.order = append(session.order, shardID)
+
+	return conn, nil
+}
+
+// Commit completes the session. Single-shard sessions use a plain COMMIT,
+// multi-shard sessions escalate to two-phase commit.
+func (m *Manager) Commit(ctx context.Context, txID string) error {
+
+	session, err := m.take(txID)
+	if err != nil {
+		return err
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	defer releaseConns(session)
+
+	if session.failed {
+		rollbackAll(ctx, session)
+		return ErrTxAborted
+	}
+
+	switch len(session.order) {
+
+	case 0:
+		return nil
+
+	case 1:
+		shardID := session.order[0]
+		if _, err := session.conns[shardID].ExecContext(ctx, "COMMIT"); err != nil {
+			return fmt.Errorf("commit on shard %s: %w", shardID, err)
+		}
+
+	default:
+		if err := twoPhaseCommit(ctx, session); err != nil {
+			return err
+		}
+	}
+
+	logger.Logger.Info("transaction committed", "tx_id", session.ID, "shards", len(session.order))
+	return nil
+}
+
+// Rollback aborts the session on every pinned shard
+func (m *Manager) Rollback(ctx context.Context, txID string) error {
+
+	session, err := m.take(txID)
+	if err != nil {
+		return err
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	defer releaseConns(session)
+
+	rollbackAll(ctx, session)
+
+	logger.Logger.Info("transaction rolled back", "tx_id", session.ID)
+	return nil
+}
+
+// Run rolls back idle transactions until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			m.expireIdle(ctx)
+		}
+	}
+}
+
+// expireIdle rolls back sessions that exceeded the idle timeout
+func (m *Manager) expireIdle(ctx context.Context) {
+
+	m.mu.Lock()
+	expired := make([]string, 0)
+	for id, session := range m.sessions {
+		if session.mu.TryLock() {
+			idle := time.Since(session.lastActivity) > m.idleTimeout
+			session.mu.Unlock()
+
+			if idle {
+				expired = append(expired, id)
+			}
+		}
+	}
+	m.mu.Unlock()
+
+	for _, id := range expired {
+		logger.Logger.Warn("transaction idle timeout", "tx_id", id)
+		_ = m.Rollback(ctx, id)
+	}
+}
+
+// Close aborts every open session, used on shutdown
+func (m *Manager) Close(ctx context.Context) {
+
+	m.mu.Lock()
+	ids := make([]string, 0, len(m.sessions))
+	for id := range m.sessions {
+		ids = append(ids, id)
+	}
+	m.mu.Unlock()
+
+	for _, id := range ids {
+		_ = m.Rollback(ctx, id)
+	}
+}
+
+// take removes a session from the registry so it cannot be used concurrently with completion
+func (m *Manager) take(txID string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[txID]
+	if !ok {
+		return nil, ErrTxNotFound
+	}
+
+	delete(m.sessions, txID)
+	return session, nil
+}
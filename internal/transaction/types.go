@@ -0,0 +1,50 @@
+package transaction
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// errors surfaced to API callers
+var (
+	ErrTxNotFound = errors.New("transaction not found")
+	ErrTxAborted  = errors.New("transaction aborted, rollback required")
+)
+
+// TxState describes the lifecycle of a session transaction
+type TxState string
+
+const (
+	TxStateActive     TxState = "active"
+	TxStateCommitted  TxState = "committed"
+	TxStateRolledBack TxState = "rolled_back"
+)
+
+// Session is a client transaction spanning one or more shards.
+// Each touched shard pins a dedicated connection for the lifetime of the session.
+type Session struct {
+	ID        string
+	ProjectID string
+
+	// API key that began the session, empty for internal callers
+	Owner string
+
+	mu           sync.Mutex
+	conns        map[string]*sql.Conn
+	order        []string
+	failed       bool
+	startedAt    time.Time
+	lastActivity time.Time
+}
+
+// Shards returns the shard IDs pinned by the session in the order they were touched
+func (s *Session) Shards() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]string, len(s.order))
+	copy(out, s.order)
+	return out
+}
@@ -0,0 +1,40 @@
+-- =========================================
+-- API keys for the HTTP API
+-- =========================================
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY,
+
+    -- NULL for admin keys, which are not bound to a project
+    project_id UUID,
+
+    name TEXT NOT NULL,
+    role TEXT NOT NULL,
+
+    -- leading characters of the secret, safe to display
+    key_prefix TEXT NOT NULL,
+
+    -- SHA-256 of the secret, the secret itself is never stored
+    key_hash TEXT NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    rotated_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+
+    CONSTRAINT fk_api_keys_project
+        FOREIGN KEY (project_id)
+        REFERENCES projects(id)
+        ON DELETE CASCADE,
+
+    CONSTRAINT chk_api_keys_role
+        CHECK (role IN ('read_only', 'read_write', 'admin')),
+
+    CONSTRAINT chk_api_keys_scope
+        CHECK ((role = 'admin') = (project_id IS NULL)),
+
+    CONSTRAINT uq_api_keys_hash
+        UNIQUE (key_hash)
+);
+
+CREATE INDEX idx_api_keys_project
+    ON api_keys(project_id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS audit_log;
+
+ALTER TABLE api_keys
+    DROP COLUMN IF EXISTS deny_broadcast_writes,
+    DROP COLUMN IF EXISTS allowed_tables;
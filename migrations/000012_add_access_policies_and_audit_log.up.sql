@@ -0,0 +1,32 @@
+-- =========================================
+-- Statement-level access policy per API key
+-- =========================================
+ALTER TABLE api_keys
+    -- NULL allows every table
+    ADD COLUMN allowed_tables TEXT[],
+    ADD COLUMN deny_broadcast_writes BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- =========================================
+-- Audit log
+-- =========================================
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    event_type TEXT NOT NULL,
+
+    -- no foreign key, records outlive the projects they refer to
+    project_id UUID,
+
+    -- caller, e.g. an API key ID
+    actor TEXT NOT NULL DEFAULT '',
+
+    statement TEXT NOT NULL DEFAULT '',
+    detail TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_audit_log_project_time
+    ON audit_log(project_id, occurred_at);
+
+CREATE INDEX idx_audit_log_type_time
+    ON audit_log(event_type, occurred_at);
@@ -0,0 +1,5 @@
+ALTER TABLE api_keys
+    DROP COLUMN IF EXISTS tenant_id;
+
+ALTER TABLE projects
+    DROP COLUMN IF EXISTS tenant_column;
@@ -0,0 +1,10 @@
+-- =========================================
+-- Tenant isolation
+-- =========================================
+ALTER TABLE projects
+    -- column carrying the tenant of a row, NULL disables isolation
+    ADD COLUMN tenant_column TEXT;
+
+ALTER TABLE api_keys
+    -- tenant a key is bound to, NULL lets the caller pass one per request
+    ADD COLUMN tenant_id TEXT;
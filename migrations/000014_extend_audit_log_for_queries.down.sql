@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_audit_log_project_fingerprint;
+DROP INDEX IF EXISTS idx_audit_log_time;
+
+ALTER TABLE audit_log
+    DROP COLUMN IF EXISTS error,
+    DROP COLUMN IF EXISTS rows_affected,
+    DROP COLUMN IF EXISTS duration_ms,
+    DROP COLUMN IF EXISTS target_shards,
+    DROP COLUMN IF EXISTS routing_mode,
+    DROP COLUMN IF EXISTS normalized,
+    DROP COLUMN IF EXISTS fingerprint;
@@ -0,0 +1,22 @@
+-- =========================================
+-- Query audit
+-- =========================================
+ALTER TABLE audit_log
+    -- pg_query fingerprint, equal for statements differing only in constants
+    ADD COLUMN fingerprint TEXT NOT NULL DEFAULT '',
+    -- statement with constants replaced by placeholders
+    ADD COLUMN normalized TEXT NOT NULL DEFAULT '',
+
+    ADD COLUMN routing_mode TEXT NOT NULL DEFAULT '',
+    ADD COLUMN target_shards UUID[],
+
+    ADD COLUMN duration_ms DOUBLE PRECISION,
+    ADD COLUMN rows_affected BIGINT,
+    ADD COLUMN error TEXT NOT NULL DEFAULT '';
+
+-- retention deletes by age
+CREATE INDEX idx_audit_log_time
+    ON audit_log(occurred_at);
+
+CREATE INDEX idx_audit_log_project_fingerprint
+    ON audit_log(project_id, fingerprint);
@@ -0,0 +1,15 @@
+-- =========================================
+-- Imported workload for shard key inference
+-- =========================================
+CREATE TABLE workload_queries (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+
+    -- pg_query fingerprint, imports of the same statement add up
+    fingerprint TEXT NOT NULL,
+    query TEXT NOT NULL,
+    calls BIGINT NOT NULL DEFAULT 1,
+
+    imported_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (project_id, fingerprint)
+);
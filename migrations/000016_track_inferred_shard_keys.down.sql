@@ -0,0 +1,3 @@
+ALTER TABLE table_shard_keys
+    DROP COLUMN IF EXISTS inferred_at,
+    DROP COLUMN IF EXISTS inferred_column;
@@ -0,0 +1,13 @@
+-- =========================================
+-- Inferred keys next to manual overrides
+-- =========================================
+ALTER TABLE table_shard_keys
+    -- column the latest inference run chose, kept even when an
+    -- override pins another column so conflicts can be reported
+    ADD COLUMN inferred_column TEXT,
+    ADD COLUMN inferred_at TIMESTAMP;
+
+UPDATE table_shard_keys
+SET inferred_column = shard_key_column,
+    inferred_at = updated_at
+WHERE is_manual_override = FALSE;
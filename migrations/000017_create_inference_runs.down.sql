@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS inference_candidates;
+DROP TABLE IF EXISTS inference_runs;
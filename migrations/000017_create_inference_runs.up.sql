@@ -0,0 +1,32 @@
+-- =========================================
+-- Shard key inference runs
+-- =========================================
+CREATE TABLE inference_runs (
+    id UUID PRIMARY KEY,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+
+    -- statements of the workload the run weighed, 0 for schema only runs
+    workload_statements BIGINT NOT NULL DEFAULT 0,
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_inference_runs_project_created
+    ON inference_runs (project_id, created_at DESC);
+
+-- every ranked candidate of every table, best first
+CREATE TABLE inference_candidates (
+    run_id UUID NOT NULL REFERENCES inference_runs(id) ON DELETE CASCADE,
+    table_name TEXT NOT NULL,
+    rank INT NOT NULL,
+
+    column_name TEXT NOT NULL,
+    score INT NOT NULL,
+    reasons TEXT[] NOT NULL DEFAULT '{}',
+
+    -- the candidate the run chose, not always rank 1 when the table
+    -- inherits the key of its co-location group
+    selected BOOLEAN NOT NULL DEFAULT FALSE,
+
+    PRIMARY KEY (run_id, table_name, rank)
+);
@@ -0,0 +1,21 @@
+-- =========================================
+-- Column statistics for shard key inference
+-- =========================================
+CREATE TABLE column_statistics (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    table_name TEXT NOT NULL,
+    column_name TEXT NOT NULL,
+
+    -- estimated distinct values, null share and share of the most common value
+    n_distinct DOUBLE PRECISION NOT NULL,
+    null_frac DOUBLE PRECISION NOT NULL DEFAULT 0,
+    top_frequency DOUBLE PRECISION NOT NULL DEFAULT 0,
+    row_count BIGINT NOT NULL DEFAULT 0,
+
+    -- pg_stats of the shards or an uploaded sample
+    source TEXT NOT NULL CHECK (source IN ('pg_stats', 'sample')),
+    shard_count INT NOT NULL DEFAULT 0,
+    collected_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (project_id, table_name, column_name)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE inference_runs
+    DROP COLUMN IF EXISTS profile;
+
+DROP TABLE IF EXISTS inference_profiles;
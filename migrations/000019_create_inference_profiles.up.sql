@@ -0,0 +1,31 @@
+-- =========================================
+-- Per project shard key inference profile
+-- =========================================
+CREATE TABLE inference_profiles (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+
+    -- score weights, penalties are stored as positive magnitudes
+    incoming_fk_weight INT NOT NULL,
+    referencing_table_weight INT NOT NULL,
+    foreign_key_weight INT NOT NULL,
+    root_affinity_weight INT NOT NULL,
+    primary_key_weight INT NOT NULL,
+    textual_penalty INT NOT NULL,
+    local_weight INT NOT NULL,
+    workload_predicate_weight INT NOT NULL,
+    workload_join_weight INT NOT NULL,
+    skew_penalty INT NOT NULL,
+    preferred_column_weight INT NOT NULL,
+
+    -- column name globs eliminated on top of the built-in rules
+    eliminated_patterns TEXT[] NOT NULL DEFAULT '{}',
+
+    -- column name globs earning the preferred column weight
+    preferred_columns TEXT[] NOT NULL DEFAULT '{}',
+
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- the profile a run scored with, NULL for runs before profiles existed
+ALTER TABLE inference_runs
+    ADD COLUMN profile JSONB;
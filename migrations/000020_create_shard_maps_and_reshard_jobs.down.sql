@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS reshard_checkpoints;
+DROP TABLE IF EXISTS reshard_moves;
+DROP TABLE IF EXISTS reshard_jobs;
+DROP TABLE IF EXISTS shard_maps;
@@ -0,0 +1,79 @@
+-- =========================================
+-- Hash slot ownership of a project
+-- =========================================
+CREATE TABLE shard_maps (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+
+    -- owners[i] is the shard holding keys hashing to slot i - 1
+    owners UUID[] NOT NULL,
+
+    -- bumped on every ownership change
+    version BIGINT NOT NULL DEFAULT 1,
+
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- =========================================
+-- Resharding jobs
+-- =========================================
+CREATE TABLE reshard_jobs (
+    id UUID PRIMARY KEY,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+
+    kind TEXT NOT NULL CHECK (kind IN ('rebalance')),
+
+    -- the shard the job moves keys to or from, kept after the shard is deleted
+    shard_id UUID NOT NULL,
+
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'failed', 'cancelled', 'completed')),
+    phase TEXT NOT NULL DEFAULT 'planned'
+        CHECK (phase IN ('planned', 'copying', 'catching_up', 'cutover', 'cleanup', 'done')),
+
+    -- shard map version the moves were planned against
+    map_version BIGINT NOT NULL,
+
+    error TEXT,
+
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP
+);
+
+-- one unfinished job per project
+CREATE UNIQUE INDEX idx_reshard_jobs_project_unfinished
+    ON reshard_jobs (project_id)
+    WHERE status IN ('pending', 'running', 'failed');
+
+CREATE INDEX idx_reshard_jobs_project_created
+    ON reshard_jobs (project_id, created_at DESC);
+
+-- hash slots changing owner
+CREATE TABLE reshard_moves (
+    job_id UUID NOT NULL REFERENCES reshard_jobs(id) ON DELETE CASCADE,
+    slot INT NOT NULL,
+
+    source_shard_id UUID NOT NULL,
+    target_shard_id UUID NOT NULL,
+
+    PRIMARY KEY (job_id, slot)
+);
+
+-- resumable progress of every table pass on every shard
+CREATE TABLE reshard_checkpoints (
+    job_id UUID NOT NULL REFERENCES reshard_jobs(id) ON DELETE CASCADE,
+    shard_id UUID NOT NULL,
+    table_name TEXT NOT NULL,
+    phase TEXT NOT NULL,
+
+    -- primary key of the last row handled, as text
+    last_key TEXT[],
+
+    rows_scanned BIGINT NOT NULL DEFAULT 0,
+    rows_written BIGINT NOT NULL DEFAULT 0,
+    done BOOLEAN NOT NULL DEFAULT FALSE,
+
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (job_id, shard_id, table_name, phase)
+);
@@ -0,0 +1,26 @@
+BEGIN;
+
+-- 1. Remove drain jobs
+DELETE FROM reshard_jobs
+WHERE kind = 'drain';
+
+ALTER TABLE reshard_jobs
+DROP CONSTRAINT IF EXISTS reshard_jobs_kind_check;
+
+ALTER TABLE reshard_jobs
+ADD CONSTRAINT reshard_jobs_kind_check
+CHECK (kind IN ('rebalance'));
+
+-- 2. Convert 'draining' shards back to 'inactive'
+UPDATE shards
+SET status = 'inactive'
+WHERE status = 'draining';
+
+ALTER TABLE shards
+DROP CONSTRAINT IF EXISTS chk_shard_status;
+
+ALTER TABLE shards
+ADD CONSTRAINT chk_shard_status
+CHECK (status IN ('active', 'inactive'));
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+-- 1. Allow shards to drain their hash slots before they are deleted
+ALTER TABLE shards
+DROP CONSTRAINT IF EXISTS chk_shard_status;
+
+ALTER TABLE shards
+ADD CONSTRAINT chk_shard_status
+CHECK (status IN ('active', 'inactive', 'draining'));
+
+-- 2. Allow drain jobs
+ALTER TABLE reshard_jobs
+DROP CONSTRAINT IF EXISTS reshard_jobs_kind_check;
+
+ALTER TABLE reshard_jobs
+ADD CONSTRAINT reshard_jobs_kind_check
+CHECK (kind IN ('rebalance', 'drain'));
+
+COMMIT;
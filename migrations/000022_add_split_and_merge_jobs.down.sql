@@ -0,0 +1,14 @@
+BEGIN;
+
+-- Remove split and merge jobs
+DELETE FROM reshard_jobs
+WHERE kind IN ('split', 'merge');
+
+ALTER TABLE reshard_jobs
+DROP CONSTRAINT IF EXISTS reshard_jobs_kind_check;
+
+ALTER TABLE reshard_jobs
+ADD CONSTRAINT reshard_jobs_kind_check
+CHECK (kind IN ('rebalance', 'drain'));
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+-- Allow split and merge jobs
+ALTER TABLE reshard_jobs
+DROP CONSTRAINT IF EXISTS reshard_jobs_kind_check;
+
+ALTER TABLE reshard_jobs
+ADD CONSTRAINT reshard_jobs_kind_check
+CHECK (kind IN ('rebalance', 'drain', 'split', 'merge'));
+
+COMMIT;
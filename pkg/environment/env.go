@@ -1,18 +1,23 @@
 package environment
 
 import (
+	"errors"
+	"io/fs"
 	"os"
 	"sql-sharding-v2/internal/config"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 func LoadEnv() error {
 	err := godotenv.Load()
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return err
 	}
 
+	// without a .env file the process environment is used as is
 	return nil
 }
 
@@ -22,4 +27,63 @@ func LoadEnvVariables() {
 	config.ApplicationDatabaseCredentials.DB_PASS = os.Getenv("DB_PASSWORD")
 	config.ApplicationDatabaseCredentials.DB_PORT = os.Getenv("DB_PORT")
 	config.ApplicationDatabaseCredentials.DB_USER = os.Getenv("DB_USER")
+
+	config.ApplicationServerSettings.HTTP_ADDR = getEnvDefault("HTTP_ADDR", ":8080")
+	config.ApplicationServerSettings.PGWIRE_ADDR = getEnvDefault("PGWIRE_ADDR", ":5433")
+	config.ApplicationServerSettings.MIGRATIONS_PATH = os.Getenv("MIGRATIONS_PATH")
+	config.ApplicationServerSettings.API_AUTH = os.Getenv("API_AUTH") != "disabled"
+
+	config.AuditSettings.BUFFER_SIZE = getEnvInt("AUDIT_BUFFER_SIZE", 10000)
+	config.AuditSettings.BATCH_SIZE = getEnvInt("AUDIT_BATCH_SIZE", 200)
+	config.AuditSettings.FLUSH_INTERVAL = getEnvDuration("AUDIT_FLUSH_INTERVAL", 2*time.Second)
+	config.AuditSettings.RETENTION = time.Duration(getEnvInt("AUDIT_RETENTION_DAYS", 30)) * 24 * time.Hour
+
+	config.TracingSettings.OTLP_ENDPOINT = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	config.TracingSettings.SERVICE_NAME = getEnvDefault("OTEL_SERVICE_NAME", "sql-sharding")
+
+	config.QueryStatsSettings.SLOW_THRESHOLD = getEnvDuration("SLOW_QUERY_THRESHOLD", 500*time.Millisecond)
+	config.QueryStatsSettings.SLOW_LOG_PATH = os.Getenv("SLOW_QUERY_LOG")
+	config.QueryStatsSettings.MAX_FINGERPRINTS = getEnvInt("QUERY_STATS_MAX_FINGERPRINTS", 5000)
+
+	config.SkewSettings.INTERVAL = getEnvDuration("SKEW_CHECK_INTERVAL", time.Minute)
+	config.SkewSettings.THRESHOLD = getEnvFloat("SKEW_THRESHOLD", 0.3)
+	config.SkewSettings.HOT_KEY_SHARE = getEnvFloat("HOT_KEY_SHARE", 0.2)
+
+	config.ReshardSettings.BATCH_SIZE = getEnvInt("RESHARD_BATCH_SIZE", 500)
+	config.ReshardSettings.CUTOVER_TIMEOUT = getEnvDuration("RESHARD_CUTOVER_TIMEOUT", time.Minute)
+}
+
+// helper to read an optional variable
+func getEnvDefault(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// helper to read an optional integer, invalid values fall back
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
+
+// helper to read an optional positive number, invalid values fall back
+func getEnvFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// helper to read an optional duration such as 500ms or 2s, invalid values fall back
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
 }
@@ -1,10 +1,7 @@
 package logger
 
 import (
-	"context"
 	"time"
-
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 type Level string
@@ -23,16 +20,38 @@ type LogEvent struct {
 	Fields    map[string]string `json:"fields,omitempty"`
 }
 
+// EventSink receives named application events, e.g. the desktop runtime
+// or the structured logger in headless mode.
+type EventSink interface {
+	Emit(name string, payload any)
+}
+
+// SlogSink writes events to the structured logger
+type SlogSink struct{}
+
+func NewSlogSink() SlogSink {
+	return SlogSink{}
+}
+
+func (SlogSink) Emit(name string, payload any) {
+	if event, ok := payload.(LogEvent); ok {
+		// log:event payloads are already written by callers through Logger
+		Logger.Debug(event.Message, "event", name, "source", event.Source, "level", event.Level)
+		return
+	}
+	Logger.Info("event", "event", name, "payload", payload)
+}
+
 type LogEmitter struct {
-	ctx context.Context
+	sink EventSink
 }
 
-func NewLogEmitter(ctx context.Context) *LogEmitter {
-	return &LogEmitter{ctx: ctx}
+func NewLogEmitter(sink EventSink) *LogEmitter {
+	return &LogEmitter{sink: sink}
 }
 
 func (e *LogEmitter) emit(level Level, msg string, source string, fields map[string]string) {
-	runtime.EventsEmit(e.ctx, "log:event", LogEvent{
+	e.sink.Emit("log:event", LogEvent{
 		Level:     level,
 		Message:   msg,
 		Source:    source,
@@ -53,3 +72,8 @@ func (e *LogEmitter) Warn(msg, source string, fields map[string]string) {
 func (e *LogEmitter) Error(msg, source string, fields map[string]string) {
 	e.emit(ERROR, msg, source, fields)
 }
+
+// Event publishes a named event with an arbitrary payload
+func (e *LogEmitter) Event(name string, payload any) {
+	e.sink.Emit(name, payload)
+}